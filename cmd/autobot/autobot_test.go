@@ -6,13 +6,24 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"maps"
+	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/cosnicolaou/automation/cmd/autobot/internal/webapi"
 	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/internal/logging"
 	"github.com/cosnicolaou/automation/internal/testutil"
+	"github.com/cosnicolaou/automation/scheduler"
+	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -27,10 +38,32 @@ var (
 			md := testutil.NewMockDevice("On", "Off", "Another")
 			md.AddCondition("weather", true)
 			md.SetOutput(true)
+			md.SetOperationDoc("on", devices.Doc{
+				Description: "turn the device on",
+				Params:      []devices.ParamDoc{{Name: "level", Description: "brightness"}},
+				Examples:    []string{"device.on(50)"},
+			})
 			return md, nil
+		},
+		"failing-device": func(string, devices.Options) (devices.Device, error) {
+			return &failingDevice{MockDevice: testutil.NewMockDevice("On")}, nil
 		}}
 )
 
+// failingDevice is a mock device with an additional operation, "broken",
+// that always fails.
+type failingDevice struct {
+	*testutil.MockDevice
+}
+
+func (d *failingDevice) Operations() map[string]devices.Operation {
+	ops := maps.Clone(d.MockDevice.Operations())
+	ops["broken"] = func(context.Context, devices.OperationArgs) (any, error) {
+		return nil, errors.New("broken")
+	}
+	return ops
+}
+
 func init() {
 	maps.Insert(devices.AvailableControllers,
 		maps.All(supportedTestControllers))
@@ -38,6 +71,224 @@ func init() {
 		maps.All(supportedTestDevices))
 }
 
+func TestCLI(t *testing.T) {
+	// cli panics if any of the command specs or flag tags are invalid.
+	cli()
+}
+
+func TestConfigTypes(t *testing.T) {
+	ctx := context.Background()
+	devices.RegisterDeviceType(devices.TypeInfo{
+		Type:        "described-device",
+		Description: "a device with a description",
+		Vendor:      "acme",
+	}, supportedTestDevices["mock-device"])
+	defer delete(devices.AvailableDevices, "described-device")
+
+	var out strings.Builder
+	config := &Config{out: &out}
+	if err := config.Types(ctx, &ConfigTypesFlags{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	o := out.String()
+	for _, s := range []string{
+		"Controller Types",
+		"mock-controller",
+		"Device Types",
+		"mock-device",
+		"failing-device",
+		"a device with a description",
+		"acme",
+	} {
+		if !strings.Contains(o, s) {
+			t.Errorf("failed to find %q in output: %v", s, o)
+		}
+	}
+}
+
+func TestConfigSun(t *testing.T) {
+	ctx := context.Background()
+	systemFile := filepath.Join(t.TempDir(), "system.yaml")
+	if err := os.WriteFile(systemFile, []byte(`time_location: America/Los_Angeles
+latitude: 37.3547
+longitude: -122.0862
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	config := &Config{out: &out}
+	fl := &ConfigSunFlags{
+		ConfigFlags: ConfigFlags{
+			ConfigFileFlags: ConfigFileFlags{
+				SystemFile: systemFile,
+				KeysFile:   filepath.Join("testdata", "keys.yaml"),
+			},
+		},
+		Date: "06/21/2025",
+	}
+	if err := config.Sun(ctx, fl, nil); err != nil {
+		t.Fatal(err)
+	}
+	o := out.String()
+	var sunset string
+	for _, line := range strings.Split(o, "\n") {
+		if f := strings.Fields(line); len(f) == 2 && f[0] == "sunset:" {
+			sunset = f[1]
+		}
+	}
+	got, err := time.Parse(time.TimeOnly, sunset)
+	if err != nil {
+		t.Fatalf("failed to find sunset in output: %v: %v", err, o)
+	}
+	// Sunset in Los Altos, CA on the summer solstice in 2025 is at 20:33.
+	want := time.Date(0, 1, 1, 20, 33, 0, 0, time.UTC)
+	if diff := got.Sub(want).Abs(); diff > time.Minute {
+		t.Errorf("got %v, want %v: %v", got, want, o)
+	}
+	if !strings.Contains(o, "sunrise:") || strings.Index(o, "sunrise:") > strings.Index(o, "sunset:") {
+		t.Errorf("sunrise is missing or not displayed before sunset: %v", o)
+	}
+}
+
+func TestConfigInit(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	fl := &ConfigInitFlags{
+		ConfigFileFlags: ConfigFileFlags{
+			SystemFile:   filepath.Join(tmpDir, "system.yaml"),
+			ScheduleFile: filepath.Join(tmpDir, "schedule.yaml"),
+		},
+	}
+	var out strings.Builder
+	config := &Config{out: &out}
+	if err := config.Init(ctx, fl, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := os.ReadFile(fl.SystemFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	system, err := devices.ParseSystemConfig(ctx, buf,
+		devices.WithDevices(devices.SupportedDevices{"noop": devices.NewNoopDevice}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err = os.ReadFile(fl.ScheduleFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schedules, err := scheduler.ParseConfig(ctx, buf, system)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(schedules.Schedules), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := len(schedules.Schedules[0].DailyActions), 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	err = config.Init(ctx, fl, nil)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+	fl.Force = true
+	if err := config.Init(ctx, fl, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigLint(t *testing.T) {
+	ctx := context.Background()
+	scheduleFile := filepath.Join(t.TempDir(), "schedule.yaml")
+	if err := os.WriteFile(scheduleFile, []byte(`schedules:
+  - name: lights-on
+    device: device
+    ranges:
+      - 01/01:01/31
+    actions:
+      on: 18:00
+  - name: lights-off
+    device: device
+    ranges:
+      - 01/01:01/31
+    actions:
+      off: 18:00
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	config := &Config{out: &out}
+	fl := &ConfigLintFlags{
+		ConfigFlags: ConfigFlags{
+			ConfigFileFlags: ConfigFileFlags{
+				SystemFile:   filepath.Join("testdata", "system.yaml"),
+				KeysFile:     filepath.Join("testdata", "keys.yaml"),
+				ScheduleFile: scheduleFile,
+			},
+		},
+		Year: 2025,
+	}
+	err := config.Lint(ctx, fl, nil)
+	if err == nil || err.Error() != "found 1 conflicting actions" {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+	if got, want := out.String(), "conflict: device: lights-off.off and lights-on.on are due within the same minute, first at 01/01/2025 18:00, 31 times\n"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	fl.ScheduleFile = filepath.Join("testdata", "schedule.yaml")
+	out.Reset()
+	if err := config.Lint(ctx, fl, nil); err != nil {
+		t.Errorf("%v: %v", err, out.String())
+	}
+}
+
+func TestConfigCheckKeys(t *testing.T) {
+	ctx := context.Background()
+	systemFile := filepath.Join(t.TempDir(), "system.yaml")
+	if err := os.WriteFile(systemFile, []byte(`time_location: Local
+controllers:
+  - name: present
+    type: mock-controller
+    key_id: key1
+  - name: absent
+    type: mock-controller
+    key_id: absent-key
+devices:
+  - name: device
+    type: mock-device
+    controller: present
+    operations:
+      on: [$secret:key1]
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	config := &Config{out: &out}
+	fl := &ConfigFlags{
+		ConfigFileFlags: ConfigFileFlags{
+			SystemFile: systemFile,
+			KeysFile:   filepath.Join("testdata", "keys.yaml"),
+		},
+	}
+	err := config.CheckKeys(ctx, fl, nil)
+	if err == nil || err.Error() != "missing keys: absent-key" {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if got, want := len(lines), 2; got != want {
+		t.Fatalf("got %v, want %v: %v", got, want, out.String())
+	}
+	if got, want := strings.Fields(lines[0]), []string{"absent-key", "missing", "controller", "absent"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := strings.Fields(lines[1]), []string{"key1", "ok", "controller", "present,", "device", "device"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
 func TestConfig(t *testing.T) {
 	ctx := context.Background()
 	var out strings.Builder
@@ -68,3 +319,639 @@ func TestConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestConfigEffective(t *testing.T) {
+	ctx := context.Background()
+	systemFile := filepath.Join(t.TempDir(), "system.yaml")
+	if err := os.WriteFile(systemFile, []byte(`time_location: America/Los_Angeles
+controllers:
+  - name: controller
+    type: mock-controller
+    login:
+      key_id: key1
+      steps:
+        - send: "{user}"
+        - send: not-a-placeholder
+          sensitive: true
+devices:
+  - name: device
+    type: mock-device
+    controller: controller
+    operations:
+      on:
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	effective := func(tz string) effectiveSystem {
+		var out strings.Builder
+		config := &Config{out: &out}
+		fl := &ConfigFlags{
+			ConfigFileFlags: ConfigFileFlags{
+				SystemFile:       systemFile,
+				KeysFile:         filepath.Join("testdata", "keys.yaml"),
+				SystemTZLocation: tz,
+			},
+		}
+		if err := config.Effective(ctx, fl, nil); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(out.String(), "not-a-placeholder") {
+			t.Errorf("sensitive login step was not redacted: %v", out.String())
+		}
+		var es effectiveSystem
+		if err := yaml.Unmarshal([]byte(out.String()), &es); err != nil {
+			t.Fatal(err)
+		}
+		return es
+	}
+
+	es := effective("")
+	if got, want := es.Location.TimeLocation, "America/Los_Angeles"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := len(es.Controllers), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := es.Controllers[0].Login.Steps[1].Send, "<redacted>"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := es.Controllers[0].Login.Steps[0].Send, "{user}"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := len(es.Devices), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := es.Devices[0].Controller, "controller"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// The timezone specified on the command line overrides the configured one.
+	if got, want := effective("Europe/London").Location.TimeLocation, "Europe/London"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestConfigHelp(t *testing.T) {
+	ctx := context.Background()
+	var out strings.Builder
+	config := &Config{out: &out}
+	fl := &ConfigFlags{
+		ConfigFileFlags: ConfigFileFlags{
+			SystemFile: filepath.Join("testdata", "system.yaml"),
+			KeysFile:   filepath.Join("testdata", "keys.yaml"),
+		},
+	}
+	if err := config.Help(ctx, fl, []string{"device.on"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "## device.on\n\nturn the device on\n\n### Parameters\n\n- `level`: brightness\n\n### Examples\n\n```\ndevice.on(50)\n```\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	out.Reset()
+	if err := config.Help(ctx, fl, []string{"device.off"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "## device.off\n\nOff operation\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	for _, arg := range []string{"device", "device.unknown", "unknown.on"} {
+		if err := config.Help(ctx, fl, []string{arg}); err == nil {
+			t.Errorf("%q: expected an error", arg)
+		}
+	}
+}
+
+func TestConfigOperations(t *testing.T) {
+	ctx := context.Background()
+	var out strings.Builder
+	config := &Config{out: &out}
+	fl := &ConfigOperationsFlags{
+		ConfigFlags: ConfigFlags{
+			ConfigFileFlags: ConfigFileFlags{
+				SystemFile: filepath.Join("testdata", "system.yaml"),
+				KeysFile:   filepath.Join("testdata", "keys.yaml"),
+			},
+		},
+		Format: "json",
+	}
+	if err := config.Operations(ctx, fl, nil); err != nil {
+		t.Fatal(err)
+	}
+	var so webapi.SystemOperations
+	if err := json.Unmarshal([]byte(out.String()), &so); err != nil {
+		t.Fatal(err)
+	}
+	names := func(nos []webapi.NamedOperations) []string {
+		var n []string
+		for _, no := range nos {
+			n = append(n, no.Name)
+		}
+		return n
+	}
+	if got, want := names(so.Controllers), []string{"controller"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := names(so.Devices), []string{"device", "other-device"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := names(so.Conditions), []string{"device", "other-device"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := so.Controllers[0].Operations[0], (webapi.OperationInfo{Name: "disable", Help: "disable the controller"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	var ops []string
+	for _, op := range so.Devices[0].Operations {
+		ops = append(ops, fmt.Sprintf("%v:%v:%v", op.Name, op.Help, op.Configured))
+	}
+	if got, want := ops, []string{"another:Another operation:true", "off:Off operation:true", "on:On operation:true"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := so.Conditions[0].Operations[0].Name, "weather"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	out.Reset()
+	fl.Format = "table"
+	if err := config.Operations(ctx, fl, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "| other-device |") {
+		t.Errorf("missing device in table output: %v", out.String())
+	}
+
+	fl.Format = "yaml"
+	if err := config.Operations(ctx, fl, nil); err == nil || !strings.Contains(err.Error(), "unsupported format") {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+}
+
+func TestConfigUses(t *testing.T) {
+	ctx := context.Background()
+	var out strings.Builder
+	config := &Config{out: &out}
+	fl := &ConfigFlags{
+		ConfigFileFlags: ConfigFileFlags{
+			SystemFile:   filepath.Join("testdata", "system.yaml"),
+			KeysFile:     filepath.Join("testdata", "keys.yaml"),
+			ScheduleFile: filepath.Join("testdata", "schedule.yaml"),
+		},
+	}
+	if err := config.Uses(ctx, fl, []string{"device"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"precondition-not-sunny: operation: on at 00:01:00\n",
+		"precondition-not-sunny: precondition: !weather(sunny) for another at 00:03:00\n",
+	} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("failed to find %q in output: %v", s, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := config.Uses(ctx, fl, []string{"controller"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "controller is not used by any schedule\n"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if err := config.Uses(ctx, fl, []string{"unknown"}); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestSchedulePrintMaxSpan(t *testing.T) {
+	ctx := context.Background()
+	var out strings.Builder
+	schedule := &Schedule{out: &out}
+	fl := &SchedulePrintFlags{
+		ConfigFileFlags: ConfigFileFlags{
+			SystemFile:   filepath.Join("testdata", "system.yaml"),
+			ScheduleFile: filepath.Join("testdata", "schedule.yaml"),
+			KeysFile:     filepath.Join("testdata", "keys.yaml"),
+		},
+		DateRange: "01/01/2025:01/31/2025",
+		MaxSpan:   scheduler.DefaultMaxCalendarSpan,
+	}
+	if err := schedule.Print(ctx, fl, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "01/02/2025") {
+		t.Errorf("missing calendar entries: %v", out.String())
+	}
+
+	fl.DateRange = "01/01/2025:12/31/2075"
+	err := schedule.Print(ctx, fl, nil)
+	if err == nil || !strings.Contains(err.Error(), "exceeds the maximum of 1098 days") {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+}
+
+func TestScheduleNameArgs(t *testing.T) {
+	ctx := context.Background()
+	var out strings.Builder
+	schedule := &Schedule{out: &out}
+	fl := &SchedulePrintFlags{
+		ConfigFileFlags: ConfigFileFlags{
+			SystemFile:   filepath.Join("testdata", "system.yaml"),
+			ScheduleFile: filepath.Join("testdata", "schedule.yaml"),
+			KeysFile:     filepath.Join("testdata", "keys.yaml"),
+		},
+		DateRange: "01/01/2025:01/02/2025",
+	}
+	if err := schedule.Print(ctx, fl, []string{" other-device "}); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); !strings.Contains(got, "other-device") || strings.Contains(got, "simple") {
+		t.Errorf("unexpected output: %v", got)
+	}
+
+	err := schedule.Print(ctx, fl, []string{"simple", "not-a-schedule"})
+	if err == nil || !strings.Contains(err.Error(), `unknown schedule: "not-a-schedule"`) {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+}
+
+func TestSchedulePrintEvaluate(t *testing.T) {
+	ctx := context.Background()
+	fl := &SchedulePrintFlags{
+		ConfigFileFlags: ConfigFileFlags{
+			SystemFile:   filepath.Join("testdata", "system.yaml"),
+			ScheduleFile: filepath.Join("testdata", "schedule.yaml"),
+			KeysFile:     filepath.Join("testdata", "keys.yaml"),
+		},
+		Date: "01/02/2025",
+	}
+	render := func(evaluate bool, schedule string) string {
+		var out strings.Builder
+		fl.Evaluate = evaluate
+		if err := (&Schedule{out: &out}).Print(ctx, fl, []string{schedule}); err != nil {
+			t.Fatal(err)
+		}
+		return out.String()
+	}
+
+	if got := render(false, "precondition-sunny"); strings.Contains(got, "[pass]") || strings.Contains(got, "[fail]") {
+		t.Errorf("unexpected annotation: %v", got)
+	}
+	for _, tc := range []struct {
+		schedule, want, notWant string
+	}{
+		{"precondition-sunny", "if device.weather(sunny) [pass]", "[fail]"},
+		{"precondition-not-sunny", "if device.!weather(sunny) [fail]", "[pass]"},
+	} {
+		got := render(true, tc.schedule)
+		if !strings.Contains(got, tc.want) || strings.Contains(got, tc.notWant) {
+			t.Errorf("%v: missing or unexpected annotation: %v", tc.schedule, got)
+		}
+	}
+}
+
+func TestScheduleLoadProfile(t *testing.T) {
+	ctx := context.Background()
+	var out strings.Builder
+	schedule := &Schedule{out: &out}
+	fl := &ScheduleLoadProfileFlags{
+		ConfigFileFlags: ConfigFileFlags{
+			SystemFile:   filepath.Join("testdata", "system.yaml"),
+			ScheduleFile: filepath.Join("testdata", "schedule.yaml"),
+			KeysFile:     filepath.Join("testdata", "keys.yaml"),
+		},
+		Year: 2025,
+		JSON: true,
+	}
+	if err := schedule.LoadProfile(ctx, fl, nil); err != nil {
+		t.Fatal(err)
+	}
+	var loads []scheduler.ControllerLoad
+	if err := json.Unmarshal([]byte(out.String()), &loads); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(loads), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	l := loads[0]
+	if got, want := l.Controller, "controller"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if l.Actions == 0 || l.PeakConcurrent > l.PeakPerMinute || l.PeakPerMinute > l.PeakPerHour || l.PeakPerHour > l.Actions {
+		t.Errorf("inconsistent load profile: %+v", l)
+	}
+}
+
+func TestScheduleSimulateDiff(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	sched := `
+schedules:
+  - name: simple
+    device: device
+    ranges:
+      - 03/01:03/02
+    actions:
+      on: 10:00
+      off: 11:00
+`
+	oldFile, newFile := filepath.Join(tmpDir, "old.yaml"), filepath.Join(tmpDir, "new.yaml")
+	if err := os.WriteFile(oldFile, []byte(sched), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(newFile, []byte(strings.ReplaceAll(sched, "off: 11:00", "off: 11:15")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	schedule := &Schedule{out: &out}
+	fl := &ScheduleSimulateDiffFlags{
+		ConfigFileFlags: ConfigFileFlags{
+			SystemFile: filepath.Join("testdata", "system.yaml"),
+			KeysFile:   filepath.Join("testdata", "keys.yaml"),
+		},
+		DateRange: "03/01/2025:03/31/2025",
+		Delay:     time.Millisecond,
+	}
+	if err := schedule.SimulateDiff(ctx, fl, []string{oldFile, newFile}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), `03/01/2025 shifted simple:device.off() 11:00:00 -> 11:15:00
+03/02/2025 shifted simple:device.off() 11:00:00 -> 11:15:00
+`; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	out.Reset()
+	if err := schedule.SimulateDiff(ctx, fl, []string{oldFile, oldFile}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "no differences\n"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestScheduleDiff(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	sched := `
+schedules:
+  - name: simple
+    device: device
+    ranges:
+      - 03/01:03/02
+    actions:
+      on: 10:00
+      off: 11:00
+  - name: porch
+    device: other-device
+    ranges:
+      - 03/02:03/10
+    actions:
+      on: 18:00
+`
+	updated := strings.ReplaceAll(sched, "off: 11:00", "off: 11:15")
+	updated = strings.ReplaceAll(updated, "on: 18:00", "off: 18:00")
+	oldFile, newFile := filepath.Join(tmpDir, "old.yaml"), filepath.Join(tmpDir, "new.yaml")
+	if err := os.WriteFile(oldFile, []byte(sched), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(newFile, []byte(updated), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	schedule := &Schedule{out: &out}
+	fl := &ScheduleDiffFlags{
+		ConfigFileFlags: ConfigFileFlags{
+			SystemFile: filepath.Join("testdata", "system.yaml"),
+			KeysFile:   filepath.Join("testdata", "keys.yaml"),
+		},
+		DateRange: "03/01/2025:03/02/2025",
+	}
+	if err := schedule.Diff(ctx, fl, []string{oldFile, newFile}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), `porch:
+  03/02/2025 added   other-device.off() 18:00:00
+  03/02/2025 removed other-device.on() 18:00:00
+simple:
+  03/01/2025 moved   device.off() 11:00:00 -> 11:15:00
+  03/02/2025 moved   device.off() 11:00:00 -> 11:15:00
+`; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	out.Reset()
+	if err := schedule.Diff(ctx, fl, []string{oldFile, oldFile}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "no differences\n"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	fl.DateRange = "01/01/2025:12/31/2027"
+	fl.MaxSpan = 365
+	if err := schedule.Diff(ctx, fl, []string{oldFile, newFile}); err == nil || !strings.Contains(err.Error(), "exceeds the maximum") {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+}
+
+func TestSimulateMetrics(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	metricsFile := filepath.Join(tmpDir, "metrics.json")
+	fl := &SimulateFlags{
+		ConfigFileFlags: ConfigFileFlags{
+			SystemFile:   filepath.Join("testdata", "system.yaml"),
+			KeysFile:     filepath.Join("testdata", "keys.yaml"),
+			ScheduleFile: filepath.Join("testdata", "schedule.yaml"),
+		},
+		DateRange: "01/01/2025:01/03/2025",
+		Delay:     time.Millisecond,
+		LogFile:   filepath.Join(tmpDir, "simulate.log"),
+		MetricsFlags: MetricsFlags{
+			MetricsFile:     metricsFile,
+			MetricsInterval: 5 * time.Millisecond,
+		},
+	}
+	schedule := &Schedule{}
+	if err := schedule.Simulate(ctx, fl, []string{"simple", "precondition-not-sunny"}); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := os.ReadFile(metricsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snapshot logging.MetricsSnapshot
+	if err := json.Unmarshal(buf, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string][3]int64{}
+	for _, om := range snapshot.Operations {
+		counts[om.Name()] = [3]int64{om.Success, om.Failure, om.Aborted}
+		if om.Latency.Min > om.Latency.Max || om.Latency.Total < om.Latency.Max {
+			t.Errorf("%v: inconsistent latency: %+v", om.Name(), om.Latency)
+		}
+	}
+	// Simulations run to the end of the year, see TestSimulateAndLogs.
+	days := int64(31 + daysInSummer(2025))
+	if got, want := counts, map[string][3]int64{
+		"simple:device.on":                      {days, 0, 0},
+		"simple:device.off":                     {days, 0, 0},
+		"simple:device.another":                 {days * 3, 0, 0},
+		"precondition-not-sunny:device.on":      {days, 0, 0},
+		"precondition-not-sunny:device.off":     {days, 0, 0},
+		"precondition-not-sunny:device.another": {0, 0, days * 3},
+	}; !maps.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestScheduleOrder(t *testing.T) {
+	ctx := context.Background()
+	scheduleFile := filepath.Join(t.TempDir(), "schedule.yaml")
+	if err := os.WriteFile(scheduleFile, []byte(`
+schedules:
+  - name: ordered
+    device: device
+    actions:
+      on: 12:00
+      off: 12:00
+    actions_detailed:
+      - action: another
+        when: 12:00
+        before: off
+  - name: unordered
+    device: device
+    actions:
+      on: 08:00
+      off: 22:00
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	schedule := &Schedule{out: &out}
+	fl := &ScheduleOrderFlags{
+		ConfigFileFlags: ConfigFileFlags{
+			SystemFile:   filepath.Join("testdata", "system.yaml"),
+			ScheduleFile: scheduleFile,
+			KeysFile:     filepath.Join("testdata", "keys.yaml"),
+		},
+	}
+	if err := schedule.Order(ctx, fl, []string{"ordered"}); err != nil {
+		t.Fatal(err)
+	}
+	var rows []string
+	for _, cells := range tableRows(out.String()) {
+		rows = append(rows, strings.Join(cells, "|"))
+	}
+	for _, row := range []string{
+		"1|12:00:00|on|",
+		"2|12:00:00|another|before off",
+		"3|12:00:00|off|",
+	} {
+		if !slices.Contains(rows, row) {
+			t.Errorf("missing %q in %v", row, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "unordered") {
+		t.Errorf("unexpected schedule in output: %v", out.String())
+	}
+
+	if err := schedule.Order(ctx, fl, []string{"unknown"}); err == nil {
+		t.Errorf("expected an error for an unknown schedule")
+	}
+}
+
+func TestSchedulePreview(t *testing.T) {
+	ctx := context.Background()
+	scheduleFile := filepath.Join(t.TempDir(), "schedule.yaml")
+	if err := os.WriteFile(scheduleFile, []byte(`
+schedules:
+  - name: porch
+    device: device
+    ranges:
+      - 03/01:03/05
+    actions:
+      on: 18:00
+      off: 23:30
+  - name: garden
+    device: other-device
+    ranges:
+      - 03/02:03/02
+    actions:
+      on: 06:00
+    actions_detailed:
+      - action: off
+        when: 19:00
+        precondition:
+          device: device
+          op: weather
+          args: ["sunny"]
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	schedule := &Schedule{out: &out}
+	fl := &SchedulePreviewFlags{
+		ConfigFileFlags: ConfigFileFlags{
+			SystemFile:   filepath.Join("testdata", "system.yaml"),
+			ScheduleFile: scheduleFile,
+			KeysFile:     filepath.Join("testdata", "keys.yaml"),
+		},
+		At:       "03/01/2025 20:00",
+		Window:   24 * time.Hour,
+		Evaluate: true,
+	}
+	if err := schedule.Preview(ctx, fl, nil); err != nil {
+		t.Fatal(err)
+	}
+	// The date column is merged for consecutive actions on the same day
+	// and hence the date is carried forward for rows where it is blank.
+	var rows []string
+	date := ""
+	for _, cells := range tableRows(out.String()) {
+		if len(cells) != 6 || strings.EqualFold(cells[0], "date") {
+			continue
+		}
+		if cells[0] != "" {
+			date = cells[0]
+		}
+		cells[0] = date
+		rows = append(rows, strings.Join(cells, "|"))
+	}
+	if got, want := rows, []string{
+		"03/01/2025|23:30:00|porch|device|off|",
+		"03/02/2025|06:00:00|garden|other-device|on|",
+		"03/02/2025|18:00:00|porch|device|on|",
+		"03/02/2025|19:00:00|garden|other-device|off|if device.weather(sunny) [pass]",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q in %v", got, want, out.String())
+	}
+	if strings.Contains(out.String(), "03/03/2025") {
+		t.Errorf("unexpected action outside of the window: %v", out.String())
+	}
+
+	fl.At = "tomorrow"
+	if err := schedule.Preview(ctx, fl, nil); err == nil || !strings.Contains(err.Error(), "invalid time") {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+}
+
+// tableRows returns the trimmed cells of each row of a rendered table,
+// ignoring the padding added to align the columns.
+func tableRows(out string) [][]string {
+	var rows [][]string
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "|") {
+			continue
+		}
+		cells := strings.Split(strings.Trim(line, "|"), "|")
+		for i, c := range cells {
+			cells[i] = strings.TrimSpace(c)
+		}
+		rows = append(rows, cells)
+	}
+	return rows
+}
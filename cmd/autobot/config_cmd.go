@@ -5,35 +5,90 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"slices"
 	"strings"
+	"time"
 
+	"cloudeng.io/datetime"
 	"cloudeng.io/datetime/schedule"
 	"cloudeng.io/logging/ctxlog"
+	"github.com/cosnicolaou/automation/cmd/autobot/internal/webapi"
 	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/net/streamconn"
 	"github.com/cosnicolaou/automation/scheduler"
+	"github.com/jedib0t/go-pretty/v6/table"
 	"gopkg.in/yaml.v3"
 )
 
 type ConfigFileFlags struct {
-	KeysFile         string  `subcmd:"keys,$HOME/.autobot-keys.yaml,path/URI to a file containing keys"`
-	SystemFile       string  `subcmd:"system,$HOME/.autobot-system.yaml,path to a file containing the lutron system configuration"`
-	SystemTZLocation string  `subcmd:"tz,,timezone of the system"`
-	ZIPCode          string  `subcmd:"zip,,zip code of the system"`
-	ZIPDatabase      string  `subcmd:"zip-db-dir,,directory containing zip code database files from geonames.org"`
-	Latitude         float64 `subcmd:"lat,,latitude of the system"`
-	Longitude        float64 `subcmd:"long,,longitude of the system"`
-	ScheduleFile     string  `subcmd:"schedule,$HOME/.lutron-schedule.yaml,path to a file containing the lutron schedule configuration"`
+	KeysFile         string `subcmd:"keys,$HOME/.autobot-keys.yaml,path/URI to a file containing keys"`
+	SystemFile       string `subcmd:"system,$HOME/.autobot-system.yaml,path to a file containing the lutron system configuration"`
+	SystemTZLocation string `subcmd:"tz,,timezone of the system"`
+	ZIPCode          string `subcmd:"zip,,zip code of the system"`
+	ZIPDatabase      string `subcmd:"zip-db-dir,,directory containing zip code database files from geonames.org"`
+	Latitude         string `subcmd:"lat,,latitude of the system"`
+	Longitude        string `subcmd:"long,,longitude of the system"`
+	ScheduleFile     string `subcmd:"schedule,$HOME/.lutron-schedule.yaml,path to a file containing the lutron schedule configuration"`
+	ConfigFile       string `subcmd:"config,,path to a single file containing both the system (under system:) and schedule (under schedules:) configurations; overrides --system and --schedule"`
+	StrictOps        bool   `subcmd:"strict-operations,false,fail to load the system configuration if any configured operation or condition is not implemented by its controller or device"`
+}
+
+// systemFile returns the name of the file containing the system configuration.
+func (fv *ConfigFileFlags) systemFile() string {
+	if fv.ConfigFile != "" {
+		return fv.ConfigFile
+	}
+	return fv.SystemFile
+}
+
+// scheduleFile returns the name of the file containing the schedules.
+func (fv *ConfigFileFlags) scheduleFile() string {
+	if fv.ConfigFile != "" {
+		return fv.ConfigFile
+	}
+	return fv.ScheduleFile
 }
 
 type ConfigFlags struct {
 	ConfigFileFlags
 }
 
+type ConfigOperationsFlags struct {
+	ConfigFlags
+	Format string `subcmd:"format,table,output format: table or json"`
+}
+
+type ConfigInitFlags struct {
+	ConfigFileFlags
+	Force bool `subcmd:"force,false,overwrite existing configuration files"`
+}
+
+type ConfigTypesFlags struct {
+	TSV bool `subcmd:"tsv,false,print the types in tab separated values"`
+}
+
+type ConfigSunFlags struct {
+	ConfigFlags
+	Date string `subcmd:"date,,date in <month>/<day>/<year> format; defaults to today"`
+}
+
+type ConfigSunDriftFlags struct {
+	ConfigFlags
+	DateRange string `subcmd:"range,,date range in <month>/<day>/<year>:<year>/<month>/<day> format"`
+	Largest   int    `subcmd:"largest,3,number of the largest day to day changes to highlight"`
+}
+
+type ConfigLintFlags struct {
+	ConfigFlags
+	Year int `subcmd:"year,0,year to check for conflicting actions; defaults to the current year"`
+}
+
 type Config struct {
 	out io.Writer
 }
@@ -69,8 +124,13 @@ func formatAction(a schedule.ActionSpec[scheduler.Action]) string {
 			fmt.Fprintf(&out, ", at most %v times", a.Repeat.Repeats)
 		}
 	}
-	if a.T.Precondition.Condition != nil {
-		fmt.Fprintf(&out, " if %v %v", a.T.Precondition.Name, a.T.Precondition.Args)
+	if pre := a.T.Precondition; len(pre.Conditions) > 0 {
+		fmt.Fprintf(&out, " if %v", pre)
+	} else if pre.Condition != nil {
+		fmt.Fprintf(&out, " if %v %v", pre.Name, pre.Args)
+	}
+	if post := a.T.Postcondition; post.Condition != nil {
+		fmt.Fprintf(&out, " verify %v %v", post.Name, post.Args)
 	}
 	return out.String()
 }
@@ -108,14 +168,29 @@ func (c *Config) Display(ctx context.Context, flags any, _ []string) error {
 		fmt.Fprintf(c.out, "Device Custom Config:\n%v\n", marshalYAML("  ", device.CustomConfig()))
 	}
 
-	if fv.ScheduleFile != "" {
-		schedules, err := scheduler.ParseConfigFile(ctx, fv.ScheduleFile, system)
+	if fv.scheduleFile() != "" {
+		schedules, err := scheduler.ParseConfigFile(ctx, fv.scheduleFile(), system)
 		if err != nil {
 			return err
 		}
 		fmt.Fprintf(c.out, "Schedules:\n")
 		for _, sched := range schedules.Schedules {
 			fmt.Fprintf(c.out, "%s\n", indentBlock("  ", sched.Dates.String()))
+			if len(sched.DaysOfWeek) > 0 {
+				fmt.Fprintf(c.out, "  on: %v\n", sched.DaysOfWeek)
+			}
+			if len(sched.LunarPhases.Phases) > 0 {
+				fmt.Fprintf(c.out, "  lunar phases: %v\n", sched.LunarPhases)
+			}
+			if dc := sched.DayCondition; dc.Condition != nil {
+				fmt.Fprintf(c.out, "  day condition: %v.%v %v\n", dc.Device, dc.Name, dc.Args)
+			}
+			if len(sched.Notify) > 0 {
+				fmt.Fprintf(c.out, "  notify: %v\n", sched.Notify)
+			}
+			if sched.TimeLocation != nil {
+				fmt.Fprintf(c.out, "  time zone: %v\n", sched.TimeLocation)
+			}
 			for _, a := range sched.DailyActions {
 				fmt.Fprintf(c.out, "    %s\n", formatAction(a))
 			}
@@ -124,6 +199,161 @@ func (c *Config) Display(ctx context.Context, flags any, _ []string) error {
 	return nil
 }
 
+const initSystemConfig = `# The system configuration: the location of the system and the
+# controllers and devices that make up the system.
+
+# The time zone used for all schedules.
+time_location: America/Los_Angeles
+
+# The latitude and longitude are used to calculate the times of dynamic
+# events such as sunrise and sunset. Alternatively, specify zip_code.
+latitude: 37.3547
+longitude: -122.0862
+
+# Controllers manage communication with devices, the noop device
+# used below does not require one.
+controllers:
+
+devices:
+  # A device whose on and off operations do nothing, replace it with
+  # a real device, see 'autobot config types' for the available types.
+  - name: lamp
+    type: noop
+    operations:
+      on:
+      off:
+`
+
+const initScheduleConfig = `# The schedule configuration: the operations to perform on the devices
+# defined in the system configuration and when to perform them.
+schedules:
+  # Turn the lamp on at sunset and off at 11pm every day of the year.
+  - name: evening-lamp
+    device: lamp
+    ranges:
+      - 01/01:12/31
+    actions:
+      on: sunset
+      off: 23:00
+`
+
+// Init writes a minimal, but valid, system and schedule configuration to
+// the configured files. Existing files are not overwritten unless
+// --force is specified.
+func (c *Config) Init(_ context.Context, flags any, _ []string) error {
+	fv := flags.(*ConfigInitFlags)
+	files := []struct {
+		name, contents string
+	}{
+		{fv.SystemFile, initSystemConfig},
+		{fv.ScheduleFile, initScheduleConfig},
+	}
+	if !fv.Force {
+		for _, f := range files {
+			if _, err := os.Stat(f.name); err == nil {
+				return fmt.Errorf("%v already exists, use --force to overwrite it", f.name)
+			}
+		}
+	}
+	for _, f := range files {
+		if err := os.WriteFile(f.name, []byte(f.contents), 0600); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.out, "wrote %v\n", f.name)
+	}
+	return nil
+}
+
+// effectiveSystem represents the configuration of a system as it is in
+// effect once all of the command line overrides have been applied.
+type effectiveSystem struct {
+	Location    effectiveLocation     `yaml:"location"`
+	Controllers []effectiveController `yaml:"controllers"`
+	Devices     []effectiveDevice     `yaml:"devices"`
+}
+
+type effectiveLocation struct {
+	TimeLocation string  `yaml:"time_location"`
+	ZIPCode      string  `yaml:"zip_code,omitempty"`
+	Latitude     float64 `yaml:"latitude"`
+	Longitude    float64 `yaml:"longitude"`
+	LatLongSet   bool    `yaml:"lat_long_set"`
+}
+
+type effectiveController struct {
+	devices.ControllerConfigCommon `yaml:",inline"`
+	Custom                         any `yaml:"custom,omitempty"`
+}
+
+type effectiveDevice struct {
+	devices.DeviceConfigCommon `yaml:",inline"`
+	Controller                 string `yaml:"controlled_by,omitempty"`
+	Custom                     any    `yaml:"custom,omitempty"`
+}
+
+const redacted = "<redacted>"
+
+// redactLogin returns a copy of the supplied login sequence with the
+// contents of its sensitive steps redacted.
+func redactLogin(ls streamconn.LoginSequence) streamconn.LoginSequence {
+	ls.Steps = slices.Clone(ls.Steps)
+	for i, step := range ls.Steps {
+		if step.Sensitive && len(step.Send) > 0 {
+			ls.Steps[i].Send = redacted
+		}
+	}
+	return ls
+}
+
+func newEffectiveSystem(system devices.System) effectiveSystem {
+	loc := system.Location
+	es := effectiveSystem{
+		Location: effectiveLocation{
+			TimeLocation: loc.TimeLocation.String(),
+			ZIPCode:      loc.ZIPCode,
+			Latitude:     loc.Latitude,
+			Longitude:    loc.Longitude,
+			LatLongSet:   loc.LatLongSet,
+		},
+	}
+	for _, name := range opNames(system.Controllers) {
+		ctrl := system.Controllers[name]
+		cfg := ctrl.Config()
+		cfg.Login = redactLogin(cfg.Login)
+		es.Controllers = append(es.Controllers, effectiveController{
+			ControllerConfigCommon: cfg,
+			Custom:                 ctrl.CustomConfig(),
+		})
+	}
+	for _, name := range opNames(system.Devices) {
+		dev := system.Devices[name]
+		es.Devices = append(es.Devices, effectiveDevice{
+			DeviceConfigCommon: dev.Config(),
+			Controller:         dev.ControlledByName(),
+			Custom:             dev.CustomConfig(),
+		})
+	}
+	return es
+}
+
+// Effective displays, as YAML, the system configuration that is in effect
+// once the location overrides specified on the command line have been
+// applied. The contents of sensitive login steps are redacted.
+func (c *Config) Effective(ctx context.Context, flags any, _ []string) error {
+	fv := flags.(*ConfigFlags)
+	ctx = ctxlog.NewJSONLogger(ctx, os.Stderr, nil)
+	_, system, err := loadSystem(ctx, &fv.ConfigFileFlags)
+	if err != nil {
+		return err
+	}
+	out, err := yaml.Marshal(newEffectiveSystem(system))
+	if err != nil {
+		return err
+	}
+	_, err = c.out.Write(out)
+	return err
+}
+
 func opNames[Map ~map[string]V, V any](m Map) []string {
 	keys := make([]string, 0, len(m))
 	for k := range m {
@@ -133,16 +363,312 @@ func opNames[Map ~map[string]V, V any](m Map) []string {
 	return keys
 }
 
+// Operations displays the controllers, devices and conditions in the
+// system, either as tables or, with --format=json, as a JSON encoded
+// webapi.SystemOperations that includes every operation and condition.
 func (c *Config) Operations(ctx context.Context, flags any, _ []string) error {
-	fv := flags.(*ConfigFlags)
+	fv := flags.(*ConfigOperationsFlags)
 	ctx = ctxlog.NewJSONLogger(ctx, os.Stderr, nil)
-	system, err := devices.ParseSystemConfigFile(ctx, fv.SystemFile)
+	switch fv.Format {
+	case "table", "json":
+	default:
+		return fmt.Errorf("unsupported format: %q, use table or json", fv.Format)
+	}
+	system, err := parseSystemConfig(ctx, &fv.ConfigFileFlags)
 	if err != nil {
 		return err
 	}
+	if fv.Format == "json" {
+		enc := json.NewEncoder(c.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(webapi.NewSystemOperations(system))
+	}
 	tm := tableManager{html: false}
-	fmt.Println(tm.Controllers(system).Render())
-	fmt.Println(tm.Devices(system).Render())
-	fmt.Println(tm.Conditions(system).Render())
+	fmt.Fprintln(c.out, tm.Controllers(system).Render())
+	fmt.Fprintln(c.out, tm.Devices(system).Render())
+	fmt.Fprintln(c.out, tm.Conditions(system).Render())
+	return nil
+}
+
+// Help displays the documentation, as markdown, for the specified
+// <controller-or-device>.<operation>.
+func (c *Config) Help(ctx context.Context, flags any, args []string) error {
+	fv := flags.(*ConfigFlags)
+	ctx = ctxlog.NewJSONLogger(ctx, os.Stderr, nil)
+	name, op, ok := strings.Cut(args[0], ".")
+	if !ok || len(name) == 0 || len(op) == 0 {
+		return fmt.Errorf("invalid: %q, should be device.operation", args[0])
+	}
+	system, err := parseSystemConfig(ctx, &fv.ConfigFileFlags)
+	if err != nil {
+		return err
+	}
+	doc, ok := system.OperationDoc(name, op)
+	if !ok {
+		return fmt.Errorf("no help available for: %v", args[0])
+	}
+	fmt.Fprint(c.out, doc.Markdown(args[0]))
+	return nil
+}
+
+// Uses displays every reference to the specified controller or device by
+// the configured schedules.
+func (c *Config) Uses(ctx context.Context, flags any, args []string) error {
+	fv := flags.(*ConfigFlags)
+	ctx = ctxlog.NewJSONLogger(ctx, os.Stderr, nil)
+	ctx, system, err := loadSystem(ctx, &fv.ConfigFileFlags)
+	if err != nil {
+		return err
+	}
+	name := args[0]
+	_, cok := system.Controllers[name]
+	_, dok := system.Devices[name]
+	if !cok && !dok {
+		return fmt.Errorf("unknown controller or device: %q", name)
+	}
+	schedules, err := scheduler.ParseConfigFile(ctx, fv.scheduleFile(), system)
+	if err != nil {
+		return err
+	}
+	uses := schedules.Uses(name)
+	if len(uses) == 0 {
+		fmt.Fprintf(c.out, "%v is not used by any schedule\n", name)
+		return nil
+	}
+	for _, u := range uses {
+		fmt.Fprintf(c.out, "%v\n", u)
+	}
+	return nil
+}
+
+// Sun displays the times of day for all of the supported dynamic
+// time of day functions (eg. sunrise, sunset) for the system's location
+// and the requested date.
+func (c *Config) Sun(ctx context.Context, flags any, _ []string) error {
+	fv := flags.(*ConfigSunFlags)
+	ctx = ctxlog.NewJSONLogger(ctx, os.Stderr, nil)
+	_, system, err := loadSystem(ctx, &fv.ConfigFileFlags)
+	if err != nil {
+		return err
+	}
+	day := datetime.CalendarDateFromTime(time.Now().In(system.Location.TimeLocation))
+	if f := fv.Date; len(f) > 0 {
+		if err := day.Parse(f); err != nil {
+			return err
+		}
+	}
+	type evaluated struct {
+		name string
+		tod  datetime.TimeOfDay
+	}
+	times := make([]evaluated, 0, len(scheduler.DailyDynamic))
+	for _, name := range opNames(scheduler.DailyDynamic) {
+		tod := scheduler.DailyDynamic[name].Evaluate(day, system.Location.Place)
+		times = append(times, evaluated{name: name, tod: tod})
+	}
+	slices.SortStableFunc(times, func(a, b evaluated) int {
+		return cmp.Compare(a.tod, b.tod)
+	})
+	fmt.Fprintf(c.out, "Location: %v\n", system.Location)
+	fmt.Fprintf(c.out, "Date: %v\n", day)
+	for _, t := range times {
+		fmt.Fprintf(c.out, "  %-10s %v\n", t.name+":", t.tod)
+	}
+	return nil
+}
+
+// SunDrift displays the time that each of the specified schedule's
+// dynamically timed actions (eg. sunset) resolves to for every day in the
+// requested range along with the change from the previous day. The
+// largest changes are highlighted.
+func (c *Config) SunDrift(ctx context.Context, flags any, args []string) error {
+	fv := flags.(*ConfigSunDriftFlags)
+	ctx = ctxlog.NewJSONLogger(ctx, os.Stderr, nil)
+	var period datetime.CalendarDateRange
+	if err := period.Parse(fv.DateRange); err != nil {
+		return err
+	}
+	ctx, system, err := loadSystem(ctx, &fv.ConfigFileFlags)
+	if err != nil {
+		return err
+	}
+	if !system.Location.LatLongSet {
+		return fmt.Errorf("latitude and longitude must be specified either directly or via a zip code")
+	}
+	schedules, err := loadSchedules(ctx, &fv.ConfigFileFlags, system)
+	if err != nil {
+		return err
+	}
+	sched, ok := schedules.Lookup(args[0])
+	if !ok {
+		return fmt.Errorf("unknown schedule: %q", args[0])
+	}
+	drifts := sched.Drift(system.Location.Place, period)
+	if len(drifts) == 0 {
+		fmt.Fprintf(c.out, "%v has no dynamically timed actions\n", sched.Name)
+		return nil
+	}
+	fmt.Fprintf(c.out, "Location: %v\n", system.Location)
+	for _, drift := range drifts {
+		largest := map[datetime.CalendarDate]bool{}
+		for _, day := range drift.Largest(fv.Largest) {
+			largest[day.Date] = true
+		}
+		fmt.Fprintf(c.out, "%v: %v %v\n", sched.Name, drift.Action, drift.Due)
+		for _, day := range drift.Days {
+			mark := ""
+			if largest[day.Date] {
+				mark = " *"
+			}
+			sign := "+"
+			if day.Delta < 0 {
+				sign = "-"
+			}
+			fmt.Fprintf(c.out, "  %v %v %v%v%v\n", day.Date, day.Due, sign, day.Delta.Abs(), mark)
+		}
+	}
+	return nil
+}
+
+// Lint reports actions, in different schedules, that perform different
+// operations on the same device within the same minute.
+func (c *Config) Lint(ctx context.Context, flags any, _ []string) error {
+	fv := flags.(*ConfigLintFlags)
+	ctx = ctxlog.NewJSONLogger(ctx, os.Stderr, nil)
+	ctx, system, err := loadSystem(ctx, &fv.ConfigFileFlags)
+	if err != nil {
+		return err
+	}
+	schedules, err := loadSchedules(ctx, &fv.ConfigFileFlags, system)
+	if err != nil {
+		return err
+	}
+	year := fv.Year
+	if year == 0 {
+		year = time.Now().In(system.Location.TimeLocation).Year()
+	}
+	cal, err := scheduler.NewCalendar(schedules, system)
+	if err != nil {
+		return err
+	}
+	conflicts := scheduler.Conflicts(cal, year)
+	for _, conflict := range conflicts {
+		fmt.Fprintf(c.out, "conflict: %v\n", conflict)
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("found %v conflicting actions", len(conflicts))
+	}
+	return nil
+}
+
+// keyIDs appends the key ids referenced by v, that is, the values of
+// any key_id fields and the ids used by any scheduler.SecretArgPrefix
+// arguments, to refs.
+func keyIDs(refs map[string][]string, usedBy string, v any) error {
+	var node yaml.Node
+	if err := node.Encode(v); err != nil {
+		return err
+	}
+	add := func(id string) {
+		if len(id) > 0 && !slices.Contains(refs[id], usedBy) {
+			refs[id] = append(refs[id], usedBy)
+		}
+	}
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		if n.Kind == yaml.ScalarNode {
+			if id, ok := strings.CutPrefix(n.Value, scheduler.SecretArgPrefix); ok {
+				add(id)
+			}
+			return
+		}
+		for i, c := range n.Content {
+			if n.Kind == yaml.MappingNode && i%2 == 0 && c.Value == "key_id" && i+1 < len(n.Content) {
+				add(n.Content[i+1].Value)
+			}
+			walk(c)
+		}
+	}
+	walk(&node)
+	return nil
+}
+
+// CheckKeys verifies that every key referenced by the controllers, devices
+// and schedules is present in the keys file.
+func (c *Config) CheckKeys(ctx context.Context, flags any, _ []string) error {
+	fv := flags.(*ConfigFlags)
+	ctx = ctxlog.NewJSONLogger(ctx, os.Stderr, nil)
+	ctx, system, err := loadSystem(ctx, &fv.ConfigFileFlags)
+	if err != nil {
+		return err
+	}
+	keys, err := ReadKeysFile(ctx, fv.KeysFile)
+	if err != nil {
+		return fmt.Errorf("failed to read keys file: %q: %w", fv.KeysFile, err)
+	}
+	refs := map[string][]string{}
+	for _, name := range opNames(system.Controllers) {
+		ctrl := system.Controllers[name]
+		for _, v := range []any{ctrl.Config(), ctrl.CustomConfig()} {
+			if err := keyIDs(refs, "controller "+name, v); err != nil {
+				return err
+			}
+		}
+	}
+	for _, name := range opNames(system.Devices) {
+		dev := system.Devices[name]
+		for _, v := range []any{dev.Config(), dev.CustomConfig()} {
+			if err := keyIDs(refs, "device "+name, v); err != nil {
+				return err
+			}
+		}
+	}
+	if fv.scheduleFile() != "" {
+		schedules, err := scheduler.ParseConfigFile(ctx, fv.scheduleFile(), system)
+		if err != nil {
+			return err
+		}
+		for _, sched := range schedules.Schedules {
+			for _, a := range sched.DailyActions {
+				args := [][]string{a.T.Args, a.T.Precondition.Args}
+				for _, c := range a.T.Precondition.Conditions {
+					args = append(args, c.Args)
+				}
+				args = append(args, a.T.Postcondition.Args)
+				if err := keyIDs(refs, "schedule "+sched.Name, args); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	var missing []string
+	for _, id := range opNames(refs) {
+		status := "ok"
+		if _, ok := keys[id]; !ok {
+			status = "missing"
+			missing = append(missing, id)
+		}
+		fmt.Fprintf(c.out, "%-20s %-8s %v\n", id, status, strings.Join(refs[id], ", "))
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing keys: %v", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Types lists all of the compiled in controller and device types.
+func (c *Config) Types(_ context.Context, flags any, _ []string) error {
+	fv := flags.(*ConfigTypesFlags)
+	tm := tableManager{}
+	for _, tw := range []table.Writer{
+		tm.Types("Controller Types", devices.RegisteredControllerTypes()),
+		tm.Types("Device Types", devices.RegisteredDeviceTypes()),
+	} {
+		if fv.TSV {
+			fmt.Fprintln(c.out, tw.RenderTSV())
+			continue
+		}
+		fmt.Fprintln(c.out, tw.Render())
+	}
 	return nil
 }
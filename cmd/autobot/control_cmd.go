@@ -6,18 +6,17 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"sort"
 	"strings"
 	"time"
 
 	"cloudeng.io/cmdutil/keystore"
-	"cloudeng.io/datetime"
 	"cloudeng.io/logging/ctxlog"
 	"github.com/cosnicolaou/automation/cmd/autobot/internal/webapi"
 	"github.com/cosnicolaou/automation/cmd/autobot/internal/webassets"
@@ -30,16 +29,41 @@ type ControlFlags struct {
 	ConfigFileFlags
 }
 
+type ControlRunFlags struct {
+	ControlFlags
+	Raw bool `subcmd:"raw,false,write only the data returned by the operation rather than the JSON encoded operation result"`
+}
+
+type ControlSelfTestFlags struct {
+	ControlFlags
+	DryRun  bool          `subcmd:"dry-run,false,only check that each configured operation can be resolved but do not run it"`
+	Timeout time.Duration `subcmd:"timeout,10s,timeout for each operation"`
+}
+
+type ControlSnapshotFlags struct {
+	ControlFlags
+	Concurrency int           `subcmd:"concurrency,8,maximum number of conditions evaluated at once"`
+	PerDevice   int           `subcmd:"per-device,1,maximum number of conditions evaluated at once on any single device"`
+	Timeout     time.Duration `subcmd:"timeout,10s,timeout for each condition"`
+}
+
 type ControlScriptFlags struct {
 	ControlFlags
 }
 
+type ControlReplFlags struct {
+	ControlFlags
+}
+
 type ControlTestPageFlags struct {
 	ControlFlags
 	WebUIFlags
 }
 
-type Control struct{}
+type Control struct {
+	in  io.Reader
+	out io.Writer
+}
 
 func (c *Control) setup(ctx context.Context, fv *ControlFlags) (context.Context, func(ctx context.Context) (devices.System, error), error) {
 	ctx = ctxlog.NewJSONLogger(ctx, os.Stderr, nil)
@@ -51,26 +75,35 @@ func (c *Control) setup(ctx context.Context, fv *ControlFlags) (context.Context,
 		return nil, nil, fmt.Errorf("failed to read keys file: %q: %w", fv.KeysFile, err)
 	}
 
-	zdb, err := loadZIPDatabase(fv.ZIPDatabase)
+	zdb, err := newReloadableZIPLookup(fv.ZIPDatabase)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to load zip database: %q: %w", fv.ZIPDatabase, err)
 	}
-	opts = append(opts, devices.WithZIPCodeLookup(zdb))
+	locOpts, err := locationOptions(&fv.ConfigFileFlags)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts = append(opts, locOpts...)
+	opts = append(opts,
+		devices.WithZIPCodeLookup(zdb),
+		devices.WithStrictOperations(fv.StrictOps))
 
 	ctx = keystore.ContextWithAuth(ctx, keys)
 
+	// The zip database is reloaded, if it has changed, whenever the
+	// system configuration is reloaded.
 	loader := func(ctx context.Context) (devices.System, error) {
-		system, err := devices.ParseSystemConfigFile(ctx, fv.SystemFile, opts...)
-		if err != nil {
-			return devices.System{}, fmt.Errorf("failed to parse system config file: %q: %w", fv.SystemFile, err)
+		if _, err := zdb.reload(); err != nil {
+			return devices.System{}, fmt.Errorf("failed to reload zip database: %q: %w", fv.ZIPDatabase, err)
 		}
-		return system, nil
+		return parseSystemConfig(ctx, &fv.ConfigFileFlags, opts...)
 	}
 	return ctx, loader, nil
 }
 
 func (c *Control) Run(ctx context.Context, flags any, args []string) error {
-	ctx, loader, err := c.setup(ctx, flags.(*ControlFlags))
+	fv := flags.(*ControlRunFlags)
+	ctx, loader, err := c.setup(ctx, &fv.ControlFlags)
 	if err != nil {
 		return err
 	}
@@ -82,11 +115,25 @@ func (c *Control) Run(ctx context.Context, flags any, args []string) error {
 	if err != nil {
 		return err
 	}
-	data, err := cc.RunOperation(ctx, os.Stdout, action)
+	if !fv.Raw {
+		data, err := cc.RunOperation(ctx, c.out, action)
+		if err != nil {
+			return err
+		}
+		return writeJSON(c.out, data)
+	}
+	// In raw mode, the operation's output is only written if
+	// the operation does not return any data.
+	var opOutput bytes.Buffer
+	data, err := cc.RunOperation(ctx, &opOutput, action)
 	if err != nil {
 		return err
 	}
-	return writeJSON(os.Stdout, data)
+	if data.Data == nil {
+		_, err := c.out.Write(opOutput.Bytes())
+		return err
+	}
+	return writeRaw(c.out, data.Data)
 }
 
 func writeJSON(w io.Writer, v interface{}) error {
@@ -95,6 +142,23 @@ func writeJSON(w io.Writer, v interface{}) error {
 	return enc.Encode(v)
 }
 
+// writeRaw writes v without any surrounding envelope, strings and byte
+// slices are written as is, all other values are JSON encoded.
+func writeRaw(w io.Writer, v any) error {
+	switch d := v.(type) {
+	case []byte:
+		_, err := w.Write(d)
+		return err
+	case string:
+		_, err := io.WriteString(w, d)
+		return err
+	case json.RawMessage:
+		_, err := w.Write(d)
+		return err
+	}
+	return json.NewEncoder(w).Encode(v)
+}
+
 func (c *Control) Condition(ctx context.Context, flags any, args []string) error {
 	ctx, loader, err := c.setup(ctx, flags.(*ControlFlags))
 	if err != nil {
@@ -108,11 +172,30 @@ func (c *Control) Condition(ctx context.Context, flags any, args []string) error
 	if err != nil {
 		return err
 	}
-	cr, err := cc.RunCondition(ctx, os.Stdout, action)
+	cr, err := cc.RunCondition(ctx, c.out, action)
+	if err != nil {
+		return err
+	}
+	return writeJSON(c.out, cr)
+}
+
+// Snapshot evaluates every configured condition on every device and
+// displays the results.
+func (c *Control) Snapshot(ctx context.Context, flags any, _ []string) error {
+	fv := flags.(*ControlSnapshotFlags)
+	ctx, loader, err := c.setup(ctx, &fv.ControlFlags)
+	if err != nil {
+		return err
+	}
+	cc, err := webapi.NewDeviceControlServer(ctx, loader)
 	if err != nil {
 		return err
 	}
-	return writeJSON(os.Stdout, cr)
+	return writeJSON(c.out, cc.Snapshot(ctx, webapi.SnapshotOptions{
+		Concurrency: fv.Concurrency,
+		PerDevice:   fv.PerDevice,
+		Timeout:     fv.Timeout,
+	}))
 }
 
 func (c *Control) RunScript(ctx context.Context, flags any, args []string) error {
@@ -144,20 +227,150 @@ func (c *Control) RunScript(ctx context.Context, flags any, args []string) error
 		if err != nil {
 			return err
 		}
-		or, err := cc.RunOperation(ctx, os.Stdout, action)
+		or, err := cc.RunOperation(ctx, c.out, action)
 		if err != nil {
 			return err
 		}
-		if err := writeJSON(os.Stdout, or); err != nil {
+		if err := writeJSON(c.out, or); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-type conditionalOps struct {
-	op   webapi.Action
-	cond webapi.Action
+const replPrompt = "> "
+
+const replHelp = `enter an operation as: <device>.<operation> [<arg>...]
+or one of the following commands:
+  help - display this message
+  list - list the operations supported by every controller and device
+  quit - exit
+`
+
+func (c *Control) replList(sys devices.System) {
+	for _, name := range opNames(sys.Controllers) {
+		cfg, _, _ := sys.ControllerConfigs(name)
+		fmt.Fprintf(c.out, "%v:%v\n", name, replOps(cfg.Operations))
+	}
+	for _, name := range opNames(sys.Devices) {
+		cfg, _, _ := sys.DeviceConfigs(name)
+		fmt.Fprintf(c.out, "%v:%v\n", name, replOps(cfg.Operations))
+	}
+}
+
+func replOps(ops map[string][]string) string {
+	if len(ops) == 0 {
+		return ""
+	}
+	return " " + strings.Join(opNames(ops), ", ")
+}
+
+// Repl loads the system once and then runs the operations read, one per
+// line, from stdin until quit is entered or stdin is closed. Controller
+// connections are thus reused across operations. Errors are displayed
+// rather than terminating the session.
+func (c *Control) Repl(ctx context.Context, flags any, _ []string) error {
+	ctx, loader, err := c.setup(ctx, &flags.(*ControlReplFlags).ControlFlags)
+	if err != nil {
+		return err
+	}
+	cc, err := webapi.NewDeviceControlServer(ctx, loader)
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(c.in)
+	for {
+		fmt.Fprint(c.out, replPrompt)
+		if !scanner.Scan() {
+			break
+		}
+		parts := strings.Fields(scanner.Text())
+		if len(parts) == 0 || strings.HasPrefix(parts[0], "#") {
+			continue
+		}
+		switch parts[0] {
+		case "help":
+			fmt.Fprint(c.out, replHelp)
+			continue
+		case "list":
+			c.replList(cc.System())
+			continue
+		case "quit", "exit":
+			return nil
+		}
+		action, err := webapi.NewActionFromArgs(parts[0], parts[1:]...)
+		if err != nil {
+			fmt.Fprintf(c.out, "error: %v\n", err)
+			continue
+		}
+		or, err := cc.RunOperation(ctx, c.out, action)
+		if err != nil {
+			fmt.Fprintf(c.out, "error: %v\n", err)
+			continue
+		}
+		if err := writeJSON(c.out, or); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintln(c.out)
+	return scanner.Err()
+}
+
+type selfTestResult struct {
+	device string
+	op     string
+	status string
+	err    error
+}
+
+func (c *Control) selfTest(ctx context.Context, cc *webapi.DeviceControlServer, fv *ControlSelfTestFlags) []selfTestResult {
+	sys := cc.System()
+	results := []selfTestResult{}
+	for _, name := range opNames(sys.Devices) {
+		cfg, _, _ := sys.DeviceConfigs(name)
+		for _, op := range opNames(cfg.Operations) {
+			res := selfTestResult{device: name, op: op}
+			if fv.DryRun {
+				res.status = "resolved"
+				if _, _, ok := sys.DeviceOp(name, op); !ok {
+					res.status = "unresolved"
+				}
+				results = append(results, res)
+				continue
+			}
+			tctx, cancel := context.WithTimeout(ctx, fv.Timeout)
+			_, err := cc.RunOperation(tctx, io.Discard, webapi.Action{Device: name, Op: op})
+			cancel()
+			res.status, res.err = "pass", err
+			if err != nil {
+				res.status = "fail"
+			}
+			results = append(results, res)
+		}
+	}
+	return results
+}
+
+// SelfTest runs every configured operation on every device, one at
+// a time, and displays the results.
+func (c *Control) SelfTest(ctx context.Context, flags any, _ []string) error {
+	fv := flags.(*ControlSelfTestFlags)
+	ctx, loader, err := c.setup(ctx, &fv.ControlFlags)
+	if err != nil {
+		return err
+	}
+	cc, err := webapi.NewDeviceControlServer(ctx, loader)
+	if err != nil {
+		return err
+	}
+	results := c.selfTest(ctx, cc, fv)
+	fmt.Fprintln(c.out, tableManager{}.SelfTest(results).Render())
+	for _, r := range results {
+		if r.status != "pass" && r.status != "resolved" {
+			return fmt.Errorf("self-test failed")
+		}
+	}
+	return nil
 }
 
 func (c *Control) ServeTestPage(ctx context.Context, flags any, _ []string) error {
@@ -177,7 +390,7 @@ func (c *Control) ServeTestPage(ctx context.Context, flags any, _ []string) erro
 	rerender := createSystemRenderer(&fv.ConfigFileFlags, loader, pages)
 
 	webassets.AppendTestServerPages(mux,
-		fv.SystemFile,
+		fv.systemFile(),
 		pages,
 	)
 
@@ -185,16 +398,19 @@ func (c *Control) ServeTestPage(ctx context.Context, flags any, _ []string) erro
 	if err != nil {
 		return err
 	}
+	audit, closeAudit, err := fv.OpenAuditLog()
+	if err != nil {
+		return err
+	}
+	defer closeAudit()
+	dc.SetAuditLog(audit)
 	dc.AppendEndpoints(ctx, mux)
 
 	_ = browser.OpenURL(url)
 	return runner()
 }
 
-func findPreconditions(ctx context.Context, system devices.System, cf *ConfigFileFlags) ([]conditionalOps, error) {
-	dedup := map[string]bool{}
-	cops := make([]conditionalOps, 0, 10)
-
+func findPreconditions(ctx context.Context, system devices.System, cf *ConfigFileFlags) ([]scheduler.ConditionalOp, error) {
 	scheds, err := loadSchedules(ctx, cf, system)
 	if err != nil {
 		return nil, err
@@ -203,40 +419,7 @@ func findPreconditions(ctx context.Context, system devices.System, cf *ConfigFil
 	if err != nil {
 		return nil, err
 	}
-	year := time.Now().Year()
-	first := datetime.NewCalendarDate(year, 1, 1)
-	last := datetime.NewCalendarDate(year, 12, 31)
-	for today := first; today <= last; today = today.Tomorrow() {
-		for _, ce := range cal.Scheduled(today) {
-			if ce.T.Precondition.Name == "" {
-				continue
-			}
-			cond := webapi.Action{
-				Device: ce.T.Precondition.Device,
-				Op:     ce.T.Precondition.Name,
-				Args:   ce.T.Precondition.Args,
-			}
-			op := webapi.Action{
-				Device: ce.T.DeviceName,
-				Op:     ce.T.Name,
-				Args:   ce.T.Args,
-			}
-			key := op.String() + "_" + cond.String()
-			if dedup[key] {
-				continue
-			}
-			cops = append(cops, conditionalOps{
-				op:   op,
-				cond: cond,
-			})
-			dedup[key] = true
-
-		}
-	}
-	sort.Slice(cops, func(i, j int) bool {
-		return cops[i].op.Device < cops[j].op.Device
-	})
-	return cops, nil
+	return scheduler.DistinctConditionalOps(cal), nil
 }
 
 func createSystemRenderer(cf *ConfigFileFlags,
@@ -0,0 +1,228 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/cmd/autobot/internal/webapi"
+)
+
+func TestControlRunRaw(t *testing.T) {
+	ctx := context.Background()
+	fl := ControlRunFlags{
+		ControlFlags: ControlFlags{
+			ConfigFileFlags: ConfigFileFlags{
+				SystemFile: filepath.Join("testdata", "system.yaml"),
+				KeysFile:   filepath.Join("testdata", "keys.yaml"),
+			},
+		},
+	}
+
+	var out strings.Builder
+	control := &Control{out: &out}
+	if err := control.Run(ctx, &fl, []string{"device.on", "a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	var envelope struct {
+		Device string          `json:"device"`
+		Op     string          `json:"operation"`
+		Data   json.RawMessage `json:"data"`
+	}
+	// Skip the operation's own output.
+	o := out.String()
+	if err := json.Unmarshal([]byte(o[strings.Index(o, "{"):]), &envelope); err != nil {
+		t.Fatalf("failed to decode %q: %v", o, err)
+	}
+	if got, want := envelope.Device, "device"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := envelope.Op, "on"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	out.Reset()
+	fl.Raw = true
+	if err := control.Run(ctx, &fl, []string{"device.on", "a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), `{"Name":"device","Args":["a","b"]}`+"\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	var data bytes.Buffer
+	if err := json.Compact(&data, envelope.Data); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := data.String(), strings.TrimSpace(out.String()); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestControlStrictOperations(t *testing.T) {
+	ctx := context.Background()
+	systemFile := filepath.Join(t.TempDir(), "system.yaml")
+	if err := os.WriteFile(systemFile, []byte(`time_location: Local
+devices:
+  - name: device
+    type: mock-device
+    operations:
+      on:
+      dim:
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	fl := ControlRunFlags{
+		ControlFlags: ControlFlags{
+			ConfigFileFlags: ConfigFileFlags{
+				SystemFile: systemFile,
+				KeysFile:   filepath.Join("testdata", "keys.yaml"),
+			},
+		},
+	}
+	var out strings.Builder
+	control := &Control{out: &out}
+	if err := control.Run(ctx, &fl, []string{"device.on"}); err != nil {
+		t.Fatal(err)
+	}
+	fl.StrictOps = true
+	err := control.Run(ctx, &fl, []string{"device.on"})
+	if err == nil || !strings.Contains(err.Error(), `device "device": operation "dim" is not implemented`) {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+}
+
+func TestControlSelfTest(t *testing.T) {
+	ctx := context.Background()
+	fl := ControlSelfTestFlags{
+		ControlFlags: ControlFlags{
+			ConfigFileFlags: ConfigFileFlags{
+				SystemFile: filepath.Join("testdata", "selftest.yaml"),
+				KeysFile:   filepath.Join("testdata", "keys.yaml"),
+			},
+		},
+		Timeout: time.Second,
+	}
+
+	var out strings.Builder
+	control := &Control{out: &out}
+	err := control.SelfTest(ctx, &fl, nil)
+	if err == nil || err.Error() != "self-test failed" {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Self Test") {
+		t.Errorf("missing table in %v", out.String())
+	}
+
+	ctx, loader, err := control.setup(ctx, &fl.ControlFlags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc, err := webapi.NewDeviceControlServer(ctx, loader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		dryRun bool
+		want   []string
+	}{
+		{false, []string{
+			"device.off: pass",
+			"device.on: pass",
+			"failing.broken: fail: failed to run operation: broken: broken",
+			"failing.missing: fail: unknown or not configured operation: failing, missing",
+			"failing.on: pass",
+		}},
+		{true, []string{
+			"device.off: resolved",
+			"device.on: resolved",
+			"failing.broken: resolved",
+			"failing.missing: unresolved",
+			"failing.on: resolved",
+		}},
+	} {
+		fl.DryRun = tc.dryRun
+		results := control.selfTest(ctx, cc, &fl)
+		got := make([]string, len(results))
+		for i, r := range results {
+			got[i] = fmt.Sprintf("%v.%v: %v", r.device, r.op, r.status)
+			if r.err != nil {
+				got[i] += ": " + r.err.Error()
+			}
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("dry-run %v: got %v, want %v", tc.dryRun, got, tc.want)
+		}
+	}
+}
+
+func TestControlRepl(t *testing.T) {
+	ctx := context.Background()
+	fl := ControlReplFlags{
+		ControlFlags: ControlFlags{
+			ConfigFileFlags: ConfigFileFlags{
+				SystemFile: filepath.Join("testdata", "system.yaml"),
+				KeysFile:   filepath.Join("testdata", "keys.yaml"),
+			},
+		},
+	}
+	input := strings.Join([]string{
+		"help",
+		"",
+		"list",
+		"device.on a b",
+		"nodevice.on",
+		"device",
+		"other-device.off",
+		"quit",
+		"device.another",
+	}, "\n")
+
+	var out strings.Builder
+	control := &Control{in: strings.NewReader(input), out: &out}
+	if err := control.Repl(ctx, &fl, nil); err != nil {
+		t.Fatal(err)
+	}
+	o := out.String()
+	for _, s := range []string{
+		"  list - list the operations supported by every controller and device\n",
+		"device: another, off, on\n",
+		"other-device: another, off, on\n",
+		"controller:\n",
+		"device[device].On: [2] a--b\n",
+		"error: unknown controller or device: nodevice\n",
+		`error: invalid: "device", should be device.operation/condition`,
+		"device[other-device].Off: [0] \n",
+	} {
+		if !strings.Contains(o, s) {
+			t.Errorf("failed to find %q in %q", s, o)
+		}
+	}
+	// Nothing is run after quit.
+	if strings.Contains(o, `"operation": "another"`) {
+		t.Errorf("operation run after quit: %v", o)
+	}
+	if got, want := strings.Count(o, replPrompt), 9; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// End of input terminates the session.
+	out.Reset()
+	control.in = strings.NewReader("device.on\n")
+	if err := control.Repl(ctx, &fl, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Count(out.String(), `"operation": "on"`), 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
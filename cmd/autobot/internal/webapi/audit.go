@@ -0,0 +1,86 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Audit events.
+const (
+	AuditReload        = "reload"
+	AuditOperation     = "operation"
+	AuditConditionally = "conditionally"
+	AuditPause         = "pause"
+	AuditResume        = "resume"
+	AuditSchedules     = "schedules"
+)
+
+// AuditEntry represents a single entry in the audit log.
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Event     string    `json:"event"`
+	User      string    `json:"user,omitempty"`
+	Remote    string    `json:"remote,omitempty"`
+	Device    string    `json:"device,omitempty"`
+	Op        string    `json:"op,omitempty"`
+	Args      []string  `json:"args,omitempty"`
+	Condition string    `json:"condition,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// AuditLog records changes made via the web API, such as config reloads,
+// manual operations and pause/resume events, as JSON encoded entries, one
+// per line. It is intended to be written to an append-only file that is
+// separate from the operational log. A nil AuditLog discards all entries.
+type AuditLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewAuditLog returns an AuditLog that writes to w.
+func NewAuditLog(w io.Writer) *AuditLog {
+	return &AuditLog{enc: json.NewEncoder(w)}
+}
+
+// Record records the entry for the specified request, the time, user
+// (if authenticated) and remote address are filled in by Record.
+func (a *AuditLog) Record(r *http.Request, entry AuditEntry) {
+	if a == nil {
+		return
+	}
+	entry.Time = time.Now()
+	entry.Remote = r.RemoteAddr
+	if user, _, ok := r.BasicAuth(); ok {
+		entry.User = user
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.enc.Encode(entry); err != nil && a.err == nil {
+		a.err = err
+	}
+}
+
+// Err returns the first error encountered writing to the audit log.
+func (a *AuditLog) Err() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.err
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
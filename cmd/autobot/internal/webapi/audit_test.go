@@ -0,0 +1,110 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package webapi_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/cmd/autobot/internal/webapi"
+	"github.com/cosnicolaou/automation/internal/logging"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+func auditEntries(t *testing.T, buf *bytes.Buffer) []webapi.AuditEntry {
+	t.Helper()
+	var entries []webapi.AuditEntry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e webapi.AuditEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestAuditLog(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now()
+	out := &bytes.Buffer{}
+	audit := webapi.NewAuditLog(out)
+
+	dc, srv := newTestServer(t, loaderFor(systemConfig, reloadedSystemConfig))
+	dc.SetAuditLog(audit)
+
+	status := webapi.NewStatusServer(logging.NewStatusRecorder(), nil, nil)
+	status.SetPauser(scheduler.NewPause())
+	status.SetAuditLog(audit)
+	mux := http.NewServeMux()
+	status.AppendEndpoints(ctx, mux)
+	statusSrv := httptest.NewServer(mux)
+	defer statusSrv.Close()
+
+	var or webapi.OperationResult
+	if got, want := postJSON(t, srv.URL+"/api/operation", webapi.Action{Device: "device", Op: "on", Args: []string{"a"}}, &or), http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/reload", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("admin", "secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	var pr webapi.PauseResponse
+	postJSON(t, statusSrv.URL+"/api/pause", nil, &pr)
+	postJSON(t, statusSrv.URL+"/api/resume", nil, &pr)
+
+	// Invalid requests are not recorded.
+	postJSON(t, srv.URL+"/api/operation", webapi.Action{Device: "device"}, nil)
+
+	if err := audit.Err(); err != nil {
+		t.Fatal(err)
+	}
+	entries := auditEntries(t, out)
+	var events []string
+	for _, e := range entries {
+		events = append(events, e.Event)
+		if e.Time.Before(start) || e.Time.After(time.Now()) {
+			t.Errorf("%v: unexpected time: %v", e.Event, e.Time)
+		}
+		if !strings.HasPrefix(e.Remote, "127.0.0.1:") {
+			t.Errorf("%v: unexpected remote: %v", e.Event, e.Remote)
+		}
+		if len(e.Error) != 0 {
+			t.Errorf("%v: unexpected error: %v", e.Event, e.Error)
+		}
+	}
+	if got, want := events, []string{webapi.AuditOperation, webapi.AuditReload, webapi.AuditPause, webapi.AuditResume}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	op := entries[0]
+	if got, want := op.Device+"."+op.Op, "device.on"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := op.Args, []string{"a"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := op.User, ""; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := entries[1].User, "admin"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
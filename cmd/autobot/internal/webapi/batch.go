@@ -0,0 +1,182 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"cloudeng.io/logging/ctxlog"
+)
+
+// BatchAction is an operation to be run as part of a batch, if Condition
+// is set the operation is only run if the condition is true.
+type BatchAction struct {
+	Action
+	Condition *Action `json:"condition,omitempty"`
+}
+
+// BatchRequest is the JSON body accepted by POST requests to /api/batch.
+// If StopOnError is set, no further actions are run once an action fails.
+type BatchRequest struct {
+	Actions     []BatchAction `json:"actions"`
+	StopOnError bool          `json:"stop_on_error,omitempty"`
+}
+
+// BatchResult is the result of a single action in a batch, Error and
+// Kind are set if either the condition or the operation failed. NotRun
+// is set if the action was not run because an earlier action failed.
+type BatchResult struct {
+	ConditionalOperationResult
+	Error  string    `json:"error,omitempty"`
+	Kind   ErrorKind `json:"kind,omitempty"`
+	NotRun bool      `json:"not_run,omitempty"`
+}
+
+// Outcomes of a BatchResult.
+const (
+	BatchSucceeded = "succeeded"
+	BatchFailed    = "failed"
+	BatchSkipped   = "skipped"
+	BatchNotRun    = "not-run"
+)
+
+// Outcome returns whether the action succeeded, failed, was skipped
+// because its condition was not met or was not run at all.
+func (br BatchResult) Outcome() string {
+	switch {
+	case br.NotRun:
+		return BatchNotRun
+	case len(br.Error) > 0:
+		return BatchFailed
+	case br.Operation == nil:
+		return BatchSkipped
+	}
+	return BatchSucceeded
+}
+
+// BatchSummary summarizes the results of a batch of actions.
+type BatchSummary struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+	NotRun    int `json:"not_run"`
+}
+
+// SummarizeResults returns a summary of the supplied results.
+func SummarizeResults(results []BatchResult) BatchSummary {
+	s := BatchSummary{Total: len(results)}
+	for _, r := range results {
+		switch r.Outcome() {
+		case BatchSucceeded:
+			s.Succeeded++
+		case BatchFailed:
+			s.Failed++
+		case BatchSkipped:
+			s.Skipped++
+		case BatchNotRun:
+			s.NotRun++
+		}
+	}
+	return s
+}
+
+// BatchResponse is returned by /api/batch and contains the result of
+// each action, in the order requested, and a summary of those results.
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+	Summary BatchSummary  `json:"summary"`
+}
+
+func (dc *DeviceControlServer) runBatchAction(ctx context.Context, writer io.Writer, r *http.Request, ba BatchAction) BatchResult {
+	var br BatchResult
+	entry := AuditEntry{
+		Event:  AuditOperation,
+		Device: ba.Device,
+		Op:     ba.Op,
+		Args:   ba.Args,
+	}
+	if ba.Condition != nil {
+		cr, err := dc.RunCondition(ctx, io.Discard, *ba.Condition)
+		if err != nil {
+			br.Error, br.Kind = err.Error(), ErrorKindOf(err)
+			return br
+		}
+		br.Condition = cr
+		if !cr.Result {
+			return br
+		}
+		entry.Event = AuditConditionally
+		entry.Condition = ba.Condition.String()
+	}
+	or, err := dc.RunOperation(ctx, writer, ba.Action)
+	entry.Error = errorString(err)
+	dc.audit.Record(r, entry)
+	if err != nil {
+		br.Error, br.Kind = err.Error(), ErrorKindOf(err)
+		return br
+	}
+	br.Operation = or
+	return br
+}
+
+// RunBatch runs the supplied actions sequentially, in order, and returns
+// the result of each. A failed action does not prevent subsequent actions
+// from being run unless stopOnError is set, in which case the results for
+// the remaining actions are marked as not run.
+func (dc *DeviceControlServer) RunBatch(ctx context.Context, writer io.Writer, r *http.Request, actions []BatchAction, stopOnError bool) BatchResponse {
+	resp := BatchResponse{Results: make([]BatchResult, 0, len(actions))}
+	stopped := false
+	for _, ba := range actions {
+		if stopped {
+			resp.Results = append(resp.Results, BatchResult{NotRun: true})
+			continue
+		}
+		br := dc.runBatchAction(ctx, writer, r, ba)
+		resp.Results = append(resp.Results, br)
+		stopped = stopOnError && br.Outcome() == BatchFailed
+	}
+	resp.Summary = SummarizeResults(resp.Results)
+	return resp
+}
+
+func decodeBatchRequest(w http.ResponseWriter, r *http.Request) (BatchRequest, error) {
+	var br BatchRequest
+	if r.Method != http.MethodPost {
+		return br, fmt.Errorf("POST required")
+	}
+	if err := decodeJSONBody(w, r, &br); err != nil {
+		return br, err
+	}
+	for i, ba := range br.Actions {
+		if _, err := validateAction(ba.Action, "operation"); err != nil {
+			return br, fmt.Errorf("action %v: %v", i, err)
+		}
+		if ba.Condition == nil {
+			continue
+		}
+		if _, err := validateAction(*ba.Condition, "condition"); err != nil {
+			return br, fmt.Errorf("action %v: %v", i, err)
+		}
+	}
+	return br, nil
+}
+
+// ServeBatch runs the actions specified by the JSON body of a POST request,
+// as a BatchRequest, and returns a BatchResponse.
+func (dc *DeviceControlServer) ServeBatch(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	ctx = ctxlog.WithAttributes(ctx, "component", "webapi", "request", r.URL.String())
+	ctxlog.Info(ctx, "batch-start")
+	br, err := decodeBatchRequest(w, r)
+	if err != nil {
+		dc.httpError(ctx, w, r.URL, "batch-end", err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp := dc.RunBatch(ctx, io.Discard, r, br.Actions, br.StopOnError)
+	dc.serveJSON(ctx, w, r.URL, "batch-end", resp)
+}
@@ -0,0 +1,152 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package webapi_test
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/cosnicolaou/automation/cmd/autobot/internal/webapi"
+)
+
+func TestSummarizeResults(t *testing.T) {
+	ok := webapi.BatchResult{}
+	ok.Operation = &webapi.OperationResult{Device: "device", Op: "on"}
+	failed := webapi.BatchResult{Error: "operation not configured", Kind: webapi.ErrorNotConfigured}
+	skipped := webapi.BatchResult{}
+	skipped.Condition = &webapi.ConditionResult{Device: "device", Cond: "raining"}
+	results := []webapi.BatchResult{ok, failed, skipped, ok, failed}
+
+	var outcomes []string
+	counts := map[string]int{}
+	for _, r := range results {
+		outcomes = append(outcomes, r.Outcome())
+		counts[r.Outcome()]++
+	}
+	if got, want := outcomes, []string{
+		webapi.BatchSucceeded,
+		webapi.BatchFailed,
+		webapi.BatchSkipped,
+		webapi.BatchSucceeded,
+		webapi.BatchFailed,
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	summary := webapi.SummarizeResults(results)
+	if got, want := summary, (webapi.BatchSummary{
+		Total:     len(results),
+		Succeeded: counts[webapi.BatchSucceeded],
+		Failed:    counts[webapi.BatchFailed],
+		Skipped:   counts[webapi.BatchSkipped],
+	}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got, want := summary, (webapi.BatchSummary{Total: 5, Succeeded: 2, Failed: 2, Skipped: 1}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got, want := webapi.SummarizeResults(nil), (webapi.BatchSummary{}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	_, srv := newTestServer(t, loaderFor(systemConfig+"      raining:\n"))
+	req := webapi.BatchRequest{Actions: []webapi.BatchAction{
+		{Action: webapi.Action{Device: "device", Op: "on", Args: []string{"a b"}}},
+		{Action: webapi.Action{Device: "device", Op: "unknown"}},
+		{Action: webapi.Action{Device: "device", Op: "off"},
+			Condition: &webapi.Action{Device: "device", Op: "raining"}},
+		{Action: webapi.Action{Device: "device", Op: "off"},
+			Condition: &webapi.Action{Device: "device", Op: "weather"}},
+		{Action: webapi.Action{Device: "not-a-device", Op: "off"}},
+	}}
+	var resp webapi.BatchResponse
+	if got, want := postJSON(t, srv.URL+"/api/batch", req, &resp), http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	var outcomes []string
+	counts := map[string]int{}
+	for _, r := range resp.Results {
+		outcomes = append(outcomes, r.Outcome())
+		counts[r.Outcome()]++
+	}
+	if got, want := outcomes, []string{
+		webapi.BatchSucceeded,
+		webapi.BatchFailed,
+		webapi.BatchSkipped,
+		webapi.BatchSucceeded,
+		webapi.BatchFailed,
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := resp.Summary, (webapi.BatchSummary{
+		Total:     len(resp.Results),
+		Succeeded: counts[webapi.BatchSucceeded],
+		Failed:    counts[webapi.BatchFailed],
+		Skipped:   counts[webapi.BatchSkipped],
+	}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got, want := resp.Summary, (webapi.BatchSummary{Total: 5, Succeeded: 2, Failed: 2, Skipped: 1}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got, want := deviceArgs(t, resp.Results[0].Operation.Data), []string{"a b"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	invalid := webapi.BatchRequest{Actions: []webapi.BatchAction{{Action: webapi.Action{Device: "device"}}}}
+	if got, want := postJSON(t, srv.URL+"/api/batch", invalid, nil), http.StatusBadRequest; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestBatchStopOnError(t *testing.T) {
+	_, srv := newTestServer(t, loaderFor(systemConfig))
+	actions := []webapi.BatchAction{
+		{Action: webapi.Action{Device: "device", Op: "on", Args: []string{"a"}}},
+		{Action: webapi.Action{Device: "device", Op: "unknown"}},
+		{Action: webapi.Action{Device: "device", Op: "off", Args: []string{"b"}}},
+		{Action: webapi.Action{Device: "device", Op: "on"}},
+	}
+	for _, tc := range []struct {
+		stopOnError bool
+		outcomes    []string
+		summary     webapi.BatchSummary
+	}{
+		{false,
+			[]string{webapi.BatchSucceeded, webapi.BatchFailed, webapi.BatchSucceeded, webapi.BatchSucceeded},
+			webapi.BatchSummary{Total: 4, Succeeded: 3, Failed: 1}},
+		{true,
+			[]string{webapi.BatchSucceeded, webapi.BatchFailed, webapi.BatchNotRun, webapi.BatchNotRun},
+			webapi.BatchSummary{Total: 4, Succeeded: 1, Failed: 1, NotRun: 2}},
+	} {
+		var resp webapi.BatchResponse
+		req := webapi.BatchRequest{Actions: actions, StopOnError: tc.stopOnError}
+		if got, want := postJSON(t, srv.URL+"/api/batch", req, &resp), http.StatusOK; got != want {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		var outcomes []string
+		for _, r := range resp.Results {
+			outcomes = append(outcomes, r.Outcome())
+		}
+		if got, want := outcomes, tc.outcomes; !slices.Equal(got, want) {
+			t.Errorf("stop_on_error: %v: got %v, want %v", tc.stopOnError, got, want)
+		}
+		if got, want := resp.Summary, tc.summary; got != want {
+			t.Errorf("stop_on_error: %v: got %+v, want %+v", tc.stopOnError, got, want)
+		}
+		if got, want := deviceArgs(t, resp.Results[0].Operation.Data), []string{"a"}; !slices.Equal(got, want) {
+			t.Errorf("stop_on_error: %v: got %q, want %q", tc.stopOnError, got, want)
+		}
+		if tc.stopOnError {
+			continue
+		}
+		if got, want := deviceArgs(t, resp.Results[2].Operation.Data), []string{"b"}; !slices.Equal(got, want) {
+			t.Errorf("stop_on_error: %v: got %q, want %q", tc.stopOnError, got, want)
+		}
+	}
+}
@@ -23,6 +23,15 @@ type DeviceControlServer struct {
 	mu       sync.Mutex
 	loaded   devices.System
 	reloader func(ctx context.Context) (devices.System, error)
+	audit    *AuditLog
+
+	snapshotOpts SnapshotOptions
+}
+
+// SetAuditLog sets the audit log used to record reloads and the
+// operations run via the API.
+func (dc *DeviceControlServer) SetAuditLog(a *AuditLog) {
+	dc.audit = a
 }
 
 func (dc *DeviceControlServer) system() devices.System {
@@ -31,23 +40,46 @@ func (dc *DeviceControlServer) system() devices.System {
 	return dc.loaded
 }
 
-func (dc *DeviceControlServer) reload(ctx context.Context) error {
+// System returns the currently loaded system.
+func (dc *DeviceControlServer) System() devices.System {
+	return dc.system()
+}
+
+func (dc *DeviceControlServer) reload(ctx context.Context) (SystemDelta, error) {
 	dc.mu.Lock()
 	defer dc.mu.Unlock()
 	system, err := dc.reloader(ctx)
 	if err != nil {
-		return err
+		return SystemDelta{}, err
 	}
+	delta := DiffSystems(dc.loaded, system)
 	dc.loaded = system
-	return nil
+	return delta, nil
+}
+
+// ReloadResponse is returned by /api/reload and contains the names of
+// all controllers and devices in the newly loaded system as well as
+// the changes relative to the previously loaded system.
+type ReloadResponse struct {
+	Controllers []string    `json:"controllers"`
+	Devices     []string    `json:"devices"`
+	Changes     SystemDelta `json:"changes"`
 }
 
 func (dc *DeviceControlServer) Reload(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	ctxlog.Info(ctx, "reload", "request", r.URL.String())
-	if err := dc.reload(ctx); err != nil {
+	delta, err := dc.reload(ctx)
+	dc.audit.Record(r, AuditEntry{Event: AuditReload, Error: errorString(err)})
+	if err != nil {
 		dc.httpError(ctx, w, r.URL, "reload", err.Error(), http.StatusInternalServerError)
 		return
 	}
+	cn, dn := names(dc.system())
+	dc.serveJSON(ctx, w, r.URL, "reload", ReloadResponse{
+		Controllers: cn,
+		Devices:     dn,
+		Changes:     delta,
+	})
 }
 
 func NewDeviceControlServer(ctx context.Context, systemLoader func(context.Context) (devices.System, error)) (*DeviceControlServer, error) {
@@ -78,9 +110,16 @@ func NewActionFromArgs(devOp string, args ...string) (Action, error) {
 // Action is a device.{operation,condition} string and optional
 // arguments and is used to represent an operation or condition
 type Action struct {
-	Device string
-	Op     string
-	Args   []string
+	Device string   `json:"device"`
+	Op     string   `json:"op"`
+	Args   []string `json:"args,omitempty"`
+}
+
+// ConditionalAction is the JSON body accepted by POST requests to
+// /api/conditionally.
+type ConditionalAction struct {
+	Operation Action `json:"operation"`
+	Condition Action `json:"condition"`
 }
 
 func (a Action) String() string {
@@ -93,14 +132,14 @@ func (dc *DeviceControlServer) RunOperationConditionally(ctx context.Context, wr
 	ctx = ctxlog.WithAttributes(ctx, "component", "webapi")
 	cr, err := dc.RunCondition(ctx, io.Discard, condition)
 	if err != nil {
-		return nil, fmt.Errorf("failed to run condition: %v: %v", condition.Op, err)
+		return nil, fmt.Errorf("failed to run condition: %v: %w", condition.Op, err)
 	}
 	if !cr.Result {
 		return nil, fmt.Errorf("condition not met: %v", condition.Op)
 	}
 	or, err := dc.RunOperation(ctx, writer, action)
 	if err != nil {
-		return nil, fmt.Errorf("failed to run operation: %v: %v", action.Op, err)
+		return nil, fmt.Errorf("failed to run operation: %v: %w", action.Op, err)
 	}
 	return or, nil
 }
@@ -110,7 +149,7 @@ func (dc *DeviceControlServer) RunOperation(ctx context.Context, writer io.Write
 	_, cok := dc.system().Controllers[action.Device]
 	_, dok := dc.system().Devices[action.Device]
 	if !cok && !dok {
-		return nil, fmt.Errorf("unknown controller or device: %v", action.Device)
+		return nil, newError(ErrorUnknownDevice, "unknown controller or device: %v", action.Device)
 	}
 
 	or := &OperationResult{
@@ -128,7 +167,7 @@ func (dc *DeviceControlServer) RunOperation(ctx context.Context, writer io.Write
 		}
 		result, err := fn(ctx, opts)
 		if err != nil {
-			return nil, fmt.Errorf("failed to run operation: %v: %v", action.Op, err)
+			return nil, deviceError(err, "failed to run operation: %v", action.Op)
 		}
 		or.Args = action.Args
 		or.Data = result
@@ -145,14 +184,14 @@ func (dc *DeviceControlServer) RunOperation(ctx context.Context, writer io.Write
 		}
 		result, err := fn(ctx, opts)
 		if err != nil {
-			return nil, fmt.Errorf("failed to run operation: %v: %v", action.Op, err)
+			return nil, deviceError(err, "failed to run operation: %v", action.Op)
 		}
 		or.Args = action.Args
 		or.Data = result
 		return or, nil
 	}
 
-	return nil, fmt.Errorf("unknown or not configured operation: %v, %v", action.Device, action.Op)
+	return nil, newError(ErrorNotConfigured, "unknown or not configured operation: %v, %v", action.Device, action.Op)
 }
 
 func (dc *DeviceControlServer) RunCondition(ctx context.Context, writer io.Writer, action Action) (*ConditionResult, error) {
@@ -160,7 +199,7 @@ func (dc *DeviceControlServer) RunCondition(ctx context.Context, writer io.Write
 	_, cok := dc.system().Controllers[action.Device]
 	_, dok := dc.system().Devices[action.Device]
 	if !cok && !dok {
-		return nil, fmt.Errorf("unknown controller or device: %v", action.Device)
+		return nil, newError(ErrorUnknownDevice, "unknown controller or device: %v", action.Device)
 	}
 	cr := &ConditionResult{
 		Device: action.Device,
@@ -176,7 +215,7 @@ func (dc *DeviceControlServer) RunCondition(ctx context.Context, writer io.Write
 		}
 		data, result, err := fn(ctx, opts)
 		if err != nil {
-			return nil, fmt.Errorf("failed to run condition: %v: %v", action.Op, err)
+			return nil, deviceError(err, "failed to run condition: %v", action.Op)
 		}
 		cr.Args = action.Args
 		cr.Result = result
@@ -184,10 +223,40 @@ func (dc *DeviceControlServer) RunCondition(ctx context.Context, writer io.Write
 		return cr, nil
 	}
 
-	return nil, fmt.Errorf("unknown or not configured condition: %v, %v", action.Device, action.Op)
+	return nil, newError(ErrorNotConfigured, "unknown or not configured condition: %v, %v", action.Device, action.Op)
 }
 
-func decodeOperationArgs(r *http.Request) (Action, error) {
+// maxRequestBodySize is the maximum size of a JSON encoded request body.
+const maxRequestBodySize = 64 * 1024
+
+// decodeJSONBody decodes the JSON encoded body of a POST request into v.
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid JSON request body: %v", err)
+	}
+	return nil
+}
+
+func validateAction(a Action, what string) (Action, error) {
+	if a.Device == "" || a.Op == "" {
+		return Action{}, fmt.Errorf("missing device or %v", what)
+	}
+	return a, nil
+}
+
+// decodeOperationArgs decodes the operation to be run from either
+// the JSON body of a POST request or the URL query parameters of
+// any other request.
+func decodeOperationArgs(w http.ResponseWriter, r *http.Request) (Action, error) {
+	if r.Method == http.MethodPost {
+		var a Action
+		if err := decodeJSONBody(w, r, &a); err != nil {
+			return Action{}, err
+		}
+		return validateAction(a, "operation")
+	}
 	pars := r.URL.Query()
 	a := Action{
 		Device: pars.Get("odev"),
@@ -200,7 +269,17 @@ func decodeOperationArgs(r *http.Request) (Action, error) {
 	return a, nil
 }
 
-func decodeConditionArgs(r *http.Request) (Action, error) {
+// decodeConditionArgs decodes the condition to be evaluated from either
+// the JSON body of a POST request or the URL query parameters of
+// any other request.
+func decodeConditionArgs(w http.ResponseWriter, r *http.Request) (Action, error) {
+	if r.Method == http.MethodPost {
+		var a Action
+		if err := decodeJSONBody(w, r, &a); err != nil {
+			return Action{}, err
+		}
+		return validateAction(a, "condition")
+	}
 	pars := r.URL.Query()
 	a := Action{
 		Device: pars.Get("cdev"),
@@ -213,23 +292,52 @@ func decodeConditionArgs(r *http.Request) (Action, error) {
 	return a, nil
 }
 
+// decodeConditionalArgs decodes the operation and condition for a
+// conditional operation from either the JSON body of a POST request,
+// as a ConditionalAction, or the URL query parameters of any other request.
+func decodeConditionalArgs(w http.ResponseWriter, r *http.Request) (op, cond Action, err error) {
+	if r.Method != http.MethodPost {
+		if op, err = decodeOperationArgs(w, r); err != nil {
+			return
+		}
+		cond, err = decodeConditionArgs(w, r)
+		return
+	}
+	var ca ConditionalAction
+	if err = decodeJSONBody(w, r, &ca); err != nil {
+		return
+	}
+	if op, err = validateAction(ca.Operation, "operation"); err != nil {
+		return
+	}
+	cond, err = validateAction(ca.Condition, "condition")
+	return
+}
+
 func (dc *DeviceControlServer) httpError(ctx context.Context, w http.ResponseWriter, u *url.URL, msg, err string, statusCode int) {
 	ctxlog.Info(ctx, msg, "component", "webapi", "request", u.String(), "code", statusCode, "error", err)
-	http.Error(w, err, http.StatusBadRequest)
+	http.Error(w, err, statusCode)
 }
 
 func (dc *DeviceControlServer) ServeOperation(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	ctx = ctxlog.WithAttributes(ctx, "component", "webapi", "request", r.URL.String())
 	ctxlog.Info(ctx, "op-start")
-	action, err := decodeOperationArgs(r)
+	action, err := decodeOperationArgs(w, r)
 	if err != nil {
-		dc.httpError(ctx, w, r.URL, "op-end", err.Error(), http.StatusBadRequest)
+		dc.serveError(ctx, w, r.URL, "op-end", invalidRequest(err))
 		return
 	}
 
 	or, err := dc.RunOperation(ctx, io.Discard, action)
+	dc.audit.Record(r, AuditEntry{
+		Event:  AuditOperation,
+		Device: action.Device,
+		Op:     action.Op,
+		Args:   action.Args,
+		Error:  errorString(err),
+	})
 	if err != nil {
-		dc.httpError(ctx, w, r.URL, "op-end", err.Error(), http.StatusInternalServerError)
+		dc.serveError(ctx, w, r.URL, "op-end", err)
 		return
 	}
 	dc.serveJSON(ctx, w, r.URL, "op-end", or)
@@ -238,27 +346,31 @@ func (dc *DeviceControlServer) ServeOperation(ctx context.Context, w http.Respon
 func (dc *DeviceControlServer) ServeOperationConditionally(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	ctx = ctxlog.WithAttributes(ctx, "component", "webapi", "request", r.URL.String())
 	ctxlog.Info(ctx, "op-start")
-	opAction, err := decodeOperationArgs(r)
-	if err != nil {
-		dc.httpError(ctx, w, r.URL, "op-end", err.Error(), http.StatusBadRequest)
-		return
-	}
-	condAction, err := decodeConditionArgs(r)
+	opAction, condAction, err := decodeConditionalArgs(w, r)
 	if err != nil {
-		dc.httpError(ctx, w, r.URL, "op-end", err.Error(), http.StatusBadRequest)
+		dc.serveError(ctx, w, r.URL, "op-end", invalidRequest(err))
 		return
 	}
 	cr, err := dc.RunCondition(ctx, io.Discard, condAction)
 	if err != nil {
-		dc.httpError(ctx, w, r.URL, "op-end", err.Error(), http.StatusInternalServerError)
+		dc.serveError(ctx, w, r.URL, "op-end", err)
+		return
 	}
 	if !cr.Result {
 		dc.serveJSON(ctx, w, r.URL, "op-end", ConditionalOperationResult{Condition: cr})
 		return
 	}
 	or, err := dc.RunOperation(ctx, io.Discard, opAction)
+	dc.audit.Record(r, AuditEntry{
+		Event:     AuditConditionally,
+		Device:    opAction.Device,
+		Op:        opAction.Op,
+		Args:      opAction.Args,
+		Condition: condAction.String(),
+		Error:     errorString(err),
+	})
 	if err != nil {
-		dc.httpError(ctx, w, r.URL, "op-end", err.Error(), http.StatusInternalServerError)
+		dc.serveError(ctx, w, r.URL, "op-end", err)
 		return
 	}
 	dc.serveJSON(ctx, w, r.URL, "op-end", ConditionalOperationResult{
@@ -270,14 +382,14 @@ func (dc *DeviceControlServer) ServeOperationConditionally(ctx context.Context,
 func (dc *DeviceControlServer) ServeCondition(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	ctx = ctxlog.WithAttributes(ctx, "component", "webapi", "request", r.URL.String())
 	ctxlog.Info(ctx, "cond-start")
-	action, err := decodeConditionArgs(r)
+	action, err := decodeConditionArgs(w, r)
 	if err != nil {
-		dc.httpError(ctx, w, r.URL, "cond-end", err.Error(), http.StatusBadRequest)
+		dc.serveError(ctx, w, r.URL, "cond-end", invalidRequest(err))
 		return
 	}
 	cr, err := dc.RunCondition(ctx, io.Discard, action)
 	if err != nil {
-		dc.httpError(ctx, w, r.URL, "cond-end", err.Error(), http.StatusInternalServerError)
+		dc.serveError(ctx, w, r.URL, "cond-end", err)
 		return
 	}
 	dc.serveJSON(ctx, w, r.URL, "cond-end", cr)
@@ -317,17 +429,28 @@ func (dc *DeviceControlServer) AppendEndpoints(ctx context.Context, mux *http.Se
 		dc.ServeOperationConditionally(ctx, w, r)
 	})
 
+	mux.HandleFunc("/api/operations", func(w http.ResponseWriter, r *http.Request) {
+		dc.ServeOperations(ctx, w, r)
+	})
+
+	mux.HandleFunc("/api/batch", func(w http.ResponseWriter, r *http.Request) {
+		dc.ServeBatch(ctx, w, r)
+	})
+
+	mux.HandleFunc("/api/evaluate", func(w http.ResponseWriter, r *http.Request) {
+		dc.ServeEvaluate(ctx, w, r)
+	})
+
+	mux.HandleFunc("/api/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		dc.ServeSnapshot(ctx, w, r)
+	})
+
+	mux.HandleFunc("/api/help", func(w http.ResponseWriter, r *http.Request) {
+		dc.ServeHelp(ctx, w, r)
+	})
+
 	mux.HandleFunc("/api/reload", func(w http.ResponseWriter, r *http.Request) {
 		dc.Reload(ctx, w, r)
-		sys := dc.system()
-		cn, dn := names(sys)
-		dc.serveJSON(ctx, w, r.URL, "reload", struct {
-			Controllers []string `json:"controllers"`
-			Devices     []string `json:"devices"`
-		}{
-			Controllers: cn,
-			Devices:     dn,
-		})
 	})
 }
 
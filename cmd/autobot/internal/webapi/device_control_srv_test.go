@@ -0,0 +1,291 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package webapi_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/cosnicolaou/automation/cmd/autobot/internal/webapi"
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/internal/testutil"
+)
+
+var (
+	supportedControllers = devices.SupportedControllers{
+		"controller": func(string, devices.Options) (devices.Controller, error) {
+			return &testutil.MockController{}, nil
+		},
+	}
+	supportedDevices = devices.SupportedDevices{
+		"device": func(string, devices.Options) (devices.Device, error) {
+			md := testutil.NewMockDevice("On", "Off")
+			md.AddCondition("weather", true)
+			md.AddCondition("raining", false)
+			return md, nil
+		},
+	}
+)
+
+const systemConfig = `
+controllers:
+  - name: controller
+    type: controller
+devices:
+  - name: device
+    type: device
+    controller: controller
+    operations:
+      on:
+      off:
+    conditions:
+      weather:
+`
+
+func loaderFor(configs ...string) func(ctx context.Context) (devices.System, error) {
+	i := 0
+	return func(ctx context.Context) (devices.System, error) {
+		cfg := configs[min(i, len(configs)-1)]
+		i++
+		return devices.ParseSystemConfig(ctx, []byte(cfg),
+			devices.WithDevices(supportedDevices),
+			devices.WithControllers(supportedControllers))
+	}
+}
+
+func newTestServer(t *testing.T, loader func(ctx context.Context) (devices.System, error)) (*webapi.DeviceControlServer, *httptest.Server) {
+	ctx := context.Background()
+	dc, err := webapi.NewDeviceControlServer(ctx, loader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	dc.AppendEndpoints(ctx, mux)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return dc, srv
+}
+
+func getJSON(t *testing.T, url string, v any) int {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+const reloadedSystemConfig = `
+controllers:
+  - name: controller
+    type: controller
+devices:
+  - name: device
+    type: device
+    controller: controller
+    operations:
+      on:
+    conditions:
+      weather:
+  - name: new-device
+    type: device
+    controller: controller
+    operations:
+      on:
+`
+
+func TestReloadDelta(t *testing.T) {
+	_, srv := newTestServer(t, loaderFor(systemConfig, reloadedSystemConfig))
+
+	var resp webapi.ReloadResponse
+	if got, want := getJSON(t, srv.URL+"/api/reload", &resp), http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := resp.Devices, []string{"device", "new-device"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	delta := resp.Changes
+	if got, want := delta.AddedDevices, []string{"new-device"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if len(delta.RemovedDevices) != 0 || len(delta.AddedControllers) != 0 || len(delta.RemovedControllers) != 0 {
+		t.Errorf("unexpected changes: %+v", delta)
+	}
+	if got, want := len(delta.Changed), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	changed := delta.Changed[0]
+	if got, want := changed.Name, "device:device"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := changed.RemovedOperations, []string{"off"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if len(changed.AddedOperations) != 0 || len(changed.AddedConditions) != 0 || len(changed.RemovedConditions) != 0 {
+		t.Errorf("unexpected changes: %+v", changed)
+	}
+
+	// Reloading the same config results in no changes.
+	resp = webapi.ReloadResponse{}
+	getJSON(t, srv.URL+"/api/reload", &resp)
+	if got, want := resp.Changes, (webapi.SystemDelta{}); !deltaEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestDiffSystemsSharedName(t *testing.T) {
+	system := func(ctrlOps, devOps map[string][]string) devices.System {
+		var cfg devices.SystemConfig
+		cfg.Controllers = []devices.ControllerConfig{{ControllerConfigCommon: devices.ControllerConfigCommon{Name: "shared", Operations: ctrlOps}}}
+		cfg.Devices = []devices.DeviceConfig{{DeviceConfigCommon: devices.DeviceConfigCommon{Name: "shared", Operations: devOps}}}
+		return devices.System{Config: cfg}
+	}
+	prev := system(map[string][]string{"on": nil}, map[string][]string{"on": nil})
+	next := system(map[string][]string{"off": nil}, map[string][]string{"on": nil, "off": nil})
+
+	// A controller and a device with the same name are reported separately.
+	delta := webapi.DiffSystems(prev, next)
+	var got []string
+	for _, c := range delta.Changed {
+		got = append(got, fmt.Sprintf("%v +%v -%v", c.Name, c.AddedOperations, c.RemovedOperations))
+	}
+	if want := []string{"controller:shared +[off] -[on]", "device:shared +[off] -[]"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func deltaEqual(a, b webapi.SystemDelta) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return string(ja) == string(jb)
+}
+
+func postJSON(t *testing.T, url string, body, v any) int {
+	t.Helper()
+	buf, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(url, "application/json", bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+// deviceArgs returns the arguments received by the mock device as
+// returned in the data field of an operation result.
+func deviceArgs(t *testing.T, data any) []string {
+	t.Helper()
+	var args []string
+	m, _ := data.(map[string]any)
+	l, _ := m["Args"].([]any)
+	for _, a := range l {
+		args = append(args, a.(string))
+	}
+	return args
+}
+
+func TestJSONRequests(t *testing.T) {
+	_, srv := newTestServer(t, loaderFor(systemConfig))
+	args := []string{"living room", "café ☕", "a&b=c?d"}
+
+	var or webapi.OperationResult
+	code := postJSON(t, srv.URL+"/api/operation",
+		webapi.Action{Device: "device", Op: "on", Args: args}, &or)
+	if got, want := code, http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := deviceArgs(t, or.Data), args; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	var cr webapi.ConditionResult
+	code = postJSON(t, srv.URL+"/api/condition",
+		webapi.Action{Device: "device", Op: "weather", Args: args}, &cr)
+	if got, want := code, http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := cr.Args, args; !slices.Equal(got, want) || !cr.Result {
+		t.Errorf("got %q, %v, want %q, true", got, cr.Result, want)
+	}
+
+	var cor webapi.ConditionalOperationResult
+	code = postJSON(t, srv.URL+"/api/conditionally", webapi.ConditionalAction{
+		Operation: webapi.Action{Device: "device", Op: "off", Args: args},
+		Condition: webapi.Action{Device: "device", Op: "weather"},
+	}, &cor)
+	if got, want := code, http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if cor.Operation == nil {
+		t.Fatalf("operation was not run")
+	}
+	if got, want := deviceArgs(t, cor.Operation.Data), args; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// GET requests with query parameters are still supported.
+	or = webapi.OperationResult{}
+	code = getJSON(t, srv.URL+"/api/operation?odev=device&op=on&oarg=a+b&oarg=c", &or)
+	if got, want := code, http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := deviceArgs(t, or.Data), []string{"a b", "c"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Invalid requests.
+	for _, body := range []any{
+		webapi.Action{Device: "device"},
+		map[string]any{"device": "device", "op": "on", "unknown": 1},
+		"not an object",
+	} {
+		if got, want := postJSON(t, srv.URL+"/api/operation", body, nil), http.StatusBadRequest; got != want {
+			t.Errorf("%v: got %v, want %v", body, got, want)
+		}
+	}
+}
+
+func TestHTTPErrorCodes(t *testing.T) {
+	calls := 0
+	_, srv := newTestServer(t, func(ctx context.Context) (devices.System, error) {
+		if calls++; calls > 1 {
+			return devices.System{}, errors.New("failed to reload")
+		}
+		return loaderFor(systemConfig)(ctx)
+	})
+	var resp webapi.ReloadResponse
+	if got, want := getJSON(t, srv.URL+"/api/reload", &resp), http.StatusInternalServerError; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := getJSON(t, srv.URL+"/api/help", nil), http.StatusBadRequest; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := getJSON(t, srv.URL+"/api/help?dev=unknown", nil), http.StatusNotFound; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
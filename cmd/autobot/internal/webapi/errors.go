@@ -0,0 +1,112 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"cloudeng.io/logging/ctxlog"
+	"github.com/cosnicolaou/automation/devices"
+)
+
+// ErrorKind classifies the errors returned by the API so that clients
+// can distinguish, for example, an unknown device from a device that
+// timed out.
+type ErrorKind string
+
+const (
+	ErrorInvalidRequest  ErrorKind = "invalid-request"  // The request was malformed.
+	ErrorUnknownDevice   ErrorKind = "unknown-device"   // No such controller or device.
+	ErrorNotConfigured   ErrorKind = "not-configured"   // No such operation or condition is configured.
+	ErrorTimeout         ErrorKind = "timeout"          // The device did not respond in time.
+	ErrorTransport       ErrorKind = "transport"        // The connection to the device failed.
+	ErrorOperationFailed ErrorKind = "operation-failed" // The operation or condition itself failed.
+)
+
+// StatusCode returns the HTTP status code used for errors of this kind.
+func (k ErrorKind) StatusCode() int {
+	switch k {
+	case ErrorInvalidRequest:
+		return http.StatusBadRequest
+	case ErrorUnknownDevice, ErrorNotConfigured:
+		return http.StatusNotFound
+	case ErrorTimeout:
+		return http.StatusGatewayTimeout
+	case ErrorTransport:
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+// Error is an error with an associated ErrorKind.
+type Error struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(kind ErrorKind, format string, args ...any) error {
+	return &Error{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+// deviceError wraps an error returned by an operation or condition with
+// an ErrorKind derived from devices.ClassifyError.
+func deviceError(err error, format string, args ...any) error {
+	kind := ErrorOperationFailed
+	switch devices.ClassifyError(err) {
+	case devices.ErrorTimeout:
+		kind = ErrorTimeout
+	case devices.ErrorTransport:
+		kind = ErrorTransport
+	}
+	return &Error{Kind: kind, Err: fmt.Errorf(format+": %w", append(args, err)...)}
+}
+
+// ErrorKindOf returns the ErrorKind of the supplied, non-nil, error,
+// errors without a kind are treated as ErrorOperationFailed.
+func ErrorKindOf(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return ErrorOperationFailed
+}
+
+// ErrorResponse is the JSON body returned by the operation and condition
+// endpoints when a request fails.
+type ErrorResponse struct {
+	Error string    `json:"error"`
+	Kind  ErrorKind `json:"kind"`
+}
+
+// serveError returns an ErrorResponse with an HTTP status code that
+// reflects the kind of the supplied error.
+func (dc *DeviceControlServer) serveError(ctx context.Context, w http.ResponseWriter, u *url.URL, msg string, err error) {
+	kind := ErrorKindOf(err)
+	code := kind.StatusCode()
+	ctxlog.Info(ctx, msg, "component", "webapi", "request", u.String(), "code", code, "kind", kind, "error", err.Error())
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error(), Kind: kind}); err != nil {
+		ctxlog.Info(ctx, msg, "component", "webapi", "request", u.String(), "error", fmt.Sprintf("failed to encode json response: %v", err))
+	}
+}
+
+func invalidRequest(err error) error {
+	return &Error{Kind: ErrorInvalidRequest, Err: err}
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package webapi_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/cosnicolaou/automation/cmd/autobot/internal/webapi"
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/internal/testutil"
+)
+
+// failingDevice is a device whose operations always fail.
+type failingDevice struct {
+	testutil.MockDevice
+}
+
+func (fd *failingDevice) Operations() map[string]devices.Operation {
+	return map[string]devices.Operation{
+		"slow": func(context.Context, devices.OperationArgs) (any, error) {
+			return nil, context.DeadlineExceeded
+		},
+		"broken": func(context.Context, devices.OperationArgs) (any, error) {
+			return nil, errors.New("broken")
+		},
+		"disconnected": func(context.Context, devices.OperationArgs) (any, error) {
+			return nil, io.ErrUnexpectedEOF
+		},
+	}
+}
+
+const failingSystemConfig = `
+devices:
+  - name: failing
+    type: failing
+    operations:
+      slow:
+      broken:
+      disconnected:
+`
+
+func postForError(t *testing.T, url string, body any) (int, webapi.ErrorResponse) {
+	t.Helper()
+	buf, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(url, "application/json", bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var er webapi.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&er); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, er
+}
+
+func TestErrorKinds(t *testing.T) {
+	_, srv := newTestServer(t, func(ctx context.Context) (devices.System, error) {
+		return devices.ParseSystemConfig(ctx, []byte(failingSystemConfig),
+			devices.WithDevices(devices.SupportedDevices{
+				"failing": func(string, devices.Options) (devices.Device, error) {
+					return &failingDevice{}, nil
+				},
+			}))
+	})
+
+	for _, tc := range []struct {
+		path   string
+		action webapi.Action
+		kind   webapi.ErrorKind
+		code   int
+	}{
+		{"/api/operation", webapi.Action{Device: "failing"}, webapi.ErrorInvalidRequest, http.StatusBadRequest},
+		{"/api/operation", webapi.Action{Device: "unknown", Op: "slow"}, webapi.ErrorUnknownDevice, http.StatusNotFound},
+		{"/api/operation", webapi.Action{Device: "failing", Op: "unknown"}, webapi.ErrorNotConfigured, http.StatusNotFound},
+		{"/api/operation", webapi.Action{Device: "failing", Op: "slow"}, webapi.ErrorTimeout, http.StatusGatewayTimeout},
+		{"/api/operation", webapi.Action{Device: "failing", Op: "disconnected"}, webapi.ErrorTransport, http.StatusBadGateway},
+		{"/api/operation", webapi.Action{Device: "failing", Op: "broken"}, webapi.ErrorOperationFailed, http.StatusInternalServerError},
+		{"/api/condition", webapi.Action{Device: "unknown", Op: "weather"}, webapi.ErrorUnknownDevice, http.StatusNotFound},
+		{"/api/condition", webapi.Action{Device: "failing", Op: "weather"}, webapi.ErrorNotConfigured, http.StatusNotFound},
+	} {
+		code, er := postForError(t, srv.URL+tc.path, tc.action)
+		if got, want := code, tc.code; got != want {
+			t.Errorf("%v: %v: got %v, want %v", tc.path, tc.action, got, want)
+		}
+		if got, want := er.Kind, tc.kind; got != want {
+			t.Errorf("%v: %v: got %v, want %v", tc.path, tc.action, got, want)
+		}
+		if len(er.Error) == 0 {
+			t.Errorf("%v: %v: missing error message", tc.path, tc.action)
+		}
+	}
+
+	// Errors encountered running a batch are classified in the same way.
+	var resp webapi.BatchResponse
+	code := postJSON(t, srv.URL+"/api/batch", webapi.BatchRequest{
+		Actions: []webapi.BatchAction{
+			{Action: webapi.Action{Device: "failing", Op: "slow"}},
+			{Action: webapi.Action{Device: "unknown", Op: "slow"}},
+		},
+	}, &resp)
+	if got, want := code, http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i, want := range []webapi.ErrorKind{webapi.ErrorTimeout, webapi.ErrorUnknownDevice} {
+		if got := resp.Results[i].Kind; got != want {
+			t.Errorf("%v: got %v, want %v", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"cloudeng.io/logging/ctxlog"
+)
+
+// Term is a single, possibly negated, condition within a
+// condition expression.
+type Term struct {
+	Negated   bool
+	Condition Action
+}
+
+func (t Term) String() string {
+	if t.Negated {
+		return "!" + t.Condition.String()
+	}
+	return t.Condition.String()
+}
+
+// Expression is a condition expression in disjunctive normal form,
+// ie. an OR of ANDs of terms.
+type Expression [][]Term
+
+// ParseExpression parses a condition expression of the form:
+//
+//	[!]device.condition[(arg, ...)] && ... || ...
+//
+// where && has a higher precedence than ||. Parentheses may only be
+// used to specify the arguments for a condition.
+func ParseExpression(expr string) (Expression, error) {
+	if len(strings.TrimSpace(expr)) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+	var parsed Expression
+	for _, or := range strings.Split(expr, "||") {
+		var and []Term
+		for _, t := range strings.Split(or, "&&") {
+			term, err := parseTerm(strings.TrimSpace(t))
+			if err != nil {
+				return nil, err
+			}
+			and = append(and, term)
+		}
+		parsed = append(parsed, and)
+	}
+	return parsed, nil
+}
+
+func parseTerm(t string) (Term, error) {
+	var term Term
+	if strings.HasPrefix(t, "!") {
+		term.Negated = true
+		t = strings.TrimSpace(t[1:])
+	}
+	var args []string
+	if idx := strings.Index(t, "("); idx >= 0 {
+		if !strings.HasSuffix(t, ")") {
+			return Term{}, fmt.Errorf("invalid term: %q, missing closing parenthesis", t)
+		}
+		for _, a := range strings.Split(t[idx+1:len(t)-1], ",") {
+			if a = strings.TrimSpace(a); len(a) > 0 {
+				args = append(args, a)
+			}
+		}
+		t = strings.TrimSpace(t[:idx])
+	}
+	a, err := NewActionFromArgs(t, args...)
+	if err != nil {
+		return Term{}, err
+	}
+	if _, err := validateAction(a, "condition"); err != nil {
+		return Term{}, fmt.Errorf("invalid term: %q: %v", t, err)
+	}
+	term.Condition = a
+	return term, nil
+}
+
+// EvaluateRequest is the JSON body accepted by POST requests to
+// /api/evaluate.
+type EvaluateRequest struct {
+	Expression string `json:"expression"`
+}
+
+// TermResult is the value of a single term in an evaluated expression,
+// Value takes account of any negation.
+type TermResult struct {
+	Term      string           `json:"term"`
+	Value     bool             `json:"value"`
+	Condition *ConditionResult `json:"condition"`
+}
+
+// EvaluateResponse is returned by /api/evaluate and contains the
+// result of the expression as well as the value of each of its terms.
+type EvaluateResponse struct {
+	Expression string       `json:"expression"`
+	Result     bool         `json:"result"`
+	Terms      []TermResult `json:"terms"`
+}
+
+// Evaluate evaluates every term in the supplied expression, without
+// short-circuiting, so that the value of each term can be reported.
+// No operations are run.
+func (dc *DeviceControlServer) Evaluate(ctx context.Context, writer io.Writer, expr Expression) (*EvaluateResponse, error) {
+	resp := &EvaluateResponse{}
+	for _, and := range expr {
+		andResult := true
+		for _, term := range and {
+			cr, err := dc.RunCondition(ctx, writer, term.Condition)
+			if err != nil {
+				return nil, err
+			}
+			value := cr.Result != term.Negated
+			resp.Terms = append(resp.Terms, TermResult{
+				Term:      term.String(),
+				Value:     value,
+				Condition: cr,
+			})
+			andResult = andResult && value
+		}
+		resp.Result = resp.Result || andResult
+	}
+	return resp, nil
+}
+
+func decodeExpression(w http.ResponseWriter, r *http.Request) (string, error) {
+	if r.Method == http.MethodPost {
+		var er EvaluateRequest
+		if err := decodeJSONBody(w, r, &er); err != nil {
+			return "", err
+		}
+		return er.Expression, nil
+	}
+	return r.URL.Query().Get("expr"), nil
+}
+
+// ServeEvaluate evaluates the condition expression specified either
+// by the JSON body of a POST request, as an EvaluateRequest, or by the
+// expr URL query parameter of any other request.
+func (dc *DeviceControlServer) ServeEvaluate(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	ctx = ctxlog.WithAttributes(ctx, "component", "webapi", "request", r.URL.String())
+	ctxlog.Info(ctx, "eval-start")
+	text, err := decodeExpression(w, r)
+	if err != nil {
+		dc.serveError(ctx, w, r.URL, "eval-end", invalidRequest(err))
+		return
+	}
+	expr, err := ParseExpression(text)
+	if err != nil {
+		dc.serveError(ctx, w, r.URL, "eval-end", invalidRequest(err))
+		return
+	}
+	resp, err := dc.Evaluate(ctx, io.Discard, expr)
+	if err != nil {
+		dc.serveError(ctx, w, r.URL, "eval-end", err)
+		return
+	}
+	resp.Expression = text
+	dc.serveJSON(ctx, w, r.URL, "eval-end", resp)
+}
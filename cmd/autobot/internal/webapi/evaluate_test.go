@@ -0,0 +1,128 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package webapi_test
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/cosnicolaou/automation/cmd/autobot/internal/webapi"
+)
+
+const evaluateSystemConfig = `
+controllers:
+  - name: controller
+    type: controller
+devices:
+  - name: device
+    type: device
+    controller: controller
+    conditions:
+      weather:
+      raining:
+`
+
+func TestParseExpression(t *testing.T) {
+	expr, err := webapi.ParseExpression(" device.weather(a, b) && !device.raining || device.raining ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := expr, (webapi.Expression{
+		{
+			{Condition: webapi.Action{Device: "device", Op: "weather", Args: []string{"a", "b"}}},
+			{Negated: true, Condition: webapi.Action{Device: "device", Op: "raining"}},
+		},
+		{
+			{Condition: webapi.Action{Device: "device", Op: "raining"}},
+		},
+	}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	for _, tc := range []string{
+		"",
+		"device",
+		"device.weather &&",
+		"device.weather(a",
+		"!.weather",
+	} {
+		if _, err := webapi.ParseExpression(tc); err == nil {
+			t.Errorf("%q: expected an error", tc)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	_, srv := newTestServer(t, loaderFor(evaluateSystemConfig))
+
+	terms := func(resp webapi.EvaluateResponse) (names []string, values []bool) {
+		for _, tr := range resp.Terms {
+			names = append(names, tr.Term)
+			values = append(values, tr.Value)
+		}
+		return
+	}
+
+	for _, tc := range []struct {
+		expr   string
+		result bool
+		terms  []string
+		values []bool
+	}{
+		{"device.weather", true,
+			[]string{"device.weather()"}, []bool{true}},
+		{"device.weather && device.raining", false,
+			[]string{"device.weather()", "device.raining()"}, []bool{true, false}},
+		{"device.weather && !device.raining", true,
+			[]string{"device.weather()", "!device.raining()"}, []bool{true, true}},
+		{"device.raining || !device.weather || device.weather(x)", true,
+			[]string{"device.raining()", "!device.weather()", "device.weather(x)"}, []bool{false, false, true}},
+	} {
+		var resp webapi.EvaluateResponse
+		code := postJSON(t, srv.URL+"/api/evaluate", webapi.EvaluateRequest{Expression: tc.expr}, &resp)
+		if got, want := code, http.StatusOK; got != want {
+			t.Fatalf("%v: got %v, want %v", tc.expr, got, want)
+		}
+		if got, want := resp.Expression, tc.expr; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got, want := resp.Result, tc.result; got != want {
+			t.Errorf("%v: got %v, want %v", tc.expr, got, want)
+		}
+		names, values := terms(resp)
+		if got, want := names, tc.terms; !slices.Equal(got, want) {
+			t.Errorf("%v: got %v, want %v", tc.expr, got, want)
+		}
+		if got, want := values, tc.values; !slices.Equal(got, want) {
+			t.Errorf("%v: got %v, want %v", tc.expr, got, want)
+		}
+		// The underlying condition result is not negated.
+		for i, tr := range resp.Terms {
+			if got, want := tr.Condition.Result, tr.Condition.Cond == "weather"; got != want {
+				t.Errorf("%v: %v: got %v, want %v", tc.expr, i, got, want)
+			}
+		}
+	}
+
+	// GET requests with a query parameter are also supported.
+	var resp webapi.EvaluateResponse
+	code := getJSON(t, srv.URL+"/api/evaluate?expr="+url.QueryEscape("!device.raining"), &resp)
+	if got, want := code, http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if !resp.Result || len(resp.Terms) != 1 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	// Invalid expressions and unknown conditions.
+	for _, expr := range []string{"device", "device.unknown", "nodevice.weather"} {
+		if got, want := postJSON(t, srv.URL+"/api/evaluate", webapi.EvaluateRequest{Expression: expr}, nil), http.StatusOK; got == want {
+			t.Errorf("%v: unexpected success", expr)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"cloudeng.io/logging/ctxlog"
+)
+
+// Help returns the documentation, as markdown, for the specified operation
+// on the named controller or device, or for all of its operations if op
+// is empty.
+func (dc *DeviceControlServer) Help(name, op string) (string, error) {
+	sys := dc.system()
+	var help map[string]string
+	if ctrl, ok := sys.Controllers[name]; ok {
+		help = ctrl.OperationsHelp()
+	} else if dev, ok := sys.Devices[name]; ok {
+		help = dev.OperationsHelp()
+	} else {
+		return "", fmt.Errorf("unknown controller or device: %v", name)
+	}
+	ops := []string{op}
+	if len(op) == 0 {
+		ops = make([]string, 0, len(help))
+		for k := range help {
+			ops = append(ops, k)
+		}
+		slices.Sort(ops)
+	}
+	docs := make([]string, 0, len(ops))
+	for _, op := range ops {
+		doc, ok := sys.OperationDoc(name, op)
+		if !ok {
+			return "", fmt.Errorf("no help available for: %v.%v", name, op)
+		}
+		docs = append(docs, doc.Markdown(name+"."+op))
+	}
+	return strings.Join(docs, "\n"), nil
+}
+
+// ServeHelp serves the documentation, as markdown, for the operation
+// specified by the dev and op URL query parameters, or for all of the
+// operations supported by dev if op is not specified.
+func (dc *DeviceControlServer) ServeHelp(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	ctx = ctxlog.WithAttributes(ctx, "component", "webapi", "request", r.URL.String())
+	ctxlog.Info(ctx, "help-start")
+	pars := r.URL.Query()
+	name := pars.Get("dev")
+	if len(name) == 0 {
+		dc.httpError(ctx, w, r.URL, "help-end", "missing device", http.StatusBadRequest)
+		return
+	}
+	md, err := dc.Help(name, pars.Get("op"))
+	if err != nil {
+		dc.httpError(ctx, w, r.URL, "help-end", err.Error(), http.StatusNotFound)
+		return
+	}
+	ctxlog.Info(ctx, "help-end", "code", http.StatusOK)
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	fmt.Fprint(w, md)
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package webapi_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/internal/testutil"
+)
+
+func documentedSystem(ctx context.Context) (devices.System, error) {
+	return devices.ParseSystemConfig(ctx, []byte(systemConfig),
+		devices.WithDevices(devices.SupportedDevices{
+			"device": func(string, devices.Options) (devices.Device, error) {
+				md := testutil.NewMockDevice("On", "Off")
+				md.AddCondition("weather", true)
+				md.SetOperationDoc("on", devices.Doc{
+					Description: "turn the device on",
+					Params:      []devices.ParamDoc{{Name: "level", Description: "brightness"}},
+					Examples:    []string{"device.on(50)"},
+				})
+				return md, nil
+			},
+		}),
+		devices.WithControllers(supportedControllers))
+}
+
+func getText(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestHelp(t *testing.T) {
+	_, srv := newTestServer(t, documentedSystem)
+
+	code, body := getText(t, srv.URL+"/api/help?dev=device&op=on")
+	if got, want := code, http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v: %v", got, want, body)
+	}
+	if got, want := body, "## device.on\n\nturn the device on\n\n### Parameters\n\n- `level`: brightness\n\n### Examples\n\n```\ndevice.on(50)\n```\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// All operations, the flat help is used for operations without
+	// structured documentation.
+	_, body = getText(t, srv.URL+"/api/help?dev=device")
+	if !strings.HasPrefix(body, "## device.off\n\nOff operation\n\n## device.on\n") {
+		t.Errorf("unexpected help: %q", body)
+	}
+
+	_, body = getText(t, srv.URL+"/api/help?dev=controller&op=enable")
+	if got, want := body, "## controller.enable\n\nenable the controller\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	for _, tc := range []string{"", "?dev=unknown", "?dev=device&op=unknown"} {
+		if code, _ := getText(t, srv.URL+"/api/help"+tc); code == http.StatusOK {
+			t.Errorf("%q: expected an error", tc)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"cloudeng.io/logging/ctxlog"
+	"github.com/cosnicolaou/automation/devices"
+)
+
+// SystemOperations describes the location of a system and the operations
+// and conditions supported by its controllers and devices. It is returned
+// by /api/operations and displayed by 'config operations --format=json'.
+// Controllers, devices, operations and conditions are sorted by name.
+type SystemOperations struct {
+	Location    SystemLocation    `json:"location"`
+	Controllers []NamedOperations `json:"controllers"`
+	Devices     []NamedOperations `json:"devices"`
+	Conditions  []NamedOperations `json:"conditions"`
+}
+
+// SystemLocation is the location of a system, Latitude and Longitude
+// are only set if they were configured.
+type SystemLocation struct {
+	TimeZone  string   `json:"time_zone"`
+	ZIPCode   string   `json:"zip_code,omitempty"`
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+}
+
+// NamedOperations represents the operations, or conditions, supported
+// by a single controller or device.
+type NamedOperations struct {
+	Name       string          `json:"name"`
+	Operations []OperationInfo `json:"operations"`
+}
+
+// OperationInfo represents a single operation or condition, Args are
+// the arguments it is configured with, if any, and Configured is true
+// if it appears in the system configuration.
+type OperationInfo struct {
+	Name       string   `json:"name"`
+	Args       []string `json:"args,omitempty"`
+	Help       string   `json:"help,omitempty"`
+	Configured bool     `json:"configured"`
+}
+
+func sortedNames[Map ~map[string]V, V any](m Map) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func newNamedOperations(name string, ops []string, args map[string][]string, help map[string]string) NamedOperations {
+	no := NamedOperations{Name: name, Operations: []OperationInfo{}}
+	for _, op := range ops {
+		pars, configured := args[op]
+		no.Operations = append(no.Operations, OperationInfo{
+			Name:       op,
+			Args:       pars,
+			Help:       help[op],
+			Configured: configured,
+		})
+	}
+	return no
+}
+
+func newSystemLocation(loc devices.Location) SystemLocation {
+	sl := SystemLocation{ZIPCode: loc.ZIPCode}
+	if loc.TimeLocation != nil {
+		sl.TimeZone = loc.TimeLocation.String()
+	}
+	if loc.LatLongSet {
+		lat, long := loc.Latitude, loc.Longitude
+		sl.Latitude, sl.Longitude = &lat, &long
+	}
+	return sl
+}
+
+// NewSystemOperations returns the SystemOperations for the supplied system.
+func NewSystemOperations(system devices.System) SystemOperations {
+	so := SystemOperations{
+		Location:    newSystemLocation(system.Location),
+		Controllers: []NamedOperations{},
+		Devices:     []NamedOperations{},
+		Conditions:  []NamedOperations{},
+	}
+	for _, name := range sortedNames(system.Controllers) {
+		ctrl := system.Controllers[name]
+		so.Controllers = append(so.Controllers, newNamedOperations(name,
+			sortedNames(ctrl.Operations()), ctrl.Config().Operations, ctrl.OperationsHelp()))
+	}
+	for _, name := range sortedNames(system.Devices) {
+		dev := system.Devices[name]
+		cfg := dev.Config()
+		so.Devices = append(so.Devices, newNamedOperations(name,
+			sortedNames(dev.Operations()), cfg.Operations, dev.OperationsHelp()))
+		if conds := dev.Conditions(); len(conds) > 0 {
+			so.Conditions = append(so.Conditions, newNamedOperations(name,
+				sortedNames(conds), cfg.Conditions, dev.ConditionsHelp()))
+		}
+	}
+	return so
+}
+
+// ServeOperations returns the SystemOperations for the currently
+// loaded system.
+func (dc *DeviceControlServer) ServeOperations(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	ctx = ctxlog.WithAttributes(ctx, "component", "webapi", "request", r.URL.String())
+	ctxlog.Info(ctx, "operations-start")
+	dc.serveJSON(ctx, w, r.URL, "operations-end", NewSystemOperations(dc.system()))
+}
@@ -0,0 +1,76 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package webapi_test
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/cosnicolaou/automation/cmd/autobot/internal/webapi"
+)
+
+const operationsSystemConfig = `
+time_location: America/Los_Angeles
+latitude: 37.4
+longitude: -122.1
+controllers:
+  - name: controller
+    type: controller
+devices:
+  - name: device
+    type: device
+    controller: controller
+    operations:
+      on: ["bright"]
+    conditions:
+      weather:
+`
+
+func TestOperations(t *testing.T) {
+	_, srv := newTestServer(t, loaderFor(operationsSystemConfig))
+
+	var so webapi.SystemOperations
+	if got, want := getJSON(t, srv.URL+"/api/operations", &so), http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	loc := so.Location
+	if got, want := loc.TimeZone, "America/Los_Angeles"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if loc.Latitude == nil || loc.Longitude == nil || *loc.Latitude != 37.4 || *loc.Longitude != -122.1 {
+		t.Errorf("unexpected latitude/longitude: %v, %v", loc.Latitude, loc.Longitude)
+	}
+
+	describe := func(nos []webapi.NamedOperations) []string {
+		var d []string
+		for _, no := range nos {
+			for _, op := range no.Operations {
+				d = append(d, fmt.Sprintf("%v.%v%v:%v:%v", no.Name, op.Name, op.Args, op.Help, op.Configured))
+			}
+		}
+		return d
+	}
+	if got, want := describe(so.Controllers), []string{
+		"controller.disable[]:disable the controller:false",
+		"controller.enable[]:enable the controller:false",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := describe(so.Devices), []string{
+		"device.off[]:Off operation:false",
+		"device.on[bright]:On operation:true",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := describe(so.Conditions), []string{
+		"device.raining[]:raining condition: outcome false:false",
+		"device.weather[]:weather condition: outcome true:true",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"cloudeng.io/logging/ctxlog"
+)
+
+// Defaults used for any SnapshotOptions fields that are not set.
+const (
+	DefaultSnapshotConcurrency = 8
+	DefaultSnapshotPerDevice   = 1
+	DefaultSnapshotTimeout     = 10 * time.Second
+)
+
+// SnapshotOptions controls how the conditions in a snapshot are evaluated.
+// Concurrency is the maximum number of conditions evaluated at once across
+// all devices and PerDevice the maximum for any single device. Timeout
+// bounds the time allowed for each condition; a condition that does not
+// complete in time is reported as failed and no longer counts against
+// either limit.
+type SnapshotOptions struct {
+	Concurrency int
+	PerDevice   int
+	Timeout     time.Duration
+}
+
+func (o SnapshotOptions) withDefaults() SnapshotOptions {
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultSnapshotConcurrency
+	}
+	if o.PerDevice <= 0 {
+		o.PerDevice = DefaultSnapshotPerDevice
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultSnapshotTimeout
+	}
+	return o
+}
+
+// SetSnapshotOptions sets the options used by the /api/snapshot endpoint.
+func (dc *DeviceControlServer) SetSnapshotOptions(opts SnapshotOptions) {
+	dc.snapshotOpts = opts
+}
+
+// SnapshotResult is the result of evaluating a single condition as part
+// of a snapshot, Error is set if the condition failed or timed out.
+type SnapshotResult struct {
+	Result bool   `json:"status"`
+	Data   any    `json:"data,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// SnapshotResponse is returned by /api/snapshot and contains the result
+// of every configured condition indexed by device and then condition.
+type SnapshotResponse struct {
+	Devices map[string]map[string]SnapshotResult `json:"devices"`
+}
+
+// Snapshot evaluates every configured condition on every device
+// concurrently, subject to the limits specified by opts.
+func (dc *DeviceControlServer) Snapshot(ctx context.Context, opts SnapshotOptions) SnapshotResponse {
+	opts = opts.withDefaults()
+	sys := dc.system()
+	resp := SnapshotResponse{Devices: map[string]map[string]SnapshotResult{}}
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		all = make(chan struct{}, opts.Concurrency)
+	)
+	for name := range sys.Devices {
+		cfg, _, _ := sys.DeviceConfigs(name)
+		if len(cfg.Conditions) == 0 {
+			continue
+		}
+		resp.Devices[name] = map[string]SnapshotResult{}
+		perDevice := make(chan struct{}, opts.PerDevice)
+		for cond := range cfg.Conditions {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				perDevice <- struct{}{}
+				defer func() { <-perDevice }()
+				all <- struct{}{}
+				defer func() { <-all }()
+				res := dc.snapshotCondition(ctx, Action{Device: name, Op: cond}, opts.Timeout)
+				mu.Lock()
+				defer mu.Unlock()
+				resp.Devices[name][cond] = res
+			}()
+		}
+	}
+	wg.Wait()
+	return resp
+}
+
+// snapshotCondition evaluates a single condition, returning once the
+// timeout expires even if the condition itself ignores its context.
+func (dc *DeviceControlServer) snapshotCondition(ctx context.Context, action Action, timeout time.Duration) SnapshotResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ch := make(chan SnapshotResult, 1)
+	go func() {
+		cr, err := dc.RunCondition(ctx, io.Discard, action)
+		if err != nil {
+			ch <- SnapshotResult{Error: err.Error()}
+			return
+		}
+		ch <- SnapshotResult{Result: cr.Result, Data: cr.Data}
+	}()
+	select {
+	case res := <-ch:
+		return res
+	case <-ctx.Done():
+		return SnapshotResult{Error: fmt.Sprintf("%v: timed out after %v", action.Op, timeout)}
+	}
+}
+
+// ServeSnapshot evaluates every configured condition on every device
+// and returns the results as a SnapshotResponse.
+func (dc *DeviceControlServer) ServeSnapshot(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	ctx = ctxlog.WithAttributes(ctx, "component", "webapi", "request", r.URL.String())
+	ctxlog.Info(ctx, "snapshot-start")
+	dc.serveJSON(ctx, w, r.URL, "snapshot-end", dc.Snapshot(ctx, dc.snapshotOpts))
+}
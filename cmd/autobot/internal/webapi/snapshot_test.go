@@ -0,0 +1,110 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package webapi_test
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/cmd/autobot/internal/webapi"
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/internal/testutil"
+)
+
+// slowDevice has a condition, slow, that ignores its context and does
+// not return until released.
+type slowDevice struct {
+	*testutil.MockDevice
+	release chan struct{}
+}
+
+func (sd *slowDevice) Conditions() map[string]devices.Condition {
+	conds := maps.Clone(sd.MockDevice.Conditions())
+	conds["slow"] = func(context.Context, devices.OperationArgs) (any, bool, error) {
+		<-sd.release
+		return nil, true, nil
+	}
+	return conds
+}
+
+const snapshotSystemConfig = `
+controllers:
+  - name: controller
+    type: controller
+devices:
+  - name: device
+    type: device
+    controller: controller
+    conditions:
+      weather:
+      raining:
+  - name: slow
+    type: slow
+    controller: controller
+    conditions:
+      weather:
+      slow:
+  - name: no-conditions
+    type: device
+    controller: controller
+`
+
+func TestSnapshot(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	supported := maps.Clone(supportedDevices)
+	supported["slow"] = func(string, devices.Options) (devices.Device, error) {
+		md := testutil.NewMockDevice()
+		md.AddCondition("weather", true)
+		return &slowDevice{MockDevice: md, release: release}, nil
+	}
+	loader := func(ctx context.Context) (devices.System, error) {
+		return devices.ParseSystemConfig(ctx, []byte(snapshotSystemConfig),
+			devices.WithDevices(supported),
+			devices.WithControllers(supportedControllers))
+	}
+	dc, srv := newTestServer(t, loader)
+	dc.SetSnapshotOptions(webapi.SnapshotOptions{Timeout: 100 * time.Millisecond})
+
+	start := time.Now()
+	var resp webapi.SnapshotResponse
+	if got, want := getJSON(t, srv.URL+"/api/snapshot", &resp), http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if took := time.Since(start); took > 5*time.Second {
+		t.Errorf("snapshot took too long: %v", took)
+	}
+
+	if got, want := len(resp.Devices), 2; got != want {
+		t.Fatalf("got %v, want %v: %+v", got, want, resp)
+	}
+	for _, tc := range []struct {
+		device, cond string
+		result       bool
+	}{
+		{"device", "weather", true},
+		{"device", "raining", false},
+		{"slow", "weather", true},
+	} {
+		res, ok := resp.Devices[tc.device][tc.cond]
+		if !ok {
+			t.Errorf("%v.%v: missing result", tc.device, tc.cond)
+			continue
+		}
+		if len(res.Error) > 0 {
+			t.Errorf("%v.%v: unexpected error: %v", tc.device, tc.cond, res.Error)
+		}
+		if got, want := res.Result, tc.result; got != want {
+			t.Errorf("%v.%v: got %v, want %v", tc.device, tc.cond, got, want)
+		}
+	}
+	if res := resp.Devices["slow"]["slow"]; !strings.Contains(res.Error, "timed out") {
+		t.Errorf("missing or unexpected error: %+v", res)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -16,22 +17,78 @@ import (
 	"cloudeng.io/datetime"
 	"cloudeng.io/logging/ctxlog"
 	"github.com/cosnicolaou/automation/internal/logging"
+	"github.com/cosnicolaou/automation/scheduler"
 )
 
 type CalenderGenerator func(schedules []string, dr datetime.CalendarDateRange) (CalendarResponse, error)
 
+// UsesLookup returns every reference to the named controller or device
+// by the currently configured schedules.
+type UsesLookup func(device string) ([]scheduler.DeviceUse, error)
+
+// ScheduleUpdater parses and validates the supplied schedule configuration
+// and, if valid, replaces the running schedules with it. It returns the
+// names of the new schedules.
+type ScheduleUpdater func(ctx context.Context, cfg []byte) ([]string, error)
+
+// Pauser is implemented by types that can pause and resume scheduling.
+type Pauser interface {
+	Pause()
+	Resume()
+	Paused() bool
+}
+
 type Status struct {
-	sr     *logging.StatusRecorder
-	calGen CalenderGenerator
+	sr       *logging.StatusRecorder
+	counters *logging.CounterStore
+	calGen   CalenderGenerator
+	pauser   Pauser
+	audit    *AuditLog
+	maxSpan  int
+	uses     UsesLookup
+	updater  ScheduleUpdater
 }
 
-func NewStatusServer(sr *logging.StatusRecorder, calGen CalenderGenerator) *Status {
+// NewStatusServer creates a new status server, counters may be nil.
+func NewStatusServer(sr *logging.StatusRecorder, counters *logging.CounterStore, calGen CalenderGenerator) *Status {
 	return &Status{
-		sr:     sr,
-		calGen: calGen,
+		sr:       sr,
+		counters: counters,
+		calGen:   calGen,
+		maxSpan:  scheduler.DefaultMaxCalendarSpan,
 	}
 }
 
+// SetMaxCalendarSpan sets the maximum number of days that may be requested
+// from the /api/calendar endpoint, zero or less means no limit.
+func (s *Status) SetMaxCalendarSpan(days int) {
+	s.maxSpan = days
+}
+
+// SetPauser sets the Pauser used by the /api/pause and /api/resume
+// endpoints, which are only available if a Pauser is set before
+// AppendEndpoints is called.
+func (s *Status) SetPauser(p Pauser) {
+	s.pauser = p
+}
+
+// SetUsesLookup sets the function used by the /api/uses endpoint, which
+// is only available if it is set before AppendEndpoints is called.
+func (s *Status) SetUsesLookup(uses UsesLookup) {
+	s.uses = uses
+}
+
+// SetScheduleUpdater sets the function used by the /api/schedules endpoint,
+// which is only available if it is set before AppendEndpoints is called.
+func (s *Status) SetScheduleUpdater(updater ScheduleUpdater) {
+	s.updater = updater
+}
+
+// SetAuditLog sets the audit log used to record pause/resume events.
+func (s *Status) SetAuditLog(a *AuditLog) {
+	s.audit = a
+}
+
 type CompletionResponse struct {
 	Schedule         string `json:"schedule"`
 	Device           string `json:"device"`
@@ -73,7 +130,7 @@ func (s *Status) completed(num int64, recent bool) []CompletionResponse {
 
 func (s *Status) httpError(ctx context.Context, w http.ResponseWriter, u *url.URL, msg, err string, statusCode int) {
 	ctxlog.Info(ctx, msg, "component", "status", "request", u.String(), "code", statusCode, "error", err)
-	http.Error(w, err, http.StatusBadRequest)
+	http.Error(w, err, statusCode)
 }
 
 func (s *Status) ServeCompleted(ctx context.Context, w http.ResponseWriter, r *http.Request) {
@@ -134,6 +191,17 @@ func (s *Status) ServePending(ctx context.Context, w http.ResponseWriter, r *htt
 	}
 }
 
+func (s *Status) ServeCounters(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	counters := []logging.Counter{}
+	if s.counters != nil {
+		counters = s.counters.Counters()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(counters); err != nil {
+		s.httpError(ctx, w, r.URL, "counters", err.Error(), http.StatusInternalServerError)
+	}
+}
+
 type CalendarResponse struct {
 	Range     string          `json:"range"`
 	Schedules []string        `json:"schedules"`
@@ -172,6 +240,10 @@ func (s *Status) ServeCalendar(ctx context.Context, w http.ResponseWriter, r *ht
 		s.httpError(ctx, w, r.URL, "calendar", err.Error(), http.StatusBadRequest)
 		return
 	}
+	if err := scheduler.CheckCalendarSpan(dr, s.maxSpan); err != nil {
+		s.httpError(ctx, w, r.URL, "calendar", err.Error(), http.StatusBadRequest)
+		return
+	}
 	cr, err := s.calGen(scheds, dr)
 	if err != nil {
 		s.httpError(ctx, w, r.URL, "calendar", err.Error(), http.StatusInternalServerError)
@@ -183,6 +255,94 @@ func (s *Status) ServeCalendar(ctx context.Context, w http.ResponseWriter, r *ht
 	}
 }
 
+// UsesResponse is returned by /api/uses.
+type UsesResponse struct {
+	Device string                `json:"device"`
+	Uses   []scheduler.DeviceUse `json:"uses"`
+}
+
+// ServeUses serves every reference to the controller or device specified
+// by the device URL query parameter by the configured schedules.
+func (s *Status) ServeUses(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	device := r.URL.Query().Get("device")
+	if len(device) == 0 {
+		s.httpError(ctx, w, r.URL, "uses", "missing device", http.StatusBadRequest)
+		return
+	}
+	uses, err := s.uses(device)
+	if err != nil {
+		s.httpError(ctx, w, r.URL, "uses", err.Error(), http.StatusNotFound)
+		return
+	}
+	if uses == nil {
+		uses = []scheduler.DeviceUse{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(UsesResponse{Device: device, Uses: uses}); err != nil {
+		s.httpError(ctx, w, r.URL, "uses", err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// SchedulesResponse is returned by /api/schedules.
+type SchedulesResponse struct {
+	Schedules []string `json:"schedules"`
+}
+
+// maxScheduleSize is the largest schedule configuration accepted by
+// /api/schedules.
+const maxScheduleSize = 1 << 20
+
+// ServeSchedules replaces the running schedules with the YAML schedule
+// configuration in the body of a POST request. The on-disk schedule file
+// is not changed and an invalid configuration is rejected leaving the
+// running schedules unchanged.
+func (s *Status) ServeSchedules(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.httpError(ctx, w, r.URL, "schedules", "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	cfg, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxScheduleSize))
+	if err != nil {
+		s.httpError(ctx, w, r.URL, "schedules", err.Error(), http.StatusBadRequest)
+		return
+	}
+	names, err := s.updater(ctx, cfg)
+	s.audit.Record(r, AuditEntry{Event: AuditSchedules, Error: errorString(err)})
+	if err != nil {
+		s.httpError(ctx, w, r.URL, "schedules", err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctxlog.Info(ctx, "schedules", "component", "status", "request", r.URL.String(), "schedules", names)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(SchedulesResponse{Schedules: names}); err != nil {
+		s.httpError(ctx, w, r.URL, "schedules", err.Error(), http.StatusInternalServerError)
+	}
+}
+
+type PauseResponse struct {
+	Paused bool `json:"paused"`
+}
+
+// ServePause pauses, or resumes, scheduling and returns the resulting state.
+func (s *Status) ServePause(ctx context.Context, w http.ResponseWriter, r *http.Request, pause bool) {
+	if r.Method != http.MethodPost {
+		s.httpError(ctx, w, r.URL, "pause", "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	if pause {
+		s.pauser.Pause()
+		s.audit.Record(r, AuditEntry{Event: AuditPause})
+	} else {
+		s.pauser.Resume()
+		s.audit.Record(r, AuditEntry{Event: AuditResume})
+	}
+	ctxlog.Info(ctx, "pause", "component", "status", "request", r.URL.String(), "paused", s.pauser.Paused())
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(PauseResponse{Paused: s.pauser.Paused()}); err != nil {
+		s.httpError(ctx, w, r.URL, "pause", err.Error(), http.StatusInternalServerError)
+	}
+}
+
 func (s *Status) AppendEndpoints(ctx context.Context, mux *http.ServeMux) {
 	mux.HandleFunc("/api/completed", func(w http.ResponseWriter, r *http.Request) {
 		s.ServeCompleted(ctx, w, r)
@@ -193,4 +353,26 @@ func (s *Status) AppendEndpoints(ctx context.Context, mux *http.ServeMux) {
 	mux.HandleFunc("/api/calendar", func(w http.ResponseWriter, r *http.Request) {
 		s.ServeCalendar(ctx, w, r)
 	})
+	mux.HandleFunc("/api/counters", func(w http.ResponseWriter, r *http.Request) {
+		s.ServeCounters(ctx, w, r)
+	})
+	if s.uses != nil {
+		mux.HandleFunc("/api/uses", func(w http.ResponseWriter, r *http.Request) {
+			s.ServeUses(ctx, w, r)
+		})
+	}
+	if s.updater != nil {
+		mux.HandleFunc("/api/schedules", func(w http.ResponseWriter, r *http.Request) {
+			s.ServeSchedules(ctx, w, r)
+		})
+	}
+	if s.pauser == nil {
+		return
+	}
+	mux.HandleFunc("/api/pause", func(w http.ResponseWriter, r *http.Request) {
+		s.ServePause(ctx, w, r, true)
+	})
+	mux.HandleFunc("/api/resume", func(w http.ResponseWriter, r *http.Request) {
+		s.ServePause(ctx, w, r, false)
+	})
 }
@@ -0,0 +1,197 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package webapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"cloudeng.io/datetime"
+	"github.com/cosnicolaou/automation/cmd/autobot/internal/webapi"
+	"github.com/cosnicolaou/automation/internal/logging"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+func TestPauseResume(t *testing.T) {
+	ctx := context.Background()
+	pause := scheduler.NewPause()
+	status := webapi.NewStatusServer(logging.NewStatusRecorder(), nil, nil)
+	status.SetPauser(pause)
+	mux := http.NewServeMux()
+	status.AppendEndpoints(ctx, mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var resp webapi.PauseResponse
+	if got, want := postJSON(t, srv.URL+"/api/pause", nil, &resp), http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if !resp.Paused || !pause.Paused() {
+		t.Errorf("not paused: %v %v", resp.Paused, pause.Paused())
+	}
+	if got := getJSON(t, srv.URL+"/api/resume", &resp); got == http.StatusOK {
+		t.Errorf("GET should not be allowed")
+	}
+	if !pause.Paused() {
+		t.Errorf("not paused")
+	}
+	if got, want := postJSON(t, srv.URL+"/api/resume", nil, &resp), http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if resp.Paused || pause.Paused() {
+		t.Errorf("still paused: %v %v", resp.Paused, pause.Paused())
+	}
+}
+
+func TestCalendarSpan(t *testing.T) {
+	ctx := context.Background()
+	calGen := func(scheds []string, dr datetime.CalendarDateRange) (webapi.CalendarResponse, error) {
+		return webapi.CalendarResponse{Range: dr.String(), Schedules: scheds}, nil
+	}
+	status := webapi.NewStatusServer(logging.NewStatusRecorder(), nil, calGen)
+	mux := http.NewServeMux()
+	status.AppendEndpoints(ctx, mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var resp webapi.CalendarResponse
+	if got, want := getJSON(t, srv.URL+"/api/calendar?from=01/01/2025&to=12/31/2027", &resp), http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := resp.Range, "01/01/2025 - 12/31/2027"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := getJSON(t, srv.URL+"/api/calendar?from=01/01/2025&to=12/31/2055", &resp), http.StatusBadRequest; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	status.SetMaxCalendarSpan(0)
+	if got, want := getJSON(t, srv.URL+"/api/calendar?from=01/01/2025&to=12/31/2055", &resp), http.StatusOK; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestStatusErrorCodes(t *testing.T) {
+	ctx := context.Background()
+	calGen := func([]string, datetime.CalendarDateRange) (webapi.CalendarResponse, error) {
+		return webapi.CalendarResponse{}, errors.New("failed to generate calendar")
+	}
+	status := webapi.NewStatusServer(logging.NewStatusRecorder(), nil, calGen)
+	mux := http.NewServeMux()
+	status.AppendEndpoints(ctx, mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var resp webapi.CalendarResponse
+	if got, want := getJSON(t, srv.URL+"/api/calendar?from=01/01/2025&to=12/31/2025", &resp), http.StatusInternalServerError; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := getJSON(t, srv.URL+"/api/calendar?from=not-a-date", &resp), http.StatusBadRequest; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+const statusSchedules = `
+schedules:
+  - name: lights
+    device: device
+    actions:
+      on: 12:00
+`
+
+func TestScheduleUpdate(t *testing.T) {
+	ctx := context.Background()
+	sys, err := loaderFor(systemConfig)(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheds, err := scheduler.ParseConfig(ctx, []byte(statusSchedules), sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner, err := scheduler.NewRunner(scheds, sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := webapi.NewStatusServer(logging.NewStatusRecorder(), nil, nil)
+	status.SetScheduleUpdater(func(ctx context.Context, cfg []byte) ([]string, error) {
+		scheds, err := scheduler.ParseConfig(ctx, cfg, sys)
+		if err != nil {
+			return nil, err
+		}
+		if err := runner.Swap(scheds); err != nil {
+			return nil, err
+		}
+		var names []string
+		for _, s := range scheds.Schedules {
+			names = append(names, s.Name)
+		}
+		return names, nil
+	})
+	mux := http.NewServeMux()
+	status.AppendEndpoints(ctx, mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	actions := func() []string {
+		var names []string
+		for _, s := range runner.Schedules().Schedules {
+			for _, a := range s.DailyActions {
+				names = append(names, s.Name+":"+a.Name)
+			}
+		}
+		return names
+	}
+
+	post := func(cfg string) (int, webapi.SchedulesResponse) {
+		var resp webapi.SchedulesResponse
+		r, err := http.Post(srv.URL+"/api/schedules", "application/yaml", strings.NewReader(cfg))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Body.Close()
+		if r.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return r.StatusCode, resp
+	}
+
+	updated := statusSchedules + `  - name: porch
+    device: device
+    actions:
+      off: 23:00
+`
+	code, resp := post(updated)
+	if got, want := code, http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := resp.Schedules, []string{"lights", "porch"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := actions(), []string{"lights:on", "porch:off"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// An invalid schedule is rejected and the current schedules retained.
+	code, _ = post(strings.ReplaceAll(updated, "device: device", "device: not-a-device"))
+	if got, want := code, http.StatusBadRequest; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := actions(), []string{"lights:on", "porch:off"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got := getJSON(t, srv.URL+"/api/schedules", &resp); got == http.StatusOK {
+		t.Errorf("GET should not be allowed")
+	}
+}
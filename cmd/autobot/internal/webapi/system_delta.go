@@ -0,0 +1,102 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/cosnicolaou/automation/devices"
+)
+
+// SystemDelta represents the differences between two system configurations.
+type SystemDelta struct {
+	AddedControllers   []string          `json:"added_controllers,omitempty"`
+	RemovedControllers []string          `json:"removed_controllers,omitempty"`
+	AddedDevices       []string          `json:"added_devices,omitempty"`
+	RemovedDevices     []string          `json:"removed_devices,omitempty"`
+	Changed            []OperationsDelta `json:"changed,omitempty"`
+}
+
+// OperationsDelta represents the changes to the configured operations
+// and conditions for a controller or device that is present in both
+// systems. Name is of the form controller:<name> or device:<name> since
+// a controller and a device may share the same name.
+type OperationsDelta struct {
+	Name              string   `json:"name"`
+	AddedOperations   []string `json:"added_operations,omitempty"`
+	RemovedOperations []string `json:"removed_operations,omitempty"`
+	AddedConditions   []string `json:"added_conditions,omitempty"`
+	RemovedConditions []string `json:"removed_conditions,omitempty"`
+}
+
+func (od OperationsDelta) empty() bool {
+	return len(od.AddedOperations) == 0 && len(od.RemovedOperations) == 0 &&
+		len(od.AddedConditions) == 0 && len(od.RemovedConditions) == 0
+}
+
+// diffKeys returns the sorted keys that are in b but not in a (added)
+// and those that are in a but not in b (removed).
+func diffKeys[V1, V2 any](a map[string]V1, b map[string]V2) (added, removed []string) {
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			added = append(added, k)
+		}
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	slices.Sort(added)
+	slices.Sort(removed)
+	return
+}
+
+func controllerConfigs(sys devices.System) map[string]devices.ControllerConfig {
+	m := map[string]devices.ControllerConfig{}
+	for _, c := range sys.Config.Controllers {
+		m[c.Name] = c
+	}
+	return m
+}
+
+func deviceConfigs(sys devices.System) map[string]devices.DeviceConfig {
+	m := map[string]devices.DeviceConfig{}
+	for _, d := range sys.Config.Devices {
+		m[d.Name] = d
+	}
+	return m
+}
+
+// DiffSystems returns the differences between the previous and next systems.
+func DiffSystems(prev, next devices.System) SystemDelta {
+	var sd SystemDelta
+	sd.AddedControllers, sd.RemovedControllers = diffKeys(prev.Controllers, next.Controllers)
+	sd.AddedDevices, sd.RemovedDevices = diffKeys(prev.Devices, next.Devices)
+
+	pc, nc := controllerConfigs(prev), controllerConfigs(next)
+	for _, name := range slices.Sorted(maps.Keys(nc)) {
+		if p, ok := pc[name]; ok {
+			od := OperationsDelta{Name: "controller:" + name}
+			od.AddedOperations, od.RemovedOperations = diffKeys(p.Operations, nc[name].Operations)
+			if !od.empty() {
+				sd.Changed = append(sd.Changed, od)
+			}
+		}
+	}
+	pd, nd := deviceConfigs(prev), deviceConfigs(next)
+	for _, name := range slices.Sorted(maps.Keys(nd)) {
+		if p, ok := pd[name]; ok {
+			od := OperationsDelta{Name: "device:" + name}
+			od.AddedOperations, od.RemovedOperations = diffKeys(p.Operations, nd[name].Operations)
+			od.AddedConditions, od.RemovedConditions = diffKeys(p.Conditions, nd[name].Conditions)
+			if !od.empty() {
+				sd.Changed = append(sd.Changed, od)
+			}
+		}
+	}
+	return sd
+}
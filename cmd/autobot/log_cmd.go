@@ -5,14 +5,19 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"strconv"
 	"time"
 
 	"cloudeng.io/datetime"
 	"github.com/cosnicolaou/automation/internal/logging"
+	"github.com/cosnicolaou/automation/scheduler"
 )
 
 type LogFlags struct {
@@ -27,6 +32,15 @@ type LogStatusFlags struct {
 	TSV              bool `subcmd:"tsv,false,print the status in tab separated values"`
 }
 
+type LogCountersFlags struct {
+	LogFlags
+	TSV bool `subcmd:"tsv,false,print the counters in tab separated values"`
+}
+
+type LogShowFlags struct {
+	JSON bool `subcmd:"json,false,print the original JSON log records"`
+}
+
 type Log struct {
 	out io.Writer
 }
@@ -71,6 +85,31 @@ func (l *Log) Status(_ context.Context, flags any, args []string) error {
 	return err
 }
 
+func (l *Log) Counters(_ context.Context, flags any, args []string) error {
+	fv := flags.(*LogCountersFlags)
+	cs, err := logging.NewCounterStore(args[0])
+	if err != nil {
+		return err
+	}
+	counters := []logging.Counter{}
+	for _, c := range cs.Counters() {
+		if len(fv.Device) > 0 && c.Device != fv.Device {
+			continue
+		}
+		if len(fv.Schedule) > 0 && c.Schedule != fv.Schedule {
+			continue
+		}
+		counters = append(counters, c)
+	}
+	tw := tableManager{}.Counters(counters)
+	if fv.TSV {
+		fmt.Fprintln(l.out, tw.RenderTSV())
+		return nil
+	}
+	fmt.Fprintln(l.out, tw.Render())
+	return nil
+}
+
 type statusRecoder struct {
 	*logging.StatusRecorder
 	pending map[int64]*logging.StatusRecord
@@ -106,7 +145,7 @@ func (sr *statusRecoder) process(le logging.Entry) error {
 		if !ok {
 			return nil
 		}
-		sr.PendingDone(pending, le.PreCondResult, le.Err)
+		sr.PendingDoneWithAttempts(pending, le.Attempts, le.PreCondResult, le.Err)
 		if sr.flags.StreamingSummary {
 			sr.print(sr.out, datetime.CalendarDateFromTime(le.Due))
 			sr.ResetCompleted()
@@ -116,8 +155,80 @@ func (sr *statusRecoder) process(le logging.Entry) error {
 	case logging.LogYearEnd:
 	case logging.LogTooLate:
 		fmt.Fprintf(sr.out, "% 70v: too late: due at: %v, delay: %v\n", le.Name(), le.Due, le.Delay)
+	case logging.LogCoalesced:
+		fmt.Fprintf(sr.out, "% 70v: coalesced: due at: %v, delay: %v\n", le.Name(), le.Due, le.Delay)
+	case logging.LogSkipped:
+		if pending, ok := sr.pending[le.ID]; ok {
+			sr.PendingDone(pending, false, scheduler.ErrSkippedWhilePaused)
+		}
+		fmt.Fprintf(sr.out, "% 70v: skipped while paused: due at: %v, delay: %v\n", le.Name(), le.Due, le.Delay)
+	case logging.LogNoChange:
+		if pending, ok := sr.pending[le.ID]; ok {
+			sr.PendingDone(pending, true, nil)
+		}
+		fmt.Fprintf(sr.out, "% 70v: no change: due at: %v\n", le.Name(), le.Due)
 	default: // ignore all other messages.
 		return nil
 	}
 	return nil
 }
+
+// Show prints all of the log records, pending, precondition, retries and
+// completion, for the action invocation with the specified id in the order
+// in which they were logged.
+func (l *Log) Show(_ context.Context, flags any, args []string) error {
+	fv := flags.(*LogShowFlags)
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid id: %q: %w", args[0], err)
+	}
+	fi, err := os.Open(args[1])
+	if err != nil {
+		return err
+	}
+	defer fi.Close()
+	sc := logging.NewScanner(fi)
+	found := false
+	for le := range sc.Entries(true) {
+		if le.ID != id {
+			continue
+		}
+		found = true
+		if fv.JSON {
+			fmt.Fprintln(l.out, le.LogEntry)
+			continue
+		}
+		if err := writeTraceEntry(l.out, le); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("no log records found for id: %v", id)
+	}
+	return sc.Err()
+}
+
+// writeTraceEntry writes a log entry as a header line containing the time
+// and message followed by one indented line per remaining attribute.
+func writeTraceEntry(out io.Writer, le logging.Entry) error {
+	dec := json.NewDecoder(bytes.NewReader([]byte(le.LogEntry)))
+	dec.UseNumber()
+	var attrs map[string]any
+	if err := dec.Decode(&attrs); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%v %v\n", attrs["time"], attrs["msg"])
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		switch k {
+		case "time", "level", "msg", "id":
+			continue
+		}
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		fmt.Fprintf(out, "    %v: %v\n", k, attrs[k])
+	}
+	return nil
+}
@@ -5,12 +5,15 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"maps"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"cloudeng.io/datetime"
 	"cloudeng.io/geospatial/astronomy"
@@ -268,3 +271,144 @@ func daysInSummer(year int) int {
 	dr := summerDateRange(year)
 	return dr.To().DayOfYear() - dr.From().DayOfYear() + 1
 }
+
+const showLog = `{"time":"2025-01-02T12:00:00Z","level":"INFO","msg":"pending","mod":"scheduler","id":7,"device":"device","op":"on","loc":"UTC"}
+{"time":"2025-01-02T12:00:00Z","level":"INFO","msg":"pending","mod":"scheduler","id":8,"device":"other","op":"off","loc":"UTC"}
+{"time":"2025-01-02T12:00:01Z","level":"INFO","msg":"precondition","mod":"scheduler","id":7,"op":"on","passed":true}
+{"time":"2025-01-02T12:00:02Z","level":"INFO","msg":"scheduler: retrying","id":7,"retries":0,"err":"oops"}
+{"time":"2025-01-02T12:00:03Z","level":"INFO","msg":"completed","mod":"scheduler","id":8,"device":"other","op":"off","loc":"UTC"}
+{"time":"2025-01-02T12:00:04Z","level":"INFO","msg":"completed","mod":"scheduler","id":7,"device":"device","op":"on","loc":"UTC","delay":1000000000}
+`
+
+func TestLogShow(t *testing.T) {
+	ctx := context.Background()
+	logfile := filepath.Join(t.TempDir(), "show.log")
+	if err := os.WriteFile(logfile, []byte(showLog), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	l := &Log{out: &out}
+	if err := l.Show(ctx, &LogShowFlags{}, []string{"7", logfile}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), `2025-01-02T12:00:00Z pending
+    device: device
+    loc: UTC
+    mod: scheduler
+    op: on
+2025-01-02T12:00:01Z precondition
+    mod: scheduler
+    op: on
+    passed: true
+2025-01-02T12:00:02Z scheduler: retrying
+    err: oops
+    retries: 0
+2025-01-02T12:00:04Z completed
+    delay: 1000000000
+    device: device
+    loc: UTC
+    mod: scheduler
+    op: on
+`; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	out.Reset()
+	if err := l.Show(ctx, &LogShowFlags{JSON: true}, []string{"8", logfile}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Count(out.String(), `"id":8`), 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if err := l.Show(ctx, &LogShowFlags{}, []string{"9", logfile}); err == nil {
+		t.Errorf("expected an error for an unknown id")
+	}
+}
+
+const attemptsLog = `{"time":"2025-01-02T12:00:00Z","level":"INFO","msg":"pending","mod":"scheduler","id":7,"schedule":"s","device":"device","op":"on","loc":"UTC","due":"2025-01-02T12:00:00Z"}
+{"time":"2025-01-02T12:00:00Z","level":"INFO","msg":"pending","mod":"scheduler","id":8,"schedule":"s","device":"other","op":"off","loc":"UTC","due":"2025-01-02T12:00:00Z"}
+{"time":"2025-01-02T12:00:03Z","level":"INFO","msg":"completed","mod":"scheduler","id":8,"schedule":"s","device":"other","op":"off","loc":"UTC","due":"2025-01-02T12:00:00Z","pre-result":true,"attempts":1}
+{"time":"2025-01-02T12:00:04Z","level":"INFO","msg":"failed","mod":"scheduler","id":7,"schedule":"s","device":"device","op":"on","loc":"UTC","due":"2025-01-02T12:00:00Z","pre-result":true,"attempts":3,"err":"oops"}
+`
+
+func TestLogStatusAttempts(t *testing.T) {
+	ctx := context.Background()
+	logfile := filepath.Join(t.TempDir(), "attempts.log")
+	if err := os.WriteFile(logfile, []byte(attemptsLog), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	l := &Log{out: &out}
+	for _, fv := range []*LogStatusFlags{{}, {StreamingSummary: true}} {
+		out.Reset()
+		if err := l.Status(ctx, fv, []string{logfile}); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := strings.Count(out.String(), "| completed (3 attempts) | oops"), 1; got < want {
+			t.Errorf("streaming: %v: got %v, want at least %v: %v", fv.StreamingSummary, got, want, out.String())
+		}
+		if got := strings.Count(out.String(), "(1 attempts)"); got != 0 {
+			t.Errorf("streaming: %v: unexpected attempts: %v", fv.StreamingSummary, out.String())
+		}
+	}
+}
+
+func TestStatusTUIRender(t *testing.T) {
+	sr := logging.NewStatusRecorder()
+	due := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+	for i, op := range []string{"on", "off", "another"} {
+		rec := sr.NewPending(&logging.StatusRecord{
+			Schedule: "simple",
+			Device:   "device",
+			Op:       op,
+			Due:      due.Add(time.Duration(i) * time.Hour),
+		})
+		switch op {
+		case "on":
+			sr.PendingDone(rec, true, nil)
+		case "off":
+			sr.PendingDone(rec, true, errors.New("oops"))
+		}
+	}
+	var out strings.Builder
+	st := &Status{out: &out}
+	st.render(sr, due, 1)
+
+	var rows []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if strings.HasPrefix(line, "| simple") {
+			fields := strings.Fields(strings.ReplaceAll(line, "|", " "))
+			rows = append(rows, fields[2]+" "+fields[len(fields)-1])
+		}
+	}
+	// Pending actions are displayed first, followed by the most recently
+	// completed one.
+	if got, want := strings.Join(rows, ", "), "another pending, off oops"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if !strings.HasPrefix(out.String(), clearScreen) {
+		t.Errorf("screen was not cleared")
+	}
+}
+
+func TestLogFollower(t *testing.T) {
+	var buf bytes.Buffer
+	lf := &logFollower{rd: &buf}
+	buf.WriteString("one\ntw")
+	lines, err := lf.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(lines), "one\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	buf.WriteString("o\nthree\n")
+	lines, err = lf.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(lines), "two\nthree\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -35,10 +35,16 @@ commands:
         arguments:
           - <name.condition> - name of the device and the condition to test
           - <parameters>...
+      - name: snapshot
+        summary: evaluate every configured condition on every device, concurrently, and display the results
       - name: script
         summary: read commands from a file
         arguments:
           - <filename> - the file to read commands from
+      - name: repl
+        summary: interactively run operations, read one per line from stdin, against a system that is loaded once
+      - name: self-test
+        summary: run every configured operation on every device, one at a time, and display the results
       - name: serve-test-page
         summary: run a local webserver with links to every operation and condition to simplify testing
         arguments:
@@ -54,15 +60,69 @@ commands:
         summary: run the scheduler using simulated time so that it skips from scheduled time to scheduled time with minimal delay
         arguments:
           - <schedule>...
+      - name: diff
+        summary: compare the actions scheduled by two schedule files over the same date range, without running them, and display the added, removed and moved actions grouped by schedule
+        arguments:
+          - <old-schedule> - the original schedule file
+          - <new-schedule> - the updated schedule file
+      - name: simulate-diff
+        summary: simulate two schedule files over the same date range and display the per-day differences in the actions fired
+        arguments:
+          - <old-schedule> - the original schedule file
+          - <new-schedule> - the updated schedule file
       - name: print
         summary: print the requested schedules, or all schedules if none are specified
         arguments:
           - <schedule>...
+      - name: load-profile
+        summary: display the peak number of operations per controller generated by the requested schedules, or all schedules if none are specified
+        arguments:
+          - <schedule>...
+      - name: order
+        summary: display the order in which the actions of the requested schedules, or all schedules if none are specified, are run when due at the same time along with any before or after constraints that were applied
+        arguments:
+          - <schedule>...
+      - name: preview
+        summary: display the actions of the requested schedules, or all schedules if none are specified, that would be run in the window following the specified instant without running them
+        arguments:
+          - <schedule>...
   - name: config
     summary: query/inspect the configuration file
     commands:
+      - name: init
+        summary: write a minimal, commented, system and schedule configuration to the configured files, refusing to overwrite existing files unless --force is specified
       - name: display
+      - name: effective
+        summary: display, as YAML, the system configuration in effect once all command line overrides have been applied
       - name: operations
+        summary: display the controllers, devices and conditions in the system, use --format=json to display every operation and condition as JSON
+      - name: uses
+        summary: display every schedule, action and precondition that refers to the specified controller or device
+        arguments:
+          - <device> - the controller or device
+      - name: help
+        summary: display the documentation, as markdown, for an operation
+        arguments:
+          - <device.operation> - the controller or device and operation
+      - name: types
+        summary: list the compiled in controller and device types
+      - name: sun
+        summary: display the sunrise, sunset and other dynamic times of day for the system's location
+      - name: sun-drift
+        summary: display the time that each of a schedule's dynamically timed actions, eg. sunset, resolves to for every day in the range along with the change from the previous day, the largest changes are marked with a *
+        arguments:
+          - <schedule>
+      - name: lint
+        summary: report actions in different schedules that perform different operations on the same device within the same minute
+      - name: check-keys
+        summary: verify that every key referenced by the system and schedule configurations is present in the keys file
+  - name: status
+    summary: display the status of running schedules
+    commands:
+      - name: tui
+        summary: continuously display the pending and recently completed actions in the terminal by following the specified log file
+        arguments:
+          - <log-file>
   - name: logs
     summary: query/inspect the log files
     commands:
@@ -70,28 +130,59 @@ commands:
         summary: run the log file through the status recorder to view completed, pending etc events.
         arguments:
           - <log-files>...
+      - name: counters
+        summary: display the per-operation success/failure counters maintained by the scheduler
+        arguments:
+          - <counters-file>
+      - name: show
+        summary: display all of the log records for the action invocation with the specified id
+        arguments:
+          - <id>
+          - <log-file>
 `
 
 func cli() *subcmd.CommandSetYAML {
 	cmd := subcmd.MustFromYAML(cmdSpec)
 
-	control := &Control{}
-	cmd.Set("control", "run").MustRunner(control.Run, &ControlFlags{})
+	control := &Control{in: os.Stdin, out: os.Stdout}
+	cmd.Set("control", "run").MustRunner(control.Run, &ControlRunFlags{})
 	cmd.Set("control", "condition").MustRunner(control.Condition, &ControlFlags{})
+	cmd.Set("control", "snapshot").MustRunner(control.Snapshot, &ControlSnapshotFlags{})
 	cmd.Set("control", "script").MustRunner(control.RunScript, &ControlScriptFlags{})
+	cmd.Set("control", "repl").MustRunner(control.Repl, &ControlReplFlags{})
+	cmd.Set("control", "self-test").MustRunner(control.SelfTest, &ControlSelfTestFlags{})
 	cmd.Set("control", "serve-test-page").MustRunner(control.ServeTestPage, &ControlTestPageFlags{})
 
 	config := &Config{out: os.Stdout}
+	cmd.Set("config", "init").MustRunner(config.Init, &ConfigInitFlags{})
 	cmd.Set("config", "display").MustRunner(config.Display, &ConfigFlags{})
-	cmd.Set("config", "operations").MustRunner(config.Operations, &ConfigFlags{})
+	cmd.Set("config", "effective").MustRunner(config.Effective, &ConfigFlags{})
+	cmd.Set("config", "operations").MustRunner(config.Operations, &ConfigOperationsFlags{})
+	cmd.Set("config", "uses").MustRunner(config.Uses, &ConfigFlags{})
+	cmd.Set("config", "help").MustRunner(config.Help, &ConfigFlags{})
+	cmd.Set("config", "types").MustRunner(config.Types, &ConfigTypesFlags{})
+	cmd.Set("config", "sun").MustRunner(config.Sun, &ConfigSunFlags{})
+	cmd.Set("config", "sun-drift").MustRunner(config.SunDrift, &ConfigSunDriftFlags{})
+	cmd.Set("config", "lint").MustRunner(config.Lint, &ConfigLintFlags{})
+	cmd.Set("config", "check-keys").MustRunner(config.CheckKeys, &ConfigFlags{})
 
-	schedule := &Schedule{}
+	schedule := &Schedule{out: os.Stdout}
 	cmd.Set("schedule", "run").MustRunner(schedule.Run, &ScheduleFlags{})
 	cmd.Set("schedule", "simulate").MustRunner(schedule.Simulate, &SimulateFlags{})
+	cmd.Set("schedule", "simulate-diff").MustRunner(schedule.SimulateDiff, &ScheduleSimulateDiffFlags{})
+	cmd.Set("schedule", "diff").MustRunner(schedule.Diff, &ScheduleDiffFlags{})
 	cmd.Set("schedule", "print").MustRunner(schedule.Print, &SchedulePrintFlags{})
+	cmd.Set("schedule", "load-profile").MustRunner(schedule.LoadProfile, &ScheduleLoadProfileFlags{})
+	cmd.Set("schedule", "order").MustRunner(schedule.Order, &ScheduleOrderFlags{})
+	cmd.Set("schedule", "preview").MustRunner(schedule.Preview, &SchedulePreviewFlags{})
+
+	status := &Status{out: os.Stdout}
+	cmd.Set("status", "tui").MustRunner(status.TUI, &StatusTUIFlags{})
 
 	log := &Log{out: os.Stdout}
 	cmd.Set("logs", "status").MustRunner(log.Status, &LogStatusFlags{})
+	cmd.Set("logs", "counters").MustRunner(log.Counters, &LogCountersFlags{})
+	cmd.Set("logs", "show").MustRunner(log.Show, &LogShowFlags{})
 	return cmd
 }
 
@@ -113,6 +204,19 @@ func init() {
 		maps.All(elkm1.SupportedDevices()))
 	maps.Insert(devices.AvailableDevices,
 		maps.All(weatherdev.SupportedDevices()))
+
+	devices.RegisterDeviceType(devices.TypeInfo{
+		Type:        "noop",
+		Description: "a device whose operations do nothing, useful for trying out schedules",
+	}, devices.NewNoopDevice)
+	devices.RegisterDeviceType(devices.TypeInfo{
+		Type:        "date-range",
+		Description: "a virtual device whose active condition is true within configured date ranges or months",
+	}, devices.NewDateRangeDevice)
+	devices.RegisterDeviceType(devices.TypeInfo{
+		Type:        "external",
+		Description: "a device whose operations and conditions are implemented by an external command using JSON over stdin/stdout",
+	}, devices.NewExternalDevice)
 }
 
 var errInterrupt = errors.New("interrupt")
@@ -0,0 +1,40 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+// handlePauseSignals pauses scheduling on receipt of SIGUSR1 and resumes
+// it on receipt of SIGUSR2 until the context is canceled.
+func handlePauseSignals(ctx context.Context, logger *slog.Logger, pause *scheduler.Pause) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-ch:
+				if sig == syscall.SIGUSR1 {
+					pause.Pause()
+				} else {
+					pause.Resume()
+				}
+				logger.Info("pause", "signal", sig.String(), "paused", pause.Paused())
+			}
+		}
+	}()
+}
@@ -0,0 +1,17 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+// handlePauseSignals is a no-op on windows, which lacks SIGUSR1/SIGUSR2.
+func handlePauseSignals(context.Context, *slog.Logger, *scheduler.Pause) {}
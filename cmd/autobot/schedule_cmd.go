@@ -6,11 +6,14 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"cloudeng.io/datetime"
@@ -23,32 +26,114 @@ import (
 	"github.com/pkg/browser"
 )
 
+type MetricsFlags struct {
+	MetricsFile     string        `subcmd:"metrics-file,,if set a JSON snapshot of per-operation metrics is periodically written to this file"`
+	MetricsInterval time.Duration `subcmd:"metrics-interval,1m,interval at which the metrics snapshot is written"`
+}
+
 type ScheduleFlags struct {
 	ConfigFileFlags
 	WebUIFlags
-	LogFile   string `subcmd:"log-file,,log file"`
-	StartDate string `subcmd:"start-date,,start date"`
-	DryRun    bool   `subcmd:"dry-run,,dry run"`
+	MetricsFlags
+	LogFile       string        `subcmd:"log-file,,log file"`
+	StartDate     string        `subcmd:"start-date,,start date"`
+	DryRun        bool          `subcmd:"dry-run,,dry run"`
+	CountersFile  string        `subcmd:"counters-file,,file used to persist per-operation success/failure counters"`
+	AllowUpdates  bool          `subcmd:"allow-schedule-updates,false,allow the running schedules to be replaced by posting a schedule configuration to /api/schedules; the schedule file is not changed"`
+	ShutdownGrace time.Duration `subcmd:"shutdown-grace,0s,time allowed for an in-flight operation to complete once the process is interrupted"`
 }
 
 type SimulateFlags struct {
 	ConfigFileFlags
 	WebUIFlags
+	MetricsFlags
 	LogFile   string        `subcmd:"log-file,,log file"`
 	DateRange string        `subcmd:"date-range,,date range in <month>/<day>/<year>:<year>/<month>/<day> format"`
 	Delay     time.Duration `subcmd:"delay,10ms,delay between each simulated time step and the scheduled time"`
 	DryRun    bool          `subcmd:"dry-run,true,dry run"`
 }
 
+type ScheduleSimulateDiffFlags struct {
+	ConfigFileFlags
+	DateRange string        `subcmd:"date-range,,date range in <month>/<day>/<year>:<year>/<month>/<day> format"`
+	Delay     time.Duration `subcmd:"delay,1ms,delay between each simulated time step and the scheduled time"`
+}
+
+type ScheduleDiffFlags struct {
+	ConfigFileFlags
+	DateRange string `subcmd:"date-range,,date range in <month>/<day>/<year>:<year>/<month>/<day> format; defaults to the current year"`
+	MaxSpan   int    `subcmd:"max-span,1098,maximum number of days in the date range; zero means no limit"`
+}
+
 type SchedulePrintFlags struct {
 	ConfigFileFlags
 	DateRange string `subcmd:"date-range,,date range in <month>/<day>/<year>:<year>/<month>/<day> 	format"`
 	Date      string `subcmd:"date,,date in <month>/<day>/<year> format"`
+	MaxSpan   int    `subcmd:"max-span,1098,maximum number of days in the date range; zero means no limit"`
+	Evaluate  bool   `subcmd:"evaluate,false,evaluate each precondition and display whether it passes; preconditions must be free of side effects"`
+}
+
+type ScheduleLoadProfileFlags struct {
+	ConfigFileFlags
+	Year int  `subcmd:"year,0,year to compute the load profile for; defaults to the current year"`
+	JSON bool `subcmd:"json,false,print the load profile as JSON"`
+}
+
+type ScheduleOrderFlags struct {
+	ConfigFileFlags
+}
+
+type SchedulePreviewFlags struct {
+	ConfigFileFlags
+	At       string        `subcmd:"at,,the instant to treat as now in RFC3339 or <month>/<day>/<year> <hour>:<minute> format in the system's time location; defaults to now"`
+	Window   time.Duration `subcmd:"window,24h,the period following the instant to display the actions for"`
+	Evaluate bool          `subcmd:"evaluate,false,evaluate each precondition and display whether it passes; preconditions must be free of side effects"`
 }
 
 type Schedule struct {
+	out       io.Writer
 	system    devices.System
 	schedules scheduler.Schedules
+	runner    *scheduler.Runner
+}
+
+// current returns the schedules currently being run, which may have
+// been replaced via /api/schedules, or the loaded schedules if they
+// are not being run.
+func (s *Schedule) current() scheduler.Schedules {
+	if s.runner != nil {
+		return s.runner.Schedules()
+	}
+	return s.schedules
+}
+
+// updateSchedules replaces the running schedules with those in the
+// supplied configuration.
+func (s *Schedule) updateSchedules(ctx context.Context, cfg []byte) ([]string, error) {
+	scheds, err := scheduler.ParseConfig(ctx, cfg, s.system)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.runner.Swap(scheds); err != nil {
+		return nil, err
+	}
+	names := make([]string, len(scheds.Schedules))
+	for i, sched := range scheds.Schedules {
+		names[i] = sched.Name
+	}
+	return names, nil
+}
+
+// startMetrics starts the periodic export of metrics if a metrics file
+// is specified, the returned function stops the export and writes a
+// final snapshot.
+func startMetrics(ctx context.Context, fv MetricsFlags, opts []scheduler.Option) ([]scheduler.Option, func() error) {
+	if len(fv.MetricsFile) == 0 {
+		return opts, func() error { return nil }
+	}
+	metrics := logging.NewMetrics()
+	stop := metrics.StartExport(ctx, fv.MetricsFile, fv.MetricsInterval)
+	return append(opts, scheduler.WithMetrics(metrics)), stop
 }
 
 func (s *Schedule) setupLogging(logfile string) (*slog.Logger, func(), error) {
@@ -78,8 +163,8 @@ func (s *Schedule) loadFiles(ctx context.Context, fv *ConfigFileFlags, deviceOpt
 	return ctx, nil
 }
 
-func (s *Schedule) serveStatusUI(ctx context.Context, cf *ConfigFileFlags, fv WebUIFlags, statusRecorder *logging.StatusRecorder, loader func(ctx context.Context) (devices.System, error)) error {
-	if len(fv.HTTPAddr) == 0 && len(fv.HTTPSAddr) == 0 {
+func (s *Schedule) serveStatusUI(ctx context.Context, cf *ConfigFileFlags, fv WebUIFlags, statusRecorder *logging.StatusRecorder, counters *logging.CounterStore, pause *scheduler.Pause, loader func(ctx context.Context) (devices.System, error), allowUpdates bool) error {
+	if len(fv.HTTPAddr) == 0 && len(fv.HTTPSAddr) == 0 && len(fv.UnixSocket) == 0 {
 		return nil
 	}
 	mux := http.NewServeMux()
@@ -90,18 +175,34 @@ func (s *Schedule) serveStatusUI(ctx context.Context, cf *ConfigFileFlags, fv We
 	statusPages := fv.StatusPages()
 	controlPages := fv.TestServerPages()
 
-	statusServer := webapi.NewStatusServer(statusRecorder, s.calendar)
+	// The audit log remains open for the lifetime of the process.
+	audit, _, err := fv.OpenAuditLog()
+	if err != nil {
+		return err
+	}
+
+	statusServer := webapi.NewStatusServer(statusRecorder, counters, s.calendar)
+	statusServer.SetMaxCalendarSpan(fv.MaxCalendarSpan)
+	statusServer.SetUsesLookup(s.uses)
+	if pause != nil {
+		statusServer.SetPauser(pause)
+	}
+	if allowUpdates && s.runner != nil {
+		statusServer.SetScheduleUpdater(s.updateSchedules)
+	}
+	statusServer.SetAuditLog(audit)
 
 	rerender := createSystemRenderer(cf, loader, controlPages)
 	controlServer, err := webapi.NewDeviceControlServer(ctx, rerender)
 	if err != nil {
 		return err
 	}
+	controlServer.SetAuditLog(audit)
 
 	statusServer.AppendEndpoints(ctx, mux)
 	controlServer.AppendEndpoints(ctx, mux)
-	webassets.AppendStatusPages(mux, cf.SystemFile, statusPages)
-	webassets.AppendControlPages(mux, cf.SystemFile, controlPages)
+	webassets.AppendStatusPages(mux, cf.systemFile(), statusPages)
+	webassets.AppendControlPages(mux, cf.systemFile(), controlPages)
 
 	go func() {
 		_ = browser.OpenURL(url)
@@ -111,8 +212,15 @@ func (s *Schedule) serveStatusUI(ctx context.Context, cf *ConfigFileFlags, fv We
 }
 
 func (s *Schedule) calendar(schedules []string, dr datetime.CalendarDateRange) (webapi.CalendarResponse, error) {
-	s.schedules.Schedules = filterSchedules(s.schedules.Schedules, schedules)
-	cal, err := scheduler.NewCalendar(s.schedules, s.system)
+	// Filter a copy since the calendar is served repeatedly.
+	current := s.current()
+	selected, err := filterSchedules(current, schedules)
+	if err != nil {
+		return webapi.CalendarResponse{}, err
+	}
+	filtered := current
+	filtered.Schedules = selected
+	cal, err := scheduler.NewCalendar(filtered, s.system)
 	if err != nil {
 		return webapi.CalendarResponse{}, err
 	}
@@ -139,6 +247,15 @@ func (s *Schedule) calendar(schedules []string, dr datetime.CalendarDateRange) (
 	}, nil
 }
 
+func (s *Schedule) uses(device string) ([]scheduler.DeviceUse, error) {
+	_, cok := s.system.Controllers[device]
+	_, dok := s.system.Devices[device]
+	if !cok && !dok {
+		return nil, fmt.Errorf("unknown controller or device: %q", device)
+	}
+	return s.current().Uses(device), nil
+}
+
 func (s *Schedule) Run(ctx context.Context, flags any, _ []string) error {
 	fv := flags.(*ScheduleFlags)
 	var start datetime.CalendarDate
@@ -163,18 +280,35 @@ func (s *Schedule) Run(ctx context.Context, flags any, _ []string) error {
 		return err
 	}
 
-	if s.system.Location.Latitude == 0 && s.system.Location.Longitude == 0 {
+	if !s.system.Location.LatLongSet {
 		return fmt.Errorf("latitude and longitude must be specified either directly or via a zip code")
 	}
 
+	if err := logConflicts(logger, s.schedules, s.system, start.Year()); err != nil {
+		return err
+	}
+
 	logger.Info("starting schedules", "start", start.String(), "loc", s.system.Location.TimeLocation.String(), "zip", s.system.Location.ZIPCode, "latitude", s.system.Location.Latitude, "longitude", s.system.Location.Longitude)
 
 	sr := logging.NewStatusRecorder()
+	pause := scheduler.NewPause()
+	handlePauseSignals(ctx, logger, pause)
 	schedulerOpts := []scheduler.Option{
 		scheduler.WithLogger(logger),
 		scheduler.WithOperationWriter(io.Discard),
 		scheduler.WithDryRun(fv.DryRun),
 		scheduler.WithStatusRecorder(sr),
+		scheduler.WithPause(pause),
+		scheduler.WithShutdownGrace(fv.ShutdownGrace),
+	}
+
+	var counters *logging.CounterStore
+	if len(fv.CountersFile) > 0 {
+		counters, err = logging.NewCounterStore(fv.CountersFile)
+		if err != nil {
+			return err
+		}
+		schedulerOpts = append(schedulerOpts, scheduler.WithCounterStore(counters))
 	}
 
 	systemLoader := func(ctx context.Context) (devices.System, error) {
@@ -185,27 +319,50 @@ func (s *Schedule) Run(ctx context.Context, flags any, _ []string) error {
 		return sys, nil
 	}
 
-	if err := s.serveStatusUI(ctx, &fv.ConfigFileFlags, fv.WebUIFlags, sr, systemLoader); err != nil {
-		return err
+	schedulerOpts, stopMetrics := startMetrics(ctx, fv.MetricsFlags, schedulerOpts)
+	runner, err := scheduler.NewRunner(s.schedules, s.system, schedulerOpts...)
+	if err != nil {
+		return errors.Join(err, stopMetrics())
 	}
+	s.runner = runner
 
-	return scheduler.RunSchedulers(ctx, s.schedules, s.system, start, schedulerOpts...)
+	if err := s.serveStatusUI(ctx, &fv.ConfigFileFlags, fv.WebUIFlags, sr, counters, pause, systemLoader, fv.AllowUpdates); err != nil {
+		return errors.Join(err, stopMetrics())
+	}
 
+	err = runner.Run(ctx, start)
+	return errors.Join(err, stopMetrics())
 }
 
-func filterSchedules(schedules []scheduler.Annual, allowed []string) []scheduler.Annual {
+// logConflicts logs a warning for each pair of conflicting actions in the
+// schedules during the specified year, see scheduler.Conflicts.
+func logConflicts(logger *slog.Logger, schedules scheduler.Schedules, system devices.System, year int) error {
+	cal, err := scheduler.NewCalendar(schedules, system)
+	if err != nil {
+		return err
+	}
+	for _, c := range scheduler.Conflicts(cal, year) {
+		logger.Warn("conflicting actions", "device", c.Device, "conflict", c.String())
+	}
+	return nil
+}
+
+// filterSchedules returns the schedules named in allowed, or all schedules
+// if allowed is empty. It returns an error if any of the names does not
+// refer to a schedule.
+func filterSchedules(schedules scheduler.Schedules, allowed []string) ([]scheduler.Annual, error) {
 	if len(allowed) == 0 || (len(allowed) == 1 && len(allowed[0]) == 0) {
-		return schedules
+		return schedules.Schedules, nil
 	}
 	filtered := []scheduler.Annual{}
-	for _, sched := range schedules {
-		for _, name := range allowed {
-			if sched.Name == name {
-				filtered = append(filtered, sched)
-			}
+	for _, name := range allowed {
+		sched, ok := schedules.Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown schedule: %q", name)
 		}
+		filtered = append(filtered, sched)
 	}
-	return filtered
+	return filtered, nil
 }
 
 func (s *Schedule) Simulate(ctx context.Context, flags any, args []string) error {
@@ -237,9 +394,12 @@ func (s *Schedule) Simulate(ctx context.Context, flags any, args []string) error
 		return err
 	}
 
-	s.schedules.Schedules = filterSchedules(s.schedules.Schedules, args)
+	s.schedules.Schedules, err = filterSchedules(s.schedules, args)
+	if err != nil {
+		return err
+	}
 
-	if s.system.Location.Latitude == 0 && s.system.Location.Longitude == 0 {
+	if !s.system.Location.LatLongSet {
 		return fmt.Errorf("latitude and longitude must be specified either directly or via a zip code")
 	}
 
@@ -253,10 +413,130 @@ func (s *Schedule) Simulate(ctx context.Context, flags any, args []string) error
 		return sys, nil
 	}
 
-	if err := s.serveStatusUI(ctx, &fv.ConfigFileFlags, fv.WebUIFlags, sr, systemLoader); err != nil {
+	if err := s.serveStatusUI(ctx, &fv.ConfigFileFlags, fv.WebUIFlags, sr, nil, nil, systemLoader, false); err != nil {
+		return err
+	}
+	schedulerOpts, stopMetrics := startMetrics(ctx, fv.MetricsFlags, schedulerOpts)
+	err = scheduler.RunSimulation(ctx, s.schedules, s.system, period, schedulerOpts...)
+	return errors.Join(err, stopMetrics())
+}
+
+// SimulateDiff simulates the schedules in two schedule files over the
+// same date range and displays the per-day differences in the actions
+// fired.
+func (s *Schedule) SimulateDiff(ctx context.Context, flags any, args []string) error {
+	fv := flags.(*ScheduleSimulateDiffFlags)
+	var period datetime.CalendarDateRange
+	if err := period.Parse(fv.DateRange); err != nil {
+		return err
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	ctx = ctxlog.WithLogger(ctx, logger)
+	ctx, sys, err := loadSystem(ctx, &fv.ConfigFileFlags)
+	if err != nil {
 		return err
 	}
-	return scheduler.RunSimulation(ctx, s.schedules, s.system, period, schedulerOpts...)
+	if !sys.Location.LatLongSet {
+		return fmt.Errorf("latitude and longitude must be specified either directly or via a zip code")
+	}
+	simulated := make([][]scheduler.SimulatedAction, len(args))
+	for i, filename := range args {
+		scheds, err := loadScheduleFile(ctx, filename, sys)
+		if err != nil {
+			return err
+		}
+		simulated[i], err = scheduler.SimulateActions(ctx, scheds, sys, period,
+			scheduler.WithLogger(logger),
+			scheduler.WithSimulationDelay(fv.Delay))
+		if err != nil {
+			return err
+		}
+	}
+	diffs := scheduler.DiffSimulatedActions(simulated[0], simulated[1])
+	if len(diffs) == 0 {
+		fmt.Fprintf(s.out, "no differences\n")
+		return nil
+	}
+	for _, d := range diffs {
+		var when string
+		switch d.Change {
+		case scheduler.SimulationAdded:
+			when = d.New.Format(time.TimeOnly)
+		case scheduler.SimulationRemoved:
+			when = d.Old.Format(time.TimeOnly)
+		default:
+			when = d.Old.Format(time.TimeOnly) + " -> " + d.New.Format(time.TimeOnly)
+		}
+		fmt.Fprintf(s.out, "%v %-7v %v %v\n", d.Date, d.Change, d.Action, when)
+	}
+	return nil
+}
+
+// Diff compares the actions scheduled, without running any of them, by
+// two schedule files over the same date range and displays the actions
+// that were added, removed or moved to a different time of day, grouped
+// by schedule.
+func (s *Schedule) Diff(ctx context.Context, flags any, args []string) error {
+	fv := flags.(*ScheduleDiffFlags)
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	ctx = ctxlog.WithLogger(ctx, logger)
+	ctx, sys, err := loadSystem(ctx, &fv.ConfigFileFlags)
+	if err != nil {
+		return err
+	}
+	var dr datetime.CalendarDateRange
+	if f := fv.DateRange; len(f) > 0 {
+		if err := dr.Parse(f); err != nil {
+			return err
+		}
+	} else {
+		year := time.Now().In(sys.Location.TimeLocation).Year()
+		dr = datetime.NewCalendarDateRange(
+			datetime.NewCalendarDate(year, 1, 1),
+			datetime.NewCalendarDate(year, 12, 31))
+	}
+	if err := scheduler.CheckCalendarSpan(dr, fv.MaxSpan); err != nil {
+		return err
+	}
+	actions := make([][]scheduler.SimulatedAction, len(args))
+	for i, filename := range args {
+		scheds, err := loadScheduleFile(ctx, filename, sys)
+		if err != nil {
+			return err
+		}
+		cal, err := scheduler.NewCalendar(scheds, sys)
+		if err != nil {
+			return err
+		}
+		actions[i] = cal.Actions(dr)
+	}
+	diffs := scheduler.DiffSimulatedActions(actions[0], actions[1])
+	if len(diffs) == 0 {
+		fmt.Fprintf(s.out, "no differences\n")
+		return nil
+	}
+	bySchedule := map[string][]scheduler.SimulationDiff{}
+	for _, d := range diffs {
+		bySchedule[d.Schedule] = append(bySchedule[d.Schedule], d)
+	}
+	for _, name := range opNames(bySchedule) {
+		fmt.Fprintf(s.out, "%v:\n", name)
+		for _, d := range bySchedule[name] {
+			change, when := d.Change, ""
+			switch d.Change {
+			case scheduler.SimulationAdded:
+				when = d.New.Format(time.TimeOnly)
+			case scheduler.SimulationRemoved:
+				when = d.Old.Format(time.TimeOnly)
+			default:
+				change = "moved"
+				when = d.Old.Format(time.TimeOnly) + " -> " + d.New.Format(time.TimeOnly)
+			}
+			action := strings.TrimPrefix(d.Action, d.Schedule+":")
+			fmt.Fprintf(s.out, "  %v %-7v %v %v\n", d.Date, change, action, when)
+		}
+	}
+	return nil
 }
 
 func (s *Schedule) Print(ctx context.Context, flags any, args []string) error {
@@ -275,20 +555,129 @@ func (s *Schedule) Print(ctx context.Context, flags any, args []string) error {
 		}
 		dr = datetime.NewCalendarDateRange(day, day)
 	}
+	if err := scheduler.CheckCalendarSpan(dr, fv.MaxSpan); err != nil {
+		return err
+	}
+
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	ctx = ctxlog.WithLogger(ctx, logger)
+	_, err := s.loadFiles(ctx, &fv.ConfigFileFlags, nil)
+	if err != nil {
+		return err
+	}
+
+	s.schedules.Schedules, err = filterSchedules(s.schedules, args)
+	if err != nil {
+		return err
+	}
+	cal, err := scheduler.NewCalendar(s.schedules, s.system)
+	if err != nil {
+		return err
+	}
+	tw := tableManager{}.Calendar(ctx, cal, dr, fv.Evaluate)
+	fmt.Fprintln(s.out, tw.Render())
+	return nil
+}
 
+// LoadProfile displays, per controller, the total number of operations
+// and the peak number of operations issued at the same time, per minute
+// and per hour, by the requested schedules, or all schedules if none are
+// specified, over the course of a year.
+func (s *Schedule) LoadProfile(ctx context.Context, flags any, args []string) error {
+	fv := flags.(*ScheduleLoadProfileFlags)
 	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
 	ctx = ctxlog.WithLogger(ctx, logger)
 	_, err := s.loadFiles(ctx, &fv.ConfigFileFlags, nil)
 	if err != nil {
 		return err
 	}
+	year := fv.Year
+	if year == 0 {
+		year = time.Now().In(s.system.Location.TimeLocation).Year()
+	}
+	s.schedules.Schedules, err = filterSchedules(s.schedules, args)
+	if err != nil {
+		return err
+	}
+	cal, err := scheduler.NewCalendar(s.schedules, s.system)
+	if err != nil {
+		return err
+	}
+	loads := scheduler.LoadProfile(cal, year)
+	if fv.JSON {
+		enc := json.NewEncoder(s.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(loads)
+	}
+	fmt.Fprintln(s.out, tableManager{}.LoadProfile(year, loads).Render())
+	return nil
+}
+
+// Order displays, for the requested schedules, or all schedules if none
+// are specified, the order in which their actions are run when due at
+// the same time once any before and after constraints have been applied.
+func (s *Schedule) Order(ctx context.Context, flags any, args []string) error {
+	fv := flags.(*ScheduleOrderFlags)
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	ctx = ctxlog.WithLogger(ctx, logger)
+	_, err := s.loadFiles(ctx, &fv.ConfigFileFlags, nil)
+	if err != nil {
+		return err
+	}
+	schedules, err := filterSchedules(s.schedules, args)
+	if err != nil {
+		return err
+	}
+	for _, sched := range schedules {
+		fmt.Fprintln(s.out, tableManager{}.ExecutionOrder(sched.Name, sched.ExecutionOrder()).Render())
+	}
+	return nil
+}
+
+// parseInstant parses an instant in RFC3339 format, or as a date and time
+// of day in the specified location.
+func parseInstant(v string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"01/02/2006 15:04:05", "01/02/2006 15:04"} {
+		if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %q: use RFC3339 or <month>/<day>/<year> <hour>:<minute>[:<second>] format", v)
+}
 
-	s.schedules.Schedules = filterSchedules(s.schedules.Schedules, args)
+// Preview displays the actions, for the requested schedules, or all
+// schedules if none are specified, that would be run in the window
+// following the specified instant, treating that instant as the current
+// time. No operations are run.
+func (s *Schedule) Preview(ctx context.Context, flags any, args []string) error {
+	fv := flags.(*SchedulePreviewFlags)
+	if fv.Window <= 0 {
+		return fmt.Errorf("invalid window: %v: must be positive", fv.Window)
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	ctx = ctxlog.WithLogger(ctx, logger)
+	_, err := s.loadFiles(ctx, &fv.ConfigFileFlags, nil)
+	if err != nil {
+		return err
+	}
+	at := time.Now()
+	if len(fv.At) > 0 {
+		if at, err = parseInstant(fv.At, s.system.Location.TimeLocation); err != nil {
+			return err
+		}
+	}
+	s.schedules.Schedules, err = filterSchedules(s.schedules, args)
+	if err != nil {
+		return err
+	}
 	cal, err := scheduler.NewCalendar(s.schedules, s.system)
 	if err != nil {
 		return err
 	}
-	tw := tableManager{}.Calendar(cal, dr)
-	fmt.Println(tw.Render())
+	tw := tableManager{}.Upcoming(ctx, cal, at, fv.Window, fv.Evaluate)
+	fmt.Fprintln(s.out, tw.Render())
 	return nil
 }
@@ -0,0 +1,93 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/cosnicolaou/automation/internal/logging"
+)
+
+type StatusTUIFlags struct {
+	LogFlags
+	Interval time.Duration `subcmd:"interval,2s,interval at which the display is refreshed"`
+	Recent   int           `subcmd:"recent,20,number of recently completed actions to display"`
+}
+
+type Status struct {
+	out io.Writer
+}
+
+// clearScreen is the ANSI escape sequence to move the cursor to the
+// top left of the terminal and clear the screen.
+const clearScreen = "\033[H\033[2J"
+
+// logFollower returns the complete lines appended to a log file since
+// it was last read, any partially written line is held back until it
+// is complete.
+type logFollower struct {
+	rd      io.Reader
+	partial []byte
+}
+
+func (lf *logFollower) next() ([]byte, error) {
+	buf, err := io.ReadAll(lf.rd)
+	if err != nil {
+		return nil, err
+	}
+	buf = append(lf.partial, buf...)
+	idx := bytes.LastIndexByte(buf, '\n')
+	lf.partial = bytes.Clone(buf[idx+1:])
+	return buf[:idx+1], nil
+}
+
+func (st *Status) render(sr *logging.StatusRecorder, now time.Time, recent int) {
+	tw := tableManager{}.Dashboard(sr, now, recent)
+	fmt.Fprint(st.out, clearScreen)
+	fmt.Fprintln(st.out, tw.Render())
+}
+
+// TUI continuously displays the pending and recently completed actions
+// recorded in the specified log file, following the log file as it is
+// appended to by a running scheduler.
+func (st *Status) TUI(ctx context.Context, flags any, args []string) error {
+	fv := flags.(*StatusTUIFlags)
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	lsf := &LogStatusFlags{LogFlags: fv.LogFlags}
+	srh := statusRecoder{
+		StatusRecorder: logging.NewStatusRecorder(),
+		pending:        make(map[int64]*logging.StatusRecord),
+		flags:          lsf,
+		out:            io.Discard,
+	}
+	follower := &logFollower{rd: f}
+	l := &Log{}
+	ticker := time.NewTicker(fv.Interval)
+	defer ticker.Stop()
+	for {
+		lines, err := follower.next()
+		if err != nil {
+			return err
+		}
+		if err := l.processLog(bytes.NewReader(lines), lsf, srh.process); err != nil {
+			return err
+		}
+		st.render(srh.StatusRecorder, time.Now(), fv.Recent)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
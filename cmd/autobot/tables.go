@@ -5,6 +5,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"slices"
 	"strings"
@@ -34,15 +35,27 @@ func formatOperationWithArgs(a scheduler.Action) string {
 func formatConditionWithArgs(a scheduler.Action) string {
 	pre := ""
 	if a.Precondition.Condition != nil {
-		pre = fmt.Sprintf("if %v.%v", a.Precondition.Device, a.Precondition.Name)
-		if a.Precondition.Args != nil {
-			pre += "(" + strings.Join(a.Precondition.Args, ", ") + ")"
-		}
+		pre = "if " + a.Precondition.String()
 	}
 	return pre
 }
 
-func (tm tableManager) Calendar(cal *scheduler.Calendar, dr datetime.CalendarDateRange) table.Writer {
+// formatEvaluation annotates a precondition with the result of
+// evaluating it.
+func formatEvaluation(ok bool, err error) string {
+	switch {
+	case err != nil:
+		return fmt.Sprintf("[error: %v]", err)
+	case ok:
+		return "[pass]"
+	}
+	return "[fail]"
+}
+
+// Calendar displays the actions scheduled for each day in the supplied
+// range, if evaluate is true each precondition is evaluated and the
+// result of that evaluation is displayed alongside it.
+func (tm tableManager) Calendar(ctx context.Context, cal *scheduler.Calendar, dr datetime.CalendarDateRange, evaluate bool) table.Writer {
 	tw := table.NewWriter()
 	tw.SetColumnConfigs([]table.ColumnConfig{
 		{Number: 1, AutoMerge: true},
@@ -54,6 +67,9 @@ func (tm tableManager) Calendar(cal *scheduler.Calendar, dr datetime.CalendarDat
 		for _, a := range actions {
 			op := formatOperationWithArgs(a.T)
 			pre := formatConditionWithArgs(a.T)
+			if evaluate && a.T.Precondition.Condition != nil {
+				pre += " " + formatEvaluation(cal.EvaluatePrecondition(ctx, a))
+			}
 			tod := datetime.NewTimeOfDay(a.When.Hour(), a.When.Minute(), a.When.Second())
 			tw.AppendRow(table.Row{day, tod, a.Schedule, a.T.DeviceName, op, pre})
 		}
@@ -62,6 +78,29 @@ func (tm tableManager) Calendar(cal *scheduler.Calendar, dr datetime.CalendarDat
 	return tw
 }
 
+// Upcoming displays the actions that would be run in the window following
+// the specified instant, with preconditions optionally evaluated as for
+// Calendar.
+func (tm tableManager) Upcoming(ctx context.Context, cal *scheduler.Calendar, at time.Time, window time.Duration, evaluate bool) table.Writer {
+	tw := table.NewWriter()
+	tw.SetTitle(fmt.Sprintf("%v + %v", at.Format(time.DateTime), window))
+	tw.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 1, AutoMerge: true},
+	})
+	tw.AppendHeader(table.Row{"Date", "Time", "Schedule", "Device", "Operation", "Condition"})
+	for _, a := range cal.Upcoming(at, window) {
+		op := formatOperationWithArgs(a.T)
+		pre := formatConditionWithArgs(a.T)
+		if evaluate && a.T.Precondition.Condition != nil {
+			pre += " " + formatEvaluation(cal.EvaluatePrecondition(ctx, a))
+		}
+		day := datetime.CalendarDateFromTime(a.When)
+		tod := datetime.NewTimeOfDay(a.When.Hour(), a.When.Minute(), a.When.Second())
+		tw.AppendRow(table.Row{day, tod, a.Schedule, a.T.DeviceName, op, pre})
+	}
+	return tw
+}
+
 func (tm tableManager) RenderHTML(tw table.Writer) string {
 	tw.SetStyle(table.Style{
 		HTML: table.HTMLOptions{
@@ -203,19 +242,21 @@ func (tm tableManager) DeviceConditions(sys devices.System) table.Writer {
 	return tw
 }
 
-func (tm tableManager) ConditionalOperations(cops []conditionalOps) table.Writer {
+func (tm tableManager) ConditionalOperations(cops []scheduler.ConditionalOp) table.Writer {
 	tw := table.NewWriter()
 	tw.SetTitle("Conditional Operations")
 	tw.AppendHeader(table.Row{"Device", "Op Conditional", "Op", "Args", "Device", "Condition", "Condition Args"})
 	for _, cop := range cops {
+		op := webapi.Action{Device: cop.Device, Op: cop.Op, Args: cop.Args}
+		cond := webapi.Action{Device: cop.Precondition.Device, Op: cop.Precondition.Name, Args: cop.Precondition.Args}
 		row := table.Row{
-			cop.op.Device,
-			tm.withAPIConditionalCall(cop.op, cop.cond),
-			tm.withAPICall(cop.op.Device, cop.op.Op, "runOperation", cop.op.Args, true),
-			strings.Join(cop.op.Args, ", "),
-			cop.cond.Device,
-			tm.withAPICall(cop.cond.Device, cop.cond.Op, "runCondition", cop.cond.Args, true),
-			strings.Join(cop.cond.Args, ", "),
+			op.Device,
+			tm.withAPIConditionalCall(op, cond),
+			tm.withAPICall(op.Device, op.Op, "runOperation", op.Args, true),
+			strings.Join(op.Args, ", "),
+			cond.Device,
+			tm.withAPICall(cond.Device, cond.Op, "runCondition", cond.Args, true),
+			strings.Join(cond.Args, ", "),
 		}
 		tw.AppendRow(row)
 	}
@@ -267,7 +308,7 @@ func (tm tableManager) Conditions(sys devices.System) table.Writer {
 }
 
 func (tm tableManager) statusRecordRow(sr *logging.StatusRecord) table.Row {
-	return table.Row{sr.Schedule, sr.Device, sr.Op, sr.Due, sr.Pending.Round(time.Second), sr.Completed.Round(time.Second), sr.PreConditionCall(), sr.Status(), sr.ErrorMessage()}
+	return table.Row{sr.Schedule, sr.Device, sr.Op, sr.Due, sr.Pending.Round(time.Second), sr.Completed.Round(time.Second), sr.PreConditionCall(), sr.StatusWithAttempts(), sr.ErrorMessage()}
 }
 
 func (tm tableManager) statusRecordHeader() table.Row {
@@ -298,7 +339,7 @@ func (tm tableManager) Completed(sr *logging.StatusRecorder, when datetime.Calen
 	tw.SetTitle(fmt.Sprintf("Completed: %v", when))
 	tw.AppendHeader(tm.statusRecordHeader())
 	for sr := range sr.Completed() {
-		tm.statusRecordRow(sr)
+		tw.AppendRow(tm.statusRecordRow(sr))
 	}
 	return tw
 }
@@ -308,7 +349,84 @@ func (tm tableManager) Pending(sr *logging.StatusRecorder, when datetime.Calenda
 	tw.SetTitle(fmt.Sprintf("Pending: %v", when))
 	tw.AppendHeader(tm.statusRecordHeader())
 	for sr := range sr.Pending() {
-		tm.statusRecordRow(sr)
+		tw.AppendRow(tm.statusRecordRow(sr))
+	}
+	return tw
+}
+
+// Dashboard returns a table of the pending actions followed by at most
+// recent of the most recently completed actions, most recent first.
+func (tm tableManager) Dashboard(sr *logging.StatusRecorder, now time.Time, recent int) table.Writer {
+	tw := table.NewWriter()
+	tw.SetTitle(fmt.Sprintf("Status at: %v", now.Round(time.Second)))
+	tw.AppendHeader(tm.statusRecordHeader())
+	for sr := range sr.Pending() {
+		tw.AppendRow(tm.statusRecordRow(sr))
+	}
+	n := 0
+	for sr := range sr.CompletedRecent() {
+		if n >= recent {
+			break
+		}
+		tw.AppendRow(tm.statusRecordRow(sr))
+		n++
+	}
+	return tw
+}
+
+func (tm tableManager) Counters(counters []logging.Counter) table.Writer {
+	tw := table.NewWriter()
+	tw.SetTitle("Operation Counters")
+	tw.AppendHeader(table.Row{"Schedule", "Device", "Operation", "Success", "Failure", "Aborted", "Last Updated"})
+	for _, c := range counters {
+		tw.AppendRow(table.Row{c.Schedule, c.Device, c.Op, c.Success, c.Failure, c.Aborted, c.LastUpdated.Round(time.Second)})
+	}
+	return tw
+}
+
+func (tm tableManager) LoadProfile(year int, loads []scheduler.ControllerLoad) table.Writer {
+	tw := table.NewWriter()
+	tw.SetTitle(fmt.Sprintf("Load Profile: %v", year))
+	tw.AppendHeader(table.Row{"Controller", "Actions", "Peak Concurrent", "At", "Peak/Minute", "At", "Peak/Hour", "At"})
+	for _, l := range loads {
+		tw.AppendRow(table.Row{l.Controller, l.Actions,
+			l.PeakConcurrent, l.PeakConcurrentAt.Format(time.DateTime),
+			l.PeakPerMinute, l.PeakMinuteAt.Format(time.DateTime),
+			l.PeakPerHour, l.PeakHourAt.Format(time.DateTime)})
+	}
+	return tw
+}
+
+func (tm tableManager) ExecutionOrder(schedule string, order []scheduler.OrderedAction) table.Writer {
+	tw := table.NewWriter()
+	tw.SetTitle(schedule)
+	tw.AppendHeader(table.Row{"#", "Due", "Action", "Constraint"})
+	for i, oa := range order {
+		tw.AppendRow(table.Row{i + 1, oa.Due, oa.Name, oa.Constraint()})
+	}
+	return tw
+}
+
+func (tm tableManager) Types(title string, types []devices.TypeInfo) table.Writer {
+	tw := table.NewWriter()
+	tw.SetTitle(title)
+	tw.AppendHeader(table.Row{"Type", "Vendor", "Version", "Description"})
+	for _, ti := range types {
+		tw.AppendRow(table.Row{ti.Type, ti.Vendor, ti.Version, ti.Description})
+	}
+	return tw
+}
+
+func (tm tableManager) SelfTest(results []selfTestResult) table.Writer {
+	tw := table.NewWriter()
+	tw.SetTitle("Self Test")
+	tw.AppendHeader(table.Row{"Device", "Operation", "Status", "Error"})
+	for _, r := range results {
+		errMsg := ""
+		if r.err != nil {
+			errMsg = r.err.Error()
+		}
+		tw.AppendRow(table.Row{r.device, r.op, r.status, errMsg})
 	}
 	return tw
 }
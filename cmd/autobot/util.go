@@ -10,7 +10,10 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 
 	"cloudeng.io/cmdutil/keystore"
@@ -19,6 +22,7 @@ import (
 	"github.com/cosnicolaou/automation/cmd/autobot/internal/zipfs"
 	"github.com/cosnicolaou/automation/devices"
 	"github.com/cosnicolaou/automation/scheduler"
+	"gopkg.in/yaml.v3"
 )
 
 func loadSystem(ctx context.Context, fv *ConfigFileFlags, opts ...devices.Option) (context.Context, devices.System, error) {
@@ -31,27 +35,118 @@ func loadSystem(ctx context.Context, fv *ConfigFileFlags, opts ...devices.Option
 	if err != nil {
 		return nil, devices.System{}, fmt.Errorf("failed to load zip database: %q: %w", fv.ZIPDatabase, err)
 	}
-	opts = append(opts, devices.WithZIPCodeLookup(zdb))
+	locOpts, err := locationOptions(fv)
+	if err != nil {
+		return nil, devices.System{}, err
+	}
+	opts = append(opts, locOpts...)
+	opts = append(opts,
+		devices.WithZIPCodeLookup(zdb),
+		devices.WithStrictOperations(fv.StrictOps))
 
-	system, err := devices.ParseSystemConfigFile(ctx, fv.SystemFile, opts...)
+	system, err := parseSystemConfig(ctx, fv, opts...)
 	if err != nil {
-		return nil, devices.System{}, fmt.Errorf("failed to parse system config file: %q: %w", fv.SystemFile, err)
+		return nil, devices.System{}, err
 	}
 
 	return keystore.ContextWithAuth(ctx, keys), system, nil
 }
 
+// locationOptions returns the options that override the location
+// specified in the system configuration with that specified on the
+// command line.
+func locationOptions(fv *ConfigFileFlags) ([]devices.Option, error) {
+	var opts []devices.Option
+	if tz := fv.SystemTZLocation; len(tz) > 0 {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone: %q: %w", tz, err)
+		}
+		opts = append(opts, devices.WithTimeLocation(loc))
+	}
+	if len(fv.ZIPCode) > 0 {
+		opts = append(opts, devices.WithZIPCode(fv.ZIPCode))
+	}
+	// The flags are strings so that a latitude or longitude of zero can
+	// be distinguished from one that was not specified.
+	if len(fv.Latitude) > 0 || len(fv.Longitude) > 0 {
+		lat, err := parseDegrees("latitude", fv.Latitude)
+		if err != nil {
+			return nil, err
+		}
+		long, err := parseDegrees("longitude", fv.Longitude)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, devices.WithLatLong(lat, long))
+	}
+	return opts, nil
+}
+
+// parseDegrees parses a latitude or longitude flag value, an empty value
+// is treated as zero.
+func parseDegrees(name, val string) (float64, error) {
+	if len(val) == 0 {
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %v: %q: %w", name, val, err)
+	}
+	return v, nil
+}
+
+// combinedConfig represents a single configuration file that contains
+// both the system configuration, under the system: key, and the
+// schedules, under the schedules: key.
+type combinedConfig struct {
+	System devices.SystemConfig `yaml:"system"`
+}
+
+// parseSystemConfig parses the system configuration from either the
+// combined configuration file, if specified, or the system file.
+func parseSystemConfig(ctx context.Context, fv *ConfigFileFlags, opts ...devices.Option) (devices.System, error) {
+	if fv.ConfigFile == "" {
+		system, err := devices.ParseSystemConfigFile(ctx, fv.SystemFile, opts...)
+		if err != nil {
+			return devices.System{}, fmt.Errorf("failed to parse system config file: %q: %w", fv.SystemFile, err)
+		}
+		return system, nil
+	}
+	data, err := os.ReadFile(fv.ConfigFile)
+	if err != nil {
+		return devices.System{}, fmt.Errorf("failed to read config file: %q: %w", fv.ConfigFile, err)
+	}
+	var cfg combinedConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return devices.System{}, fmt.Errorf("failed to parse config file: %q: %w", fv.ConfigFile, err)
+	}
+	if err := cfg.System.ResolveIncludes(ctx, fv.ConfigFile); err != nil {
+		return devices.System{}, fmt.Errorf("failed to parse system config in: %q: %w", fv.ConfigFile, err)
+	}
+	system, err := cfg.System.CreateSystem(ctx, opts...)
+	if err != nil {
+		return devices.System{}, fmt.Errorf("failed to parse system config in: %q: %w", fv.ConfigFile, err)
+	}
+	return system, nil
+}
+
 func loadSchedules(ctx context.Context, fv *ConfigFileFlags, sys devices.System) (scheduler.Schedules, error) {
-	if fv.ScheduleFile == "" {
+	filename := fv.scheduleFile()
+	if filename == "" {
 		return scheduler.Schedules{}, fmt.Errorf("no schedule file specified")
 	}
-	cfg, err := os.ReadFile(fv.ScheduleFile)
+	return loadScheduleFile(ctx, filename, sys)
+}
+
+func loadScheduleFile(ctx context.Context, filename string, sys devices.System) (scheduler.Schedules, error) {
+	cfg, err := os.ReadFile(filename)
 	if err != nil {
-		return scheduler.Schedules{}, fmt.Errorf("failed to read schedule file: %q: %v", fv.ScheduleFile, err)
+		return scheduler.Schedules{}, fmt.Errorf("failed to read schedule file: %q: %v", filename, err)
 	}
 	scheds, err := scheduler.ParseConfig(ctx, cfg, sys)
 	if err != nil {
-		return scheduler.Schedules{}, fmt.Errorf("failed to parse schedule file: %q: %v", fv.ScheduleFile, err)
+		return scheduler.Schedules{}, fmt.Errorf("failed to parse schedule file: %q: %v", filename, err)
 	}
 	return scheds, nil
 }
@@ -122,3 +217,76 @@ func loadZIPDatabase(dbdir string) (zipLookup, error) {
 	}
 	return zipLookup{DB: db}, nil
 }
+
+// reloadableZIPLookup is a zip code lookup whose database is reloaded
+// by reload whenever any of the files in its directory are changed,
+// added or removed. The embedded database is never reloaded.
+type reloadableZIPLookup struct {
+	dbdir string
+
+	mu    sync.RWMutex
+	zl    zipLookup
+	stamp string
+}
+
+func newReloadableZIPLookup(dbdir string) (*reloadableZIPLookup, error) {
+	rz := &reloadableZIPLookup{dbdir: dbdir}
+	if _, err := rz.reload(); err != nil {
+		return nil, err
+	}
+	return rz, nil
+}
+
+func (rz *reloadableZIPLookup) Lookup(zip string) (float64, float64, error) {
+	rz.mu.RLock()
+	defer rz.mu.RUnlock()
+	return rz.zl.Lookup(zip)
+}
+
+// zipDatabaseStamp returns a string that changes whenever any of the
+// files in dbdir are changed, added or removed.
+func zipDatabaseStamp(dbdir string) (string, error) {
+	var out strings.Builder
+	err := filepath.WalkDir(dbdir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&out, "%v:%v:%v\n", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return out.String(), err
+}
+
+// reload reloads the database if it has changed since it was last
+// loaded, returning true if it was reloaded. The existing database
+// remains in use if the reload fails.
+func (rz *reloadableZIPLookup) reload() (bool, error) {
+	stamp := ""
+	if len(rz.dbdir) > 0 {
+		var err error
+		if stamp, err = zipDatabaseStamp(rz.dbdir); err != nil {
+			return false, fmt.Errorf("failed to read zipcode database directory %v: %v", rz.dbdir, err)
+		}
+	}
+	rz.mu.RLock()
+	unchanged := rz.zl.DB != nil && stamp == rz.stamp
+	rz.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+	zl, err := loadZIPDatabase(rz.dbdir)
+	if err != nil {
+		return false, err
+	}
+	rz.mu.Lock()
+	defer rz.mu.Unlock()
+	rz.zl, rz.stamp = zl, stamp
+	return true, nil
+}
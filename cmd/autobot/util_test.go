@@ -5,8 +5,13 @@
 package main
 
 import (
+	"context"
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/devices"
 )
 
 func TestZIP(t *testing.T) {
@@ -44,3 +49,125 @@ func TestZIP(t *testing.T) {
 		}
 	}
 }
+
+func TestZIPReload(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "GB.txt")
+	write := func(lat, long string, when time.Time) {
+		line := "GB\tCB4 3EN\tCambridge\tEngland\tENG\tCambridgeshire\t11609029\t\t\t" + lat + "\t" + long + "\t6\n"
+		if err := os.WriteFile(filename, []byte(line), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filename, when, when); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lookup := func(zl *reloadableZIPLookup, lat, long float64) {
+		t.Helper()
+		glat, glong, err := zl.Lookup("ENG CB4 3EN")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if glat != lat || glong != long {
+			t.Errorf("got %v, %v, want %v, %v", glat, glong, lat, long)
+		}
+	}
+
+	now := time.Now().Truncate(time.Second)
+	write("52.2169", "0.1185", now)
+	zl, err := newReloadableZIPLookup(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lookup(zl, 52.2169, 0.1185)
+
+	reloaded, err := zl.reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded {
+		t.Errorf("unchanged database was reloaded")
+	}
+
+	write("52.3", "0.2", now.Add(time.Minute))
+	reloaded, err = zl.reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reloaded {
+		t.Errorf("changed database was not reloaded")
+	}
+	lookup(zl, 52.3, 0.2)
+}
+
+func TestCombinedConfig(t *testing.T) {
+	ctx := context.Background()
+	fv := &ConfigFileFlags{
+		KeysFile:   filepath.Join("testdata", "keys.yaml"),
+		ConfigFile: filepath.Join("testdata", "combined.yaml"),
+		// Ignored since ConfigFile is set.
+		SystemFile:   filepath.Join("testdata", "does-not-exist.yaml"),
+		ScheduleFile: filepath.Join("testdata", "does-not-exist.yaml"),
+	}
+	ctx, sys, err := loadSystem(ctx, fv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sys.Controllers["controller"]; !ok {
+		t.Errorf("missing controller")
+	}
+	if _, _, ok := sys.DeviceOp("device", "on"); !ok {
+		t.Errorf("missing device operation")
+	}
+	if got, want := sys.Location.ZIPCode, "CA 94024"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	scheds, err := loadSchedules(ctx, fv, sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(scheds.Schedules), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	sched := scheds.Schedules[0]
+	if got, want := sched.Name, "simple"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := len(sched.DailyActions), 3; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if sched.DailyActions[2].T.Precondition.Condition == nil {
+		t.Errorf("precondition was not resolved")
+	}
+}
+
+func TestLocationOptions(t *testing.T) {
+	ctx := context.Background()
+	spec := "latitude: 37.7749\nlongitude: 122.4194\n"
+	for _, tc := range []struct {
+		lat, long         string
+		wantLat, wantLong float64
+	}{
+		{"", "", 37.7749, 122.4194},
+		{"0", "0", 0, 0},
+		{"51.5", "-0.12", 51.5, -0.12},
+	} {
+		fv := &ConfigFileFlags{Latitude: tc.lat, Longitude: tc.long}
+		opts, err := locationOptions(fv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sys, err := devices.ParseSystemConfig(ctx, []byte(spec), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		loc := sys.Location
+		if !loc.LatLongSet || loc.Latitude != tc.wantLat || loc.Longitude != tc.wantLong {
+			t.Errorf("%q, %q: got %v, %v, %v", tc.lat, tc.long, loc.LatLongSet, loc.Latitude, loc.Longitude)
+		}
+	}
+
+	if _, err := locationOptions(&ConfigFileFlags{Latitude: "north"}); err == nil {
+		t.Errorf("expected an error for an invalid latitude")
+	}
+}
@@ -6,15 +6,19 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"time"
 
 	"cloudeng.io/cmdutil"
 	"cloudeng.io/logging/ctxlog"
 	"cloudeng.io/sync/errgroup"
 	wa "cloudeng.io/webapp/webassets"
+	"github.com/cosnicolaou/automation/cmd/autobot/internal/webapi"
 	"github.com/cosnicolaou/automation/cmd/autobot/internal/webassets"
 )
 
@@ -25,6 +29,51 @@ type WebUIFlags struct {
 	CertFile          string `subcmd:"ssl-cert,,certificate file"`
 	KeyFile           string `subcmd:"ssl-key,,key file"`
 	Assets            string `subcmd:"web-assets,,path to assets"`
+	UnixSocket        string `subcmd:"unix-socket,,path of a unix domain socket to listen on instead of the http/https addresses"`
+	AuditLog          string `subcmd:"audit-log,,append-only file used to record config reloads and manual operations as well as pause/resume events"`
+	MaxCalendarSpan   int    `subcmd:"max-calendar-span,1098,maximum number of days that may be requested from the calendar api; zero means no limit"`
+	HTTPTimeoutFlags
+}
+
+// HTTPTimeoutFlags configures the timeouts used by the web server. The
+// write timeout is disabled by default since it bounds the time taken to
+// respond to a request, including running operations on devices that may
+// legitimately take longer than any fixed limit, and would otherwise
+// truncate long-lived streaming responses.
+type HTTPTimeoutFlags struct {
+	ReadHeaderTimeout time.Duration `subcmd:"http-read-header-timeout,5s,time allowed to read request headers"`
+	ReadTimeout       time.Duration `subcmd:"http-read-timeout,30s,time allowed to read an entire request; zero means no limit"`
+	WriteTimeout      time.Duration `subcmd:"http-write-timeout,0s,time allowed to write a response; zero means no limit"`
+	IdleTimeout       time.Duration `subcmd:"http-idle-timeout,2m,time to keep idle keep-alive connections open; zero means use the read timeout"`
+}
+
+// newServer returns an http.Server configured with the timeouts
+// specified by fv.
+func (fv HTTPTimeoutFlags) newServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: fv.ReadHeaderTimeout,
+		ReadTimeout:       fv.ReadTimeout,
+		WriteTimeout:      fv.WriteTimeout,
+		IdleTimeout:       fv.IdleTimeout,
+	}
+}
+
+// OpenAuditLog opens the audit log file, if one is specified, for appending.
+// The returned AuditLog is nil if no file is specified.
+func (fv WebUIFlags) OpenAuditLog() (*webapi.AuditLog, func(), error) {
+	if len(fv.AuditLog) == 0 {
+		return nil, func() {}, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(fv.AuditLog), 0700); err != nil {
+		return nil, func() {}, err
+	}
+	f, err := os.OpenFile(fv.AuditLog, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, func() {}, err
+	}
+	return webapi.NewAuditLog(f), func() { f.Close() }, nil
 }
 
 func (fv WebUIFlags) TestServerPages() *webassets.TestServerPages {
@@ -47,16 +96,8 @@ func (fv WebUIFlags) createTLSServer(ctx context.Context, mux *http.ServeMux) (s
 		logger.Info("redirecting to", "url", redirectURL)
 		http.Redirect(w, r, redirectURL, http.StatusMovedPermanently)
 	})
-	redirectServer := &http.Server{
-		Addr:              fv.HTTPAddr,
-		Handler:           redirectMux,
-		ReadHeaderTimeout: 5 * time.Second,
-	}
-	server := &http.Server{
-		Addr:              fv.HTTPSAddr,
-		Handler:           mux,
-		ReadHeaderTimeout: 5 * time.Second,
-	}
+	redirectServer := fv.newServer(fv.HTTPAddr, redirectMux)
+	server := fv.newServer(fv.HTTPSAddr, mux)
 	start = func() error {
 		var g errgroup.T
 		g.Go(func() error {
@@ -84,11 +125,7 @@ func (fv WebUIFlags) createTLSServer(ctx context.Context, mux *http.ServeMux) (s
 }
 
 func (fv WebUIFlags) createHTTPServer(ctx context.Context, mux *http.ServeMux) (start func() error, stop func(), url string, err error) {
-	server := &http.Server{
-		Addr:              fv.HTTPAddr,
-		Handler:           mux,
-		ReadHeaderTimeout: 5 * time.Second,
-	}
+	server := fv.newServer(fv.HTTPAddr, mux)
 	start = func() error {
 		ctxlog.Info(ctx, "starting web server", "url", url)
 		return server.ListenAndServeTLS(fv.CertFile, fv.KeyFile)
@@ -100,12 +137,68 @@ func (fv WebUIFlags) createHTTPServer(ctx context.Context, mux *http.ServeMux) (
 	return
 }
 
+// unixSocketMode is the file mode used for the unix domain socket so that
+// a reverse proxy running as a different user in the same group can
+// connect to it.
+const unixSocketMode = 0660
+
+func (fv WebUIFlags) createUnixSocketServer(ctx context.Context, mux *http.ServeMux) (start func() error, stop func(), url string, err error) {
+	path := fv.UnixSocket
+	// Remove any stale socket left behind by a previous instance, but
+	// never any other type of file.
+	if fi, serr := os.Lstat(path); serr == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			err = fmt.Errorf("%v exists and is not a unix domain socket", path)
+			return
+		}
+		if err = os.Remove(path); err != nil {
+			return
+		}
+	}
+	// The socket is created in a private directory, so that it is never
+	// accessible with the permissions implied by the umask, and is moved
+	// into place once its mode has been set.
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".sock")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, "s")
+	ln, err := net.Listen("unix", tmp)
+	if err != nil {
+		return
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err = os.Chmod(tmp, unixSocketMode); err != nil {
+		ln.Close()
+		return
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		ln.Close()
+		return
+	}
+	server := fv.newServer("", mux)
+	start = func() error {
+		ctxlog.Info(ctx, "starting web server", "url", url)
+		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+	stop = func() {
+		_ = server.Shutdown(ctx)
+		_ = os.Remove(path)
+	}
+	url = "unix://" + path
+	return
+}
+
 func (fv WebUIFlags) CreateWebServer(ctx context.Context, mux *http.ServeMux) (func() error, string, error) {
-	if fv.HTTPSAddr == "" && fv.HTTPAddr == "" {
+	if fv.HTTPSAddr == "" && fv.HTTPAddr == "" && fv.UnixSocket == "" {
 		return func() error { return nil }, "", nil
 	}
 
-	tls := fv.HTTPSAddr != ""
+	tls := fv.UnixSocket == "" && fv.HTTPSAddr != ""
 	if tls && (fv.CertFile == "" || fv.KeyFile == "") {
 		return func() error { return nil }, "", fmt.Errorf("ssl-cert and ssl-key flags are required for tls")
 	}
@@ -114,9 +207,12 @@ func (fv WebUIFlags) CreateWebServer(ctx context.Context, mux *http.ServeMux) (f
 	var stop func()
 	var url string
 	var err error
-	if fv.HTTPSAddr != "" {
+	switch {
+	case fv.UnixSocket != "":
+		start, stop, url, err = fv.createUnixSocketServer(ctx, mux)
+	case fv.HTTPSAddr != "":
 		start, stop, url, err = fv.createTLSServer(ctx, mux)
-	} else {
+	default:
 		start, stop, url, err = fv.createHTTPServer(ctx, mux)
 	}
 	if err != nil {
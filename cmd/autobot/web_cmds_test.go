@@ -0,0 +1,115 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cloudeng.io/cmdutil/flags"
+)
+
+func TestUnixSocket(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "autobot.sock")
+	// A stale socket file is replaced.
+	if ln, err := net.Listen("unix", path); err == nil {
+		ln.(*net.UnixListener).SetUnlinkOnClose(false)
+		ln.Close()
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("hello over unix"))
+	})
+	fv := WebUIFlags{UnixSocket: path}
+	start, stop, url, err := fv.createUnixSocketServer(ctx, mux)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := url, "unix://"+path; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fi.Mode().Perm(), os.FileMode(unixSocketMode); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// The private directory used to create the socket is removed.
+	if entries, err := os.ReadDir(filepath.Dir(path)); err != nil || len(entries) != 1 {
+		t.Errorf("unexpected directory contents: %v: %v", entries, err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- start() }()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://unix/hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(body), "hello over unix"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	stop()
+	if err := <-errCh; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket was not removed: %v", err)
+	}
+
+	// Refuse to replace a file that is not a socket.
+	if err := os.WriteFile(path, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := fv.createUnixSocketServer(ctx, mux); err == nil {
+		t.Errorf("expected an error for a non-socket file")
+	}
+}
+
+func TestHTTPTimeouts(t *testing.T) {
+	var fv WebUIFlags
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	if err := flags.RegisterFlagsInStruct(fs, "subcmd", &fv, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Parse([]string{"--http-read-timeout=1m", "--http-write-timeout=10m"}); err != nil {
+		t.Fatal(err)
+	}
+	srv := fv.newServer("127.0.0.1:0", http.NewServeMux())
+	if got, want := srv.ReadHeaderTimeout, 5*time.Second; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := srv.ReadTimeout, time.Minute; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := srv.WriteTimeout, 10*time.Minute; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := srv.IdleTimeout, 2*time.Minute; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package devices
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"cloudeng.io/datetime"
+	"gopkg.in/yaml.v3"
+)
+
+// DateRangeConfig represents the configuration of a DateRangeDevice.
+// Ranges are in <month>/<day>:<month>/<day> format, or any other format
+// accepted by datetime.DateRange, and may span the end of the year,
+// eg. 12/01:02/28. Months is a comma separated list of months, eg.
+// dec,jan,feb.
+type DateRangeConfig struct {
+	Ranges []string `yaml:"ranges,flow"`
+	Months string   `yaml:"months"`
+}
+
+// DateRangeDevice is a virtual device, that requires no controller, whose
+// active condition is true when the date on which an action is due is
+// within any of its configured date ranges or months. It allows a single
+// schedule to gate actions seasonally via a precondition rather than
+// being split into multiple schedules.
+type DateRangeDevice struct {
+	DeviceBase[DateRangeConfig]
+	controller Controller
+	ranges     []datetime.DateRange
+	months     datetime.MonthList
+}
+
+// NewDateRangeDevice creates a new DateRangeDevice, it has the signature
+// required for use with SupportedDevices.
+func NewDateRangeDevice(string, Options) (Device, error) {
+	return &DateRangeDevice{}, nil
+}
+
+func (dd *DateRangeDevice) SetController(c Controller) {
+	dd.controller = c
+}
+
+func (dd *DateRangeDevice) ControlledBy() Controller {
+	return dd.controller
+}
+
+func (dd *DateRangeDevice) UnmarshalYAML(node *yaml.Node) error {
+	if err := node.Decode(&dd.DeviceConfigCustom); err != nil {
+		return err
+	}
+	cfg := dd.DeviceConfigCustom
+	dd.ranges = make([]datetime.DateRange, len(cfg.Ranges))
+	for i, r := range cfg.Ranges {
+		if err := dd.ranges[i].Parse(r); err != nil {
+			return fmt.Errorf("device %q: invalid date range: %w", dd.Name, err)
+		}
+	}
+	if len(cfg.Months) > 0 {
+		if err := dd.months.Parse(cfg.Months); err != nil {
+			return fmt.Errorf("device %q: %w", dd.Name, err)
+		}
+	}
+	if len(dd.ranges) == 0 && len(dd.months) == 0 {
+		return fmt.Errorf("device %q: at least one of ranges or months must be specified", dd.Name)
+	}
+	return nil
+}
+
+// Contains returns true if the date of the supplied time is within any
+// of the device's date ranges or months.
+func (dd *DateRangeDevice) Contains(when time.Time) bool {
+	date := datetime.DateFromTime(when)
+	if slices.Contains(dd.months, date.Month()) {
+		return true
+	}
+	year := when.Year()
+	for _, dr := range dd.ranges {
+		from, to := dr.From(year).Normalize(year, true), dr.To(year).Normalize(year, false)
+		if from <= to {
+			if from <= date && date <= to {
+				return true
+			}
+			continue
+		}
+		// The range spans the end of the year.
+		if date >= from || date <= to {
+			return true
+		}
+	}
+	return false
+}
+
+func (dd *DateRangeDevice) active(_ context.Context, opts OperationArgs) (any, bool, error) {
+	when := opts.Due
+	if when.IsZero() {
+		when = time.Now()
+		if loc := opts.Place.TimeLocation; loc != nil {
+			when = when.In(loc)
+		}
+	}
+	return datetime.CalendarDateFromTime(when).String(), dd.Contains(when), nil
+}
+
+func (dd *DateRangeDevice) Conditions() map[string]Condition {
+	return map[string]Condition{
+		"active": dd.active,
+	}
+}
+
+func (dd *DateRangeDevice) ConditionsHelp() map[string]string {
+	return map[string]string{
+		"active": "true if the date is within any of the configured date ranges or months",
+	}
+}
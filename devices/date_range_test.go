@@ -0,0 +1,99 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package devices_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/devices"
+)
+
+const dateRangeSpec = `
+time_location: UTC
+devices:
+  - name: winter
+    type: date-range
+    ranges: [12/01:02/28]
+    conditions:
+      active:
+  - name: summer
+    type: date-range
+    months: jun,jul,aug
+    ranges: [05/15:05/31]
+    conditions:
+      active:
+`
+
+func TestDateRangeCondition(t *testing.T) {
+	ctx := context.Background()
+	opts := devices.WithDevices(devices.SupportedDevices{
+		"date-range": devices.NewDateRangeDevice,
+	})
+	sys, err := devices.ParseSystemConfig(ctx, []byte(dateRangeSpec), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		device, date string
+		active       bool
+	}{
+		{"winter", "2024-12-01", true},
+		{"winter", "2025-01-15", true},
+		{"winter", "2025-02-28", true},
+		{"winter", "2025-03-01", false},
+		{"winter", "2025-11-30", false},
+		{"summer", "2025-05-14", false},
+		{"summer", "2025-05-15", true},
+		{"summer", "2025-07-04", true},
+		{"summer", "2025-08-31", true},
+		{"summer", "2025-09-01", false},
+	} {
+		due, err := time.Parse(time.DateOnly, tc.date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, negate := range []bool{false, true} {
+			name := "active"
+			if negate {
+				name = "!active"
+			}
+			cond, _, ok := sys.DeviceCondition(tc.device, name)
+			if !ok {
+				t.Fatalf("%v: condition %v not found", tc.device, name)
+			}
+			_, active, err := cond(ctx, devices.OperationArgs{Due: due})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := active, tc.active != negate; got != want {
+				t.Errorf("%v: %v: %v: got %v, want %v", tc.device, name, tc.date, got, want)
+			}
+		}
+	}
+
+	for _, tc := range []struct {
+		spec, err string
+	}{
+		{`
+devices:
+  - name: none
+    type: date-range
+`, "at least one of ranges or months must be specified"},
+		{`
+devices:
+  - name: invalid
+    type: date-range
+    ranges: [12/01]
+`, "invalid date range"},
+	} {
+		_, err := devices.ParseSystemConfig(ctx, []byte(tc.spec), opts)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("missing or unexpected error: %v, want %v", err, tc.err)
+		}
+	}
+}
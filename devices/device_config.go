@@ -6,10 +6,14 @@ package devices
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"slices"
 	"time"
 
 	"cloudeng.io/cmdutil/cmdyaml"
 	"cloudeng.io/datetime"
+	"github.com/cosnicolaou/automation/net/streamconn"
 	"gopkg.in/yaml.v3"
 )
 
@@ -19,20 +23,86 @@ var (
 )
 
 // RetryConfig represents the configuration for retrying an operation.
-// Timeout is the initial time to wait for a successful operation and
-// Retries is the number of exponential backoff steps to take before
-// giving up, zero means no retries, one means retry once, etc.
+// Timeout is the initial time to wait for a successful operation, which
+// is doubled for every subsequent attempt, and Retries is the number of
+// additional attempts to make before giving up, zero means no retries,
+// one means retry once, etc.
+// Backoff, if specified, is an explicit list of the delays to use
+// between attempts, the last of which is used for all subsequent
+// attempts. RetryOn, if specified, lists the kinds of error that are
+// worth retrying, DefaultRetryOn is used otherwise.
 type RetryConfig struct {
-	Timeout time.Duration `yaml:"timeout"` // the initial time to wait for a successful operation
-	Retries int           `yaml:"retries"` // the number of exponential backoff steps to take before giving up, zero means try once, one means retry once, etc.
+	Timeout time.Duration   `yaml:"timeout"`       // the initial time to wait for a successful operation
+	Retries int             `yaml:"retries"`       // the number of exponential backoff steps to take before giving up, zero means try once, one means retry once, etc.
+	Backoff []time.Duration `yaml:"backoff,flow"`  // explicit delays between attempts, overrides the default
+	RetryOn []ErrorKind     `yaml:"retry_on,flow"` // the kinds of error to retry, defaults to timeout and transport errors
+}
+
+// ShouldRetry returns true if the supplied error is of a kind that
+// should be retried.
+func (rc RetryConfig) ShouldRetry(err error) bool {
+	kinds := rc.RetryOn
+	if kinds == nil {
+		kinds = DefaultRetryOn
+	}
+	return slices.Contains(kinds, ClassifyError(err))
+}
+
+// Delay returns the delay to use after the specified, zero based,
+// failed attempt.
+func (rc RetryConfig) Delay(attempt int) time.Duration {
+	if len(rc.Backoff) > 0 {
+		return rc.Backoff[min(attempt, len(rc.Backoff)-1)]
+	}
+	return rc.Timeout
+}
+
+// AttemptTimeout returns the timeout to use for the specified, zero
+// based, attempt, that is, Timeout doubled for every preceding attempt.
+func (rc RetryConfig) AttemptTimeout(attempt int) time.Duration {
+	timeout := rc.Timeout
+	for range attempt {
+		if timeout > math.MaxInt64/2 {
+			return math.MaxInt64
+		}
+		timeout *= 2
+	}
+	return timeout
+}
+
+func (rc RetryConfig) validate() error {
+	if rc.Backoff != nil && len(rc.Backoff) == 0 {
+		return fmt.Errorf("backoff must not be empty when specified")
+	}
+	for _, d := range rc.Backoff {
+		if d < 0 {
+			return fmt.Errorf("backoff delays must not be negative: %v", d)
+		}
+	}
+	for _, k := range rc.RetryOn {
+		if _, err := ParseErrorKind(string(k)); err != nil {
+			return fmt.Errorf("retry_on: %w", err)
+		}
+	}
+	return nil
 }
 
 // ControllerConfigCommon represents the common configuration for a controller.
+// CommandsPerMinute, if non-zero, limits the rate at which the scheduler will
+// issue operations to the devices attached to the controller. Login is an
+// optional, declarative, login sequence that controllers may execute using
+// LoginSequence.Run rather than implementing their own login handshake.
+// Shadow, if true, replaces the controller, and the devices it controls,
+// with wrappers that record, rather than issue, all operations; this allows
+// for schedules to be rehearsed before being trusted with real devices.
 type ControllerConfigCommon struct {
-	Name        string `yaml:"name"`
-	Type        string `yaml:"type"`
-	RetryConfig `yaml:",inline"`
-	Operations  map[string][]string `yaml:"operations"`
+	Name              string `yaml:"name"`
+	Type              string `yaml:"type"`
+	RetryConfig       `yaml:",inline"`
+	Operations        map[string][]string      `yaml:"operations"`
+	CommandsPerMinute int                      `yaml:"commands_per_minute"`
+	Login             streamconn.LoginSequence `yaml:"login"`
+	Shadow            bool                     `yaml:"shadow"`
 }
 
 // ControllerConfig represents the configuration for a controller allowing
@@ -46,6 +116,9 @@ func (lp *ControllerConfig) UnmarshalYAML(node *yaml.Node) error {
 	if err := node.Decode(&lp.ControllerConfigCommon); err != nil {
 		return err
 	}
+	if err := lp.RetryConfig.validate(); err != nil {
+		return fmt.Errorf("controller %q: %w", lp.Name, err)
+	}
 	if lp.Timeout == 0 {
 		lp.Timeout = time.Minute
 	}
@@ -60,6 +133,10 @@ type DeviceConfigCommon struct {
 	Operations     map[string][]string `yaml:"operations"`
 	Conditions     map[string][]string `yaml:"conditions"`
 	RetryConfig    `yaml:",inline"`
+	// LeadTime is how long before their due time that scheduled operations
+	// on the device are issued, to compensate for a controller that is
+	// consistently slow to actuate the device.
+	LeadTime time.Duration `yaml:"lead_time"`
 }
 
 // DeviceConfig represents the configuration for a device allowing
@@ -73,6 +150,9 @@ func (lp *DeviceConfig) UnmarshalYAML(node *yaml.Node) error {
 	if err := node.Decode(&lp.DeviceConfigCommon); err != nil {
 		return err
 	}
+	if err := lp.RetryConfig.validate(); err != nil {
+		return fmt.Errorf("device %q: %w", lp.Name, err)
+	}
 	if lp.Timeout == 0 {
 		lp.Timeout = time.Minute
 	}
@@ -106,16 +186,29 @@ func (tz *TimeLocation) UnmarshalYAML(node *yaml.Node) error {
 type LocationConfig struct {
 	TimeLocation *TimeLocation `yaml:"time_location" cmd:"the system location for time in time.Location format"`
 	ZIPCode      string        `yaml:"zip_code" cmd:"the zip/postal for the system used to determine it's latitude and longitude, but not used for time"`
-	Latitude     float64       `yaml:"latitude" cmd:"the latitude for the location"`
-	Longitude    float64       `yaml:"longitude" cmd:"the longitude for the location"`
+	Latitude     *float64      `yaml:"latitude" cmd:"the latitude for the location"`
+	Longitude    *float64      `yaml:"longitude" cmd:"the longitude for the location"`
 }
 
 type Location struct {
 	datetime.Place
 	ZIPCode string
+	// LatLongSet is true if the latitude and longitude were specified,
+	// either directly or via a zip code lookup, so that a location on
+	// the equator or prime meridian can be distinguished from an unset one.
+	LatLongSet bool
+}
+
+// String returns the place and zip code for the location.
+func (l Location) String() string {
+	return fmt.Sprintf("{%v %v}", l.Place, l.ZIPCode)
 }
 
+// SystemConfig represents the configuration of a system. Includes lists
+// additional system configuration files whose controllers and devices are
+// merged into this one, see ResolveIncludes.
 type SystemConfig struct {
+	Includes    []string           `yaml:"includes" cmd:"additional system configuration files to be merged into this one"`
 	Location    LocationConfig     `yaml:",inline"`
 	Controllers []ControllerConfig `yaml:"controllers" cmd:"the controllers that are being configured"`
 	Devices     []DeviceConfig     `yaml:"devices" cmd:"the devices that are being configured"`
@@ -212,16 +305,23 @@ func ParseSystemConfigFile(ctx context.Context, cfgFile string, opts ...Option)
 	if err := cmdyaml.ParseConfigFile(ctx, cfgFile, &cfg); err != nil {
 		return System{}, err
 	}
+	if err := cfg.ResolveIncludes(ctx, cfgFile); err != nil {
+		return System{}, err
+	}
 	return cfg.CreateSystem(ctx, opts...)
 }
 
 // ParseSystemConfig parses the supplied configuration data and returns
-// a System using CreateSystem.
+// a System using CreateSystem. Any included files are interpreted relative
+// to the current directory.
 func ParseSystemConfig(ctx context.Context, cfgData []byte, opts ...Option) (System, error) {
 	var cfg SystemConfig
 	if err := yaml.Unmarshal(cfgData, &cfg); err != nil {
 		return System{}, err
 	}
+	if err := cfg.ResolveIncludes(ctx, ""); err != nil {
+		return System{}, err
+	}
 	return cfg.CreateSystem(ctx, opts...)
 }
 
@@ -231,12 +331,16 @@ func buildLocation(cfg LocationConfig, opts []Option) (Location, error) {
 		opt(&o)
 	}
 	loc := Location{
-		Place: datetime.Place{
-			Latitude:  cfg.Latitude,
-			Longitude: cfg.Longitude,
-		},
 		ZIPCode: cfg.ZIPCode,
 	}
+	if (cfg.Latitude == nil) != (cfg.Longitude == nil) {
+		return loc, fmt.Errorf("latitude and longitude must be specified together")
+	}
+	if cfg.Latitude != nil {
+		loc.Latitude = *cfg.Latitude
+		loc.Longitude = *cfg.Longitude
+		loc.LatLongSet = true
+	}
 	if cfg.TimeLocation != nil {
 		loc.TimeLocation = cfg.TimeLocation.Location
 	}
@@ -251,23 +355,23 @@ func buildLocation(cfg LocationConfig, opts []Option) (Location, error) {
 		loc.TimeLocation = tz
 	}
 
-	if o.latitude != 0 {
+	if o.latLongSet {
 		loc.Latitude = o.latitude
-	}
-	if o.longitude != 0 {
 		loc.Longitude = o.longitude
+		loc.LatLongSet = true
 	}
 	if o.zipCode != "" {
 		loc.ZIPCode = o.zipCode
 	}
 
-	if loc.ZIPCode != "" && loc.Latitude == 0 && loc.Longitude == 0 && o.zipCodeLookup != nil {
+	if loc.ZIPCode != "" && !loc.LatLongSet && o.zipCodeLookup != nil {
 		lat, long, err := o.zipCodeLookup.Lookup(loc.ZIPCode)
 		if err != nil {
 			return loc, err
 		}
 		loc.Latitude = lat
 		loc.Longitude = long
+		loc.LatLongSet = true
 	}
 	return loc, nil
 }
@@ -6,6 +6,11 @@ package devices_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
 	"reflect"
 	"slices"
 	"strings"
@@ -154,6 +159,88 @@ func TestParseConfig(t *testing.T) {
 
 }
 
+func TestRetryBackoff(t *testing.T) {
+	ctx := context.Background()
+	spec := `
+devices:
+  - name: d
+    type: device
+    retries: 5
+    backoff: [1s, 5s, 30s]
+`
+	system, err := devices.ParseSystemConfig(ctx, []byte(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc := system.Devices["d"].Config().RetryConfig
+	for i, want := range []time.Duration{time.Second, 5 * time.Second, 30 * time.Second, 30 * time.Second} {
+		if got := rc.Delay(i); got != want {
+			t.Errorf("%v: got %v, want %v", i, got, want)
+		}
+	}
+	if got, want := (devices.RetryConfig{Timeout: time.Minute}).Delay(3), time.Minute; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, tc := range []struct {
+		backoff, err string
+	}{
+		{"[]", "backoff must not be empty"},
+		{"[1s, -1s]", "backoff delays must not be negative"},
+	} {
+		_, err := devices.ParseSystemConfig(ctx, []byte(strings.ReplaceAll(spec, "[1s, 5s, 30s]", tc.backoff)))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%v: unexpected or missing error: %v", tc.backoff, err)
+		}
+	}
+}
+
+func TestRetryOn(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		err  error
+		kind devices.ErrorKind
+	}{
+		{context.DeadlineExceeded, devices.ErrorTimeout},
+		{fmt.Errorf("read: %w", os.ErrDeadlineExceeded), devices.ErrorTimeout},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, devices.ErrorTransport},
+		{io.EOF, devices.ErrorTransport},
+		{fmt.Errorf("busy: %w", devices.ErrDeviceRejected), devices.ErrorRejected},
+		{errors.New("oops"), devices.ErrorOther},
+	} {
+		if got, want := devices.ClassifyError(tc.err), tc.kind; got != want {
+			t.Errorf("%v: got %v, want %v", tc.err, got, want)
+		}
+	}
+
+	spec := `
+devices:
+  - name: d
+    type: device
+    retry_on: [device-rejected, other]
+`
+	system, err := devices.ParseSystemConfig(ctx, []byte(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc := system.Devices["d"].Config().RetryConfig
+	if got, want := rc.RetryOn, []devices.ErrorKind{devices.ErrorRejected, devices.ErrorOther}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if rc.ShouldRetry(context.DeadlineExceeded) || !rc.ShouldRetry(devices.ErrDeviceRejected) {
+		t.Errorf("unexpected retry decision for %v", rc.RetryOn)
+	}
+	var defaults devices.RetryConfig
+	if !defaults.ShouldRetry(context.DeadlineExceeded) || defaults.ShouldRetry(devices.ErrDeviceRejected) {
+		t.Errorf("unexpected default retry decision")
+	}
+
+	_, err = devices.ParseSystemConfig(ctx, []byte(strings.ReplaceAll(spec, "other", "sometimes")))
+	if err == nil || !strings.Contains(err.Error(), `retry_on: unknown error kind: "sometimes"`) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+}
+
 func TestBuildDevices(t *testing.T) {
 	ctx := context.Background()
 
@@ -256,7 +343,7 @@ func TestParsePlaceAndZIP(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to parse system config: %v", err)
 	}
-	if got, want := system.Location, (devices.Location{ZIPCode: "94102", Place: datetime.Place{TimeLocation: time.Local, Latitude: 37.7749, Longitude: 122.4194}}); !reflect.DeepEqual(got, want) {
+	if got, want := system.Location, (devices.Location{ZIPCode: "94102", LatLongSet: true, Place: datetime.Place{TimeLocation: time.Local, Latitude: 37.7749, Longitude: 122.4194}}); !reflect.DeepEqual(got, want) {
 		t.Errorf("got %+v, want %+v", got, want)
 	}
 
@@ -264,7 +351,7 @@ func TestParsePlaceAndZIP(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to parse system config: %v", err)
 	}
-	if got, want := system.Location, (devices.Location{ZIPCode: "12345", Place: datetime.Place{TimeLocation: time.Local, Latitude: 23, Longitude: 43}}); !reflect.DeepEqual(got, want) {
+	if got, want := system.Location, (devices.Location{ZIPCode: "12345", LatLongSet: true, Place: datetime.Place{TimeLocation: time.Local, Latitude: 23, Longitude: 43}}); !reflect.DeepEqual(got, want) {
 		t.Errorf("got %+v, want %+v", got, want)
 	}
 
@@ -275,7 +362,7 @@ func TestParsePlaceAndZIP(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to parse system config: %v", err)
 	}
-	if got, want := system.Location, (devices.Location{ZIPCode: "12345", Place: datetime.Place{TimeLocation: time.Local, Latitude: 100, Longitude: -100}}); !reflect.DeepEqual(got, want) {
+	if got, want := system.Location, (devices.Location{ZIPCode: "12345", LatLongSet: true, Place: datetime.Place{TimeLocation: time.Local, Latitude: 100, Longitude: -100}}); !reflect.DeepEqual(got, want) {
 		t.Errorf("got %+v, want %+v", got, want)
 	}
 
@@ -283,11 +370,50 @@ func TestParsePlaceAndZIP(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to parse system config: %v", err)
 	}
-	if got, want := system.Location, (devices.Location{ZIPCode: "94102", Place: datetime.Place{TimeLocation: time.Local, Latitude: 200, Longitude: -200}}); !reflect.DeepEqual(got, want) {
+	if got, want := system.Location, (devices.Location{ZIPCode: "94102", LatLongSet: true, Place: datetime.Place{TimeLocation: time.Local, Latitude: 200, Longitude: -200}}); !reflect.DeepEqual(got, want) {
 		t.Errorf("got %+v, want %+v", got, want)
 	}
 }
 
+func TestLatLongSet(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		spec     string
+		opts     []devices.Option
+		expected devices.Location
+	}{
+		// An equatorial location on the prime meridian is not unset and
+		// is not overridden by a zip code lookup.
+		{"latitude: 0\nlongitude: 0\nzip_code: 94102", []devices.Option{devices.WithZIPCodeLookup(ziplookup{})},
+			devices.Location{ZIPCode: "94102", LatLongSet: true, Place: datetime.Place{TimeLocation: time.Local}}},
+		{"latitude: 0\nlongitude: -78.5", nil,
+			devices.Location{LatLongSet: true, Place: datetime.Place{TimeLocation: time.Local, Longitude: -78.5}}},
+		{"", []devices.Option{devices.WithLatLong(0, 0)},
+			devices.Location{LatLongSet: true, Place: datetime.Place{TimeLocation: time.Local}}},
+		{"latitude: 10\nlongitude: 10", []devices.Option{devices.WithLatLong(0, 20)},
+			devices.Location{LatLongSet: true, Place: datetime.Place{TimeLocation: time.Local, Longitude: 20}}},
+		// Genuinely unset.
+		{"", nil,
+			devices.Location{Place: datetime.Place{TimeLocation: time.Local}}},
+		{"zip_code: 94102", nil,
+			devices.Location{ZIPCode: "94102", Place: datetime.Place{TimeLocation: time.Local}}},
+	} {
+		system, err := devices.ParseSystemConfig(ctx, []byte(tc.spec), tc.opts...)
+		if err != nil {
+			t.Errorf("%q: failed to parse system config: %v", tc.spec, err)
+			continue
+		}
+		if got, want := system.Location, tc.expected; !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got %+v, want %+v", tc.spec, got, want)
+		}
+	}
+
+	_, err := devices.ParseSystemConfig(ctx, []byte("latitude: 0"))
+	if err == nil || !strings.Contains(err.Error(), "latitude and longitude must be specified together") {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+}
+
 func TestOperations(t *testing.T) {
 
 	ctx := context.Background()
@@ -333,3 +459,19 @@ func TestOperations(t *testing.T) {
 		t.Errorf("got %q, want %q", got, want)
 	}
 }
+
+func TestStrictOperations(t *testing.T) {
+	ctx := context.Background()
+	spec := strings.Replace(simpleSpec, "      on: [on, command]\n", "      on: [on, command]\n      dim: [50]\n", 1)
+	if _, err := devices.ParseSystemConfig(ctx, []byte(spec)); err != nil {
+		t.Fatalf("failed to parse system config: %v", err)
+	}
+	_, err := devices.ParseSystemConfig(ctx, []byte(spec), devices.WithStrictOperations(true))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if got, want := err.Error(), `device "d": operation "dim" is not implemented
+device "e": condition "weather" is not implemented`; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
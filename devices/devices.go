@@ -6,8 +6,11 @@ package devices
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"slices"
 	"time"
 
 	"cloudeng.io/datetime"
@@ -82,8 +85,10 @@ type Options struct {
 	loc           *time.Location
 	latitude      float64
 	longitude     float64
+	latLongSet    bool
 	zipCode       string
 	zipCodeLookup ZIPCodeLookup
+	strictOps     bool
 	Custom        any
 }
 
@@ -103,6 +108,7 @@ func WithLatLong(lat, long float64) Option {
 	return func(o *Options) {
 		o.latitude = lat
 		o.longitude = long
+		o.latLongSet = true
 	}
 }
 
@@ -130,6 +136,16 @@ func WithControllers(c SupportedControllers) Option {
 	}
 }
 
+// WithStrictOperations requires that every operation and condition listed
+// in the configuration for a controller or device is implemented by it,
+// otherwise CreateSystem fails. By default such operations are only
+// reported as unavailable when they are invoked.
+func WithStrictOperations(strict bool) Option {
+	return func(o *Options) {
+		o.strictOps = strict
+	}
+}
+
 type SupportedControllers map[string]func(typ string, opts Options) (Controller, error)
 
 type SupportedDevices map[string]func(typ string, opts Options) (Device, error)
@@ -152,9 +168,42 @@ func CreateSystem(_ context.Context, controllerCfg []ControllerConfig, deviceCfg
 			dev.SetController(ctrl)
 		}
 	}
+	if options.strictOps {
+		if err := checkConfiguredOperations(controllers, devices); err != nil {
+			return nil, nil, err
+		}
+	}
+	shadowSystem(controllers, devices)
 	return controllers, devices, nil
 }
 
+func missingFromMap[V any](kind, name, what string, configured map[string][]string, implemented map[string]V) []error {
+	var errs []error
+	for _, op := range slices.Sorted(maps.Keys(configured)) {
+		if _, ok := implemented[op]; !ok {
+			errs = append(errs, fmt.Errorf("%v %q: %v %q is not implemented", kind, name, what, op))
+		}
+	}
+	return errs
+}
+
+// checkConfiguredOperations returns an error for every operation or
+// condition listed in the configuration of a controller or device that
+// is not implemented by it.
+func checkConfiguredOperations(controllers map[string]Controller, devices map[string]Device) error {
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(controllers)) {
+		ctrl := controllers[name]
+		errs = append(errs, missingFromMap("controller", name, "operation", ctrl.Config().Operations, ctrl.Operations())...)
+	}
+	for _, name := range slices.Sorted(maps.Keys(devices)) {
+		dev := devices[name]
+		errs = append(errs, missingFromMap("device", name, "operation", dev.Config().Operations, dev.Operations())...)
+		errs = append(errs, missingFromMap("device", name, "condition", dev.Config().Conditions, dev.Conditions())...)
+	}
+	return errors.Join(errs...)
+}
+
 func CreateControllers(config []ControllerConfig, options Options) (map[string]Controller, error) {
 	controllers := map[string]Controller{}
 	availableControllers := options.Controllers
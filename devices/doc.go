@@ -0,0 +1,80 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package devices
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParamDoc documents a single parameter of an operation.
+type ParamDoc struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Doc represents structured documentation for an operation.
+type Doc struct {
+	Description string     `json:"description"`
+	Params      []ParamDoc `json:"params,omitempty"`
+	Examples    []string   `json:"examples,omitempty"`
+}
+
+// OperationDocumenter may be implemented by controllers and devices that
+// provide structured documentation for their operations in addition to
+// that returned by OperationsHelp.
+type OperationDocumenter interface {
+	OperationDoc(op string) (Doc, bool)
+}
+
+// OperationDoc returns the documentation for the specified operation
+// provided by the supplied controller or device. The controller or
+// device's OperationDoc method is used if it implements OperationDocumenter,
+// otherwise the documentation is created from its OperationsHelp.
+func OperationDoc(cd interface{ OperationsHelp() map[string]string }, op string) (Doc, bool) {
+	if od, ok := cd.(OperationDocumenter); ok {
+		return od.OperationDoc(op)
+	}
+	help, ok := cd.OperationsHelp()[op]
+	if !ok {
+		return Doc{}, false
+	}
+	return Doc{Description: help}, true
+}
+
+// Markdown renders the documentation as markdown using the supplied title,
+// typically of the form <device>.<operation>, as its heading.
+func (d Doc) Markdown(title string) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "## %s\n", title)
+	if len(d.Description) > 0 {
+		fmt.Fprintf(&out, "\n%s\n", d.Description)
+	}
+	if len(d.Params) > 0 {
+		out.WriteString("\n### Parameters\n\n")
+		for _, p := range d.Params {
+			fmt.Fprintf(&out, "- `%s`: %s\n", p.Name, p.Description)
+		}
+	}
+	if len(d.Examples) > 0 {
+		out.WriteString("\n### Examples\n")
+		for _, e := range d.Examples {
+			fmt.Fprintf(&out, "\n```\n%s\n```\n", e)
+		}
+	}
+	return out.String()
+}
+
+// OperationDoc returns the documentation for the specified operation on the
+// named controller or device.
+func (s System) OperationDoc(name, op string) (Doc, bool) {
+	if ctrl, ok := s.Controllers[name]; ok {
+		return OperationDoc(ctrl, op)
+	}
+	if dev, ok := s.Devices[name]; ok {
+		return OperationDoc(dev, op)
+	}
+	return Doc{}, false
+}
@@ -0,0 +1,50 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package devices_test
+
+import (
+	"testing"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/internal/testutil"
+)
+
+func TestOperationDoc(t *testing.T) {
+	dev := testutil.NewMockDevice("on", "off")
+	dev.SetOperationDoc("on", devices.Doc{
+		Description: "turn the device on",
+		Params:      []devices.ParamDoc{{Name: "level", Description: "brightness, 0-100"}},
+		Examples:    []string{"device.on(50)"},
+	})
+
+	doc, ok := devices.OperationDoc(dev, "on")
+	if !ok {
+		t.Fatal("missing doc")
+	}
+	if got, want := doc.Markdown("device.on"), "## device.on\n\nturn the device on\n\n### Parameters\n\n- `level`: brightness, 0-100\n\n### Examples\n\n```\ndevice.on(50)\n```\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	doc, ok = devices.OperationDoc(dev, "off")
+	if !ok {
+		t.Fatal("missing doc")
+	}
+	if got, want := doc.Markdown("device.off"), "## device.off\n\nOff operation\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, ok := devices.OperationDoc(dev, "unknown"); ok {
+		t.Errorf("unexpected doc for unknown operation")
+	}
+
+	// Controllers that do not implement OperationDocumenter.
+	doc, ok = devices.OperationDoc(&testutil.MockController{}, "enable")
+	if !ok {
+		t.Fatal("missing doc")
+	}
+	if got, want := doc.Description, "enable the controller"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package devices
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+)
+
+// ErrorKind classifies the errors returned by operations and conditions.
+type ErrorKind string
+
+const (
+	ErrorTimeout   ErrorKind = "timeout"         // The operation did not complete in time.
+	ErrorTransport ErrorKind = "transport"       // The connection to the device failed.
+	ErrorRejected  ErrorKind = "device-rejected" // The device received, but rejected, the request.
+	ErrorOther     ErrorKind = "other"           // Any other error.
+)
+
+// ErrDeviceRejected should be wrapped by operations whose request was
+// received, but rejected, by the device. Such errors are considered
+// permanent and hence not worth retrying.
+var ErrDeviceRejected = errors.New("device rejected the request")
+
+// DefaultRetryOn are the kinds of error that are retried when a RetryConfig
+// does not specify retry_on.
+var DefaultRetryOn = []ErrorKind{ErrorTimeout, ErrorTransport}
+
+// ParseErrorKind parses the supplied string as an ErrorKind.
+func ParseErrorKind(val string) (ErrorKind, error) {
+	switch k := ErrorKind(val); k {
+	case ErrorTimeout, ErrorTransport, ErrorRejected, ErrorOther:
+		return k, nil
+	}
+	return "", fmt.Errorf("unknown error kind: %q", val)
+}
+
+// ClassifyError returns the kind of the supplied, non-nil, error.
+func ClassifyError(err error) ErrorKind {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrDeviceRejected):
+		return ErrorRejected
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		return ErrorTimeout
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return ErrorTimeout
+		}
+		return ErrorTransport
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, net.ErrClosed):
+		return ErrorTransport
+	}
+	return ErrorOther
+}
@@ -0,0 +1,168 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package devices
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExternalConfig represents the configuration of an ExternalDevice.
+type ExternalConfig struct {
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args,flow"`
+}
+
+// ExternalRequest is written, JSON encoded, to the standard input of an
+// external device's command for each operation or condition invoked on
+// the device. Kind is either "operation" or "condition".
+type ExternalRequest struct {
+	Kind   string    `json:"kind"`
+	Device string    `json:"device"`
+	Name   string    `json:"name"`
+	Args   []string  `json:"args"`
+	Due    time.Time `json:"due"`
+}
+
+// ExternalResponse is read, JSON encoded, from the standard output of an
+// external device's command. Data is returned as the result of the
+// operation or condition, Result is the outcome of a condition and
+// a non-empty Error is returned as an error. Output, if any, is written
+// to the operation's writer.
+type ExternalResponse struct {
+	Data   any    `json:"data,omitempty"`
+	Result bool   `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+	Output string `json:"output,omitempty"`
+}
+
+// ExternalDevice is a device whose operations and conditions are
+// implemented by an external command, allowing for device types that are
+// not compiled in. The command is run once per invocation with an
+// ExternalRequest on its standard input and must write an
+// ExternalResponse to its standard output and exit with a zero status.
+// Its standard error is written to the operation's writer. The device
+// supports the operations and conditions listed in its configuration.
+type ExternalDevice struct {
+	DeviceBase[ExternalConfig]
+	controller Controller
+}
+
+// NewExternalDevice creates a new ExternalDevice, it has the signature
+// required for use with SupportedDevices.
+func NewExternalDevice(string, Options) (Device, error) {
+	return &ExternalDevice{}, nil
+}
+
+func (ed *ExternalDevice) SetController(c Controller) {
+	ed.controller = c
+}
+
+func (ed *ExternalDevice) ControlledBy() Controller {
+	return ed.controller
+}
+
+func (ed *ExternalDevice) UnmarshalYAML(node *yaml.Node) error {
+	if err := node.Decode(&ed.DeviceConfigCustom); err != nil {
+		return err
+	}
+	if len(ed.DeviceConfigCustom.Command) == 0 {
+		return fmt.Errorf("device %q: command must be specified", ed.Name)
+	}
+	return nil
+}
+
+// ErrExternalCommand is returned, wrapped, for all failures to run an
+// external device's command or to parse its response.
+var ErrExternalCommand = errors.New("external command failed")
+
+func (ed *ExternalDevice) invoke(ctx context.Context, kind, name string, opts OperationArgs) (ExternalResponse, error) {
+	req, err := json.Marshal(ExternalRequest{
+		Kind:   kind,
+		Device: ed.Name,
+		Name:   name,
+		Args:   opts.Args,
+		Due:    opts.Due,
+	})
+	if err != nil {
+		return ExternalResponse{}, err
+	}
+	cfg := ed.DeviceConfigCustom
+	cmd := exec.CommandContext(ctx, cfg.Command, cfg.Args...)
+	cmd.Stdin = bytes.NewReader(req)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if opts.Writer != nil {
+		cmd.Stderr = opts.Writer
+	}
+	if err := cmd.Run(); err != nil {
+		// Return ctx.Err() so that timeouts are classified as such, and
+		// wrap the cause, if any, to retain the reason for the timeout.
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			if cause := context.Cause(ctx); cause != ctxErr {
+				return ExternalResponse{}, fmt.Errorf("%w: %w", ctxErr, cause)
+			}
+			return ExternalResponse{}, ctxErr
+		}
+		return ExternalResponse{}, fmt.Errorf("%w: %v: %v.%v: %w", ErrExternalCommand, cfg.Command, ed.Name, name, err)
+	}
+	var resp ExternalResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return ExternalResponse{}, fmt.Errorf("%w: %v: %v.%v: invalid response: %v", ErrExternalCommand, cfg.Command, ed.Name, name, err)
+	}
+	if len(resp.Output) > 0 && opts.Writer != nil {
+		_, _ = io.WriteString(opts.Writer, resp.Output)
+	}
+	if len(resp.Error) > 0 {
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
+
+func (ed *ExternalDevice) Operations() map[string]Operation {
+	ops := make(map[string]Operation, len(ed.DeviceConfigCommon.Operations))
+	for name := range ed.DeviceConfigCommon.Operations {
+		ops[name] = func(ctx context.Context, opts OperationArgs) (any, error) {
+			resp, err := ed.invoke(ctx, "operation", name, opts)
+			return resp.Data, err
+		}
+	}
+	return ops
+}
+
+func (ed *ExternalDevice) Conditions() map[string]Condition {
+	conds := make(map[string]Condition, len(ed.DeviceConfigCommon.Conditions))
+	for name := range ed.DeviceConfigCommon.Conditions {
+		conds[name] = func(ctx context.Context, opts OperationArgs) (any, bool, error) {
+			resp, err := ed.invoke(ctx, "condition", name, opts)
+			return resp.Data, resp.Result, err
+		}
+	}
+	return conds
+}
+
+func (ed *ExternalDevice) OperationsHelp() map[string]string {
+	return ed.help(ed.DeviceConfigCommon.Operations)
+}
+
+func (ed *ExternalDevice) ConditionsHelp() map[string]string {
+	return ed.help(ed.DeviceConfigCommon.Conditions)
+}
+
+func (ed *ExternalDevice) help(configured map[string][]string) map[string]string {
+	help := make(map[string]string, len(configured))
+	for name := range configured {
+		help[name] = "implemented by " + ed.DeviceConfigCustom.Command
+	}
+	return help
+}
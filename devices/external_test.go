@@ -0,0 +1,158 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package devices_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/devices"
+)
+
+// externalScript implements the external device protocol by matching
+// on the raw request rather than parsing it.
+const externalScript = `#!/bin/sh
+req=$(cat)
+echo "request: $req" >&2
+case "$req" in
+*'"kind":"operation"'*'"name":"on"'*)
+	printf '%s\n' '{"data":{"state":"on"},"output":"switched on\n"}';;
+*'"kind":"condition"'*'"name":"raining"'*'"args":["heavy"]'*)
+	printf '%s\n' '{"data":"heavy rain","result":true}';;
+*'"kind":"condition"'*'"name":"raining"'*)
+	printf '%s\n' '{"result":false}';;
+*'"name":"broken"'*)
+	printf '%s\n' '{"error":"device is broken"}';;
+*'"name":"crash"'*)
+	exit 3;;
+*'"name":"slow"'*)
+	exec sleep 10;;
+*)
+	printf '%s\n' 'not json';;
+esac
+`
+
+func TestExternalDevice(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a shell script")
+	}
+	ctx := context.Background()
+	script := filepath.Join(t.TempDir(), "device.sh")
+	if err := os.WriteFile(script, []byte(externalScript), 0700); err != nil {
+		t.Fatal(err)
+	}
+	sys, err := devices.ParseSystemConfig(ctx, []byte(`
+devices:
+  - name: ext
+    type: external
+    command: `+script+`
+    operations:
+      on:
+      broken:
+      crash:
+      garbled:
+      slow:
+    conditions:
+      raining:
+`), devices.WithDevices(devices.SupportedDevices{
+		"external": devices.NewExternalDevice,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	op, _, ok := sys.DeviceOp("ext", "on")
+	if !ok {
+		t.Fatal("on operation not found")
+	}
+	var out bytes.Buffer
+	data, err := op(ctx, devices.OperationArgs{Writer: &out, Args: []string{"a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := data, any(map[string]any{"state": "on"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := out.String(), `"device":"ext","name":"on","args":["a"]`; !strings.Contains(got, want) {
+		t.Errorf("got %v, does not contain %v", got, want)
+	}
+	if got, want := out.String(), "switched on\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("got %v, does not end with %v", got, want)
+	}
+
+	cond, _, ok := sys.DeviceCondition("ext", "raining")
+	if !ok {
+		t.Fatal("raining condition not found")
+	}
+	for _, tc := range []struct {
+		args   []string
+		data   any
+		result bool
+	}{
+		{[]string{"heavy"}, "heavy rain", true},
+		{nil, nil, false},
+	} {
+		data, result, err := cond(ctx, devices.OperationArgs{Args: tc.args})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := data, tc.data; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got, want := result, tc.result; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+
+	for _, tc := range []struct {
+		op, err string
+		wrapped bool
+	}{
+		{"broken", "device is broken", false},
+		{"crash", "exit status 3", true},
+		{"garbled", "invalid response", true},
+	} {
+		op, _, _ := sys.DeviceOp("ext", tc.op)
+		_, err := op(ctx, devices.OperationArgs{})
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%v: missing or unexpected error: %v", tc.op, err)
+		}
+		if got, want := errors.Is(err, devices.ErrExternalCommand), tc.wrapped; got != want {
+			t.Errorf("%v: got %v, want %v", tc.op, got, want)
+		}
+	}
+
+	// Timeouts are reported as such, regardless of their cause.
+	errCause := errors.New("cause")
+	tctx, cancel := context.WithTimeoutCause(ctx, 20*time.Millisecond, errCause)
+	defer cancel()
+	op, _, _ = sys.DeviceOp("ext", "slow")
+	_, err = op(tctx, devices.OperationArgs{})
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, errCause) {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+	if got, want := devices.ClassifyError(err), devices.ErrorTimeout; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	_, err = devices.ParseSystemConfig(ctx, []byte(`
+devices:
+  - name: ext
+    type: external
+`), devices.WithDevices(devices.SupportedDevices{
+		"external": devices.NewExternalDevice,
+	}))
+	if err == nil || !strings.Contains(err.Error(), "command must be specified") {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+}
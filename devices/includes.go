@@ -0,0 +1,126 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package devices
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"cloudeng.io/cmdutil/cmdyaml"
+)
+
+// ResolveIncludes reads the system configuration files listed in
+// cfg.Includes, and any that they in turn include, and merges their
+// controllers and devices into cfg. Relative include paths are interpreted
+// relative to the directory containing filename, which is the file that
+// cfg was read from, or the current directory if filename is empty.
+// Controller and device names must not be repeated across files and
+// each location setting may be specified in at most one of them.
+func (cfg *SystemConfig) ResolveIncludes(ctx context.Context, filename string) error {
+	if len(filename) == 0 {
+		filename = "<config>"
+	}
+	m := &configMerger{
+		controllers: map[string]string{},
+		devices:     map[string]string{},
+		location:    map[string]string{},
+		visited:     map[string]bool{},
+	}
+	if abs, err := filepath.Abs(filename); err == nil {
+		m.visited[abs] = true
+	}
+	if err := m.record(filename, cfg); err != nil {
+		return err
+	}
+	return m.includes(ctx, filename, cfg, cfg)
+}
+
+// configMerger records the file in which each controller, device and
+// location setting was first defined.
+type configMerger struct {
+	controllers, devices, location map[string]string
+	visited                        map[string]bool
+}
+
+func (m *configMerger) includes(ctx context.Context, filename string, from, into *SystemConfig) error {
+	dir := filepath.Dir(filename)
+	for _, inc := range from.Includes {
+		if !filepath.IsAbs(inc) {
+			inc = filepath.Join(dir, inc)
+		}
+		abs, err := filepath.Abs(inc)
+		if err != nil {
+			return err
+		}
+		if m.visited[abs] {
+			return fmt.Errorf("%q: %q is included more than once", filename, inc)
+		}
+		m.visited[abs] = true
+		var included SystemConfig
+		if err := cmdyaml.ParseConfigFile(ctx, inc, &included); err != nil {
+			return fmt.Errorf("%q: failed to parse included file: %w", filename, err)
+		}
+		if err := m.record(inc, &included); err != nil {
+			return err
+		}
+		into.Controllers = append(into.Controllers, included.Controllers...)
+		into.Devices = append(into.Devices, included.Devices...)
+		mergeLocation(&into.Location, included.Location)
+		if err := m.includes(ctx, inc, &included, into); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *configMerger) record(filename string, cfg *SystemConfig) error {
+	for _, c := range cfg.Controllers {
+		if prev, ok := m.controllers[c.Name]; ok && prev != filename {
+			return fmt.Errorf("%q: controller %q is already defined in %q", filename, c.Name, prev)
+		}
+		m.controllers[c.Name] = filename
+	}
+	for _, d := range cfg.Devices {
+		if prev, ok := m.devices[d.Name]; ok && prev != filename {
+			return fmt.Errorf("%q: device %q is already defined in %q", filename, d.Name, prev)
+		}
+		m.devices[d.Name] = filename
+	}
+	loc := cfg.Location
+	for _, setting := range []struct {
+		name string
+		set  bool
+	}{
+		{"time_location", loc.TimeLocation != nil},
+		{"zip_code", len(loc.ZIPCode) > 0},
+		{"latitude", loc.Latitude != nil},
+		{"longitude", loc.Longitude != nil},
+	} {
+		if !setting.set {
+			continue
+		}
+		if prev, ok := m.location[setting.name]; ok {
+			return fmt.Errorf("%q: %v is already specified in %q", filename, setting.name, prev)
+		}
+		m.location[setting.name] = filename
+	}
+	return nil
+}
+
+func mergeLocation(into *LocationConfig, from LocationConfig) {
+	if from.TimeLocation != nil {
+		into.TimeLocation = from.TimeLocation
+	}
+	if len(from.ZIPCode) > 0 {
+		into.ZIPCode = from.ZIPCode
+	}
+	if from.Latitude != nil {
+		into.Latitude = from.Latitude
+	}
+	if from.Longitude != nil {
+		into.Longitude = from.Longitude
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package devices_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/automation/devices"
+)
+
+func writeConfigs(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, contents := range files {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestIncludes(t *testing.T) {
+	ctx := context.Background()
+	dir := writeConfigs(t, map[string]string{
+		"system.yaml": `
+time_location: America/Los_Angeles
+includes:
+  - rooms/kitchen.yaml
+controllers:
+  - name: c
+    type: controller
+devices:
+  - name: hall
+    controller: c
+    type: device
+`,
+		"rooms/kitchen.yaml": `
+zip_code: "94024"
+includes:
+  - garden.yaml
+devices:
+  - name: kitchen
+    controller: c
+    type: device
+`,
+		"rooms/garden.yaml": `
+controllers:
+  - name: g
+    type: controller
+devices:
+  - name: garden
+    controller: g
+    type: device
+`,
+	})
+
+	sys, err := devices.ParseSystemConfigFile(ctx, filepath.Join(dir, "system.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, d := range sys.Config.Devices {
+		names = append(names, d.Name)
+	}
+	if got, want := names, []string{"hall", "kitchen", "garden"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := len(sys.Controllers), 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := sys.Devices["garden"].ControlledByName(), "g"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := sys.Location.TimeLocation.String(), "America/Los_Angeles"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := sys.Location.ZIPCode, "94024"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	ctx := context.Background()
+	dir := writeConfigs(t, map[string]string{
+		"duplicate-controller.yaml": `
+includes:
+  - controllers.yaml
+controllers:
+  - name: c
+    type: controller
+`,
+		"controllers.yaml": `
+controllers:
+  - name: c
+    type: controller
+`,
+		"duplicate-location.yaml": `
+time_location: UTC
+includes:
+  - location.yaml
+`,
+		"location.yaml": `
+time_location: Local
+`,
+		"cycle.yaml": `
+includes:
+  - cycle.yaml
+`,
+	})
+	for _, tc := range []struct {
+		file, err string
+	}{
+		{"duplicate-controller.yaml", `controller "c" is already defined in`},
+		{"duplicate-location.yaml", `time_location is already specified in`},
+		{"cycle.yaml", `is included more than once`},
+		{"missing.yaml", `no such file`},
+	} {
+		_, err := devices.ParseSystemConfigFile(ctx, filepath.Join(dir, tc.file))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%v: missing or unexpected error: %v, want %v", tc.file, err, tc.err)
+		}
+	}
+}
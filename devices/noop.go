@@ -0,0 +1,55 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package devices
+
+import (
+	"context"
+	"fmt"
+)
+
+// NoopDevice is a device whose on and off operations do nothing other
+// than write their invocation to the operation's writer. It requires no
+// controller and is intended for trying out schedules.
+type NoopDevice struct {
+	DeviceBase[struct{}]
+	controller Controller
+}
+
+// NewNoopDevice creates a new NoopDevice, it has the signature required
+// for use with SupportedDevices.
+func NewNoopDevice(string, Options) (Device, error) {
+	return &NoopDevice{}, nil
+}
+
+func (nd *NoopDevice) SetController(c Controller) {
+	nd.controller = c
+}
+
+func (nd *NoopDevice) ControlledBy() Controller {
+	return nd.controller
+}
+
+func (nd *NoopDevice) op(name string) Operation {
+	return func(_ context.Context, opts OperationArgs) (any, error) {
+		if opts.Writer != nil {
+			fmt.Fprintf(opts.Writer, "noop[%s].%s: %v\n", nd.Name, name, opts.Args)
+		}
+		return nil, nil
+	}
+}
+
+func (nd *NoopDevice) Operations() map[string]Operation {
+	return map[string]Operation{
+		"on":  nd.op("on"),
+		"off": nd.op("off"),
+	}
+}
+
+func (nd *NoopDevice) OperationsHelp() map[string]string {
+	return map[string]string{
+		"on":  "does nothing",
+		"off": "does nothing",
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package devices
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// TypeInfo provides human readable metadata for a controller or device
+// type.
+type TypeInfo struct {
+	Type        string
+	Description string
+	Vendor      string
+	Version     string
+}
+
+var (
+	typeInfoMu          sync.Mutex
+	controllerTypeInfos = map[string]TypeInfo{}
+	deviceTypeInfos     = map[string]TypeInfo{}
+)
+
+// RegisterControllerType adds the supplied controller constructor to
+// AvailableControllers along with its metadata.
+func RegisterControllerType(info TypeInfo, fn func(typ string, opts Options) (Controller, error)) {
+	typeInfoMu.Lock()
+	defer typeInfoMu.Unlock()
+	AvailableControllers[info.Type] = fn
+	controllerTypeInfos[info.Type] = info
+}
+
+// RegisterDeviceType adds the supplied device constructor to
+// AvailableDevices along with its metadata.
+func RegisterDeviceType(info TypeInfo, fn func(typ string, opts Options) (Device, error)) {
+	typeInfoMu.Lock()
+	defer typeInfoMu.Unlock()
+	AvailableDevices[info.Type] = fn
+	deviceTypeInfos[info.Type] = info
+}
+
+func registered[M ~map[string]F, F any](available M, infos map[string]TypeInfo) []TypeInfo {
+	typeInfoMu.Lock()
+	defer typeInfoMu.Unlock()
+	types := make([]TypeInfo, 0, len(available))
+	for typ := range available {
+		info, ok := infos[typ]
+		if !ok {
+			info = TypeInfo{Type: typ}
+		}
+		types = append(types, info)
+	}
+	slices.SortFunc(types, func(a, b TypeInfo) int {
+		return strings.Compare(a.Type, b.Type)
+	})
+	return types
+}
+
+// RegisteredControllerTypes returns the metadata for all of the types in
+// AvailableControllers sorted by type. Types added directly to
+// AvailableControllers, rather than via RegisterControllerType, have
+// no metadata other than their type.
+func RegisteredControllerTypes() []TypeInfo {
+	return registered(AvailableControllers, controllerTypeInfos)
+}
+
+// RegisteredDeviceTypes returns the metadata for all of the types in
+// AvailableDevices sorted by type. Types added directly to
+// AvailableDevices, rather than via RegisterDeviceType, have no
+// metadata other than their type.
+func RegisteredDeviceTypes() []TypeInfo {
+	return registered(AvailableDevices, deviceTypeInfos)
+}
@@ -0,0 +1,70 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package devices_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/internal/testutil"
+)
+
+func TestRegistry(t *testing.T) {
+	devices.RegisterDeviceType(devices.TypeInfo{
+		Type:        "registered-device",
+		Description: "a device for testing",
+		Vendor:      "acme",
+		Version:     "1.0",
+	}, func(string, devices.Options) (devices.Device, error) {
+		return testutil.NewMockDevice("on"), nil
+	})
+	devices.AvailableDevices["unregistered-device"] = nil
+	defer delete(devices.AvailableDevices, "registered-device")
+	defer delete(devices.AvailableDevices, "unregistered-device")
+
+	types := devices.RegisteredDeviceTypes()
+	if !slices.IsSortedFunc(types, func(a, b devices.TypeInfo) int {
+		return strings.Compare(a.Type, b.Type)
+	}) {
+		t.Errorf("types are not sorted: %v", types)
+	}
+	find := func(typ string) devices.TypeInfo {
+		for _, ti := range types {
+			if ti.Type == typ {
+				return ti
+			}
+		}
+		t.Fatalf("type %q not found", typ)
+		return devices.TypeInfo{}
+	}
+	if got, want := find("registered-device"), (devices.TypeInfo{
+		Type:        "registered-device",
+		Description: "a device for testing",
+		Vendor:      "acme",
+		Version:     "1.0",
+	}); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := find("unregistered-device"), (devices.TypeInfo{Type: "unregistered-device"}); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	devices.RegisterControllerType(devices.TypeInfo{
+		Type:        "registered-controller",
+		Description: "a controller for testing",
+	}, func(string, devices.Options) (devices.Controller, error) {
+		return &testutil.MockController{}, nil
+	})
+	defer delete(devices.AvailableControllers, "registered-controller")
+	ctrls := devices.RegisteredControllerTypes()
+	if !slices.Contains(ctrls, devices.TypeInfo{Type: "registered-controller", Description: "a controller for testing"}) {
+		t.Errorf("registered controller not found: %v", ctrls)
+	}
+	if _, ok := devices.AvailableControllers["registered-controller"]; !ok {
+		t.Errorf("registered controller not available")
+	}
+}
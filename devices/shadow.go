@@ -0,0 +1,91 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package devices
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// shadowController wraps a Controller configured with shadow: true so
+// that its operations are recorded rather than being issued to the
+// real controller.
+type shadowController struct {
+	Controller
+}
+
+func (sc *shadowController) Operations() map[string]Operation {
+	return shadowOperations(sc.Config().Name, sc.Controller.Operations())
+}
+
+func (sc *shadowController) OperationDoc(op string) (Doc, bool) {
+	return OperationDoc(sc.Controller, op)
+}
+
+// shadowDevice wraps a Device whose controller is configured with
+// shadow: true so that its operations and conditions are recorded
+// rather than being issued to the real device.
+type shadowDevice struct {
+	Device
+}
+
+func (sd *shadowDevice) Operations() map[string]Operation {
+	return shadowOperations(sd.Config().Name, sd.Device.Operations())
+}
+
+func (sd *shadowDevice) OperationDoc(op string) (Doc, bool) {
+	return OperationDoc(sd.Device, op)
+}
+
+// Conditions are always met for shadowed devices, as per dry runs.
+func (sd *shadowDevice) Conditions() map[string]Condition {
+	name := sd.Config().Name
+	conds := sd.Device.Conditions()
+	shadowed := make(map[string]Condition, len(conds))
+	for cond := range conds {
+		shadowed[cond] = func(_ context.Context, opts OperationArgs) (any, bool, error) {
+			writeShadowRecord(opts, name, cond)
+			return nil, true, nil
+		}
+	}
+	return shadowed
+}
+
+func shadowOperations(name string, ops map[string]Operation) map[string]Operation {
+	shadowed := make(map[string]Operation, len(ops))
+	for op := range ops {
+		shadowed[op] = func(_ context.Context, opts OperationArgs) (any, error) {
+			writeShadowRecord(opts, name, op)
+			return nil, nil
+		}
+	}
+	return shadowed
+}
+
+func writeShadowRecord(opts OperationArgs, name, op string) {
+	if opts.Writer == nil {
+		return
+	}
+	fmt.Fprintf(opts.Writer, "shadow: %v.%v(%v)\n", name, op, strings.Join(opts.Args, ", "))
+}
+
+// shadowSystem wraps all controllers configured with shadow: true, and
+// the devices they control, so that their operations are recorded, to
+// the Writer supplied to each operation, rather than actuating any
+// hardware. Unlike a dry run, the full dispatch path, including argument
+// resolution, is exercised.
+func shadowSystem(controllers map[string]Controller, devices map[string]Device) {
+	for name, ctrl := range controllers {
+		if ctrl.Config().Shadow {
+			controllers[name] = &shadowController{Controller: ctrl}
+		}
+	}
+	for name, dev := range devices {
+		if ctrl, ok := controllers[dev.ControlledByName()]; ok && ctrl.Config().Shadow {
+			devices[name] = &shadowDevice{Device: dev}
+		}
+	}
+}
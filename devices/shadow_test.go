@@ -0,0 +1,139 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package devices_test
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/internal/testutil"
+)
+
+// dialingController counts the number of times that its transport
+// is 'dialed' by the operations of it, or its devices.
+type dialingController struct {
+	testutil.MockController
+	dials atomic.Int64
+}
+
+func (c *dialingController) Operations() map[string]devices.Operation {
+	return map[string]devices.Operation{
+		"enable": func(context.Context, devices.OperationArgs) (any, error) {
+			c.dials.Add(1)
+			return nil, nil
+		},
+	}
+}
+
+func (c *dialingController) Implementation() any {
+	return c
+}
+
+type dialingDevice struct {
+	*testutil.MockDevice
+}
+
+func (d *dialingDevice) Operations() map[string]devices.Operation {
+	return map[string]devices.Operation{
+		"on": func(context.Context, devices.OperationArgs) (any, error) {
+			d.ControlledBy().Implementation().(*dialingController).dials.Add(1)
+			return nil, nil
+		},
+	}
+}
+
+const shadowSystemConfig = `
+controllers:
+  - name: real
+    type: controller
+    operations:
+      enable:
+  - name: shadow
+    type: controller
+    shadow: true
+    operations:
+      enable:
+devices:
+  - name: real-device
+    type: device
+    controller: real
+    operations:
+      on: [default]
+  - name: shadow-device
+    type: device
+    controller: shadow
+    operations:
+      on: [default]
+    conditions:
+      weather:
+`
+
+func TestShadowController(t *testing.T) {
+	ctx := context.Background()
+	sys, err := devices.ParseSystemConfig(ctx, []byte(shadowSystemConfig),
+		devices.WithControllers(devices.SupportedControllers{
+			"controller": func(string, devices.Options) (devices.Controller, error) {
+				return &dialingController{}, nil
+			},
+		}),
+		devices.WithDevices(devices.SupportedDevices{
+			"device": func(string, devices.Options) (devices.Device, error) {
+				md := testutil.NewMockDevice()
+				md.AddCondition("weather", false)
+				return &dialingDevice{MockDevice: md}, nil
+			},
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	run := func(op devices.Operation, args []string, ok bool) {
+		t.Helper()
+		if !ok {
+			t.Fatal("missing operation")
+		}
+		if _, err := op(ctx, devices.OperationArgs{Writer: &out, Args: args}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dials := func(name string) int64 {
+		return sys.Controllers[name].Implementation().(*dialingController).dials.Load()
+	}
+
+	op, args, ok := sys.ControllerOp("shadow", "enable")
+	run(op, args, ok)
+	op, args, ok = sys.DeviceOp("shadow-device", "on")
+	run(op, args, ok)
+	cond, _, ok := sys.DeviceCondition("shadow-device", "weather")
+	if !ok {
+		t.Fatal("missing condition")
+	}
+	if _, met, err := cond(ctx, devices.OperationArgs{Writer: &out}); err != nil || !met {
+		t.Errorf("unexpected condition result: %v %v", met, err)
+	}
+	if got, want := out.String(), "shadow: shadow.enable()\nshadow: shadow-device.on(default)\nshadow: shadow-device.weather()\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := dials("shadow"), int64(0); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Non-shadowed controllers and devices are unaffected.
+	out.Reset()
+	op, args, ok = sys.ControllerOp("real", "enable")
+	run(op, args, ok)
+	op, args, ok = sys.DeviceOp("real-device", "on")
+	run(op, args, ok)
+	if got, want := out.String(), ""; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := dials("real"), int64(2); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
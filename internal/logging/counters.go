@@ -0,0 +1,141 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Counter records the number of successful, failed and aborted (ie.
+// precondition returned false) invocations of a scheduled operation.
+type Counter struct {
+	Schedule    string    `json:"schedule"`
+	Device      string    `json:"device"`
+	Op          string    `json:"op"`
+	Success     int64     `json:"success"`
+	Failure     int64     `json:"failure"`
+	Aborted     int64     `json:"aborted"`
+	LastUpdated time.Time `json:"last_updated"`
+}
+
+func (c Counter) Name() string {
+	return fmt.Sprintf("%v:%v.%v", c.Schedule, c.Device, c.Op)
+}
+
+// CounterStore maintains per-action success/failure counters that are
+// persisted to a file so that they survive restarts. The file is
+// rewritten atomically (via a temporary file and rename) on every update.
+type CounterStore struct {
+	mu       sync.Mutex
+	filename string
+	counters map[string]*Counter
+}
+
+// NewCounterStore creates a new CounterStore backed by the specified file,
+// loading any existing counters from it. A non-existent file is treated
+// as an empty store.
+func NewCounterStore(filename string) (*CounterStore, error) {
+	cs := &CounterStore{
+		filename: filename,
+		counters: map[string]*Counter{},
+	}
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cs, nil
+		}
+		return nil, err
+	}
+	var counters []Counter
+	if err := json.Unmarshal(buf, &counters); err != nil {
+		return nil, fmt.Errorf("failed to parse counters file: %v: %w", filename, err)
+	}
+	for _, c := range counters {
+		cs.counters[c.Name()] = &c
+	}
+	return cs, nil
+}
+
+// Record updates the counters for the specified operation and persists
+// the result.
+func (cs *CounterStore) Record(schedule, device, op string, aborted bool, err error) error {
+	if cs == nil {
+		return nil
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	key := Counter{Schedule: schedule, Device: device, Op: op}.Name()
+	c, ok := cs.counters[key]
+	if !ok {
+		c = &Counter{Schedule: schedule, Device: device, Op: op}
+		cs.counters[key] = c
+	}
+	switch {
+	case err != nil:
+		c.Failure++
+	case aborted:
+		c.Aborted++
+	default:
+		c.Success++
+	}
+	c.LastUpdated = time.Now()
+	return cs.save()
+}
+
+// Counters returns a copy of all counters sorted by name.
+func (cs *CounterStore) Counters() []Counter {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.sorted()
+}
+
+func (cs *CounterStore) sorted() []Counter {
+	counters := make([]Counter, 0, len(cs.counters))
+	for _, c := range cs.counters {
+		counters = append(counters, *c)
+	}
+	slices.SortFunc(counters, func(a, b Counter) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return counters
+}
+
+func (cs *CounterStore) save() error {
+	buf, err := json.MarshalIndent(cs.sorted(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(cs.filename, buf)
+}
+
+func writeFileAtomically(filename string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
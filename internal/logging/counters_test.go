@@ -0,0 +1,81 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package logging_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cosnicolaou/automation/internal/logging"
+)
+
+func TestCounterStore(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "counters.json")
+	cs, err := logging.NewCounterStore(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(cs.Counters()), 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	record := func(cs *logging.CounterStore, device, op string, aborted bool, err error) {
+		t.Helper()
+		if err := cs.Record("sched", device, op, aborted, err); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record(cs, "dev", "on", false, nil)
+	record(cs, "dev", "on", false, nil)
+	record(cs, "dev", "on", false, errors.New("oops"))
+	record(cs, "dev", "off", true, nil)
+
+	cs, err = logging.NewCounterStore(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record(cs, "dev", "off", false, nil)
+
+	cs, err = logging.NewCounterStore(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counters := cs.Counters()
+	if got, want := len(counters), 2; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i, tc := range []struct {
+		name                      string
+		success, failure, aborted int64
+	}{
+		{"sched:dev.off", 1, 0, 1},
+		{"sched:dev.on", 2, 1, 0},
+	} {
+		c := counters[i]
+		if got, want := c.Name(), tc.name; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got, want := c.Success, tc.success; got != want {
+			t.Errorf("%v: success: got %v, want %v", tc.name, got, want)
+		}
+		if got, want := c.Failure, tc.failure; got != want {
+			t.Errorf("%v: failure: got %v, want %v", tc.name, got, want)
+		}
+		if got, want := c.Aborted, tc.aborted; got != want {
+			t.Errorf("%v: aborted: got %v, want %v", tc.name, got, want)
+		}
+	}
+
+	// Make sure no temporary files are left behind.
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(entries), 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -28,12 +28,12 @@ func TestLogs(t *testing.T) {
 		now, now.Add(time.Minute*13), time.Minute)
 	logging.WriteCompletion(logger, id, nil, true,
 		"device", "on",
-		"pre-test", true,
+		"pre-test", true, 1,
 		now, now.Add(time.Minute*13), now.Add(time.Minute*14), time.Minute)
 	logging.WriteYearEnd(logger, 2024, time.Hour)
 	logging.WriteCompletion(logger, id, io.EOF, true,
 		"device", "on",
-		"pre-test", true,
+		"pre-test", true, 3,
 		now, now.Add(time.Minute*13), now.Add(time.Minute*14), time.Minute)
 
 	var logs []logging.Entry
@@ -59,6 +59,12 @@ func TestLogs(t *testing.T) {
 	if got, want := logs[4].Msg, logging.LogFailed; got != want {
 		t.Errorf("got %v, want %v", got, want)
 	}
+	if got, want := logs[2].Attempts, 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := logs[4].StatusRecord().Attempts, 3; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
 
 }
 
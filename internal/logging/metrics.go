@@ -0,0 +1,145 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Latency records summary statistics for the time taken by an operation.
+type Latency struct {
+	Min   time.Duration `json:"min"`
+	Max   time.Duration `json:"max"`
+	Mean  time.Duration `json:"mean"`
+	Total time.Duration `json:"total"`
+}
+
+// OperationMetrics records the number of successful, failed and aborted
+// invocations of a scheduled operation and the time taken by them.
+type OperationMetrics struct {
+	Schedule string  `json:"schedule"`
+	Device   string  `json:"device"`
+	Op       string  `json:"op"`
+	Success  int64   `json:"success"`
+	Failure  int64   `json:"failure"`
+	Aborted  int64   `json:"aborted"`
+	Latency  Latency `json:"latency"`
+}
+
+func (om OperationMetrics) Name() string {
+	return Counter{Schedule: om.Schedule, Device: om.Device, Op: om.Op}.Name()
+}
+
+// MetricsSnapshot is a point in time copy of all of the metrics
+// maintained by Metrics.
+type MetricsSnapshot struct {
+	Time       time.Time          `json:"time"`
+	Operations []OperationMetrics `json:"operations"`
+}
+
+// Metrics is an in-memory registry of per-operation metrics. Unlike
+// CounterStore it is not persisted on every update, rather snapshots
+// may be written periodically using StartExport.
+type Metrics struct {
+	mu  sync.Mutex
+	ops map[string]*OperationMetrics
+}
+
+// NewMetrics creates a new, empty, Metrics registry.
+func NewMetrics() *Metrics {
+	return &Metrics{ops: map[string]*OperationMetrics{}}
+}
+
+// Record updates the metrics for the specified operation, latency is
+// the time taken by the operation, including any retries.
+func (m *Metrics) Record(schedule, device, op string, aborted bool, err error, latency time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	om := OperationMetrics{Schedule: schedule, Device: device, Op: op}
+	key := om.Name()
+	o, ok := m.ops[key]
+	if !ok {
+		o = &om
+		m.ops[key] = o
+	}
+	switch {
+	case err != nil:
+		o.Failure++
+	case aborted:
+		o.Aborted++
+	default:
+		o.Success++
+	}
+	if n := o.Success + o.Failure + o.Aborted; n == 1 || latency < o.Latency.Min {
+		o.Latency.Min = latency
+	}
+	o.Latency.Max = max(o.Latency.Max, latency)
+	o.Latency.Total += latency
+	o.Latency.Mean = o.Latency.Total / time.Duration(o.Success+o.Failure+o.Aborted)
+}
+
+// Snapshot returns a copy of the current metrics sorted by name.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ops := make([]OperationMetrics, 0, len(m.ops))
+	for _, o := range m.ops {
+		ops = append(ops, *o)
+	}
+	slices.SortFunc(ops, func(a, b OperationMetrics) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return MetricsSnapshot{Time: time.Now(), Operations: ops}
+}
+
+// WriteFile writes a JSON encoded snapshot of the current metrics to
+// the specified file, atomically.
+func (m *Metrics) WriteFile(filename string) error {
+	buf, err := json.MarshalIndent(m.Snapshot(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(filename, buf)
+}
+
+// StartExport starts writing a snapshot of the metrics to the specified
+// file at the specified interval until the context is canceled or the
+// returned function is called. The returned function writes a final
+// snapshot and returns the first error encountered writing any snapshot.
+func (m *Metrics) StartExport(ctx context.Context, filename string, interval time.Duration) func() error {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() {
+		var first error
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				if err := m.WriteFile(filename); first == nil {
+					first = err
+				}
+				done <- first
+				return
+			case <-ticker.C:
+				if err := m.WriteFile(filename); first == nil {
+					first = err
+				}
+			}
+		}
+	}()
+	return func() error {
+		cancel()
+		return <-done
+	}
+}
@@ -31,6 +31,8 @@ type logEntry struct {
 	NumActions    int       `json:"#actions"`
 	YearEndDelay  int       `json:"year-end-delay"`
 	Err           string    `json:"err"`
+	Attempts      int       `json:"attempts"`
+	Output        string    `json:"output"`
 	Date          Date      `json:"date"`
 	Now           time.Time `json:"now"`
 	Due           time.Time `json:"due"`
@@ -95,6 +97,7 @@ func (le Entry) StatusRecord() *StatusRecord {
 		PreConditionResult: le.PreCondResult,
 		Due:                le.Due,
 		Delay:              le.Delay,
+		Attempts:           le.Attempts,
 	}
 	return sr
 }
@@ -43,6 +43,7 @@ type StatusRecord struct {
 	Completed          time.Time // Time the operation was completed set by Finalize
 	PreConditionResult bool      // Set using the argument to Finalize
 	Error              error     // Set using the argument to Finalize
+	Attempts           int       // Number of attempts made to run the operation, set by PendingDoneWithAttempts
 
 	listID list.DoubleID[*StatusRecord]
 }
@@ -61,6 +62,15 @@ func (sr *StatusRecord) Status() string {
 	return "completed"
 }
 
+// StatusWithAttempts returns the status with the number of attempts
+// appended if the operation was retried.
+func (sr *StatusRecord) StatusWithAttempts() string {
+	if sr.Attempts > 1 {
+		return fmt.Sprintf("%v (%v attempts)", sr.Status(), sr.Attempts)
+	}
+	return sr.Status()
+}
+
 func (sr *StatusRecord) Name() string {
 	return fmt.Sprintf("%v:%v.%v", sr.Schedule, sr.Device, sr.Op)
 }
@@ -86,6 +96,22 @@ func (s *StatusRecorder) PendingDone(sr *StatusRecord, precondition bool, err er
 	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.pendingDoneLocked(sr, precondition, err)
+}
+
+// PendingDoneWithAttempts is like PendingDone but also records the number
+// of attempts made to run the operation.
+func (s *StatusRecorder) PendingDoneWithAttempts(sr *StatusRecord, attempts int, precondition bool, err error) {
+	if sr == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sr.Attempts = attempts
+	s.pendingDoneLocked(sr, precondition, err)
+}
+
+func (s *StatusRecorder) pendingDoneLocked(sr *StatusRecord, precondition bool, err error) {
 	sr.Completed = time.Now().In(sr.Due.Location())
 	sr.PreConditionResult = precondition
 	sr.Error = err
@@ -93,6 +119,17 @@ func (s *StatusRecorder) PendingDone(sr *StatusRecord, precondition bool, err er
 	s.waiting.RemoveItem(sr.listID)
 }
 
+// PendingCanceled removes a pending record for an action that will
+// not be executed, eg. because its scheduler was stopped.
+func (s *StatusRecorder) PendingCanceled(sr *StatusRecord) {
+	if sr == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.waiting.RemoveItem(sr.listID)
+}
+
 func (s *StatusRecorder) NewPending(sr *StatusRecord) *StatusRecord {
 	if sr == nil {
 		return sr
@@ -45,7 +45,7 @@ func WritePending(l *slog.Logger, overdue, dryRun bool, device, op string, args
 // every operation non-overdue that was logged as pending. The id must be the value
 // returned by LogPending.
 func WriteCompletion(l *slog.Logger, id int64, err error,
-	dryRun bool, device, op, precondition string, preconditionResult bool, started, now, dueAt time.Time, delay time.Duration) {
+	dryRun bool, device, op, precondition string, preconditionResult bool, attempts int, started, now, dueAt time.Time, delay time.Duration) {
 	msg := LogCompleted
 	if err != nil {
 		msg = LogFailed
@@ -57,6 +57,7 @@ func WriteCompletion(l *slog.Logger, id int64, err error,
 		"op", op,
 		"pre", precondition,
 		"pre-result", preconditionResult,
+		"attempts", attempts,
 		"started", started,
 		"loc", dueAt.Location().String(),
 		"now", now,
@@ -67,13 +68,94 @@ func WriteCompletion(l *slog.Logger, id int64, err error,
 	)
 }
 
+// WriteCoalesced logs an overdue operation that is skipped because a
+// more recent instance of the same operation is also due. Coalesced
+// operations are not logged as being completed.
+func WriteCoalesced(l *slog.Logger, dryRun bool, device, op string, args []string, now, dueAt, supersededBy time.Time, delay time.Duration) int64 {
+	id := atomic.AddInt64(&invocationID, 1)
+	l.Info(LogCoalesced,
+		"dry-run", dryRun,
+		"id", id,
+		"device", device,
+		"op", op,
+		"args", args,
+		"loc", dueAt.Location().String(),
+		"now", now,
+		"due", dueAt,
+		"superseded-by", supersededBy,
+		"delay", delay,
+		"delay-str", delay.String(),
+	)
+	return id
+}
+
+// WriteSkipped logs an action that was held whilst scheduling was paused
+// and that was then skipped because, on resumption, it was overdue by more
+// than the allowed grace period. The id must be the value returned by
+// WritePending.
+func WriteSkipped(l *slog.Logger, id int64, dryRun bool, device, op string, now, dueAt time.Time, delay time.Duration) {
+	l.Info(LogSkipped,
+		"dry-run", dryRun,
+		"id", id,
+		"device", device,
+		"op", op,
+		"loc", dueAt.Location().String(),
+		"now", now,
+		"due", dueAt,
+		"delay", delay,
+		"delay-str", delay.String(),
+	)
+}
+
+// WriteNoChange logs an action that was not run because the same operation,
+// with the same arguments, was the last one commanded on the device that
+// day. The id must be the value returned by WritePending.
+func WriteNoChange(l *slog.Logger, id int64, dryRun bool, device, op string, args []string, now, dueAt time.Time) {
+	l.Info(LogNoChange,
+		"dry-run", dryRun,
+		"id", id,
+		"device", device,
+		"op", op,
+		"args", args,
+		"loc", dueAt.Location().String(),
+		"now", now,
+		"due", dueAt,
+	)
+}
+
+// WriteDayAborted logs the abandonment of the remaining actions for the
+// day following the failure of a critical action. The id must be the
+// value returned by WritePending for the failed action.
+func WriteDayAborted(l *slog.Logger, id int64, dryRun bool, device, op string, dueAt time.Time, remaining int, err error) {
+	l.Info(LogDayAborted,
+		"dry-run", dryRun,
+		"id", id,
+		"device", device,
+		"op", op,
+		"loc", dueAt.Location().String(),
+		"due", dueAt,
+		"remaining", remaining,
+		"err", err,
+	)
+}
+
 const (
-	LogPending   = "pending"
-	LogCompleted = "completed"
-	LogFailed    = "failed"
-	LogTooLate   = "too-late"
-	LogYearEnd   = "year-end"
-	LogNewDay    = "day"
+	LogPending    = "pending"
+	LogCompleted  = "completed"
+	LogFailed     = "failed"
+	LogTooLate    = "too-late"
+	LogCoalesced  = "coalesced"
+	LogSkipped    = "skipped"
+	LogNoChange   = "no-change"
+	LogPaused     = "paused"
+	LogDayCond    = "day-condition"
+	LogYearEnd    = "year-end"
+	LogNewDay     = "day"
+	LogDayAborted = "day-aborted"
+
+	LogDraining     = "draining"
+	LogDrained      = "drained"
+	LogDrainTimeout = "drain-timeout"
 )
 
 // WriteYearEndLog logs the completion of the year-end processing, that is,
@@ -83,6 +165,19 @@ func WriteYearEnd(l *slog.Logger, year int, delay time.Duration) {
 	l.Info(LogYearEnd, "year", year, "year-end-delay", delay)
 }
 
+// WriteDayCondition logs the result of evaluating a schedule's day
+// condition for the specified date.
+func WriteDayCondition(l *slog.Logger, date datetime.CalendarDate, device, condition string, args []string, result bool, err error) {
+	l.Info(LogDayCond,
+		"date", date.String(),
+		"device", device,
+		"pre", condition,
+		"pre-args", args,
+		"pre-result", result,
+		"err", err,
+	)
+}
+
 func WriteNewDay(l *slog.Logger, date datetime.CalendarDate, nActions int) {
 	l.Info(LogNewDay, "date", date.String(), "#actions", nActions)
 }
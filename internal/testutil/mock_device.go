@@ -26,6 +26,7 @@ type MockDevice struct {
 	operationsHelp map[string]string
 	conditions     map[string]devices.Condition
 	conditionsHelp map[string]string
+	operationDocs  map[string]devices.Doc
 	useWriter      bool
 }
 
@@ -57,6 +58,23 @@ func (d *MockDevice) AddCondition(name string, outcome bool) {
 	d.conditionsHelp[name] = fmt.Sprintf("%s condition: outcome %v", name, outcome)
 }
 
+// SetOperationDoc sets the structured documentation returned by
+// OperationDoc for the specified operation.
+func (d *MockDevice) SetOperationDoc(op string, doc devices.Doc) {
+	if d.operationDocs == nil {
+		d.operationDocs = map[string]devices.Doc{}
+	}
+	d.operationDocs[op] = doc
+}
+
+func (d *MockDevice) OperationDoc(op string) (devices.Doc, bool) {
+	if doc, ok := d.operationDocs[op]; ok {
+		return doc, true
+	}
+	help, ok := d.operationsHelp[op]
+	return devices.Doc{Description: help}, ok
+}
+
 func (d *MockDevice) Implementation() any {
 	return d
 }
@@ -0,0 +1,147 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package streamconn
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"time"
+)
+
+// ReadOptions controls how a ConnReader reads from its connection.
+type ReadOptions struct {
+	// Timeout is the fixed deadline used for each call to ReadUntil
+	// unless InactivityTimeout is set.
+	Timeout time.Duration
+	// MaxReadBytes is the maximum number of bytes that ReadUntil will
+	// read whilst looking for one of its expected strings before
+	// returning ErrReadLimitExceeded. Zero implies no limit.
+	MaxReadBytes int
+	// InactivityTimeout, if set, replaces Timeout with a deadline that
+	// is reset every time data is received.
+	InactivityTimeout time.Duration
+	// MaxReadTime is the maximum time that ReadUntil may take when
+	// InactivityTimeout is set. Zero implies no limit.
+	MaxReadTime time.Duration
+}
+
+// ConnReader implements ReadUntil for transports that are layered
+// on a net.Conn, such as TCP and TLS.
+type ConnReader struct {
+	conn net.Conn
+	rd   *bufio.Reader
+	opts ReadOptions
+}
+
+// NewConnReader returns a ConnReader for conn that uses a read buffer
+// of the specified size, or that used by bufio.NewReader if size is zero.
+func NewConnReader(conn net.Conn, size int, opts ReadOptions) *ConnReader {
+	var rd *bufio.Reader
+	if size > 0 {
+		rd = bufio.NewReaderSize(conn, size)
+	} else {
+		rd = bufio.NewReader(conn)
+	}
+	return &ConnReader{conn: conn, rd: rd, opts: opts}
+}
+
+// BufferSize returns the size of the read buffer.
+func (cr *ConnReader) BufferSize() int {
+	return cr.rd.Size()
+}
+
+// extendReadDeadline is used when an inactivity timeout is in effect to
+// extend the read deadline prior to reading more data from the connection.
+func (cr *ConnReader) extendReadDeadline(start time.Time) error {
+	if cr.opts.InactivityTimeout == 0 || cr.rd.Buffered() > 0 {
+		return nil
+	}
+	deadline := time.Now().Add(cr.opts.InactivityTimeout)
+	if cr.opts.MaxReadTime > 0 {
+		if limit := start.Add(cr.opts.MaxReadTime); limit.Before(deadline) {
+			deadline = limit
+		}
+	}
+	return cr.conn.SetReadDeadline(deadline)
+}
+
+// readTimeoutError distinguishes between no data being received at
+// all, data ceasing to arrive and the overall time limit being exceeded
+// when an inactivity timeout is in effect.
+func (cr *ConnReader) readTimeoutError(start time.Time, n int, err error) error {
+	if cr.opts.InactivityTimeout == 0 || !errors.Is(err, os.ErrDeadlineExceeded) {
+		return err
+	}
+	if cr.opts.MaxReadTime > 0 && time.Since(start) >= cr.opts.MaxReadTime {
+		return fmt.Errorf("no complete response within %v, %v bytes received: %w", cr.opts.MaxReadTime, n, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("no data received within %v: %w", cr.opts.InactivityTimeout, err)
+	}
+	return fmt.Errorf("no further data received within %v, %v bytes received: %w", cr.opts.InactivityTimeout, n, err)
+}
+
+func (cr *ConnReader) readUntil(ctx context.Context, expected []string) ([]byte, int, error) {
+	for i, e := range expected {
+		if len(e) == 0 {
+			return nil, i, nil
+		}
+	}
+	exp := slices.Clone(expected)
+	buf := make([]byte, 0, 1024)
+	start := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return buf, -1, ctx.Err()
+		default:
+		}
+		if err := cr.extendReadDeadline(start); err != nil {
+			return buf, -1, err
+		}
+		nb, err := cr.rd.ReadByte()
+		if err != nil {
+			return buf, -1, cr.readTimeoutError(start, len(buf), err)
+		}
+		buf = append(buf, nb)
+		if cr.opts.MaxReadBytes > 0 && len(buf) > cr.opts.MaxReadBytes {
+			return buf, -1, ErrReadLimitExceeded
+		}
+		for i, e := range exp {
+			if e[0] == nb {
+				if len(e) == 1 {
+					return buf, i, nil
+				}
+				exp[i] = e[1:]
+				continue
+			}
+			exp[i] = expected[i]
+		}
+	}
+}
+
+// ReadUntil reads from the connection until one of the expected strings
+// is found and returns all of the data read, including the expected
+// string. An empty expected string results in an immediate return.
+func (cr *ConnReader) ReadUntil(ctx context.Context, expected []string) ([]byte, error) {
+	buf, _, err := cr.ReadUntilMatch(ctx, expected)
+	return buf, err
+}
+
+// ReadUntilMatch is like ReadUntil but also returns the index, in
+// expected, of the string that was found. The index is -1 on error.
+func (cr *ConnReader) ReadUntilMatch(ctx context.Context, expected []string) ([]byte, int, error) {
+	if cr.opts.InactivityTimeout == 0 {
+		if err := cr.conn.SetReadDeadline(time.Now().Add(cr.opts.Timeout)); err != nil {
+			return nil, -1, err
+		}
+	}
+	return cr.readUntil(ctx, expected)
+}
@@ -0,0 +1,57 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package streamconn_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/net/streamconn"
+)
+
+func TestConnReader(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		input    string
+		expected []string
+		output   string
+		match    int
+		err      error
+	}{
+		{"hello\nOK> more", []string{"OK> "}, "hello\nOK> ", 0, nil},
+		{"hello\nERROR> ", []string{"OK> ", "ERROR> "}, "hello\nERROR> ", 1, nil},
+		{"anything", []string{"OK> ", ""}, "", 1, nil},
+		{strings.Repeat("x", 64), []string{"OK> "}, "", -1, streamconn.ErrReadLimitExceeded},
+		{"no prompt", []string{"OK> "}, "", -1, os.ErrDeadlineExceeded},
+	} {
+		client, server := net.Pipe()
+		go func() {
+			server.Write([]byte(tc.input))
+		}()
+		cr := streamconn.NewConnReader(client, 0, streamconn.ReadOptions{
+			Timeout:      100 * time.Millisecond,
+			MaxReadBytes: 32,
+		})
+		buf, match, err := cr.ReadUntilMatch(ctx, tc.expected)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%q: missing or unexpected error: %v", tc.input, err)
+		}
+		if got, want := match, tc.match; got != want {
+			t.Errorf("%q: got %v, want %v", tc.input, got, want)
+		}
+		if err == nil {
+			if got, want := string(buf), tc.output; got != want {
+				t.Errorf("%q: got %q, want %q", tc.input, got, want)
+			}
+		}
+		client.Close()
+		server.Close()
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package streamconn
+
+import (
+	"context"
+	"strings"
+)
+
+// LineTerminator is the terminator used for commands and responses
+// by LineSession.
+const LineTerminator = "\r\n"
+
+// LineSession wraps a Session for use with line oriented protocols, that
+// is, those where a command is sent as a single line and the response is
+// read as a single line, both terminated by LineTerminator.
+type LineSession struct {
+	*Session
+}
+
+// NewLineSession returns a LineSession that uses the supplied session.
+func NewLineSession(s *Session) *LineSession {
+	return &LineSession{Session: s}
+}
+
+// Command sends cmd, appending LineTerminator if not already present, and
+// reads the response up to and including the next LineTerminator. The
+// response is returned with the terminator removed. Any error encountered
+// by the session, including by prior calls to Send or SendSensitive, is
+// returned.
+func (ls *LineSession) Command(ctx context.Context, cmd string) (string, error) {
+	if !strings.HasSuffix(cmd, LineTerminator) {
+		cmd += LineTerminator
+	}
+	ls.Send(ctx, []byte(cmd))
+	out, err := ls.ReadUntil(ctx, LineTerminator)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), LineTerminator), nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package streamconn_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/net/netutil"
+	"github.com/cosnicolaou/automation/net/streamconn"
+)
+
+// mockTransport records everything sent to it and returns the contents
+// of responses, one call to ReadUntil at a time.
+type mockTransport struct {
+	sent      bytes.Buffer
+	responses []string
+	sendErr   error
+}
+
+func (m *mockTransport) Send(_ context.Context, buf []byte) (int, error) {
+	if m.sendErr != nil {
+		return 0, m.sendErr
+	}
+	return m.sent.Write(buf)
+}
+
+func (m *mockTransport) SendSensitive(ctx context.Context, buf []byte) (int, error) {
+	return m.Send(ctx, buf)
+}
+
+func (m *mockTransport) ReadUntil(_ context.Context, expected []string) ([]byte, error) {
+	if len(m.responses) == 0 {
+		return nil, errors.New("no more responses")
+	}
+	r := m.responses[0]
+	m.responses = m.responses[1:]
+	for _, e := range expected {
+		if strings.HasSuffix(r, e) {
+			return []byte(r), nil
+		}
+	}
+	return nil, errors.New("unexpected response")
+}
+
+func (m *mockTransport) Close(context.Context) error {
+	return nil
+}
+
+func TestLineSession(t *testing.T) {
+	ctx := context.Background()
+	var mgr streamconn.SessionManager
+	idle := netutil.NewIdleTimer(time.Minute)
+
+	mt := &mockTransport{responses: []string{"OK 1\r\n", "OK 2\r\n"}}
+	sess := mgr.New(mt, idle)
+	ls := streamconn.NewLineSession(sess)
+	for i, cmd := range []string{"first", "second\r\n"} {
+		resp, err := ls.Command(ctx, cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := resp, []string{"OK 1", "OK 2"}[i]; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+	if got, want := mt.sent.String(), "first\r\nsecond\r\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	sess.Release()
+
+	// Errors from Send are returned by Command and persist.
+	mt = &mockTransport{responses: []string{"OK\r\n"}, sendErr: errors.New("send failed")}
+	sess = mgr.New(mt, idle)
+	ls = streamconn.NewLineSession(sess)
+	for range 2 {
+		if _, err := ls.Command(ctx, "cmd"); err == nil || err.Error() != "send failed" {
+			t.Errorf("missing or unexpected error: %v", err)
+		}
+	}
+	if err := sess.Err(); err == nil || err.Error() != "send failed" {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+	sess.Release()
+
+	// Errors from ReadUntil are returned by Command.
+	mt = &mockTransport{}
+	sess = mgr.New(mt, idle)
+	ls = streamconn.NewLineSession(sess)
+	if _, err := ls.Command(ctx, "cmd"); err == nil || err.Error() != "no more responses" {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+	sess.Release()
+}
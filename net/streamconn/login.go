@@ -0,0 +1,89 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package streamconn
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloudeng.io/cmdutil/keystore"
+)
+
+// Placeholders that may appear in the Send field of a LoginStep and that
+// are replaced by the user and token of the LoginSequence's key.
+const (
+	LoginUser  = "{user}"
+	LoginToken = "{token}"
+)
+
+// LoginStep represents a single step in a login sequence: Send, if not
+// empty, is sent and then, if Expect is not empty, the response is read
+// until one of the expected strings is found. Sensitive steps are sent
+// using SendSensitive so that their contents are never logged, any step
+// whose Send contains LoginToken is always treated as sensitive.
+type LoginStep struct {
+	Send      string   `yaml:"send"`
+	Expect    []string `yaml:"expect"`
+	Sensitive bool     `yaml:"sensitive"`
+}
+
+// LoginSequence represents a declarative, multi-step, login handshake.
+// The user and token used to replace the LoginUser and LoginToken
+// placeholders are obtained from the keystore, stored in the context,
+// for KeyID.
+type LoginSequence struct {
+	KeyID string      `yaml:"key_id"`
+	Steps []LoginStep `yaml:"steps"`
+}
+
+func (ls LoginSequence) expand(ctx context.Context, step LoginStep) (string, bool, error) {
+	send, sensitive := step.Send, step.Sensitive
+	if !strings.Contains(send, LoginUser) && !strings.Contains(send, LoginToken) {
+		return send, sensitive, nil
+	}
+	key := keystore.AuthFromContextForID(ctx, ls.KeyID)
+	if strings.Contains(send, LoginToken) {
+		if len(key.Token) == 0 {
+			return "", false, fmt.Errorf("no token found for key %q", ls.KeyID)
+		}
+		send = strings.ReplaceAll(send, LoginToken, key.Token)
+		sensitive = true
+	}
+	if strings.Contains(send, LoginUser) {
+		if len(key.User) == 0 {
+			return "", false, fmt.Errorf("no user found for key %q", ls.KeyID)
+		}
+		send = strings.ReplaceAll(send, LoginUser, key.User)
+	}
+	return send, sensitive, nil
+}
+
+// Run executes the login sequence against the supplied session, returning
+// the response to the final step that expected one.
+func (ls LoginSequence) Run(ctx context.Context, s *Session) ([]byte, error) {
+	var out []byte
+	for i, step := range ls.Steps {
+		send, sensitive, err := ls.expand(ctx, step)
+		if err != nil {
+			return nil, fmt.Errorf("login step %v: %w", i, err)
+		}
+		if len(send) > 0 {
+			if sensitive {
+				s.SendSensitive(ctx, []byte(send))
+			} else {
+				s.Send(ctx, []byte(send))
+			}
+		}
+		if len(step.Expect) == 0 {
+			continue
+		}
+		out, err = s.ReadUntil(ctx, step.Expect...)
+		if err != nil {
+			return nil, fmt.Errorf("login step %v: %w", i, err)
+		}
+	}
+	return out, s.Err()
+}
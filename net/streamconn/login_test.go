@@ -0,0 +1,96 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package streamconn_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudeng.io/cmdutil/keystore"
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/net/netutil"
+	"github.com/cosnicolaou/automation/net/streamconn"
+	"gopkg.in/yaml.v3"
+)
+
+const loginConfig = `
+name: c
+type: controller
+login:
+  key_id: ctrl
+  steps:
+    - expect: ["login: "]
+    - send: "{user}\r\n"
+      expect: ["Password: "]
+    - send: "{token}\r\n"
+      expect: ["> "]
+    - send: "pin-1234\r\n"
+      sensitive: true
+      expect: ["ready> "]
+`
+
+func TestLoginSequence(t *testing.T) {
+	var cfg devices.ControllerConfig
+	if err := yaml.Unmarshal([]byte(loginConfig), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	login := cfg.Login
+	if got, want := len(login.Steps), 4; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	ctx := keystore.ContextWithAuth(context.Background(), keystore.Keys{
+		"ctrl": {ID: "ctrl", User: "admin", Token: "s3cret"},
+	})
+	var recording bytes.Buffer
+	mt := &mockTransport{responses: []string{"login: ", "Password: ", "> ", "ready> "}}
+	var mgr streamconn.SessionManager
+	sess := mgr.New(streamconn.NewRecorder(mt, &recording), netutil.NewIdleTimer(time.Minute))
+	out, err := login.Run(ctx, sess)
+	sess.Release()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(out), "ready> "; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := mt.sent.String(), "admin\r\ns3cret\r\npin-1234\r\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// The token and the explicitly sensitive step are not recorded.
+	log := recording.String()
+	if !strings.Contains(log, "admin") {
+		t.Errorf("user was not recorded: %v", log)
+	}
+	for _, secret := range []string{"s3cret", "pin-1234"} {
+		if strings.Contains(log, secret) {
+			t.Errorf("sensitive data %q was recorded: %v", secret, log)
+		}
+	}
+	if got, want := strings.Count(log, streamconn.RecordSendSensitive), 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// A missing key is an error.
+	mt = &mockTransport{responses: []string{"login: "}}
+	sess = mgr.New(mt, netutil.NewIdleTimer(time.Minute))
+	_, err = login.Run(context.Background(), sess)
+	sess.Release()
+	if err == nil || !strings.Contains(err.Error(), `login step 1: no user found for key "ctrl"`) {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+
+	// Unexpected responses are reported.
+	mt = &mockTransport{responses: []string{"login: ", "denied\r\n"}}
+	sess = mgr.New(mt, netutil.NewIdleTimer(time.Minute))
+	_, err = login.Run(ctx, sess)
+	sess.Release()
+	if err == nil || err.Error() != "login step 1: unexpected response" {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package streamconn
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+)
+
+// Record types used in a recorded session.
+const (
+	RecordSend          = "send"
+	RecordSendSensitive = "send-sensitive"
+	RecordReadUntil     = "read-until"
+	RecordClose         = "close"
+)
+
+// Record represents a single interaction with a Transport as written by
+// the Transport returned by NewRecorder, one JSON encoded record per line.
+// The data for SendSensitive is never recorded.
+type Record struct {
+	Op       string   `json:"op"`
+	Data     string   `json:"data,omitempty"`
+	Expected []string `json:"expected,omitempty"`
+	Err      string   `json:"err,omitempty"`
+}
+
+type recorder struct {
+	mu  sync.Mutex
+	t   Transport
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder returns a Transport that records all interactions with
+// the supplied Transport to w. Any error encountered writing to w is
+// returned by Close.
+func NewRecorder(t Transport, w io.Writer) Transport {
+	return &recorder{t: t, enc: json.NewEncoder(w)}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func (r *recorder) record(rec Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(rec); err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *recorder) Send(ctx context.Context, buf []byte) (int, error) {
+	n, err := r.t.Send(ctx, buf)
+	r.record(Record{Op: RecordSend, Data: string(buf), Err: errString(err)})
+	return n, err
+}
+
+func (r *recorder) SendSensitive(ctx context.Context, buf []byte) (int, error) {
+	n, err := r.t.SendSensitive(ctx, buf)
+	r.record(Record{Op: RecordSendSensitive, Err: errString(err)})
+	return n, err
+}
+
+func (r *recorder) ReadUntil(ctx context.Context, expected []string) ([]byte, error) {
+	buf, err := r.t.ReadUntil(ctx, expected)
+	r.record(Record{Op: RecordReadUntil, Data: string(buf), Expected: expected, Err: errString(err)})
+	return buf, err
+}
+
+func (r *recorder) Close(ctx context.Context) error {
+	err := r.t.Close(ctx)
+	r.record(Record{Op: RecordClose, Err: errString(err)})
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+type replay struct {
+	mu      sync.Mutex
+	records []Record
+	err     error
+}
+
+// NewReplay returns a Transport that replays a session recorded by the
+// Transport returned by NewRecorder. Each call must match the next
+// recorded interaction: Send must be called with the recorded data,
+// ReadUntil with the recorded expected strings and it returns the
+// recorded response and error. A mismatch, or running out of records,
+// results in an error that is also returned by all subsequent calls.
+func NewReplay(rd io.Reader) Transport {
+	rp := &replay{}
+	sc := bufio.NewScanner(rd)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var rec Record
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			rp.err = fmt.Errorf("replay: invalid record: %q: %w", sc.Text(), err)
+			return rp
+		}
+		rp.records = append(rp.records, rec)
+	}
+	rp.err = sc.Err()
+	return rp
+}
+
+func (rp *replay) next(op string) (Record, error) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if rp.err != nil {
+		return Record{}, rp.err
+	}
+	if len(rp.records) == 0 {
+		rp.err = fmt.Errorf("replay: no more records, expected %q", op)
+		return Record{}, rp.err
+	}
+	rec := rp.records[0]
+	rp.records = rp.records[1:]
+	if rec.Op != op {
+		rp.err = fmt.Errorf("replay: got %q, want %q", op, rec.Op)
+		return Record{}, rp.err
+	}
+	return rec, nil
+}
+
+func (rp *replay) diverged(format string, args ...any) error {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.err = fmt.Errorf(format, args...)
+	return rp.err
+}
+
+func recordedError(rec Record) error {
+	if len(rec.Err) == 0 {
+		return nil
+	}
+	return errors.New(rec.Err)
+}
+
+func (rp *replay) Send(_ context.Context, buf []byte) (int, error) {
+	rec, err := rp.next(RecordSend)
+	if err != nil {
+		return 0, err
+	}
+	if rec.Data != string(buf) {
+		return 0, rp.diverged("replay: sent %q, recorded %q", buf, rec.Data)
+	}
+	return len(buf), recordedError(rec)
+}
+
+func (rp *replay) SendSensitive(_ context.Context, buf []byte) (int, error) {
+	rec, err := rp.next(RecordSendSensitive)
+	if err != nil {
+		return 0, err
+	}
+	return len(buf), recordedError(rec)
+}
+
+func (rp *replay) ReadUntil(_ context.Context, expected []string) ([]byte, error) {
+	rec, err := rp.next(RecordReadUntil)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Equal(rec.Expected, expected) {
+		return nil, rp.diverged("replay: read until %q, recorded %q", expected, rec.Expected)
+	}
+	return []byte(rec.Data), recordedError(rec)
+}
+
+func (rp *replay) Close(_ context.Context) error {
+	rec, err := rp.next(RecordClose)
+	if err != nil {
+		return err
+	}
+	return recordedError(rec)
+}
@@ -0,0 +1,71 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package streamconn_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/net/netutil"
+	"github.com/cosnicolaou/automation/net/streamconn"
+)
+
+func runCommands(ctx context.Context, t *testing.T, tr streamconn.Transport) []string {
+	var mgr streamconn.SessionManager
+	sess := mgr.New(tr, netutil.NewIdleTimer(time.Minute))
+	defer sess.Release()
+	sess.SendSensitive(ctx, []byte("password\r\n"))
+	ls := streamconn.NewLineSession(sess)
+	var responses []string
+	for _, cmd := range []string{"status", "on"} {
+		resp, err := ls.Command(ctx, cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		responses = append(responses, resp)
+	}
+	if err := tr.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return responses
+}
+
+func TestRecordReplay(t *testing.T) {
+	ctx := context.Background()
+	var recording bytes.Buffer
+	mt := &mockTransport{responses: []string{"idle\r\n", "OK\r\n"}}
+	recorded := runCommands(ctx, t, streamconn.NewRecorder(mt, &recording))
+
+	if strings.Contains(recording.String(), "password") {
+		t.Errorf("sensitive data was recorded: %v", recording.String())
+	}
+	if got, want := strings.Count(recording.String(), "\n"), 6; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	replayed := runCommands(ctx, t, streamconn.NewReplay(bytes.NewReader(recording.Bytes())))
+	if got, want := strings.Join(replayed, ","), strings.Join(recorded, ","); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := strings.Join(replayed, ","), "idle,OK"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// A different command sequence fails to replay.
+	rp := streamconn.NewReplay(bytes.NewReader(recording.Bytes()))
+	if _, err := rp.SendSensitive(ctx, []byte("other")); err != nil {
+		t.Fatal(err)
+	}
+	_, err := rp.Send(ctx, []byte("off\r\n"))
+	if err == nil || !strings.Contains(err.Error(), `recorded "status\r\n"`) {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+	if _, err2 := rp.ReadUntil(ctx, []string{"\r\n"}); err2 != err {
+		t.Errorf("got %v, want %v", err2, err)
+	}
+}
@@ -5,7 +5,9 @@
 package streamconn
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"sync"
 	"sync/atomic"
 
@@ -13,6 +15,11 @@ import (
 	"github.com/cosnicolaou/automation/net/netutil"
 )
 
+// ErrReadLimitExceeded is returned by Transport.ReadUntil when more than the
+// configured maximum number of bytes are read without finding any of the
+// expected strings.
+var ErrReadLimitExceeded = errors.New("read limit exceeded")
+
 // Transport is the interface for a transport layer connection.
 type Transport interface {
 	Send(ctx context.Context, buf []byte) (int, error)
@@ -23,6 +30,27 @@ type Transport interface {
 	Close(ctx context.Context) error
 }
 
+// MatchTransport is implemented by transports that can report which of
+// the expected strings was found by ReadUntil. Session.ReadUntilMatch
+// uses it when available.
+type MatchTransport interface {
+	// ReadUntilMatch is like ReadUntil but also returns the index, in
+	// expected, of the string that was found.
+	ReadUntilMatch(ctx context.Context, expected []string) ([]byte, int, error)
+}
+
+// MatchedSuffix returns the index of the first of the expected strings
+// that buf ends with, or -1 if there is none. It can be used to determine
+// which of the expected strings was found by a Transport's ReadUntil.
+func MatchedSuffix(buf []byte, expected []string) int {
+	for i, e := range expected {
+		if bytes.HasSuffix(buf, []byte(e)) {
+			return i
+		}
+	}
+	return -1
+}
+
 // SessionManager is a manager for creating and releasing sessions
 // and ensures that only one session is active at a time.
 // Session.Release() must be called to release a session
@@ -113,16 +141,32 @@ func (s *Session) SendSensitive(ctx context.Context, buf []byte) {
 // expected strings is found. It returns the data read and an error if
 // any. On error it returns an empty byte slice (not nil) and the error.
 func (s *Session) ReadUntil(ctx context.Context, expected ...string) ([]byte, error) {
+	out, _, err := s.ReadUntilMatch(ctx, expected...)
+	return out, err
+}
+
+// ReadUntilMatch is like ReadUntil but also returns the index, in expected,
+// of the string that was found, eg. to distinguish between an OK and an
+// error prompt. The index is -1 on error.
+func (s *Session) ReadUntilMatch(ctx context.Context, expected ...string) ([]byte, int, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if s.err != nil {
-		return []byte{}, s.err
+		return []byte{}, -1, s.err
 	}
 	s.idle.Reset(ctx)
-	out, err := s.conn.ReadUntil(ctx, expected)
+	var out []byte
+	var match int
+	var err error
+	if mt, ok := s.conn.(MatchTransport); ok {
+		out, match, err = mt.ReadUntilMatch(ctx, expected)
+	} else {
+		out, err = s.conn.ReadUntil(ctx, expected)
+		match = MatchedSuffix(out, expected)
+	}
 	if err != nil {
 		s.err = err
-		return []byte{}, err
+		return []byte{}, -1, err
 	}
-	return out, nil
+	return out, match, nil
 }
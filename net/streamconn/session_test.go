@@ -0,0 +1,70 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package streamconn_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/net/netutil"
+	"github.com/cosnicolaou/automation/net/streamconn"
+)
+
+// matchTransport implements streamconn.MatchTransport and always
+// reports the last of the expected strings as being matched.
+type matchTransport struct {
+	mockTransport
+}
+
+func (m *matchTransport) ReadUntilMatch(ctx context.Context, expected []string) ([]byte, int, error) {
+	buf, err := m.ReadUntil(ctx, expected)
+	return buf, len(expected) - 1, err
+}
+
+func TestSessionReadUntilMatch(t *testing.T) {
+	ctx := context.Background()
+	var mgr streamconn.SessionManager
+	idle := netutil.NewIdleTimer(time.Minute)
+
+	// The match is determined from the data read for transports that
+	// do not implement MatchTransport.
+	mt := &mockTransport{responses: []string{"done\nOK> ", "failed\nERROR> "}}
+	sess := mgr.New(mt, idle)
+	for _, want := range []int{0, 1} {
+		_, match, err := sess.ReadUntilMatch(ctx, "OK> ", "ERROR> ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := match; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+	if _, match, err := sess.ReadUntilMatch(ctx, "OK> "); err == nil || match != -1 {
+		t.Errorf("missing error or unexpected match: %v, %v", err, match)
+	}
+	sess.Release()
+
+	// And by the transport for those that do.
+	sess = mgr.New(&matchTransport{mockTransport{responses: []string{"done\nOK> "}}}, idle)
+	buf, match, err := sess.ReadUntilMatch(ctx, "OK> ", "> ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := match, 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := string(buf), "done\nOK> "; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	sess.Release()
+
+	if got, want := streamconn.MatchedSuffix([]byte("abc"), []string{"x", "bc", "c"}), 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := streamconn.MatchedSuffix([]byte("abc"), []string{"x"}), -1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"cloudeng.io/logging/ctxlog"
+	"github.com/cosnicolaou/automation/net/streamconn"
+)
+
+type tcpConn struct {
+	conn    net.Conn
+	rd      *streamconn.ConnReader
+	addr    string
+	timeout time.Duration
+}
+
+// Option represents an option to Dial.
+type Option func(*options)
+
+type options struct {
+	readBufferSize    int
+	maxReadBytes      int
+	inactivityTimeout time.Duration
+	maxReadTime       time.Duration
+}
+
+// WithReadBufferSize sets the size of the buffer used for reading from
+// the connection, the default is that used by bufio.NewReader.
+func WithReadBufferSize(size int) Option {
+	return func(o *options) {
+		o.readBufferSize = size
+	}
+}
+
+// WithMaxReadBytes sets the maximum number of bytes that ReadUntil will
+// read whilst looking for one of its expected strings before returning
+// streamconn.ErrReadLimitExceeded. The default of zero implies no limit.
+func WithMaxReadBytes(n int) Option {
+	return func(o *options) {
+		o.maxReadBytes = n
+	}
+}
+
+// WithInactivityTimeout replaces the fixed deadline, of the timeout supplied
+// to Dial, used by ReadUntil with one that is reset every time data is
+// received. WithMaxReadTime can be used to place an overall limit on the
+// time taken by ReadUntil in this case.
+func WithInactivityTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.inactivityTimeout = d
+	}
+}
+
+// WithMaxReadTime sets the maximum time that ReadUntil may take when
+// WithInactivityTimeout is in effect. The default of zero implies no limit.
+func WithMaxReadTime(d time.Duration) Option {
+	return func(o *options) {
+		o.maxReadTime = d
+	}
+}
+
+// Dial connects to the specified address, using timeout for the connection
+// itself as well as for each subsequent Send and ReadUntil.
+func Dial(ctx context.Context, addr string, timeout time.Duration, opts ...Option) (streamconn.Transport, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	ctxlog.Info(ctx, "tcp: dialing", "addr", addr)
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		ctxlog.Error(ctx, "tcp: dial failed", "addr", addr, "err", err)
+		return nil, err
+	}
+	return &tcpConn{
+		conn: conn,
+		rd: streamconn.NewConnReader(conn, o.readBufferSize, streamconn.ReadOptions{
+			Timeout:           timeout,
+			MaxReadBytes:      o.maxReadBytes,
+			InactivityTimeout: o.inactivityTimeout,
+			MaxReadTime:       o.maxReadTime,
+		}),
+		addr:    addr,
+		timeout: timeout,
+	}, nil
+}
+
+func (tc *tcpConn) send(ctx context.Context, buf []byte, sensitive bool) (int, error) {
+	if err := tc.conn.SetWriteDeadline(time.Now().Add(tc.timeout)); err != nil {
+		ctxlog.Error(ctx, "tcp: send failed to set write deadline", "addr", tc.addr, "err", err)
+		return -1, err
+	}
+	n, err := tc.conn.Write(buf)
+	if sensitive {
+		ctxlog.Info(ctx, "tcp: sent", "addr", tc.addr, "text", "***", "err", err)
+	} else {
+		ctxlog.Info(ctx, "tcp: sent", "addr", tc.addr, "text", string(buf), "err", err)
+	}
+	return n, err
+}
+
+func (tc *tcpConn) Send(ctx context.Context, buf []byte) (int, error) {
+	return tc.send(ctx, buf, false)
+}
+
+func (tc *tcpConn) SendSensitive(ctx context.Context, buf []byte) (int, error) {
+	return tc.send(ctx, buf, true)
+}
+
+func (tc *tcpConn) ReadUntil(ctx context.Context, expected []string) ([]byte, error) {
+	buf, _, err := tc.ReadUntilMatch(ctx, expected)
+	return buf, err
+}
+
+func (tc *tcpConn) ReadUntilMatch(ctx context.Context, expected []string) ([]byte, int, error) {
+	buf, match, err := tc.rd.ReadUntilMatch(ctx, expected)
+	if err != nil {
+		ctxlog.Error(ctx, "tcp: readUntil failed", "addr", tc.addr, "text", expected, "err", err)
+		return nil, -1, err
+	}
+	ctxlog.Info(ctx, "tcp: readUntil", "addr", tc.addr, "text", expected, "match", match)
+	return buf, match, err
+}
+
+func (tc *tcpConn) Close(ctx context.Context) error {
+	if err := tc.conn.Close(); err != nil {
+		ctxlog.Error(ctx, "tcp: close failed", "addr", tc.addr, "err", err)
+		return err
+	}
+	ctxlog.Info(ctx, "tcp: close", "addr", tc.addr)
+	return nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/net/streamconn"
+	"github.com/cosnicolaou/automation/net/streamconn/tcp"
+)
+
+// newServer starts a server that writes a login prompt and then
+// responds to each line it receives with the line and an OK prompt, or
+// with an ERROR prompt for the line "fail". A line of "silent" is not
+// responded to.
+func newServer(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.Write([]byte("login: "))
+				sc := bufio.NewScanner(conn)
+				for sc.Scan() {
+					switch line := sc.Text(); line {
+					case "fail":
+						conn.Write([]byte("ERROR> "))
+					case "silent":
+					default:
+						conn.Write([]byte(line + "\nOK> "))
+					}
+				}
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestTCP(t *testing.T) {
+	ctx := context.Background()
+	addr := newServer(t)
+
+	conn, err := tcp.Dial(ctx, addr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close(ctx)
+
+	buf, err := conn.ReadUntil(ctx, []string{"login: "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(buf), "login: "; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if _, err := conn.SendSensitive(ctx, []byte("secret\n")); err != nil {
+		t.Fatal(err)
+	}
+	buf, err = conn.ReadUntil(ctx, []string{"OK> ", "ERROR> "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(buf), "secret\nOK> "; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if _, err := conn.Send(ctx, []byte("fail\n")); err != nil {
+		t.Fatal(err)
+	}
+	buf, match, err := conn.(streamconn.MatchTransport).ReadUntilMatch(ctx, []string{"OK> ", "ERROR> "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(buf), "ERROR> "; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := match, 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if _, err := conn.Send(ctx, []byte("silent\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ReadUntil(ctx, []string{"OK> "}); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+}
+
+func TestTCPReadLimits(t *testing.T) {
+	ctx := context.Background()
+	addr := newServer(t)
+
+	conn, err := tcp.Dial(ctx, addr, time.Second, tcp.WithMaxReadBytes(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close(ctx)
+	if _, err := conn.ReadUntil(ctx, []string{"login: "}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Send(ctx, []byte(strings.Repeat("x", 32)+"\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ReadUntil(ctx, []string{"OK> "}); !errors.Is(err, streamconn.ErrReadLimitExceeded) {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+
+	if _, err := tcp.Dial(ctx, "127.0.0.1:1", time.Second); err == nil {
+		t.Errorf("expected an error dialing a closed port")
+	}
+}
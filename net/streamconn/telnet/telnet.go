@@ -5,7 +5,10 @@
 package telnet
 
 import (
+	"bufio"
 	"context"
+	"net"
+	"slices"
 	"time"
 
 	"cloudeng.io/logging/ctxlog"
@@ -14,19 +17,71 @@ import (
 )
 
 type telnetConn struct {
-	conn    *telnet.Conn
-	addr    string
-	timeout time.Duration
+	conn         *telnet.Conn
+	addr         string
+	timeout      time.Duration
+	maxReadBytes int
 }
 
-func Dial(ctx context.Context, addr string, timeout time.Duration) (streamconn.Transport, error) {
-	conn, err := telnet.Dial("tcp", addr)
+// Option represents an option to Dial.
+type Option func(*options)
+
+type options struct {
+	readBufferSize int
+	maxReadBytes   int
+}
+
+// WithReadBufferSize sets the size of the buffer used for reading from
+// the underlying network connection.
+func WithReadBufferSize(size int) Option {
+	return func(o *options) {
+		o.readBufferSize = size
+	}
+}
+
+// WithMaxReadBytes sets the maximum number of bytes that ReadUntil will
+// read whilst looking for one of its expected strings before returning
+// streamconn.ErrReadLimitExceeded. The default of zero implies no limit.
+func WithMaxReadBytes(n int) Option {
+	return func(o *options) {
+		o.maxReadBytes = n
+	}
+}
+
+// bufferedConn is a net.Conn whose reads are buffered using a
+// buffer of a specified size.
+type bufferedConn struct {
+	net.Conn
+	rd *bufio.Reader
+}
+
+func (bc *bufferedConn) Read(buf []byte) (int, error) {
+	return bc.rd.Read(buf)
+}
+
+func dial(addr string, o options) (*telnet.Conn, error) {
+	if o.readBufferSize <= 0 {
+		return telnet.Dial("tcp", addr)
+	}
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return telnet.NewConn(&bufferedConn{Conn: conn, rd: bufio.NewReaderSize(conn, o.readBufferSize)})
+}
+
+func Dial(ctx context.Context, addr string, timeout time.Duration, opts ...Option) (streamconn.Transport, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	conn, err := dial(addr, o)
 	if err != nil {
 		ctxlog.Error(ctx, "telnet: dial failed", "addr", addr, "err", err)
 		return nil, err
 	}
 	ctxlog.Info(ctx, "telnet: dialed", "addr", addr)
-	return &telnetConn{conn: conn, addr: addr, timeout: timeout}, nil
+	return &telnetConn{conn: conn, addr: addr, timeout: timeout, maxReadBytes: o.maxReadBytes}, nil
 }
 
 func (tc *telnetConn) send(ctx context.Context, buf []byte, sensitive bool) (int, error) {
@@ -51,12 +106,51 @@ func (tc *telnetConn) SendSensitive(ctx context.Context, buf []byte) (int, error
 	return tc.send(ctx, buf, true)
 }
 
+// readUntilLimited is like telnet.Conn.ReadUntil but returns
+// streamconn.ErrReadLimitExceeded if more than maxReadBytes are read
+// without finding any of the expected strings.
+func (tc *telnetConn) readUntilLimited(expected []string) ([]byte, error) {
+	for _, e := range expected {
+		if len(e) == 0 {
+			return nil, nil
+		}
+	}
+	exp := slices.Clone(expected)
+	buf := make([]byte, 0, min(tc.maxReadBytes, 1024))
+	for {
+		nb, err := tc.conn.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, nb)
+		if len(buf) > tc.maxReadBytes {
+			return nil, streamconn.ErrReadLimitExceeded
+		}
+		for i, e := range exp {
+			if e[0] == nb {
+				if len(e) == 1 {
+					return buf, nil
+				}
+				exp[i] = e[1:]
+				continue
+			}
+			exp[i] = expected[i]
+		}
+	}
+}
+
 func (tc *telnetConn) ReadUntil(ctx context.Context, expected []string) ([]byte, error) {
 	if err := tc.conn.SetReadDeadline(time.Now().Add(tc.timeout)); err != nil {
 		ctxlog.Error(ctx, "telnet: readUntil failed to set read deadline", "addr", tc.addr, "err", err)
 		return nil, err
 	}
-	buf, err := tc.conn.ReadUntil(expected...)
+	var buf []byte
+	var err error
+	if tc.maxReadBytes > 0 {
+		buf, err = tc.readUntilLimited(expected)
+	} else {
+		buf, err = tc.conn.ReadUntil(expected...)
+	}
 	if err != nil {
 		ctxlog.Error(ctx, "telnet: readUntil failed", "addr", tc.addr, "text", expected, "err", err)
 		return nil, err
@@ -0,0 +1,11 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package tls
+
+import "github.com/cosnicolaou/automation/net/streamconn"
+
+func ReadBufferSize(t streamconn.Transport) int {
+	return t.(*tlsConn).rd.BufferSize()
+}
@@ -5,11 +5,11 @@
 package tls
 
 import (
-	"bufio"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
-	"slices"
+	"os"
 	"time"
 
 	"cloudeng.io/logging/ctxlog"
@@ -18,12 +18,115 @@ import (
 
 type tlsConn struct {
 	conn    *tls.Conn
-	rd      *bufio.Reader
+	rd      *streamconn.ConnReader
 	addr    string
 	timeout time.Duration
 }
 
-func Dial(ctx context.Context, addr string, version string, timeout time.Duration) (streamconn.Transport, error) {
+// Option represents an option to Dial.
+type Option func(*options)
+
+type options struct {
+	certFile, keyFile string
+	caFile            string
+	verify            bool
+	readBufferSize    int
+	maxReadBytes      int
+	inactivityTimeout time.Duration
+	maxReadTime       time.Duration
+}
+
+// WithClientCertificate configures Dial to present the certificate and
+// key in the specified PEM files to the server, ie. for mutual TLS.
+func WithClientCertificate(certFile, keyFile string) Option {
+	return func(o *options) {
+		o.certFile, o.keyFile = certFile, keyFile
+	}
+}
+
+// WithCAFile configures Dial to use the certificate authorities in the
+// specified PEM file, rather than the system's, to verify the server's
+// certificate. It has no effect unless WithVerify(true) is also supplied.
+func WithCAFile(caFile string) Option {
+	return func(o *options) {
+		o.caFile = caFile
+	}
+}
+
+// WithVerify controls whether the server's certificate is verified. The
+// default is to not verify it (ie. InsecureSkipVerify is set) since many
+// controllers use self-signed certificates.
+func WithVerify(verify bool) Option {
+	return func(o *options) {
+		o.verify = verify
+	}
+}
+
+// WithReadBufferSize sets the size of the buffer used for reading from
+// the connection, the default is that used by bufio.NewReader.
+func WithReadBufferSize(size int) Option {
+	return func(o *options) {
+		o.readBufferSize = size
+	}
+}
+
+// WithMaxReadBytes sets the maximum number of bytes that ReadUntil will
+// read whilst looking for one of its expected strings before returning
+// streamconn.ErrReadLimitExceeded. The default of zero implies no limit.
+func WithMaxReadBytes(n int) Option {
+	return func(o *options) {
+		o.maxReadBytes = n
+	}
+}
+
+// WithInactivityTimeout replaces the fixed deadline, of the timeout supplied
+// to Dial, used by ReadUntil with one that is reset every time data is
+// received. This allows for devices that respond slowly but steadily, ie.
+// ReadUntil will only time out if no data at all is received for the
+// specified duration. WithMaxReadTime can be used to place an overall
+// limit on the time taken by ReadUntil in this case.
+func WithInactivityTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.inactivityTimeout = d
+	}
+}
+
+// WithMaxReadTime sets the maximum time that ReadUntil may take when
+// WithInactivityTimeout is in effect. The default of zero implies no limit.
+func WithMaxReadTime(d time.Duration) Option {
+	return func(o *options) {
+		o.maxReadTime = d
+	}
+}
+
+func (o options) configure(cfg *tls.Config) error {
+	cfg.InsecureSkipVerify = !o.verify //nolint:gosec
+	if len(o.certFile) > 0 || len(o.keyFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(o.certFile, o.keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	if len(o.caFile) > 0 {
+		pem, err := os.ReadFile(o.caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in CA file: %v", o.caFile)
+		}
+		cfg.RootCAs = pool
+	}
+	return nil
+}
+
+func Dial(ctx context.Context, addr string, version string, timeout time.Duration, opts ...Option) (streamconn.Transport, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
 	ids := []uint16{}
 	for _, cs := range tls.CipherSuites() {
 		ids = append(ids, cs.ID)
@@ -32,8 +135,10 @@ func Dial(ctx context.Context, addr string, version string, timeout time.Duratio
 		ids = append(ids, cs.ID)
 	}
 	cfg := tls.Config{
-		InsecureSkipVerify: true, //nolint:gosec
-		CipherSuites:       ids,
+		CipherSuites: ids,
+	}
+	if err := o.configure(&cfg); err != nil {
+		return nil, err
 	}
 	switch version {
 	case "1.0":
@@ -45,14 +150,23 @@ func Dial(ctx context.Context, addr string, version string, timeout time.Duratio
 	default:
 		return nil, fmt.Errorf("unsupported tls version: %v", version)
 	}
-	ctxlog.Info(ctx, "tls: dialing", "addr", addr, "version", version)
+	ctxlog.Info(ctx, "tls: dialing", "addr", addr, "version", version, "verify", o.verify, "client-cert", len(cfg.Certificates) > 0)
 	conn, err := tls.Dial("tcp", addr, &cfg)
 	if err != nil {
 		ctxlog.Error(ctx, "tls: dial failed", "addr", addr, "err", err)
 		return nil, err
 	}
-	rd := bufio.NewReader(conn)
-	return &tlsConn{conn: conn, rd: rd, addr: addr, timeout: timeout}, nil
+	return &tlsConn{
+		conn: conn,
+		rd: streamconn.NewConnReader(conn, o.readBufferSize, streamconn.ReadOptions{
+			Timeout:           timeout,
+			MaxReadBytes:      o.maxReadBytes,
+			InactivityTimeout: o.inactivityTimeout,
+			MaxReadTime:       o.maxReadTime,
+		}),
+		addr:    addr,
+		timeout: timeout,
+	}, nil
 }
 
 func (tc *tlsConn) send(ctx context.Context, buf []byte, sensitive bool) (int, error) {
@@ -77,51 +191,19 @@ func (tc *tlsConn) SendSensitive(ctx context.Context, buf []byte) (int, error) {
 	return tc.send(ctx, buf, true)
 }
 
-func (tc *tlsConn) readUntil(ctx context.Context, expected []string) ([]byte, error) {
-	for _, e := range expected {
-		if len(e) == 0 {
-			return nil, nil
-		}
-	}
-	exp := slices.Clone(expected)
-	buf := make([]byte, 0, 1024)
-	for {
-		select {
-		case <-ctx.Done():
-			return buf, ctx.Err()
-		default:
-		}
-		nb, err := tc.rd.ReadByte()
-		if err != nil {
-			return buf, err
-		}
-		buf = append(buf, nb)
-		for i, e := range exp {
-			if e[0] == nb {
-				if len(e) == 1 {
-					return buf, nil
-				}
-				exp[i] = e[1:]
-				continue
-			}
-			exp[i] = expected[i]
-		}
-
-	}
+func (tc *tlsConn) ReadUntil(ctx context.Context, expected []string) ([]byte, error) {
+	buf, _, err := tc.ReadUntilMatch(ctx, expected)
+	return buf, err
 }
 
-func (tc *tlsConn) ReadUntil(ctx context.Context, expected []string) ([]byte, error) {
-	if err := tc.conn.SetReadDeadline(time.Now().Add(tc.timeout)); err != nil {
-		ctxlog.Error(ctx, "tls: readUntil failed to set read deadline", "addr", tc.addr, "err", err)
-		return nil, err
-	}
-	buf, err := tc.readUntil(ctx, expected)
+func (tc *tlsConn) ReadUntilMatch(ctx context.Context, expected []string) ([]byte, int, error) {
+	buf, match, err := tc.rd.ReadUntilMatch(ctx, expected)
 	if err != nil {
 		ctxlog.Error(ctx, "tls: readUntil failed", "addr", tc.addr, "text", expected, "err", err)
-		return nil, err
+		return nil, -1, err
 	}
-	ctxlog.Info(ctx, "tls: readUntil", "addr", tc.addr, "text", expected)
-	return buf, err
+	ctxlog.Info(ctx, "tls: readUntil", "addr", tc.addr, "text", expected, "match", match)
+	return buf, match, err
 }
 
 func (tc *tlsConn) Close(ctx context.Context) error {
@@ -0,0 +1,259 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package tls_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/net/streamconn"
+	streamtls "github.com/cosnicolaou/automation/net/streamconn/tls"
+)
+
+func writePEM(t *testing.T, filename, blockType string, der []byte) string {
+	t.Helper()
+	if err := os.WriteFile(filename, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+// newClientCert creates a self-signed CA and a client certificate signed
+// by it, returning the CA and the names of the certificate and key files.
+func newClientCert(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "test-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca,
+		writePEM(t, filepath.Join(dir, "client.crt"), "CERTIFICATE", der),
+		writePEM(t, filepath.Join(dir, "client.key"), "EC PRIVATE KEY", keyDER)
+}
+
+func TestMutualTLS(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	ca, certFile, keyFile := newClientCert(t, dir)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "hello %v\n", r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca)
+	srv.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+		MinVersion: tls.VersionTLS12,
+	}
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+	addr := srv.Listener.Addr().String()
+	serverCA := writePEM(t, filepath.Join(dir, "server-ca.crt"), "CERTIFICATE", srv.Certificate().Raw)
+
+	get := func(opts ...streamtls.Option) (string, error) {
+		conn, err := streamtls.Dial(ctx, addr, "1.2", 5*time.Second, opts...)
+		if err != nil {
+			return "", err
+		}
+		defer conn.Close(ctx)
+		if _, err := conn.Send(ctx, []byte("GET / HTTP/1.0\r\n\r\n")); err != nil {
+			return "", err
+		}
+		buf, err := conn.ReadUntil(ctx, []string{"test-client\n"})
+		return string(buf), err
+	}
+
+	// No client certificate.
+	if _, err := get(); err == nil {
+		t.Errorf("expected an error without a client certificate")
+	}
+
+	// Client certificate, server certificate not verified.
+	out, err := get(streamtls.WithClientCertificate(certFile, keyFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(out, "hello test-client\n") {
+		t.Errorf("unexpected response: %q", out)
+	}
+
+	// Client certificate, server certificate verified.
+	out, err = get(streamtls.WithClientCertificate(certFile, keyFile),
+		streamtls.WithVerify(true), streamtls.WithCAFile(serverCA))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(out, "hello test-client\n") {
+		t.Errorf("unexpected response: %q", out)
+	}
+
+	// Server verification fails without the server's CA.
+	_, err = get(streamtls.WithClientCertificate(certFile, keyFile),
+		streamtls.WithVerify(true))
+	if err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+
+	// Invalid certificate files.
+	if _, err := get(streamtls.WithClientCertificate(keyFile, certFile)); err == nil {
+		t.Errorf("expected an error for invalid certificate files")
+	}
+}
+
+func TestReadLimits(t *testing.T) {
+	ctx := context.Background()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintf(w, "%s\nend\n", strings.Repeat("x", 4096))
+	}))
+	defer srv.Close()
+	addr := srv.Listener.Addr().String()
+
+	get := func(opts ...streamtls.Option) (streamconn.Transport, []byte, error) {
+		conn, err := streamtls.Dial(ctx, addr, "1.2", 5*time.Second, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close(ctx)
+		if _, err := conn.Send(ctx, []byte("GET / HTTP/1.0\r\n\r\n")); err != nil {
+			t.Fatal(err)
+		}
+		buf, err := conn.ReadUntil(ctx, []string{"end\n"})
+		return conn, buf, err
+	}
+
+	conn, buf, err := get(streamtls.WithReadBufferSize(64 * 1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := streamtls.ReadBufferSize(conn), 64*1024; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if !strings.HasSuffix(string(buf), "end\n") {
+		t.Errorf("unexpected response: %q", buf)
+	}
+
+	conn, _, err = get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := streamtls.ReadBufferSize(conn), 4096; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	_, _, err = get(streamtls.WithMaxReadBytes(1024))
+	if !errors.Is(err, streamconn.ErrReadLimitExceeded) {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+	if _, _, err = get(streamtls.WithMaxReadBytes(8192)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestInactivityTimeout(t *testing.T) {
+	ctx := context.Background()
+	// The server trickles out its response one byte at a time.
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		for _, b := range []byte("0123456789\n") {
+			if _, err := w.Write([]byte{b}); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			time.Sleep(25 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+	addr := srv.Listener.Addr().String()
+
+	get := func(timeout time.Duration, opts ...streamtls.Option) error {
+		conn, err := streamtls.Dial(ctx, addr, "1.2", timeout, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close(ctx)
+		if _, err := conn.Send(ctx, []byte("GET / HTTP/1.0\r\n\r\n")); err != nil {
+			t.Fatal(err)
+		}
+		_, err = conn.ReadUntil(ctx, []string{"9\n"})
+		return err
+	}
+
+	// The fixed deadline is too short for the entire response.
+	if err := get(100 * time.Millisecond); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+
+	// The response completes since data keeps arriving.
+	if err := get(100*time.Millisecond, streamtls.WithInactivityTimeout(time.Second)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// But fails when an overall cap is imposed.
+	err := get(100*time.Millisecond, streamtls.WithInactivityTimeout(time.Second), streamtls.WithMaxReadTime(100*time.Millisecond))
+	if !errors.Is(err, os.ErrDeadlineExceeded) || !strings.Contains(err.Error(), "no complete response within 100ms") {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+
+	// Or when the gaps between bytes exceed the inactivity timeout.
+	err = get(time.Second, streamtls.WithInactivityTimeout(5*time.Millisecond))
+	if !errors.Is(err, os.ErrDeadlineExceeded) || !strings.Contains(err.Error(), "no further data received within 5ms") {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+}
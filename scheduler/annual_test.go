@@ -28,7 +28,7 @@ func TestParseAnnualDynamic(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	sched := scheds.Lookup("dynamic")
+	sched := lookupSchedule(t, scheds, "dynamic")
 
 	if got, want := len(sched.Dates.Dynamic), 2; got != want {
 		t.Fatalf("got %d, want %d", got, want)
@@ -5,8 +5,12 @@
 package scheduler
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"sort"
+	"sync"
+	"time"
 
 	"cloudeng.io/datetime"
 	"cloudeng.io/datetime/schedule"
@@ -16,6 +20,10 @@ import (
 type Calendar struct {
 	place      datetime.Place
 	schedulers []*Scheduler
+
+	mu                 sync.Mutex
+	conditionalOps     []ConditionalOp // cached by DistinctConditionalOps.
+	conditionalOpsYear int
 }
 
 func NewCalendar(schedules Schedules, system devices.System, opts ...Option) (*Calendar, error) {
@@ -38,11 +46,32 @@ type CalendarEntry struct {
 	schedule.Active[Action]
 }
 
-func (c *Calendar) Scheduled(date datetime.CalendarDate) []CalendarEntry {
-	yp := datetime.YearPlace{
-		Year:  date.Year(),
-		Place: c.place,
+// DefaultMaxCalendarSpan is the default limit, in days, on the size of
+// the date ranges that calendars are expanded for, roughly three years.
+const DefaultMaxCalendarSpan = 3 * 366
+
+// CalendarSpan returns the number of days in the supplied date range.
+func CalendarSpan(dr datetime.CalendarDateRange) int {
+	from := dr.From().Time(datetime.NewTimeOfDay(0, 0, 0), time.UTC)
+	to := dr.To().Time(datetime.NewTimeOfDay(0, 0, 0), time.UTC)
+	return int(to.Sub(from)/(24*time.Hour)) + 1
+}
+
+// CheckCalendarSpan returns an error if the supplied date range spans more
+// than maxDays days. Expanding a calendar for a very large date range, with
+// its dynamic times of day, can take a very long time. A maxDays of zero
+// or less disables the check.
+func CheckCalendarSpan(dr datetime.CalendarDateRange, maxDays int) error {
+	if maxDays <= 0 {
+		return nil
 	}
+	if span := CalendarSpan(dr); span > maxDays {
+		return fmt.Errorf("date range %v spans %v days, which exceeds the maximum of %v days", dr, span, maxDays)
+	}
+	return nil
+}
+
+func (c *Calendar) Scheduled(date datetime.CalendarDate) []CalendarEntry {
 	month, day := date.Month(), date.Day()
 	today := datetime.NewDateRange(
 		datetime.NewDate(month, day),
@@ -50,8 +79,12 @@ func (c *Calendar) Scheduled(date datetime.CalendarDate) []CalendarEntry {
 	)
 	actions := make([]CalendarEntry, 0, 50)
 	for _, schedule := range c.schedulers {
-		for perDay := range schedule.scheduler.Scheduled(yp, schedule.schedule.Dates, today) {
-			for action := range perDay.Active(c.place) {
+		yp := datetime.YearPlace{
+			Year:  date.Year(),
+			Place: schedule.place,
+		}
+		for perDay := range schedule.schedule.scheduled(schedule.scheduler, yp, today) {
+			for action := range perDay.Active(schedule.place) {
 				actions = append(actions, CalendarEntry{
 					Schedule: schedule.schedule.Name,
 					Active:   action,
@@ -64,3 +97,43 @@ func (c *Calendar) Scheduled(date datetime.CalendarDate) []CalendarEntry {
 	})
 	return actions
 }
+
+// Actions returns the actions scheduled by all of the calendar's schedules
+// for each day in the specified range, ordered as per SimulateActions.
+// Unlike SimulateActions nothing is run and hence the results can be
+// computed quickly for long periods. The results are suitable for use
+// with DiffSimulatedActions.
+func (c *Calendar) Actions(dr datetime.CalendarDateRange) []SimulatedAction {
+	var actions []SimulatedAction
+	for day := dr.From(); day <= dr.To(); day = day.Tomorrow() {
+		for _, e := range c.Scheduled(day) {
+			actions = append(actions, SimulatedAction{
+				Schedule: e.Schedule,
+				Device:   e.T.DeviceName,
+				Op:       e.T.Name,
+				Args:     e.T.Args,
+				Due:      e.When,
+			})
+		}
+	}
+	sortSimulatedActions(actions)
+	return actions
+}
+
+// EvaluatePrecondition evaluates the precondition, if any, of the supplied
+// entry as of its due time. It is intended for previewing what will
+// actually run and hence assumes that the condition is free of side
+// effects. Entries without a precondition always pass.
+func (c *Calendar) EvaluatePrecondition(ctx context.Context, entry CalendarEntry) (bool, error) {
+	pre := entry.T.Precondition
+	if pre.Condition == nil {
+		return true, nil
+	}
+	_, ok, err := pre.Condition(ctx, devices.OperationArgs{
+		Due:    entry.When,
+		Place:  c.place,
+		Writer: io.Discard,
+		Args:   pre.Args,
+	})
+	return ok, err
+}
@@ -0,0 +1,52 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"time"
+
+	"cloudeng.io/datetime/schedule"
+)
+
+// CompletionEvent describes the outcome of an action that was run, it
+// mirrors the completion record that is logged for the action.
+type CompletionEvent struct {
+	Schedule  string
+	Device    string
+	Op        string
+	Args      []string
+	Due       time.Time
+	Started   time.Time
+	Completed time.Time
+	Aborted   bool // The action's precondition was not met.
+	Err       error
+}
+
+// WithCompletionCallback sets a function to be called with the outcome of
+// every action run by the scheduler, including dry runs. The function is
+// called synchronously, once the action's completion has been logged, and
+// may be called concurrently if WithConcurrentActions is in effect.
+func WithCompletionCallback(fn func(CompletionEvent)) Option {
+	return func(o *options) {
+		o.onCompletion = fn
+	}
+}
+
+func (s *Scheduler) completionCallback(a schedule.Active[Action], started, completed time.Time, aborted bool, err error) {
+	if s.onCompletion == nil {
+		return
+	}
+	s.onCompletion(CompletionEvent{
+		Schedule:  s.schedule.Name,
+		Device:    a.T.DeviceName,
+		Op:        a.T.Name,
+		Args:      a.T.Args,
+		Due:       a.When,
+		Started:   started,
+		Completed: completed,
+		Aborted:   aborted,
+		Err:       err,
+	})
+}
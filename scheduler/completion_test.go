@@ -0,0 +1,92 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/cosnicolaou/automation/internal/logging"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+const completionSchedule = `
+schedules:
+  - name: completion
+    device: device
+    ranges:
+      - 01/02:01/03
+    actions:
+      on: 12:00
+    actions_detailed:
+      - action: off
+        when: 13:00
+        args: ["now"]
+      - action: another
+        when: 14:00
+        precondition:
+          device: device
+          op: "!weather"
+`
+
+func TestCompletionCallback(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	sched := parseSchedule(t, sys, completionSchedule)
+
+	var mu sync.Mutex
+	var events []scheduler.CompletionEvent
+	_, logRecorder := runScheduleForYear(ctx, t, sys, sched, 2024,
+		scheduler.WithCompletionCallback(func(e scheduler.CompletionEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, e)
+		}))
+
+	var logs []logging.Entry
+	for _, l := range logRecorder.Logs(t) {
+		if l.Msg == logging.LogCompleted || l.Msg == logging.LogFailed {
+			logs = append(logs, l)
+		}
+	}
+	if got, want := len(events), 6; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := len(events), len(logs); got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i, e := range events {
+		l := logs[i]
+		if got, want := e.Schedule, l.Schedule; got != want {
+			t.Errorf("%v: got %v, want %v", i, got, want)
+		}
+		if got, want := e.Device+"."+e.Op, l.Device+"."+l.Op; got != want {
+			t.Errorf("%v: got %v, want %v", i, got, want)
+		}
+		if got, want := e.Due, l.Due; !got.Equal(want) {
+			t.Errorf("%v: got %v, want %v", i, got, want)
+		}
+		if got, want := e.Started, l.Started; !got.Equal(want) {
+			t.Errorf("%v: got %v, want %v", i, got, want)
+		}
+		if got, want := e.Completed, l.Now; !got.Equal(want) {
+			t.Errorf("%v: got %v, want %v", i, got, want)
+		}
+		if got, want := e.Aborted, !l.PreCondResult; got != want {
+			t.Errorf("%v: got %v, want %v", i, got, want)
+		}
+		if e.Err != nil || l.Err != nil {
+			t.Errorf("%v: unexpected errors: %v, %v", i, e.Err, l.Err)
+		}
+	}
+	if got, want := events[1].Args, []string{"now"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if !events[2].Aborted || events[0].Aborted {
+		t.Errorf("unexpected aborted status: %+v", events)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"context"
+	"sync"
+)
+
+// WithConcurrentActions runs the actions for any given day concurrently
+// once they are due, so that a slow operation does not delay unrelated
+// actions that follow it. Actions on the same device are still run in
+// order, one at a time, and ordered (ie. those with a before or after
+// constraint) and critical actions are run only once all preceding
+// actions have completed and before any subsequent ones are started.
+func WithConcurrentActions(v bool) Option {
+	return func(o *options) {
+		o.concurrentActions = v
+	}
+}
+
+// WithMaxConcurrentOps limits the number of operations that may be run
+// at the same time, across all of the schedulers created with the same
+// Option, eg. by a single call to RunSchedulers, to n. Each attempt to
+// run an operation waits until it can be run within this limit. There is
+// no limit if n <= 0.
+func WithMaxConcurrentOps(n int) Option {
+	var sem opSemaphore
+	if n > 0 {
+		sem = make(opSemaphore, n)
+	}
+	return func(o *options) {
+		o.opSemaphore = sem
+	}
+}
+
+// opSemaphore is a counting semaphore, a nil opSemaphore imposes no limit.
+type opSemaphore chan struct{}
+
+// acquire blocks until the semaphore is acquired or the context is
+// canceled.
+func (s opSemaphore) acquire(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s opSemaphore) release() {
+	if s != nil {
+		<-s
+	}
+}
+
+// actionDispatcher runs actions in their own goroutines whilst ensuring
+// that actions on the same device are run in the order in which they
+// are dispatched.
+type actionDispatcher struct {
+	wg      sync.WaitGroup
+	devices map[string]chan struct{} // closed when the last action dispatched for a device completes.
+}
+
+func newActionDispatcher() *actionDispatcher {
+	return &actionDispatcher{devices: map[string]chan struct{}{}}
+}
+
+// dispatch runs fn in a goroutine once the previous action dispatched
+// for the same device has completed.
+func (d *actionDispatcher) dispatch(device string, fn func()) {
+	prev := d.devices[device]
+	done := make(chan struct{})
+	d.devices[device] = done
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		defer close(done)
+		if prev != nil {
+			<-prev
+		}
+		fn()
+	}()
+}
+
+// wait waits for all dispatched actions to complete.
+func (d *actionDispatcher) wait() {
+	d.wg.Wait()
+	clear(d.devices)
+}
@@ -0,0 +1,289 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/internal/testutil"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+type interval struct {
+	start, end time.Time
+}
+
+func (i interval) overlaps(o interval) bool {
+	return i.start.Before(o.end) && o.start.Before(i.end)
+}
+
+// intervals records when each device's operations were running.
+type intervals struct {
+	mu  sync.Mutex
+	ops map[string]interval
+}
+
+func (iv *intervals) get(device string) interval {
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+	return iv.ops[device]
+}
+
+type sleepyDevice struct {
+	testutil.MockDevice
+	intervals *intervals
+}
+
+func (sd *sleepyDevice) Operations() map[string]devices.Operation {
+	op := func(context.Context, devices.OperationArgs) (any, error) {
+		start := time.Now()
+		time.Sleep(100 * time.Millisecond)
+		sd.intervals.mu.Lock()
+		defer sd.intervals.mu.Unlock()
+		sd.intervals.ops[sd.Config().Name] = interval{start: start, end: time.Now()}
+		return nil, nil
+	}
+	return map[string]devices.Operation{"on": op, "off": op}
+}
+
+const concurrentSchedule = `
+schedules:
+  - name: independent
+    device: one
+    ranges:
+      - 01/02:01/02
+    actions_detailed:
+      - action: on
+        when: 12:00
+      - action: off
+        when: 12:00
+`
+
+func TestConcurrentActions(t *testing.T) {
+	ctx := context.Background()
+	iv := &intervals{ops: map[string]interval{}}
+	sys, err := devices.ParseSystemConfig(ctx, []byte(`
+devices:
+  - name: one
+    type: sleepy
+    operations:
+      on:
+      off:
+  - name: two
+    type: sleepy
+    operations:
+      on:
+      off:
+`), devices.WithDevices(devices.SupportedDevices{
+		"sleepy": func(string, devices.Options) (devices.Device, error) {
+			return &sleepyDevice{intervals: iv}, nil
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// run runs the supplied schedule with its off action retargeted to
+	// device two and returns whether the operations on the two devices
+	// overlapped.
+	run := func(cfg string, opts ...scheduler.Option) bool {
+		sched := parseSchedule(t, sys, cfg)
+		sched.DailyActions = slices.Clone(sched.DailyActions)
+		for i, a := range sched.DailyActions {
+			if a.Name == "off" {
+				sched.DailyActions[i].T.DeviceName = "two"
+			}
+		}
+		_, logRecorder := runScheduleForYear(ctx, t, sys, sched, 2024, opts...)
+		if err := containsError(logRecorder.Logs(t)); err != nil {
+			t.Fatal(err)
+		}
+		one, two := iv.get("one"), iv.get("two")
+		if one.start.IsZero() || two.start.IsZero() {
+			t.Fatalf("operations were not run: %v, %v", one, two)
+		}
+		return one.overlaps(two)
+	}
+
+	if run(concurrentSchedule) {
+		t.Errorf("operations overlap without concurrent actions")
+	}
+	if !run(concurrentSchedule, scheduler.WithConcurrentActions(true)) {
+		t.Errorf("operations on independent devices did not overlap")
+	}
+
+	ordered := concurrentSchedule + "        after: on\n"
+	sched := parseSchedule(t, sys, ordered)
+	for _, a := range sched.DailyActions {
+		if !a.T.Ordered {
+			t.Errorf("%v: not marked as ordered", a.Name)
+		}
+	}
+	if run(ordered, scheduler.WithConcurrentActions(true)) {
+		t.Errorf("ordered operations overlap")
+	}
+}
+
+// countingDevice records the maximum number of its operations, across all
+// instances, that are run at the same time.
+type countingDevice struct {
+	testutil.MockDevice
+	counter *opCounter
+}
+
+type opCounter struct {
+	mu           sync.Mutex
+	running, max int
+}
+
+func (oc *opCounter) reset() int {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	m := oc.max
+	oc.running, oc.max = 0, 0
+	return m
+}
+
+func (cd *countingDevice) Operations() map[string]devices.Operation {
+	return map[string]devices.Operation{
+		"on": func(context.Context, devices.OperationArgs) (any, error) {
+			oc := cd.counter
+			oc.mu.Lock()
+			oc.running++
+			oc.max = max(oc.max, oc.running)
+			oc.mu.Unlock()
+			time.Sleep(100 * time.Millisecond)
+			oc.mu.Lock()
+			oc.running--
+			oc.mu.Unlock()
+			return nil, nil
+		},
+	}
+}
+
+func TestMaxConcurrentOps(t *testing.T) {
+	ctx := context.Background()
+	counter := &opCounter{}
+	devs := []string{"one", "two", "three", "four", "five"}
+	sysCfg := "devices:\n"
+	for _, d := range devs {
+		sysCfg += "  - name: " + d + "\n    type: counting\n    operations:\n      on:\n"
+	}
+	sys, err := devices.ParseSystemConfig(ctx, []byte(sysCfg),
+		devices.WithDevices(devices.SupportedDevices{
+			"counting": func(string, devices.Options) (devices.Device, error) {
+				return &countingDevice{counter: counter}, nil
+			},
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// run runs one schedule per device, concurrently, with all of the
+	// operations due at the same time.
+	run := func(opts ...scheduler.Option) int {
+		var wg sync.WaitGroup
+		for _, d := range devs {
+			sched := parseSchedule(t, sys, `
+schedules:
+  - name: `+d+`
+    device: `+d+`
+    ranges:
+      - 01/02:01/02
+    actions:
+      on: 12:00
+`)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				runScheduleForYear(ctx, t, sys, sched, 2024, opts...)
+			}()
+		}
+		wg.Wait()
+		return counter.reset()
+	}
+
+	if got := run(); got <= 2 {
+		t.Errorf("operations did not overlap: %v", got)
+	}
+	if got, want := run(scheduler.WithMaxConcurrentOps(2)), 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := run(scheduler.WithMaxConcurrentOps(0)); got <= 2 {
+		t.Errorf("operations did not overlap: %v", got)
+	}
+}
+
+// stubbornDevice's on operation ignores its context and hence continues
+// to run after it has timed out.
+type stubbornDevice struct {
+	testutil.MockDevice
+	counter *opCounter
+}
+
+func (sd *stubbornDevice) Operations() map[string]devices.Operation {
+	return map[string]devices.Operation{
+		"on": func(context.Context, devices.OperationArgs) (any, error) {
+			oc := sd.counter
+			oc.mu.Lock()
+			oc.running++
+			oc.max = max(oc.max, oc.running)
+			oc.mu.Unlock()
+			time.Sleep(100 * time.Millisecond)
+			oc.mu.Lock()
+			oc.running--
+			oc.mu.Unlock()
+			return nil, nil
+		},
+	}
+}
+
+func TestMaxConcurrentOpsTimeout(t *testing.T) {
+	ctx := context.Background()
+	counter := &opCounter{}
+	sys, err := devices.ParseSystemConfig(ctx, []byte(`
+devices:
+  - name: stubborn
+    type: stubborn
+    timeout: 10ms
+    retries: 2
+    operations:
+      on:
+`), devices.WithDevices(devices.SupportedDevices{
+		"stubborn": func(string, devices.Options) (devices.Device, error) {
+			return &stubbornDevice{counter: counter}, nil
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sched := parseSchedule(t, sys, `
+schedules:
+  - name: stubborn
+    device: stubborn
+    ranges:
+      - 01/02:01/02
+    actions:
+      on: 12:00
+`)
+
+	// Each attempt times out, but the next attempt is not started until
+	// the operation for the previous one has returned.
+	_, logRecorder := runScheduleForYear(ctx, t, sys, sched, 2024,
+		scheduler.WithMaxConcurrentOps(1),
+		scheduler.WithRetrySleep(func(time.Duration) {}))
+	if err := containsError(logRecorder.Logs(t)); err == nil {
+		t.Errorf("expected a timeout error")
+	}
+	time.Sleep(150 * time.Millisecond)
+	if got, want := counter.reset(), 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
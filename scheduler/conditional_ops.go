@@ -0,0 +1,80 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"cloudeng.io/datetime"
+)
+
+// ConditionalOp represents a scheduled operation that is guarded by
+// a precondition.
+type ConditionalOp struct {
+	Device       string
+	Op           string
+	Args         []string
+	Precondition Precondition
+}
+
+func (co ConditionalOp) key() string {
+	return co.Device + "." + co.Op + "(" + strings.Join(co.Args, ",") + ")_" +
+		co.Precondition.Device + "." + co.Precondition.Name + "(" + strings.Join(co.Precondition.Args, ",") + ")"
+}
+
+func compareConditionalOps(a, b ConditionalOp) int {
+	return cmp.Or(
+		cmp.Compare(a.Device, b.Device),
+		cmp.Compare(a.Op, b.Op),
+		slices.Compare(a.Args, b.Args),
+		cmp.Compare(a.Precondition.Device, b.Precondition.Device),
+		cmp.Compare(a.Precondition.Name, b.Precondition.Name),
+		slices.Compare(a.Precondition.Args, b.Precondition.Args),
+	)
+}
+
+// DistinctConditionalOps returns the distinct operations, and their
+// preconditions, that are scheduled at any point during the current
+// year by any of the schedules in the calendar, ordered by device,
+// operation, arguments and then precondition. Operations with combined
+// preconditions are not included. The results are computed
+// once per year for any given calendar and must not be modified.
+func DistinctConditionalOps(cal *Calendar) []ConditionalOp {
+	year := time.Now().In(cal.place.TimeLocation).Year()
+	cal.mu.Lock()
+	defer cal.mu.Unlock()
+	if cal.conditionalOps != nil && cal.conditionalOpsYear == year {
+		return cal.conditionalOps
+	}
+	yp := datetime.YearPlace{Year: year, Place: cal.place}
+	wholeYear := datetime.NewDateRange(datetime.NewDate(1, 1), datetime.NewDate(12, 31))
+	dedup := map[string]bool{}
+	cops := []ConditionalOp{}
+	for _, s := range cal.schedulers {
+		for perDay := range s.schedule.scheduled(s.scheduler, yp, wholeYear) {
+			for _, spec := range perDay.Specs {
+				if pre := spec.T.Precondition; pre.Name == "" || len(pre.Conditions) > 0 {
+					continue
+				}
+				co := ConditionalOp{
+					Device:       spec.T.DeviceName,
+					Op:           spec.T.Name,
+					Args:         spec.T.Args,
+					Precondition: spec.T.Precondition,
+				}
+				if key := co.key(); !dedup[key] {
+					dedup[key] = true
+					cops = append(cops, co)
+				}
+			}
+		}
+	}
+	slices.SortFunc(cops, compareConditionalOps)
+	cal.conditionalOps, cal.conditionalOpsYear = cops, year
+	return cops
+}
@@ -0,0 +1,96 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+const conditionalSchedules = `
+schedules:
+  - name: morning
+    device: device
+    ranges:
+      - 01/02:01/05
+      - 06/01:06/30
+    actions_detailed:
+      - action: on
+        when: 08:00
+        repeat: 1h
+        num_repeats: 3
+        precondition:
+          device: device
+          op: weather
+          args: ["sunny"]
+      - action: off
+        when: 09:00
+        precondition:
+          device: device
+          op: weather
+          args: ["cloudy"]
+      - action: another
+        when: 10:00
+  - name: evening
+    device: device
+    ranges:
+      - 03/01:03/31
+    actions_detailed:
+      - action: on
+        when: 18:00
+        precondition:
+          device: device
+          op: weather
+          args: ["sunny"]
+      - action: on
+        when: 19:00
+        args: ["bright"]
+        precondition:
+          device: device
+          op: weather
+          args: ["sunny"]
+      - action: another
+        when: 20:00
+        precondition:
+          device: device
+          op: weather
+`
+
+func TestDistinctConditionalOps(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	scheds, err := scheduler.ParseConfig(ctx, []byte(conditionalSchedules), sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal, err := scheduler.NewCalendar(scheds, sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cops := scheduler.DistinctConditionalOps(cal)
+	var got []string
+	for _, co := range cops {
+		got = append(got, fmt.Sprintf("%v.%v%v if %v.%v%v", co.Device, co.Op, co.Args,
+			co.Precondition.Device, co.Precondition.Name, co.Precondition.Args))
+	}
+	want := []string{
+		"device.another[] if device.weather[]",
+		"device.off[] if device.weather[cloudy]",
+		"device.on[] if device.weather[sunny]",
+		"device.on[bright] if device.weather[sunny]",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// The results are cached.
+	if again := scheduler.DistinctConditionalOps(cal); &again[0] != &cops[0] {
+		t.Errorf("results were not cached")
+	}
+}
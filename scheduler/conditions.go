@@ -0,0 +1,164 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cosnicolaou/automation/devices"
+)
+
+// ConditionMatch determines how the results of a set of conditions
+// are combined.
+type ConditionMatch int
+
+const (
+	MatchAll ConditionMatch = iota // All conditions must be true.
+	MatchAny                       // At least one condition must be true.
+)
+
+func (m ConditionMatch) String() string {
+	switch m {
+	case MatchAll:
+		return "all"
+	case MatchAny:
+		return "any"
+	}
+	return fmt.Sprintf("unknown(%d)", int(m))
+}
+
+// ParseConditionMatch parses one of "all" or "any", an empty string is
+// treated as "all".
+func ParseConditionMatch(val string) (ConditionMatch, error) {
+	switch strings.ToLower(strings.TrimSpace(val)) {
+	case "", "all":
+		return MatchAll, nil
+	case "any":
+		return MatchAny, nil
+	}
+	return MatchAll, fmt.Errorf("invalid condition match: %q, must be one of all or any", val)
+}
+
+// NewCombinedPrecondition returns a Precondition whose Condition evaluates
+// the supplied conditions, as per EvalConditionsParallel with the specified
+// concurrency, and whose Name describes them.
+func NewCombinedPrecondition(match ConditionMatch, concurrency int, conds []Precondition) Precondition {
+	p := Precondition{Conditions: conds, Match: match, Concurrency: concurrency}
+	p.Name = p.String()
+	p.Condition = func(ctx context.Context, opts devices.OperationArgs) (any, bool, error) {
+		ok, _, err := EvalConditionsParallel(ctx, match, concurrency, opts, conds)
+		return nil, ok, err
+	}
+	return p
+}
+
+// EvalConditions evaluates the supplied conditions in order and stops
+// as soon as the result is known: for MatchAll at the first condition that
+// is false and for MatchAny at the first that is true. The names, as per
+// Precondition.String, of the conditions that were false, or that returned
+// an error, are returned. An error stops evaluation immediately.
+func EvalConditions(ctx context.Context, match ConditionMatch, opts devices.OperationArgs, conds []Precondition) (bool, []string, error) {
+	var failed []string
+	for _, c := range conds {
+		copts := opts
+		copts.Args = c.Args
+		_, ok, err := c.Condition(ctx, copts)
+		if err != nil {
+			return false, append(failed, c.String()), fmt.Errorf("%v: %w", c.String(), err)
+		}
+		if ok && match == MatchAny {
+			return true, nil, nil
+		}
+		if !ok {
+			failed = append(failed, c.String())
+			if match == MatchAll {
+				return false, failed, nil
+			}
+		}
+	}
+	return match == MatchAll, failed, nil
+}
+
+type conditionResult struct {
+	idx int
+	ok  bool
+	err error
+}
+
+// EvalConditionsParallel evaluates the supplied conditions concurrently,
+// with at most concurrency evaluations in progress at any one time (a value
+// of zero or less implies no limit and a value of one that the conditions
+// are evaluated in order as per EvalConditions). Evaluation is
+// short-circuited: for MatchAny the remaining evaluations are canceled as
+// soon as one condition is true and for MatchAll as soon as one is false.
+// The names of the conditions that were false, or that returned an error,
+// are returned in the order in which they were specified. Any error
+// encountered cancels all outstanding evaluations and is returned
+// immediately.
+func EvalConditionsParallel(ctx context.Context, match ConditionMatch, concurrency int, opts devices.OperationArgs, conds []Precondition) (bool, []string, error) {
+	if concurrency == 1 {
+		return EvalConditions(ctx, match, opts, conds)
+	}
+	if len(conds) == 0 {
+		return match == MatchAll, nil, nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if concurrency <= 0 || concurrency > len(conds) {
+		concurrency = len(conds)
+	}
+	results := make(chan conditionResult, len(conds))
+	sem := make(chan struct{}, concurrency)
+	go func() {
+		for i, c := range conds {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			go func() {
+				defer func() { <-sem }()
+				copts := opts
+				copts.Args = c.Args
+				_, ok, err := c.Condition(ctx, copts)
+				results <- conditionResult{idx: i, ok: ok, err: err}
+			}()
+		}
+	}()
+	failed := make([]bool, len(conds))
+	names := func() []string {
+		var n []string
+		for i, f := range failed {
+			if f {
+				n = append(n, conds[i].String())
+			}
+		}
+		return n
+	}
+	for range conds {
+		var r conditionResult
+		select {
+		case r = <-results:
+		case <-ctx.Done():
+			return false, names(), ctx.Err()
+		}
+		if r.err != nil {
+			failed[r.idx] = true
+			return false, names(), fmt.Errorf("%v: %w", conds[r.idx].String(), r.err)
+		}
+		switch {
+		case match == MatchAny && r.ok:
+			return true, nil, nil
+		case !r.ok:
+			failed[r.idx] = true
+			if match == MatchAll {
+				return false, names(), nil
+			}
+		}
+	}
+	return match == MatchAll, names(), nil
+}
@@ -0,0 +1,352 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+// slowCondition returns a condition that returns result after delay unless
+// its context is canceled first, in which case canceled is closed.
+func slowCondition(name string, delay time.Duration, result bool, canceled chan struct{}) scheduler.Precondition {
+	return scheduler.Precondition{
+		Device: "dev",
+		Name:   name,
+		Condition: func(ctx context.Context, _ devices.OperationArgs) (any, bool, error) {
+			select {
+			case <-time.After(delay):
+				return nil, result, nil
+			case <-ctx.Done():
+				close(canceled)
+				return nil, false, ctx.Err()
+			}
+		},
+	}
+}
+
+func TestEvalConditionsParallel(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		match  scheduler.ConditionMatch
+		result bool
+		failed []string
+	}{
+		{scheduler.MatchAny, true, nil},
+		{scheduler.MatchAll, false, []string{"dev.fast"}},
+	} {
+		fastCanceled, slowCanceled := make(chan struct{}), make(chan struct{})
+		conds := []scheduler.Precondition{
+			slowCondition("slow", time.Hour, !tc.result, slowCanceled),
+			slowCondition("fast", 10*time.Millisecond, tc.result, fastCanceled),
+		}
+		start := time.Now()
+		ok, failed, err := scheduler.EvalConditionsParallel(ctx, tc.match, 0, devices.OperationArgs{}, conds)
+		if err != nil {
+			t.Fatalf("%v: %v", tc.match, err)
+		}
+		if got, want := ok, tc.result; got != want {
+			t.Errorf("%v: got %v, want %v", tc.match, got, want)
+		}
+		if got, want := failed, tc.failed; !slices.Equal(got, want) {
+			t.Errorf("%v: got %v, want %v", tc.match, got, want)
+		}
+		if took := time.Since(start); took > time.Minute {
+			t.Errorf("%v: short-circuit failed: took %v", tc.match, took)
+		}
+		select {
+		case <-slowCanceled:
+		case <-time.After(5 * time.Second):
+			t.Errorf("%v: slow condition was not canceled", tc.match)
+		}
+		select {
+		case <-fastCanceled:
+			t.Errorf("%v: fast condition was canceled", tc.match)
+		default:
+		}
+	}
+
+	// All conditions are evaluated when there is no short-circuit.
+	conds := []scheduler.Precondition{
+		slowCondition("a", time.Millisecond, true, make(chan struct{})),
+		slowCondition("b", time.Millisecond, true, make(chan struct{})),
+	}
+	ok, failed, err := scheduler.EvalConditionsParallel(ctx, scheduler.MatchAll, 2, devices.OperationArgs{}, conds)
+	if err != nil || !ok || failed != nil {
+		t.Errorf("got %v, %v, %v, want true, [], nil", ok, failed, err)
+	}
+
+	// Errors cancel outstanding evaluations.
+	slowCanceled := make(chan struct{})
+	conds = []scheduler.Precondition{
+		slowCondition("slow", time.Hour, true, slowCanceled),
+		{Device: "dev", Name: "err", Condition: func(context.Context, devices.OperationArgs) (any, bool, error) {
+			return nil, false, errors.New("oops")
+		}},
+	}
+	_, failed, err = scheduler.EvalConditionsParallel(ctx, scheduler.MatchAny, 0, devices.OperationArgs{}, conds)
+	if err == nil || err.Error() != "dev.err: oops" {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+	if got, want := failed, []string{"dev.err"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	<-slowCanceled
+}
+
+func TestEvalConditions(t *testing.T) {
+	ctx := context.Background()
+	var evaluated []string
+	cond := func(name string, result bool) scheduler.Precondition {
+		return scheduler.Precondition{
+			Device: "dev",
+			Name:   name,
+			Condition: func(context.Context, devices.OperationArgs) (any, bool, error) {
+				evaluated = append(evaluated, name)
+				return nil, result, nil
+			},
+		}
+	}
+	yes, no, also := cond("yes", true), cond("no", false), cond("also", false)
+	for _, tc := range []struct {
+		match     scheduler.ConditionMatch
+		conds     []scheduler.Precondition
+		result    bool
+		failed    []string
+		evaluated []string
+	}{
+		{scheduler.MatchAll, []scheduler.Precondition{yes, yes}, true, nil, []string{"yes", "yes"}},
+		{scheduler.MatchAll, []scheduler.Precondition{no, yes}, false, []string{"dev.no"}, []string{"no"}},
+		{scheduler.MatchAny, []scheduler.Precondition{yes, no}, true, nil, []string{"yes"}},
+		{scheduler.MatchAny, []scheduler.Precondition{no, yes}, true, nil, []string{"no", "yes"}},
+		{scheduler.MatchAny, []scheduler.Precondition{no, also}, false, []string{"dev.no", "dev.also"}, []string{"no", "also"}},
+	} {
+		evaluated = nil
+		ok, failed, err := scheduler.EvalConditions(ctx, tc.match, devices.OperationArgs{}, tc.conds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := ok, tc.result; got != want {
+			t.Errorf("%v: got %v, want %v", tc.match, got, want)
+		}
+		if got, want := failed, tc.failed; !slices.Equal(got, want) {
+			t.Errorf("%v: got %v, want %v", tc.match, got, want)
+		}
+		if got, want := evaluated, tc.evaluated; !slices.Equal(got, want) {
+			t.Errorf("%v: got %v, want %v", tc.match, got, want)
+		}
+	}
+
+	conds := []scheduler.Precondition{no, {Device: "dev", Name: "err", Condition: func(context.Context, devices.OperationArgs) (any, bool, error) {
+		return nil, false, errors.New("oops")
+	}}, yes}
+	_, failed, err := scheduler.EvalConditions(ctx, scheduler.MatchAny, devices.OperationArgs{}, conds)
+	if err == nil || err.Error() != "dev.err: oops" {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+	if got, want := failed, []string{"dev.no", "dev.err"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+const combinedPreconditionsSchedule = `
+schedules:
+  - name: combined
+    device: device
+    ranges:
+      - 01/02:01/02
+    actions_detailed:
+      - action: on
+        when: 12:00
+        precondition:
+          conditions:
+            - device: device
+              op: weather
+              args: ["sunny"]
+            - device: device
+              op: "!weather"
+              args: ["snow"]
+      - action: off
+        when: 12:01
+        precondition:
+          match: any
+          conditions:
+            - device: device
+              op: "!weather"
+              args: ["snow"]
+            - device: device
+              op: weather
+              args: ["sunny"]
+      - action: another
+        when: 12:02
+        precondition:
+          device: device
+          op: weather
+`
+
+func TestCombinedPreconditions(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "UTC")
+	sched := parseSchedule(t, sys, combinedPreconditionsSchedule)
+
+	pre := sched.DailyActions[0].T.Precondition
+	if got, want := pre.Name, "all(device.weather(sunny), device.!weather(snow))"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := sched.DailyActions[1].T.Precondition.Match, scheduler.MatchAny; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// The single condition syntax is unchanged.
+	if pre := sched.DailyActions[2].T.Precondition; pre.Name != "weather" || len(pre.Conditions) != 0 {
+		t.Errorf("unexpected single precondition: %v", pre)
+	}
+
+	_, logRecorder := runScheduleForYear(ctx, t, sys, sched, 2024)
+	var got []string
+	for _, l := range logRecorder.Lines() {
+		var entry struct {
+			Msg    string   `json:"msg"`
+			Op     string   `json:"op"`
+			Passed bool     `json:"passed"`
+			Match  string   `json:"match"`
+			Failed []string `json:"failed"`
+		}
+		if err := json.Unmarshal([]byte(l), &entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry.Msg == "precondition" {
+			got = append(got, fmt.Sprintf("%v %v %v %v", entry.Op, entry.Passed, entry.Match, entry.Failed))
+		}
+	}
+	if want := []string{
+		"on false all [device.!weather(snow)]",
+		"off true any []",
+		"another true  []",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, tc := range []struct {
+		precondition, err string
+	}{
+		{`
+          device: device
+          op: weather
+          conditions:
+            - device: device
+              op: weather`, "both an op"},
+		{`
+          match: some
+          conditions:
+            - device: device
+              op: weather`, "invalid condition match"},
+		{`
+          match: any`, "requires a list of conditions"},
+		{`
+          device: device
+          op: weather
+          concurrency: 2`, "requires a list of conditions"},
+		{`
+          concurrency: -1
+          conditions:
+            - device: device
+              op: weather`, "must not be negative"},
+		{`
+          conditions:
+            - device: device
+              op: unknown`, `unknown precondition: "unknown"`},
+	} {
+		cfg := `
+schedules:
+  - name: combined
+    device: device
+    ranges:
+      - 01/02:01/02
+    actions_detailed:
+      - action: on
+        when: 12:00
+        precondition:` + tc.precondition + "\n"
+		_, err := scheduler.ParseConfig(ctx, []byte(cfg), sys)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("unexpected or missing error: %v, want %q", err, tc.err)
+		}
+	}
+}
+
+const concurrentPreconditionsSchedule = `
+schedules:
+  - name: concurrent
+    device: device
+    ranges:
+      - 01/02:01/02
+    actions_detailed:
+      - action: on
+        when: 12:00
+        precondition:
+          concurrency: %v
+          conditions:
+            - device: device
+              op: weather
+            - device: device
+              op: weather
+            - device: device
+              op: weather
+`
+
+func TestConcurrentPreconditions(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "UTC")
+	for _, tc := range []struct {
+		concurrency, overlap int
+	}{
+		{0, 3},
+		{2, 2},
+		{1, 1},
+	} {
+		sched := parseSchedule(t, sys, fmt.Sprintf(concurrentPreconditionsSchedule, tc.concurrency))
+		pre := &sched.DailyActions[0].T.Precondition
+		if got, want := pre.Concurrency, tc.concurrency; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		// Replace the configured conditions with slow ones that record
+		// how many of them are being evaluated at the same time.
+		var mu sync.Mutex
+		inflight, overlap := 0, 0
+		conds := make([]scheduler.Precondition, len(pre.Conditions))
+		for i, c := range pre.Conditions {
+			c.Condition = func(context.Context, devices.OperationArgs) (any, bool, error) {
+				mu.Lock()
+				inflight++
+				overlap = max(overlap, inflight)
+				mu.Unlock()
+				time.Sleep(100 * time.Millisecond)
+				mu.Lock()
+				inflight--
+				mu.Unlock()
+				return nil, true, nil
+			}
+			conds[i] = c
+		}
+		*pre = scheduler.NewCombinedPrecondition(pre.Match, pre.Concurrency, conds)
+
+		deviceRecorder, _ := runScheduleForYear(ctx, t, sys, sched, 2024)
+		if got, want := len(deviceRecorder.Lines()), 1; got != want {
+			t.Errorf("concurrency %v: got %v, want %v", tc.concurrency, got, want)
+		}
+		if got, want := overlap, tc.overlap; got != want {
+			t.Errorf("concurrency %v: got %v, want %v", tc.concurrency, got, want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"cloudeng.io/datetime"
+)
+
+// ConflictingAction is one of the actions involved in a Conflict.
+type ConflictingAction struct {
+	Schedule string `json:"schedule"`
+	Op       string `json:"op"`
+}
+
+// Conflict represents two actions, in different schedules, that perform
+// different operations on the same device within the same minute, the
+// outcome of which depends on the order in which they happen to be run.
+// First is the first time that the conflict occurs and Occurrences the
+// number of times that it occurs.
+type Conflict struct {
+	Device      string               `json:"device"`
+	Actions     [2]ConflictingAction `json:"actions"`
+	First       time.Time            `json:"first"`
+	Occurrences int                  `json:"occurrences"`
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%v: %v.%v and %v.%v are due within the same minute, first at %v, %v times",
+		c.Device,
+		c.Actions[0].Schedule, c.Actions[0].Op,
+		c.Actions[1].Schedule, c.Actions[1].Op,
+		c.First.Format("01/02/2006 15:04"), c.Occurrences)
+}
+
+// Conflicts returns the conflicting actions in the schedules in the
+// calendar during the specified year, ordered by their first occurrence.
+func Conflicts(cal *Calendar, year int) []Conflict {
+	type slot struct {
+		device string
+		minute time.Time
+	}
+	type scheduled struct {
+		ConflictingAction
+		when time.Time
+	}
+	wholeYear := datetime.NewDateRange(datetime.NewDate(1, 1), datetime.NewDate(12, 31))
+	slots := map[slot][]scheduled{}
+	for _, s := range cal.schedulers {
+		yp := datetime.YearPlace{Year: year, Place: s.place}
+		for perDay := range s.schedule.scheduled(s.scheduler, yp, wholeYear) {
+			for active := range perDay.Active(s.place) {
+				key := slot{device: active.T.DeviceName, minute: active.When.Truncate(time.Minute)}
+				slots[key] = append(slots[key], scheduled{
+					ConflictingAction: ConflictingAction{Schedule: s.schedule.Name, Op: active.Name},
+					when:              active.When,
+				})
+			}
+		}
+	}
+	type pair struct {
+		device string
+		a, b   ConflictingAction
+	}
+	conflicts := map[pair]*Conflict{}
+	for key, actions := range slots {
+		for i, a := range actions {
+			for _, b := range actions[i+1:] {
+				if a.Schedule == b.Schedule || a.Op == b.Op {
+					continue
+				}
+				x, y := a, b
+				if cmp.Compare(x.Schedule, y.Schedule) > 0 {
+					x, y = y, x
+				}
+				p := pair{device: key.device, a: x.ConflictingAction, b: y.ConflictingAction}
+				first := x.when
+				if y.when.Before(first) {
+					first = y.when
+				}
+				c, ok := conflicts[p]
+				if !ok {
+					c = &Conflict{Device: key.device, Actions: [2]ConflictingAction{p.a, p.b}, First: first}
+					conflicts[p] = c
+				}
+				if first.Before(c.First) {
+					c.First = first
+				}
+				c.Occurrences++
+			}
+		}
+	}
+	result := make([]Conflict, 0, len(conflicts))
+	for _, c := range conflicts {
+		result = append(result, *c)
+	}
+	slices.SortFunc(result, func(a, b Conflict) int {
+		return cmp.Or(a.First.Compare(b.First), cmp.Compare(a.Device, b.Device),
+			cmp.Compare(a.Actions[0].Schedule, b.Actions[0].Schedule),
+			cmp.Compare(a.Actions[0].Op, b.Actions[0].Op),
+			cmp.Compare(a.Actions[1].Schedule, b.Actions[1].Schedule),
+			cmp.Compare(a.Actions[1].Op, b.Actions[1].Op))
+	})
+	return result
+}
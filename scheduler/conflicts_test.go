@@ -0,0 +1,95 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+const conflictSystem = `
+time_location: UTC
+devices:
+  - name: porch
+    type: device
+    operations:
+      on:
+      off:
+  - name: garden
+    type: device
+    operations:
+      on:
+      off:
+`
+
+const conflictSchedules = `
+schedules:
+  - name: porch-on
+    device: porch
+    ranges:
+      - 03/01:03/03
+    actions:
+      on: 18:00
+  - name: porch-off
+    device: porch
+    ranges:
+      - 03/02:03/05
+    actions:
+      off: 18:00:30
+  # The same operation at the same time is not a conflict.
+  - name: porch-on-again
+    device: porch
+    ranges:
+      - 03/01:03/01
+    actions:
+      on: 18:00
+  # Nor are operations on different devices or within the same schedule.
+  - name: garden
+    device: garden
+    ranges:
+      - 03/01:03/05
+    actions:
+      on: 18:00
+      off: 18:00
+`
+
+func TestConflicts(t *testing.T) {
+	ctx := context.Background()
+	sys, err := devices.ParseSystemConfig(ctx, []byte(conflictSystem),
+		devices.WithDevices(supportedDevices))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheds, err := scheduler.ParseConfig(ctx, []byte(conflictSchedules), sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal, err := scheduler.NewCalendar(scheds, sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conflicts := scheduler.Conflicts(cal, 2025)
+	if got, want := len(conflicts), 1; got != want {
+		t.Fatalf("got %v, want %v: %v", got, want, conflicts)
+	}
+	if got, want := conflicts[0], (scheduler.Conflict{
+		Device: "porch",
+		Actions: [2]scheduler.ConflictingAction{
+			{Schedule: "porch-off", Op: "off"},
+			{Schedule: "porch-on", Op: "on"},
+		},
+		First:       time.Date(2025, 3, 2, 18, 0, 0, 0, time.UTC),
+		Occurrences: 2,
+	}); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := conflicts[0].String(), "porch: porch-off.off and porch-on.on are due within the same minute, first at 03/02/2025 18:00, 2 times"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
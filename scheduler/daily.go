@@ -5,8 +5,11 @@
 package scheduler
 
 import (
+	"cmp"
 	"fmt"
 	"slices"
+	"strings"
+	"time"
 
 	"cloudeng.io/datetime/schedule"
 	"github.com/cosnicolaou/automation/devices"
@@ -17,21 +20,149 @@ type Precondition struct {
 	Name      string
 	Condition devices.Condition
 	Args      []string
+	// Conditions, if non-empty, are combined as per Match to form
+	// the precondition, see NewCombinedPrecondition.
+	Conditions []Precondition
+	Match      ConditionMatch
+	// Concurrency bounds the number of Conditions that are evaluated
+	// at once, zero implies no limit.
+	Concurrency int
+}
+
+// String returns device.name(args) for a single condition and
+// match(device.name(args), ...) for combined ones.
+func (p Precondition) String() string {
+	if len(p.Conditions) == 0 {
+		s := p.Device + "." + p.Name
+		if len(p.Args) > 0 {
+			s += "(" + strings.Join(p.Args, ", ") + ")"
+		}
+		return s
+	}
+	conds := make([]string, len(p.Conditions))
+	for i, c := range p.Conditions {
+		conds[i] = c.String()
+	}
+	return fmt.Sprintf("%v(%v)", p.Match, strings.Join(conds, ", "))
+}
+
+// PreconditionErrorAction determines how an error encountered evaluating
+// an action's precondition is handled.
+type PreconditionErrorAction int
+
+const (
+	PreconditionErrorFail PreconditionErrorAction = iota // The action fails with the precondition's error.
+	PreconditionErrorRun                                 // The action is run as if the precondition were true.
+	PreconditionErrorSkip                                // The action is skipped as if the precondition were false.
+)
+
+// ParsePreconditionErrorAction parses one of "fail", "run" or "skip",
+// an empty string is treated as "fail".
+func ParsePreconditionErrorAction(val string) (PreconditionErrorAction, error) {
+	switch strings.ToLower(strings.TrimSpace(val)) {
+	case "", "fail":
+		return PreconditionErrorFail, nil
+	case "run":
+		return PreconditionErrorRun, nil
+	case "skip":
+		return PreconditionErrorSkip, nil
+	}
+	return PreconditionErrorFail, fmt.Errorf("invalid precondition error action: %q, must be one of run, skip or fail", val)
+}
+
+func (p PreconditionErrorAction) String() string {
+	switch p {
+	case PreconditionErrorFail:
+		return "fail"
+	case PreconditionErrorRun:
+		return "run"
+	case PreconditionErrorSkip:
+		return "skip"
+	}
+	return fmt.Sprintf("unknown(%d)", int(p))
 }
 
 // Action represents a single action to be taken on any given day.
 type Action struct {
 	devices.Action
 	Precondition Precondition
+	// OnPreconditionError determines how errors evaluating the
+	// precondition are handled.
+	OnPreconditionError PreconditionErrorAction
+	// Postcondition, if set, is evaluated once the operation has
+	// completed successfully and the operation is considered to have
+	// failed, with ErrPostconditionNotMet, if it is false.
+	Postcondition Precondition
+	Coalesce      bool // Skip overdue instances of this action if a more recent one is also due.
+	LogOnChange   bool // Only log completions whose result differs from the previous one.
+	// MaxTotalTime, if non-zero, limits the total time spent on the
+	// action across all retries.
+	MaxTotalTime time.Duration
+	// SkipIfUnchanged skips the action if the same operation, with the
+	// same arguments, was the last one successfully commanded on the
+	// device that day.
+	SkipIfUnchanged bool
+	// Critical actions abort the remaining actions for the day, for
+	// the schedule, if they fail.
+	Critical bool
+	// SuccessWhen, if set, is evaluated over the data returned by the
+	// operation and the action is treated as having failed if it is
+	// not satisfied.
+	SuccessWhen SuccessPredicate
+	// Ordered is set for actions that are constrained to run before, or
+	// after, another action and are therefore never run concurrently
+	// with other actions, see WithConcurrentActions.
+	Ordered bool
+	// Before and After are the actions, if any, that this action was
+	// constrained to run immediately before or after.
+	Before, After string
+	// Priority orders the action relative to others due at the same
+	// time, lower values run first. Before and After take precedence.
+	Priority int
+	// RelativeTo, if its Schedule is set, is the action in another
+	// schedule, and the offset from it, that this action's due time was
+	// specified relative to. The due time is resolved when the schedules
+	// are created.
+	RelativeTo ActionRef
+	// Until, if set, is the time of day after which the action is no
+	// longer repeated.
+	Until RepeatUntil
+	// OnController is set for operations that are implemented by the
+	// controller named by DeviceName rather than by a device, in which
+	// case Controller, rather than Device, is set by New.
+	OnController bool
+	Controller   devices.Controller
+}
+
+// retryConfig returns the timeout and retry configuration of the device,
+// or controller, that implements the action.
+func (a Action) retryConfig() devices.RetryConfig {
+	if a.Controller != nil {
+		return a.Controller.Config().RetryConfig
+	}
+	return a.Device.Config().RetryConfig
+}
+
+// controller returns the controller that the action is issued via.
+func (a Action) controller() devices.Controller {
+	if a.Controller != nil {
+		return a.Controller
+	}
+	return a.Device.ControlledBy()
 }
 
 // orderActionsStatic orders the actions in the supplied slice of
-// actions according to the before and after constraints in actionDetailed.
+// actions, which must be sorted by due time, according to their priority
+// and then the before and after constraints in actionDetailed.
 func orderActionsStatic(actions schedule.ActionSpecs[Action], detailed []actionDetailed) (schedule.ActionSpecs[Action], error) {
 	if len(detailed) == 0 {
 		return actions, nil
 	}
 
+	slices.SortStableFunc(actions, func(a, b schedule.ActionSpec[Action]) int {
+		return cmp.Or(cmp.Compare(a.Due, b.Due), cmp.Compare(a.T.Priority, b.T.Priority))
+	})
+
 	order := map[string]int{}
 	for i, a := range actions {
 		order[a.Name] = i
@@ -73,6 +204,24 @@ func orderActionsStatic(actions schedule.ActionSpecs[Action], detailed []actionD
 	return actions, nil
 }
 
+// markOrdered sets Ordered for all of the actions that are the subject
+// or target of a before or after constraint.
+func markOrdered(actions schedule.ActionSpecs[Action], detailed []actionDetailed) {
+	ordered := map[string]bool{}
+	for _, wa := range detailed {
+		if len(wa.Before) == 0 && len(wa.After) == 0 {
+			continue
+		}
+		ordered[wa.Action] = true
+		ordered[wa.Before+wa.After] = true
+	}
+	for i := range actions {
+		if ordered[actions[i].Name] {
+			actions[i].T.Ordered = true
+		}
+	}
+}
+
 func validateOpName(detailed actionDetailed) (before bool, name string, err error) {
 	if len(detailed.Before) != 0 && len(detailed.After) != 0 {
 		return false, "", fmt.Errorf("action %v cannot have both before and after specified", detailed.Action)
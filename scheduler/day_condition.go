@@ -0,0 +1,58 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"cloudeng.io/datetime"
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/internal/logging"
+)
+
+// dayConditionResult caches the result of evaluating a schedule's
+// day condition for a single date.
+type dayConditionResult struct {
+	date datetime.CalendarDate
+	ok   bool
+}
+
+// dayConditionMet returns true if the schedule has no day condition, or
+// if its day condition is true for the specified date. The condition is
+// evaluated at most once per date, an error evaluating it is treated as
+// the condition being false. Day conditions are not evaluated for dry runs.
+func (s *Scheduler) dayConditionMet(ctx context.Context, date datetime.CalendarDate) bool {
+	pre := s.schedule.DayCondition
+	if pre.Condition == nil || s.dryRun {
+		return true
+	}
+	if s.dayCondition.date == date {
+		return s.dayCondition.ok
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.dayConditionTimeout)
+	defer cancel()
+	ctx = withStatusRecorder(ctx, s.statusRecorder)
+	_, ok, err := pre.Condition(ctx, devices.OperationArgs{
+		Due:    date.Time(datetime.NewTimeOfDay(0, 0, 0), s.place.TimeLocation),
+		Place:  s.place,
+		Writer: s.opWriter,
+		Args:   pre.Args,
+	})
+	logging.WriteDayCondition(s.logger, date, pre.Device, pre.Name, pre.Args, ok, err)
+	ok = ok && err == nil
+	s.dayCondition = dayConditionResult{date: date, ok: ok}
+	return ok
+}
+
+// dayConditionTimeout returns the timeout to use for evaluating the
+// schedule's day condition, that is, the timeout configured for the
+// device that implements it.
+func dayConditionTimeout(sched Annual, system devices.System) time.Duration {
+	if dev := system.Devices[sched.DayCondition.Device]; dev != nil && dev.Config().Timeout > 0 {
+		return dev.Config().Timeout
+	}
+	return time.Minute
+}
@@ -0,0 +1,162 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/internal/logging"
+	"github.com/cosnicolaou/automation/internal/testutil"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+// seasonDevice implements a condition that is true on even numbered
+// days of the month only and records the dates it was evaluated for.
+type seasonDevice struct {
+	testutil.MockDevice
+	mu    sync.Mutex
+	dates []string
+}
+
+func (sd *seasonDevice) Conditions() map[string]devices.Condition {
+	return map[string]devices.Condition{"in_season": sd.inSeason}
+}
+
+func (sd *seasonDevice) inSeason(_ context.Context, opts devices.OperationArgs) (any, bool, error) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	sd.dates = append(sd.dates, opts.Due.Format("01/02"))
+	return nil, opts.Due.Day()%2 == 0, nil
+}
+
+const dayConditionSystem = `
+time_location: Local
+devices:
+  - name: device
+    type: device
+    operations:
+      on:
+      off:
+  - name: pool
+    type: season
+    conditions:
+      in_season:
+`
+
+const dayConditionSchedule = `
+schedules:
+  - name: pool-season
+    device: device
+    ranges:
+      - 01/01:01/06
+    day_condition:
+      device: pool
+      op: in_season
+    actions:
+      on: 12:00
+      off: 13:00
+`
+
+func TestDayCondition(t *testing.T) {
+	ctx := context.Background()
+	season := &seasonDevice{}
+	supported := maps.Clone(supportedDevices)
+	supported["season"] = func(string, devices.Options) (devices.Device, error) {
+		return season, nil
+	}
+	sys, err := devices.ParseSystemConfig(ctx, []byte(dayConditionSystem),
+		devices.WithDevices(supported),
+		devices.WithControllers(supportedControllers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sched := parseSchedule(t, sys, dayConditionSchedule)
+	if got, want := sched.DayCondition.Name, "in_season"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	year := 2024
+	ts := &timesource{ch: make(chan time.Time, 1)}
+	deviceRecorder, logRecorder, opts := newRecordersAndLogger(ts)
+	s := createScheduler(t, sys, sched, opts...)
+
+	// Only provide ticks for the days on which the condition is true.
+	all, times, ticks := allActive(s, year, time.Millisecond*5)
+	var gatedTimes, gatedTicks []time.Time
+	var gated []testAction
+	for i, a := range all {
+		if a.when.Day()%2 == 0 {
+			gated = append(gated, a)
+			gatedTimes = append(gatedTimes, times[i])
+			gatedTicks = append(gatedTicks, ticks[i])
+		}
+	}
+	_, gatedTicks = appendYearEndTimesTicks(year, sys.Location.TimeLocation, gatedTimes, gatedTicks)
+	runScheduler(ctx, t, s, year, ts, gatedTicks)
+
+	logs := logRecorder.Logs(t)
+	if err := containsError(logs); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(deviceRecorder.Lines()), 6; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i, l := range logs[:len(logs)-1] {
+		if got, want := l.Due, gated[i].when; !got.Equal(want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+
+	// The condition is evaluated once per day.
+	if got, want := strings.Join(season.dates, " "), "01/01 01/02 01/03 01/04 01/05 01/06"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	nDayConds := 0
+	for _, l := range logRecorder.Lines() {
+		if strings.Contains(l, `"msg":"`+logging.LogDayCond+`"`) {
+			nDayConds++
+		}
+	}
+	if got, want := nDayConds, 6; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Unknown day conditions are reported.
+	_, err = scheduler.ParseConfig(ctx, []byte(strings.ReplaceAll(dayConditionSchedule, "op: in_season", "op: unknown")), sys)
+	if err == nil || !strings.Contains(err.Error(), `unknown day condition: "unknown"`) {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+
+	// Fields that are only meaningful for lists of conditions, and
+	// devices without an op, are rejected rather than ignored.
+	for _, tc := range []struct {
+		replacement, err string
+	}{
+		{"op: in_season\n      match: any", `does not support match: "any"`},
+		{"op: in_season\n      concurrency: 2", "does not support concurrency: 2"},
+		{"op: in_season\n      conditions:\n        - device: pool\n          op: in_season", "does not support a list of conditions"},
+		{"", `day condition for device: "pool" is missing an op`},
+	} {
+		cfg := strings.ReplaceAll(dayConditionSchedule, "op: in_season", tc.replacement)
+		_, err = scheduler.ParseConfig(ctx, []byte(cfg), sys)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%q: missing or unexpected error: %v", tc.replacement, err)
+		}
+	}
+
+	// The built-in last_op condition is supported.
+	cfg := strings.ReplaceAll(dayConditionSchedule, "op: in_season", "op: \"!last_op\"\n      args: [\"on\"]")
+	cfg = strings.ReplaceAll(cfg, "device: pool", "device: device")
+	sched = parseSchedule(t, sys, cfg)
+	if got, want := sched.DayCondition.Name, "!last_op"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+	"time"
+
+	"cloudeng.io/datetime"
+	"cloudeng.io/datetime/schedule"
+)
+
+// DaysOfWeek represents a set of days of the week. An empty set
+// includes all days.
+type DaysOfWeek []time.Weekday
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// ParseDaysOfWeek parses a comma separated list of day names, eg.
+// "mon,thu" or "monday, thursday". An empty string results in an
+// empty set.
+func ParseDaysOfWeek(val string) (DaysOfWeek, error) {
+	if len(strings.TrimSpace(val)) == 0 {
+		return nil, nil
+	}
+	var days DaysOfWeek
+	for _, p := range strings.Split(strings.ReplaceAll(val, " ", ""), ",") {
+		d, ok := weekdayNames[strings.ToLower(p)]
+		if !ok {
+			return nil, fmt.Errorf("invalid day of week: %q", p)
+		}
+		if !slices.Contains(days, d) {
+			days = append(days, d)
+		}
+	}
+	slices.Sort(days)
+	return days, nil
+}
+
+// Include returns true if the specified date falls on one of
+// the days in the set, or if the set is empty.
+func (d DaysOfWeek) Include(cd datetime.CalendarDate) bool {
+	if len(d) == 0 {
+		return true
+	}
+	wd := time.Date(cd.Year(), time.Month(cd.Month()), cd.Day(), 0, 0, 0, 0, time.UTC).Weekday()
+	return slices.Contains(d, wd)
+}
+
+func (d DaysOfWeek) String() string {
+	var out strings.Builder
+	for i, wd := range d {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(wd.String()[:3])
+	}
+	return out.String()
+}
+
+// scheduled returns the days, and associated actions, scheduled by sched
+// for the specified year and bounds that also fall on one of the schedule's
+// days of the week and are close enough to one of its lunar phases. The
+// repeats of actions with an until time are bounded accordingly.
+func (a Annual) scheduled(sched *schedule.AnnualScheduler[Action], yp datetime.YearPlace, bounds datetime.DateRange) iter.Seq[schedule.Scheduled[Action]] {
+	all := sched.Scheduled(yp, a.Dates, bounds)
+	until := a.hasRepeatUntil()
+	if len(a.DaysOfWeek) == 0 && len(a.LunarPhases.Phases) == 0 && !until {
+		return all
+	}
+	return func(yield func(schedule.Scheduled[Action]) bool) {
+		for day := range all {
+			if !a.DaysOfWeek.Include(day.Date) || !a.LunarPhases.Include(day.Date, yp.Place) {
+				continue
+			}
+			if until {
+				day = boundRepeats(day, yp.Place)
+			}
+			if !yield(day) {
+				return
+			}
+		}
+	}
+}
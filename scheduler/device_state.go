@@ -0,0 +1,52 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"slices"
+	"sync"
+
+	"cloudeng.io/datetime"
+)
+
+type deviceState struct {
+	op   string
+	args []string
+	date datetime.CalendarDate
+}
+
+// deviceStates records the last operation, and its arguments, successfully
+// commanded on each device and is shared by all of the schedulers created
+// by RunSchedulers so that operations issued by different schedules on
+// the same device are taken into account.
+type deviceStates struct {
+	mu   sync.Mutex
+	last map[string]deviceState
+}
+
+func newDeviceStates() *deviceStates {
+	return &deviceStates{last: map[string]deviceState{}}
+}
+
+func withDeviceStates(ds *deviceStates) Option {
+	return func(o *options) {
+		o.deviceStates = ds
+	}
+}
+
+func (ds *deviceStates) record(device, op string, args []string, date datetime.CalendarDate) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.last[device] = deviceState{op: op, args: slices.Clone(args), date: date}
+}
+
+// unchanged returns true if the last operation commanded on the device
+// on the specified date was op with the same arguments.
+func (ds *deviceStates) unchanged(device, op string, args []string, date datetime.CalendarDate) bool {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	last, ok := ds.last[device]
+	return ok && last.date == date && last.op == op && slices.Equal(last.args, args)
+}
@@ -0,0 +1,69 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"cloudeng.io/datetime"
+)
+
+// DayDrift represents the time of day that a dynamically timed action
+// resolves to on a given date and the change from the time it resolved
+// to on the previous day.
+type DayDrift struct {
+	Date  datetime.CalendarDate
+	Due   datetime.TimeOfDay
+	Delta time.Duration
+}
+
+// ActionDrift represents the day to day drift of a single action whose
+// due time is dynamic, eg. sunset.
+type ActionDrift struct {
+	Action string
+	Due    string // The dynamic due time and offset, eg. sunset-30m.
+	Days   []DayDrift
+}
+
+// Largest returns the n days with the largest absolute change from the
+// previous day, in order of decreasing change.
+func (ad ActionDrift) Largest(n int) []DayDrift {
+	days := slices.Clone(ad.Days)
+	slices.SortStableFunc(days, func(a, b DayDrift) int {
+		return cmp.Compare(b.Delta.Abs(), a.Delta.Abs())
+	})
+	return days[:min(n, len(days))]
+}
+
+// Drift returns the day to day drift, over the specified date range, of
+// each of the schedule's actions whose due time is dynamic. The change
+// for the first day in the range is relative to the day before it.
+func (a Annual) Drift(place datetime.Place, dr datetime.CalendarDateRange) []ActionDrift {
+	var drifts []ActionDrift
+	for _, action := range a.DailyActions {
+		dyn := action.Dynamic
+		if dyn.Due == nil {
+			continue
+		}
+		due := func(day datetime.CalendarDate) datetime.TimeOfDay {
+			return dyn.Due.Evaluate(day, place).Add(dyn.Offset)
+		}
+		ad := ActionDrift{Action: action.Name, Due: formatDue(action)}
+		prev := due(dr.From().Yesterday())
+		for day := range dr.Dates() {
+			tod := due(day)
+			ad.Days = append(ad.Days, DayDrift{
+				Date:  day,
+				Due:   tod,
+				Delta: tod.Duration() - prev.Duration(),
+			})
+			prev = tod
+		}
+		drifts = append(drifts, ad)
+	}
+	return drifts
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"cloudeng.io/datetime"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+func TestDrift(t *testing.T) {
+	sys := createSystem(t, "America/Los_Angeles")
+	sched := parseSchedule(t, sys, `
+schedules:
+  - name: sunset
+    device: device
+    ranges:
+      - 01/01:12/31
+    actions:
+      on: sunset-30m
+      off: 23:00
+`)
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	place := datetime.Place{TimeLocation: loc, Latitude: 37.3547, Longitude: -122.0862}
+	var dr datetime.CalendarDateRange
+	if err := dr.Parse("03/01/2025:03/31/2025"); err != nil {
+		t.Fatal(err)
+	}
+
+	drifts := sched.Drift(place, dr)
+	if got, want := len(drifts), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	drift := drifts[0]
+	if got, want := drift.Due, "Sunset-30m0s"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := len(drift.Days), 31; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	sunset := scheduler.DailyDynamic["sunset"]
+	prev := sunset.Evaluate(dr.From().Yesterday(), place)
+	for i, day := range drift.Days {
+		cd := datetime.NewCalendarDate(2025, datetime.March, i+1)
+		tod := sunset.Evaluate(cd, place)
+		if got, want := day.Date, cd; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got, want := day.Due, tod.Add(-30*time.Minute); got != want {
+			t.Errorf("%v: got %v, want %v", cd, got, want)
+		}
+		if got, want := day.Delta, tod.Duration()-prev.Duration(); got != want {
+			t.Errorf("%v: got %v, want %v", cd, got, want)
+		}
+		prev = tod
+	}
+
+	// The change to daylight savings time on 3/9/2025 is the largest.
+	largest := drift.Largest(2)
+	if got, want := len(largest), 2; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := largest[0].Date, datetime.NewCalendarDate(2025, datetime.March, 9); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if largest[0].Delta.Abs() < largest[1].Delta.Abs() {
+		t.Errorf("not sorted by decreasing change: %v", largest)
+	}
+}
@@ -105,14 +105,59 @@ func (atl *ActionTimeList) Parse(val string) error {
 	return nil
 }
 
+// twelveHourSuffix returns true if v ends in am or pm, and pm is true
+// for the latter.
+func twelveHourSuffix(v string) (pm, ok bool) {
+	lv := strings.ToLower(strings.TrimSpace(v))
+	switch {
+	case strings.HasSuffix(lv, "pm"):
+		return true, true
+	case strings.HasSuffix(lv, "am"):
+		return false, true
+	}
+	return false, false
+}
+
+// ParseTimeOfDay parses a time of day in either 24-hour, HH:MM[:SS], or
+// 12-hour, H:MM[:SS]am|pm, notation. 24-hour notation is the canonical
+// form. For 12-hour notation the hour must be between 1 and 12, with
+// 12:00am being midnight and 12:00pm noon.
+func ParseTimeOfDay(v string) (datetime.TimeOfDay, error) {
+	v = strings.TrimSpace(v)
+	pm, ok := twelveHourSuffix(v)
+	if !ok {
+		var tod datetime.TimeOfDay
+		err := tod.Parse(v)
+		return tod, err
+	}
+	hms := strings.TrimSpace(v[:len(v)-2])
+	var tod datetime.TimeOfDay
+	if !strings.Contains(hms, ":") || tod.Parse(hms) != nil {
+		return 0, fmt.Errorf("invalid 12-hour time of day: %q, expected H:MM[:SS]am|pm", v)
+	}
+	hour := tod.Hour()
+	if hour < 1 || hour > 12 {
+		return 0, fmt.Errorf("invalid 12-hour time of day: %q, hour must be between 1 and 12", v)
+	}
+	hour %= 12
+	if pm {
+		hour += 12
+	}
+	return datetime.NewTimeOfDay(hour, tod.Minute(), tod.Second()), nil
+}
+
 // ParseAction parses a time of day that may contain
 // a dynamic time of day function with a +- delta. Valid dynamic
-// time of day functions are defined by DailyDynamic.
+// time of day functions are defined by DailyDynamic. Literal times
+// of day may be in 24 or 12-hour notation, see ParseTimeOfDay.
 func ParseActionTime(v string) (datetime.TimeOfDay, datetime.DynamicTimeOfDay, time.Duration, error) {
-	var tod datetime.TimeOfDay
-	if err := tod.Parse(v); err == nil {
+	tod, err := ParseTimeOfDay(v)
+	if err == nil {
 		return tod, nil, 0, nil
 	}
+	if _, ok := twelveHourSuffix(v); ok {
+		return datetime.TimeOfDay(0), nil, 0, err
+	}
 	dyn, delta, err := parseFunctionAndDelta(v)
 	if err != nil {
 		return datetime.TimeOfDay(0), nil, 0, err
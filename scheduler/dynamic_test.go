@@ -0,0 +1,77 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"cloudeng.io/datetime"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+func TestParseTimeOfDay(t *testing.T) {
+	for _, tc := range []struct {
+		input string
+		want  datetime.TimeOfDay
+	}{
+		{"15:00", datetime.NewTimeOfDay(15, 0, 0)},
+		{"08:30:10", datetime.NewTimeOfDay(8, 30, 10)},
+		{"3:00pm", datetime.NewTimeOfDay(15, 0, 0)},
+		{"3:00 PM", datetime.NewTimeOfDay(15, 0, 0)},
+		{"12:00am", datetime.NewTimeOfDay(0, 0, 0)},
+		{"12:30am", datetime.NewTimeOfDay(0, 30, 0)},
+		{"12:00pm", datetime.NewTimeOfDay(12, 0, 0)},
+		{"11:59:30pm", datetime.NewTimeOfDay(23, 59, 30)},
+		{"1:05am", datetime.NewTimeOfDay(1, 5, 0)},
+	} {
+		got, err := scheduler.ParseTimeOfDay(tc.input)
+		if err != nil {
+			t.Errorf("%v: %v", tc.input, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%v: got %v, want %v", tc.input, got, tc.want)
+		}
+		literal, dyn, _, err := scheduler.ParseActionTime(tc.input)
+		if err != nil || dyn != nil || literal != tc.want {
+			t.Errorf("%v: got %v, %v, %v, want %v", tc.input, literal, dyn, err, tc.want)
+		}
+	}
+
+	for _, input := range []string{"13:00pm", "0:30am", "3pm", "3:00xm", "25:00", "3:00:00:00pm"} {
+		if _, err := scheduler.ParseTimeOfDay(input); err == nil {
+			t.Errorf("%v: expected an error", input)
+		}
+	}
+	_, _, _, err := scheduler.ParseActionTime("13:00pm")
+	if err == nil || !strings.Contains(err.Error(), "hour must be between 1 and 12") {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+}
+
+func TestTwelveHourSchedule(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	scheds, err := scheduler.ParseConfig(ctx, []byte(`
+schedules:
+  - name: lights
+    device: device
+    actions:
+      on: 7:30pm
+      off: 12:15am
+`), sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var times []string
+	for _, a := range lookupSchedule(t, scheds, "lights").DailyActions {
+		times = append(times, a.Name+"@"+a.Due.String())
+	}
+	if got, want := strings.Join(times, " "), "off@00:15:00 on@19:30:00"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"cloudeng.io/datetime/schedule"
+)
+
+// WithJitter delays each action by a random duration in [0, maxJitter) so that
+// actions that are due at the same time, eg. at sunset, are spread out
+// rather than all being issued simultaneously. The jitter never delays an
+// action past the end of its day or past a subsequent action that is
+// constrained to run before or after another. The time spent waiting for
+// a jittered action is not counted when determining if subsequent actions
+// are overdue. The source of randomness may be specified for testing
+// purposes, a randomly seeded source is used if src is nil. The jitter
+// applied to an action is recorded in its pending log record.
+func WithJitter(maxJitter time.Duration, src rand.Source) Option {
+	if maxJitter <= 0 {
+		return func(o *options) {
+			o.jitter = nil
+		}
+	}
+	if src == nil {
+		src = rand.NewPCG(rand.Uint64(), rand.Uint64())
+	}
+	j := &jitter{max: maxJitter, rnd: rand.New(src)}
+	return func(o *options) {
+		o.jitter = j
+	}
+}
+
+// jitter is shared by all of the schedulers created with the same
+// Option, as is its source of randomness, and hence must be safe for
+// concurrent use.
+type jitter struct {
+	mu  sync.Mutex
+	max time.Duration
+	rnd *rand.Rand
+}
+
+// next returns a random duration in [0, min(max, limit)).
+func (j *jitter) next(limit time.Duration) time.Duration {
+	limit = min(j.max, limit)
+	if limit <= 0 {
+		return 0
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return time.Duration(j.rnd.Float64() * float64(limit))
+}
+
+// jitterFor returns the jitter to apply to the i'th action in the supplied
+// list of actions for the day.
+func (s *Scheduler) jitterFor(actions []schedule.Active[Action], i int) time.Duration {
+	if s.jitter == nil {
+		return 0
+	}
+	due := actions[i].When
+	y, m, d := due.Date()
+	limit := time.Date(y, m, d+1, 0, 0, 0, 0, due.Location()).Sub(due)
+	for _, next := range actions[i+1:] {
+		if next.T.Ordered {
+			limit = min(limit, next.When.Sub(due))
+			break
+		}
+	}
+	return s.jitter.next(limit)
+}
+
+func laterOf(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
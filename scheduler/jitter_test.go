@@ -0,0 +1,174 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"cloudeng.io/datetime"
+	"github.com/cosnicolaou/automation/internal/logging"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+const jitterSchedule = `
+schedules:
+  - name: jittered
+    device: device
+    ranges:
+      - 01/02:01/02
+    actions:
+      a: 12:00:00
+      b: 23:59:59
+    actions_detailed:
+      - action: another
+        when: 12:00:01
+        before: off
+      - action: off
+        when: 12:00:01
+`
+
+// maxSource always returns the largest possible value and hence results
+// in the largest possible jitter.
+type maxSource struct{}
+
+func (maxSource) Uint64() uint64 { return ^uint64(0) }
+
+func pendingJitter(t *testing.T, lines []string) (map[string]time.Duration, map[string]time.Time) {
+	jitter, due := map[string]time.Duration{}, map[string]time.Time{}
+	for _, l := range lines {
+		var e struct {
+			Msg    string        `json:"msg"`
+			Op     string        `json:"op"`
+			Due    time.Time     `json:"due"`
+			Jitter time.Duration `json:"jitter"`
+		}
+		if err := json.Unmarshal([]byte(l), &e); err != nil {
+			t.Fatal(err)
+		}
+		if e.Msg == logging.LogPending && len(e.Op) > 0 {
+			if _, ok := jitter[e.Op]; ok {
+				t.Errorf("duplicate pending record for %v", e.Op)
+			}
+			jitter[e.Op], due[e.Op] = e.Jitter, e.Due
+		}
+	}
+	return jitter, due
+}
+
+func TestJitter(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "UTC")
+	sched := parseSchedule(t, sys, jitterSchedule)
+
+	maxJitter := 1500 * time.Millisecond
+	_, logRecorder := runScheduleForYear(ctx, t, sys, sched, 2024,
+		scheduler.WithJitter(maxJitter, maxSource{}))
+	jitter, due := pendingJitter(t, logRecorder.Lines())
+	for _, tc := range []struct {
+		op       string
+		min, max time.Duration
+	}{
+		// Bounded by another, which is constrained to run before off.
+		{"a", 900 * time.Millisecond, time.Second},
+		// Bounded by off which is due at the same time.
+		{"another", 0, 0},
+		{"off", maxJitter - 10*time.Millisecond, maxJitter},
+		// Bounded by the end of the day.
+		{"b", 900 * time.Millisecond, time.Second},
+	} {
+		got, ok := jitter[tc.op]
+		if !ok {
+			t.Errorf("%v: missing pending record", tc.op)
+			continue
+		}
+		if got < tc.min || got > tc.max || (tc.max > 0 && got == tc.max) {
+			t.Errorf("%v: got %v, want [%v, %v)", tc.op, got, tc.min, tc.max)
+		}
+	}
+	// The nominal due time is logged.
+	if got, want := due["b"], time.Date(2024, 1, 2, 23, 59, 59, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// No jitter is recorded by default.
+	_, logRecorder = runScheduleForYear(ctx, t, sys, sched, 2024)
+	for _, l := range logRecorder.Lines() {
+		var e map[string]any
+		if err := json.Unmarshal([]byte(l), &e); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := e["jitter"]; ok {
+			t.Errorf("unexpected jitter: %v", l)
+		}
+	}
+}
+
+const coScheduledJitterSchedule = `
+schedules:
+  - name: jittered
+    device: device
+    actions:
+      on: 00:00
+      off: 00:00
+`
+
+func TestJitterCoScheduled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sys := createSystem(t, "Local")
+	logRecorder := newRecorder()
+	logger := slog.New(slog.NewJSONHandler(logRecorder, nil))
+
+	now := time.Now().In(sys.Location.TimeLocation)
+	today := datetime.DateFromTime(now)
+	sched := parseSchedule(t, sys, coScheduledJitterSchedule)
+	sched.Dates.Ranges = []datetime.DateRange{datetime.NewDateRange(today, today)}
+	due := now.Add(time.Second).Truncate(time.Second)
+	for i := range sched.DailyActions {
+		sched.DailyActions[i].Due = datetime.TimeOfDayFromTime(due)
+	}
+
+	// The jitter for both actions is larger than the overdue grace and
+	// the second action must not be skipped as overdue as a result of
+	// waiting for the jitter applied to the first.
+	maxJitter := time.Millisecond * 300
+	s := createScheduler(t, sys, sched,
+		scheduler.WithLogger(logger),
+		scheduler.WithOperationWriter(newRecorder()),
+		scheduler.WithOverdueGrace(time.Millisecond*50),
+		scheduler.WithJitter(maxJitter, maxSource{}))
+
+	go func() {
+		time.Sleep(time.Until(due) + maxJitter*3)
+		cancel()
+	}()
+	if err := s.RunYear(ctx, datetime.NewCalendarDate(now.Year(), 1, 1)); err != nil && !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+
+	var completed []string
+	for _, l := range logRecorder.Lines() {
+		e, err := logging.ParseLogLine(l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch e.Msg {
+		case logging.LogCompleted:
+			completed = append(completed, e.Op)
+		case logging.LogTooLate:
+			t.Errorf("unexpected overdue action: %v", l)
+		}
+	}
+	slices.Sort(completed)
+	if got, want := completed, []string{"off", "on"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloudeng.io/datetime"
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/internal/logging"
+)
+
+// LastOpCondition is the name of a built-in condition that can be used
+// in a precondition, or postcondition, for any device or controller
+// without being configured for it. It is true if the most recent
+// operation successfully completed on that device, by any schedule, was
+// the operation named by its single argument, eg:
+//
+//	precondition:
+//	  device: porch
+//	  op: last_op
+//	  args: ["on"]
+//
+// Negation, ie. "!last_op", is supported. The lookback window is the
+// calendar day on which the action being run is due, ie. from midnight
+// in the schedule's time zone until the time of evaluation, so that
+// operations from previous days are never considered. Operations that
+// failed, or were skipped due to a precondition, are ignored. The
+// condition consults the status recorder provided via WithStatusRecorder
+// and returns an error if there is none.
+const LastOpCondition = "last_op"
+
+// ErrNoStatusRecorder is returned by the LastOpCondition condition when
+// the scheduler was not configured with a status recorder.
+var ErrNoStatusRecorder = errors.New("no status recorder")
+
+type statusRecorderKey struct{}
+
+func withStatusRecorder(ctx context.Context, sr *logging.StatusRecorder) context.Context {
+	if sr == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, statusRecorderKey{}, sr)
+}
+
+func statusRecorderFromContext(ctx context.Context) *logging.StatusRecorder {
+	sr, _ := ctx.Value(statusRecorderKey{}).(*logging.StatusRecorder)
+	return sr
+}
+
+func isLastOpCondition(op string) bool {
+	return strings.TrimPrefix(op, "!") == LastOpCondition
+}
+
+// lastOp returns the most recent operation successfully completed on
+// the specified device on the same day as, and before, due.
+func lastOp(sr *logging.StatusRecorder, device string, due time.Time) (string, bool) {
+	day := datetime.CalendarDateFromTime(due)
+	for rec := range sr.CompletedRecent() {
+		if rec.Device != device || rec.Aborted() || rec.Error != nil {
+			continue
+		}
+		recDue := rec.Due.In(due.Location())
+		if !recDue.Before(due) || datetime.CalendarDateFromTime(recDue) != day {
+			continue
+		}
+		return rec.Op, true
+	}
+	return "", false
+}
+
+// newLastOpCondition returns the implementation of LastOpCondition, or
+// its negation, for the specified device.
+func newLastOpCondition(device, op string) devices.Condition {
+	negated := strings.HasPrefix(op, "!")
+	return func(ctx context.Context, opts devices.OperationArgs) (any, bool, error) {
+		if len(opts.Args) != 1 {
+			return nil, false, fmt.Errorf("%v: expected a single operation name as an argument", LastOpCondition)
+		}
+		sr := statusRecorderFromContext(ctx)
+		if sr == nil {
+			return nil, false, fmt.Errorf("%v: %w", LastOpCondition, ErrNoStatusRecorder)
+		}
+		last, _ := lastOp(sr, device, opts.Due)
+		return last, (last == opts.Args[0]) != negated, nil
+	}
+}
+
+// parseLastOpCondition validates a reference to LastOpCondition.
+func parseLastOpCondition(sys devices.System, c condition) (Precondition, error) {
+	_, isDevice := sys.Devices[c.Device]
+	_, isController := sys.Controllers[c.Device]
+	if !isDevice && !isController {
+		return Precondition{}, fmt.Errorf("unknown device or controller: %q for %v", c.Device, LastOpCondition)
+	}
+	if len(c.Args) != 1 {
+		return Precondition{}, fmt.Errorf("%v for device: %q requires a single operation name as an argument", LastOpCondition, c.Device)
+	}
+	return Precondition{
+		Device:    c.Device,
+		Name:      c.Op,
+		Condition: newLastOpCondition(c.Device, c.Op),
+		Args:      c.Args,
+	}, nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/automation/internal/logging"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+const lastOpSchedule = `
+schedules:
+  - name: last-op
+    device: device
+    ranges:
+      - 01/02:01/03
+    actions_detailed:
+      - action: b
+        when: 11:00
+        precondition:
+          device: device
+          op: last_op
+          args: ["a"]
+      - action: on
+        when: 12:00
+      - action: off
+        when: 13:00
+        precondition:
+          device: device
+          op: last_op
+          args: ["on"]
+      - action: c
+        when: 14:00
+        precondition:
+          device: device
+          op: last_op
+          args: ["on"]
+      - action: a
+        when: 15:00
+        precondition:
+          device: device
+          op: "!last_op"
+          args: ["on"]
+`
+
+func TestLastOpCondition(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	sched := parseSchedule(t, sys, lastOpSchedule)
+
+	sr := logging.NewStatusRecorder()
+	runScheduleForYear(ctx, t, sys, sched, 2024, scheduler.WithStatusRecorder(sr))
+
+	var got []string
+	for rec := range sr.Completed() {
+		got = append(got, fmt.Sprintf("%v %v %v %v", rec.Due.Format("01/02 15:04"), rec.Op, rec.Status(), rec.ErrorMessage()))
+	}
+	// The action 'b' is always aborted since 'a' is only ever run on the
+	// previous day, which is outside of the lookback window.
+	want := []string{
+		"01/02 11:00 b aborted ",
+		"01/02 12:00 on completed ",
+		"01/02 13:00 off completed ",
+		"01/02 14:00 c aborted ",
+		"01/02 15:00 a completed ",
+		"01/03 11:00 b aborted ",
+		"01/03 12:00 on completed ",
+		"01/03 13:00 off completed ",
+		"01/03 14:00 c aborted ",
+		"01/03 15:00 a completed ",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Without a status recorder the condition fails.
+	_, logRecorder := runScheduleForYear(ctx, t, sys, sched, 2024)
+	failed := 0
+	for _, l := range logRecorder.Logs(t) {
+		if l.Msg != logging.LogFailed {
+			continue
+		}
+		failed++
+		if err := l.Err; err == nil || !strings.Contains(err.Error(), scheduler.ErrNoStatusRecorder.Error()) {
+			t.Errorf("missing or unexpected error: %v", err)
+		}
+	}
+	if got, want := failed, 8; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestLastOpConditionErrors(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	for _, tc := range []struct {
+		device, args, err string
+	}{
+		{"unknown", `["on"]`, `unknown device or controller: "unknown" for last_op`},
+		{"device", `[]`, `last_op for device: "device" requires a single operation name as an argument`},
+		{"device", `["on", "off"]`, `last_op for device: "device" requires a single operation name as an argument`},
+	} {
+		_, err := scheduler.ParseConfig(ctx, []byte(fmt.Sprintf(`
+schedules:
+  - name: invalid
+    device: device
+    actions_detailed:
+      - action: on
+        when: 12:00
+        precondition:
+          device: %v
+          op: last_op
+          args: %v
+`, tc.device, tc.args)), sys)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%v: missing or unexpected error: %v", tc.device, err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"cloudeng.io/datetime"
+)
+
+// ControllerLoad summarizes the load that the schedules in a calendar
+// will place on a single controller over the course of a year, that is,
+// the total number of operations issued to the devices attached to it
+// and the peak number issued at the same instant, within any one minute
+// and within any one hour, along with the start of the first period in
+// which each peak occurs. Devices that are not attached to a controller
+// are reported under an empty controller name.
+type ControllerLoad struct {
+	Controller       string    `json:"controller"`
+	Actions          int       `json:"actions"`
+	PeakConcurrent   int       `json:"peak_concurrent"`
+	PeakConcurrentAt time.Time `json:"peak_concurrent_at"`
+	PeakPerMinute    int       `json:"peak_per_minute"`
+	PeakMinuteAt     time.Time `json:"peak_minute_at"`
+	PeakPerHour      int       `json:"peak_per_hour"`
+	PeakHourAt       time.Time `json:"peak_hour_at"`
+}
+
+// peakWithin returns the maximum number of the supplied, sorted, times
+// that fall within any window of the specified duration along with
+// the first time in that window.
+func peakWithin(times []time.Time, window time.Duration) (int, time.Time) {
+	peak, at, start := 0, time.Time{}, 0
+	for end, t := range times {
+		for t.Sub(times[start]) >= window {
+			start++
+		}
+		if n := end - start + 1; n > peak {
+			peak, at = n, times[start]
+		}
+	}
+	return peak, at
+}
+
+// LoadProfile returns the load that the schedules in the calendar will
+// place on each controller during the specified year, ordered by
+// controller name.
+func LoadProfile(cal *Calendar, year int) []ControllerLoad {
+	wholeYear := datetime.NewDateRange(datetime.NewDate(1, 1), datetime.NewDate(12, 31))
+	perController := map[string][]time.Time{}
+	for _, s := range cal.schedulers {
+		yp := datetime.YearPlace{Year: year, Place: s.place}
+		for perDay := range s.schedule.scheduled(s.scheduler, yp, wholeYear) {
+			for active := range perDay.Active(s.place) {
+				var ctrl string
+				switch {
+				case active.T.OnController:
+					ctrl = active.T.DeviceName
+				case active.T.Device != nil:
+					ctrl = active.T.Device.ControlledByName()
+				}
+				perController[ctrl] = append(perController[ctrl], active.When)
+			}
+		}
+	}
+	loads := make([]ControllerLoad, 0, len(perController))
+	for ctrl, times := range perController {
+		slices.SortFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+		cl := ControllerLoad{Controller: ctrl, Actions: len(times)}
+		cl.PeakConcurrent, cl.PeakConcurrentAt = peakWithin(times, time.Nanosecond)
+		cl.PeakPerMinute, cl.PeakMinuteAt = peakWithin(times, time.Minute)
+		cl.PeakPerHour, cl.PeakHourAt = peakWithin(times, time.Hour)
+		loads = append(loads, cl)
+	}
+	slices.SortFunc(loads, func(a, b ControllerLoad) int {
+		return cmp.Compare(a.Controller, b.Controller)
+	})
+	return loads
+}
@@ -0,0 +1,124 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+const loadProfileSystem = `
+time_location: UTC
+controllers:
+  - name: busy
+    type: controller
+  - name: quiet
+    type: controller
+devices:
+  - name: device
+    type: device
+    controller: busy
+    operations:
+      a:
+      b:
+      c:
+      d:
+  - name: other
+    type: device
+    controller: quiet
+    operations:
+      on:
+`
+
+const loadProfileSchedules = `
+schedules:
+  - name: clustered
+    device: device
+    ranges:
+      - 01/02:01/03
+    actions:
+      a: 12:00
+      b: 12:00
+      c: 12:00
+      d: 12:00
+  - name: later
+    device: device
+    ranges:
+      - 01/03:01/03
+    actions_detailed:
+      - action: a
+        when: 12:00:30
+      - action: b
+        when: 12:00:59
+      - action: c
+        when: 12:30
+      - action: d
+        when: 13:00
+  - name: quiet
+    device: other
+    ranges:
+      - 02/01:02/01
+    actions:
+      on: 08:00
+`
+
+func TestLoadProfile(t *testing.T) {
+	ctx := context.Background()
+	sys, err := devices.ParseSystemConfig(ctx, []byte(loadProfileSystem),
+		devices.WithDevices(supportedDevices),
+		devices.WithControllers(supportedControllers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheds, err := scheduler.ParseConfig(ctx, []byte(loadProfileSchedules), sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal, err := scheduler.NewCalendar(scheds, sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loads := scheduler.LoadProfile(cal, 2025)
+	if got, want := len(loads), 2; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2025, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	for i, want := range []scheduler.ControllerLoad{
+		{
+			Controller:       "busy",
+			Actions:          12,
+			PeakConcurrent:   4,
+			PeakConcurrentAt: at(1, 2, 12, 0),
+			PeakPerMinute:    6,
+			PeakMinuteAt:     at(1, 3, 12, 0),
+			PeakPerHour:      7,
+			PeakHourAt:       at(1, 3, 12, 0),
+		},
+		{
+			Controller:       "quiet",
+			Actions:          1,
+			PeakConcurrent:   1,
+			PeakConcurrentAt: at(2, 1, 8, 0),
+			PeakPerMinute:    1,
+			PeakMinuteAt:     at(2, 1, 8, 0),
+			PeakPerHour:      1,
+			PeakHourAt:       at(2, 1, 8, 0),
+		},
+	} {
+		got := loads[i]
+		if got.Controller != want.Controller || got.Actions != want.Actions ||
+			got.PeakConcurrent != want.PeakConcurrent || !got.PeakConcurrentAt.Equal(want.PeakConcurrentAt) ||
+			got.PeakPerMinute != want.PeakPerMinute || !got.PeakMinuteAt.Equal(want.PeakMinuteAt) ||
+			got.PeakPerHour != want.PeakPerHour || !got.PeakHourAt.Equal(want.PeakHourAt) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	}
+}
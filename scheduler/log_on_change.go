@@ -0,0 +1,83 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// heldRecords is a slog.Handler that holds on to all of the records
+// it is given until flush is called, at which point they are written,
+// with their original times, to the underlying handler. Records that
+// are never flushed are discarded. The handlers returned by WithAttrs
+// and WithGroup share the held records with the handler they were
+// derived from so that flushing any one of them flushes all of the
+// records in the order that they were logged.
+type heldRecords struct {
+	handler slog.Handler
+	held    *held
+}
+
+type held struct {
+	mu      sync.Mutex
+	records []heldRecord
+}
+
+type heldRecord struct {
+	handler slog.Handler
+	record  slog.Record
+}
+
+func newHeldRecords(h slog.Handler) *heldRecords {
+	return &heldRecords{handler: h, held: &held{}}
+}
+
+func (h *heldRecords) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *heldRecords) Handle(_ context.Context, r slog.Record) error {
+	h.held.mu.Lock()
+	defer h.held.mu.Unlock()
+	h.held.records = append(h.held.records, heldRecord{h.handler, r.Clone()})
+	return nil
+}
+
+func (h *heldRecords) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &heldRecords{handler: h.handler.WithAttrs(attrs), held: h.held}
+}
+
+func (h *heldRecords) WithGroup(name string) slog.Handler {
+	return &heldRecords{handler: h.handler.WithGroup(name), held: h.held}
+}
+
+func (h *heldRecords) flush(ctx context.Context) {
+	h.held.mu.Lock()
+	defer h.held.mu.Unlock()
+	for _, r := range h.held.records {
+		_ = r.handler.Handle(ctx, r.record)
+	}
+	h.held.records = nil
+}
+
+// resultChanged records the result of the latest invocation of action
+// and returns true if it differs from that of the previous invocation.
+// The first invocation is always considered to be a change.
+func (s *Scheduler) resultChanged(action Action, result any, aborted bool, err error) bool {
+	key := action.DeviceName + "." + action.Name + "(" + strings.Join(action.Args, ",") + ")"
+	val := fmt.Sprintf("%v|%v|%v", result, aborted, err)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lastResults == nil {
+		s.lastResults = map[string]string{}
+	}
+	prev, ok := s.lastResults[key]
+	s.lastResults[key] = val
+	return !ok || prev != val
+}
@@ -0,0 +1,188 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"cloudeng.io/datetime"
+)
+
+// LunarPhase represents a phase of the moon.
+type LunarPhase int
+
+const (
+	NewMoon LunarPhase = iota
+	FullMoon
+)
+
+// LunarDynamic defines the names of the lunar phases that may be
+// used to select the dates on which a schedule applies.
+var LunarDynamic = map[string]LunarPhase{
+	"new-moon":  NewMoon,
+	"full-moon": FullMoon,
+}
+
+func (lp LunarPhase) String() string {
+	switch lp {
+	case NewMoon:
+		return "new-moon"
+	case FullMoon:
+		return "full-moon"
+	}
+	return fmt.Sprintf("unknown(%d)", int(lp))
+}
+
+const (
+	synodicMonth = 29.530588861
+	unixEpochJD  = 2440587.5
+)
+
+func deg(d float64) float64 {
+	return d * math.Pi / 180
+}
+
+// phaseJDE returns the Julian Ephemeris Day of the lunar phase
+// for the specified lunation number, k, where k = 0 corresponds to
+// the new moon of January 6th, 2000. The algorithm, including its
+// principal periodic terms, is from chapter 49 of Jean Meeus'
+// Astronomical Algorithms and is accurate to within a few minutes.
+func (lp LunarPhase) phaseJDE(k float64) float64 {
+	if lp == FullMoon {
+		k += 0.5
+	}
+	t := k / 1236.85
+	t2, t3, t4 := t*t, t*t*t, t*t*t*t
+	jde := 2451550.09766 + synodicMonth*k + 0.00015437*t2 - 0.000000150*t3 + 0.00000000073*t4
+	e := 1 - 0.002516*t - 0.0000074*t2
+	m := deg(2.5534 + 29.10535670*k - 0.0000014*t2 - 0.00000011*t3)
+	mp := deg(201.5643 + 385.81693528*k + 0.0107582*t2 + 0.00001238*t3 - 0.000000058*t4)
+	f := deg(160.7108 + 390.67050284*k - 0.0016118*t2 - 0.00000227*t3 + 0.000000011*t4)
+	om := deg(124.7746 - 1.56375588*k + 0.0020672*t2 + 0.00000215*t3)
+	c := []float64{-0.40720, 0.17241, 0.01608, 0.01039, 0.00739, -0.00514, 0.00208}
+	if lp == FullMoon {
+		c = []float64{-0.40614, 0.17302, 0.01614, 0.01043, 0.00734, -0.00515, 0.00209}
+	}
+	jde += c[0]*math.Sin(mp) +
+		c[1]*e*math.Sin(m) +
+		c[2]*math.Sin(2*mp) +
+		c[3]*math.Sin(2*f) +
+		c[4]*e*math.Sin(mp-m) +
+		c[5]*e*math.Sin(mp+m) +
+		c[6]*e*e*math.Sin(2*m) -
+		0.00111*math.Sin(mp-2*f) -
+		0.00057*math.Sin(mp+2*f) +
+		0.00056*e*math.Sin(2*mp+m) -
+		0.00042*math.Sin(3*mp) +
+		0.00042*e*math.Sin(m+2*f) +
+		0.00038*e*math.Sin(m-2*f) -
+		0.00024*e*math.Sin(2*mp-m) -
+		0.00017*math.Sin(om)
+	return jde
+}
+
+func jdeToTime(jde float64) time.Time {
+	secs := (jde - unixEpochJD) * 86400
+	return time.Unix(0, int64(secs*1e9)).UTC()
+}
+
+func lunation(t time.Time) float64 {
+	jd := float64(t.Unix())/86400 + unixEpochJD
+	return math.Floor((jd - 2451550.09766) / synodicMonth)
+}
+
+// Times returns the times, in UTC, at which the lunar phase occurs
+// during the specified year, in UTC.
+func (lp LunarPhase) Times(year int) []time.Time {
+	start := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+	var times []time.Time
+	for k := lunation(start) - 1; ; k++ {
+		t := jdeToTime(lp.phaseJDE(k))
+		if !t.Before(end) {
+			break
+		}
+		if !t.Before(start) {
+			times = append(times, t)
+		}
+	}
+	return times
+}
+
+// LunarPhases represents a set of lunar phases and a tolerance, in days,
+// either side of the date on which each phase occurs. An empty set
+// includes all dates.
+type LunarPhases struct {
+	Phases    []LunarPhase
+	Tolerance int
+}
+
+// ParseLunarPhases parses a comma separated list of lunar phase names,
+// as defined by LunarDynamic, eg. "full-moon,new-moon". An empty string
+// results in an empty set.
+func ParseLunarPhases(val string, tolerance int) (LunarPhases, error) {
+	if len(strings.TrimSpace(val)) == 0 {
+		return LunarPhases{}, nil
+	}
+	if tolerance < 0 {
+		return LunarPhases{}, fmt.Errorf("lunar phase tolerance must be zero or greater: %v", tolerance)
+	}
+	lp := LunarPhases{Tolerance: tolerance}
+	for _, p := range strings.Split(strings.ReplaceAll(val, " ", ""), ",") {
+		phase, ok := LunarDynamic[strings.ToLower(p)]
+		if !ok {
+			return LunarPhases{}, fmt.Errorf("unknown lunar phase: %q", p)
+		}
+		if !slices.Contains(lp.Phases, phase) {
+			lp.Phases = append(lp.Phases, phase)
+		}
+	}
+	slices.Sort(lp.Phases)
+	return lp, nil
+}
+
+// Include returns true if the specified date is within the tolerance
+// of the date, at the specified place, on which any of the phases
+// occurs, or if the set is empty.
+func (lp LunarPhases) Include(cd datetime.CalendarDate, place datetime.Place) bool {
+	if len(lp.Phases) == 0 {
+		return true
+	}
+	loc := place.TimeLocation
+	if loc == nil {
+		loc = time.UTC
+	}
+	day := time.Date(cd.Year(), time.Month(cd.Month()), cd.Day(), 12, 0, 0, 0, loc)
+	k := lunation(day)
+	for _, phase := range lp.Phases {
+		for _, d := range []float64{-1, 0, 1} {
+			pt := jdeToTime(phase.phaseJDE(k + d)).In(loc)
+			pd := time.Date(pt.Year(), pt.Month(), pt.Day(), 12, 0, 0, 0, loc)
+			days := int(math.Round(math.Abs(day.Sub(pd).Hours()) / 24))
+			if days <= lp.Tolerance {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (lp LunarPhases) String() string {
+	var out strings.Builder
+	for i, p := range lp.Phases {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(p.String())
+	}
+	if lp.Tolerance > 0 {
+		fmt.Fprintf(&out, " (+/- %v days)", lp.Tolerance)
+	}
+	return out.String()
+}
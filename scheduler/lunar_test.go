@@ -0,0 +1,98 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"cloudeng.io/datetime"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+// Full moons in 2024, UTC, as published by the US Naval Observatory.
+var fullMoons2024 = []string{
+	"2024-01-25 17:54",
+	"2024-02-24 12:30",
+	"2024-03-25 07:00",
+	"2024-04-23 23:49",
+	"2024-05-23 13:53",
+	"2024-06-22 01:08",
+	"2024-07-21 10:17",
+	"2024-08-19 18:26",
+	"2024-09-18 02:34",
+	"2024-10-17 11:26",
+	"2024-11-15 21:28",
+	"2024-12-15 09:02",
+}
+
+func TestLunarPhaseTimes(t *testing.T) {
+	times := scheduler.FullMoon.Times(2024)
+	if got, want := len(times), len(fullMoons2024); got != want {
+		t.Fatalf("got %v, want %v: %v", got, want, times)
+	}
+	for i, ref := range fullMoons2024 {
+		want, err := time.Parse("2006-01-02 15:04", ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := times[i].Sub(want).Abs(); diff > 10*time.Minute {
+			t.Errorf("%v: got %v, want %v (diff %v)", i, times[i], want, diff)
+		}
+	}
+}
+
+const lunarSchedule = `
+schedules:
+  - name: lunar
+    device: device
+    ranges:
+      - 01/01:12/31
+    lunar_phases: full-moon
+    lunar_phase_tolerance: %v
+    actions:
+      on: 22:00
+`
+
+func TestLunarPhaseSchedule(t *testing.T) {
+	sys := createSystem(t, "UTC")
+	scheduledDates := func(tolerance int) []string {
+		sched := parseSchedule(t, sys, fmt.Sprintf(lunarSchedule, tolerance))
+		s := createScheduler(t, sys, sched)
+		var dates []string
+		for day := range s.ScheduledYearEnd(datetime.NewCalendarDate(2024, 1, 1)) {
+			dates = append(dates, fmt.Sprintf("2024-%02d-%02d", day.Date.Month(), day.Date.Day()))
+		}
+		return dates
+	}
+
+	var want []string
+	for _, ref := range fullMoons2024 {
+		want = append(want, ref[:len("2024-01-25")])
+	}
+	if got := scheduledDates(0); !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	got := scheduledDates(1)
+	if got, want := len(got), 3*len(fullMoons2024); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, w := range want {
+		if !slices.Contains(got, w) {
+			t.Errorf("missing %v", w)
+		}
+	}
+	if !slices.Contains(got, "2024-01-24") || !slices.Contains(got, "2024-01-26") {
+		t.Errorf("tolerance not applied: %v", got)
+	}
+
+	_, err := scheduler.ParseLunarPhases("blue-moon", 0)
+	if err == nil {
+		t.Errorf("expected an error for an unknown lunar phase")
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"cloudeng.io/datetime/schedule"
+)
+
+// Notification describes an action that failed or was aborted because
+// its precondition was not met.
+type Notification struct {
+	Schedule string
+	Device   string
+	Op       string
+	Args     []string
+	Due      time.Time
+	Aborted  bool
+	Err      error
+}
+
+// Notifier is implemented by notification backends, eg. email or SMS.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// Notifiers is a registry of named Notifiers from which a schedule
+// selects the one to use via its notify: configuration.
+type Notifiers map[string]Notifier
+
+// AvailableNotifiers is the registry of notifiers used when the
+// WithNotifiers option is not supplied.
+var AvailableNotifiers = Notifiers{}
+
+// WithNotifiers sets the registry of notifiers that schedules may select from.
+func WithNotifiers(n Notifiers) Option {
+	return func(o *options) {
+		o.notifiers = n
+	}
+}
+
+func (s *Scheduler) notify(ctx context.Context, a schedule.Active[Action], aborted bool, err error) {
+	if s.notifier == nil || s.dryRun || (!aborted && err == nil) {
+		return
+	}
+	n := Notification{
+		Schedule: s.schedule.Name,
+		Device:   a.T.DeviceName,
+		Op:       a.T.Name,
+		Args:     a.T.Args,
+		Due:      a.When,
+		Aborted:  aborted,
+		Err:      err,
+	}
+	if nerr := s.notifier.Notify(ctx, n); nerr != nil {
+		s.logger.Warn("failed to send notification", "notifier", s.schedule.Notify, "device", a.T.DeviceName, "op", a.T.Name, "err", nerr)
+	}
+}
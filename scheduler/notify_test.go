@@ -0,0 +1,96 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+type fakeNotifier struct {
+	mu            sync.Mutex
+	notifications []scheduler.Notification
+}
+
+func (fn *fakeNotifier) Notify(_ context.Context, n scheduler.Notification) error {
+	fn.mu.Lock()
+	defer fn.mu.Unlock()
+	fn.notifications = append(fn.notifications, n)
+	return nil
+}
+
+func (fn *fakeNotifier) events() []string {
+	fn.mu.Lock()
+	defer fn.mu.Unlock()
+	var events []string
+	for _, n := range fn.notifications {
+		events = append(events, n.Schedule+":"+n.Device+"."+n.Op)
+	}
+	return events
+}
+
+const notifySchedules = `
+schedules:
+  - name: security
+    device: slow
+    notify: sms
+    ranges:
+      - 01/02:01/02
+    actions:
+      on: 12:00
+  - name: garden
+    device: slow
+    notify: email
+    ranges:
+      - 01/02:01/02
+    actions:
+      on: 13:00
+  - name: lights
+    device: device
+    notify: sms
+    ranges:
+      - 01/02:01/02
+    actions:
+      on: 14:00
+`
+
+func TestNotifiers(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	scheds, err := scheduler.ParseConfig(ctx, []byte(notifySchedules), sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := lookupSchedule(t, scheds, "garden").Notify, "email"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	sms, email := &fakeNotifier{}, &fakeNotifier{}
+	notifiers := scheduler.WithNotifiers(scheduler.Notifiers{"sms": sms, "email": email})
+	for _, sched := range scheds.Schedules {
+		runScheduleForYear(ctx, t, sys, sched, 2024, notifiers)
+	}
+
+	if got, want := strings.Join(sms.events(), " "), "security:slow.on"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := strings.Join(email.events(), " "), "garden:slow.on"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, n := range append(sms.notifications, email.notifications...) {
+		if n.Err == nil || n.Aborted {
+			t.Errorf("unexpected notification: %+v", n)
+		}
+	}
+
+	_, err = scheduler.New(lookupSchedule(t, scheds, "garden"), sys, scheduler.WithNotifiers(scheduler.Notifiers{"sms": sms}))
+	if err == nil || !strings.Contains(err.Error(), `unknown notifier: "email"`) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+)
+
+// WithOperationOutputInLog captures, in addition to writing it to the
+// operation writer, up to limit bytes of the output written by each
+// action's operation and attaches it to the action's completion log
+// record under the "output" key, so that what a device printed can be
+// correlated with the action that caused it. A limit of zero, the
+// default, disables capturing the output.
+func WithOperationOutputInLog(limit int) Option {
+	return func(o *options) {
+		o.opOutputLimit = limit
+	}
+}
+
+// truncatedSuffix is appended to captured output that exceeds the limit.
+const truncatedSuffix = "...[truncated]"
+
+// cappedBuffer retains at most limit bytes of the output written to it,
+// it is shared by all attempts to run an action.
+type cappedBuffer struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (cb *cappedBuffer) Write(p []byte) (int, error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if room := cb.limit - cb.buf.Len(); len(p) > room {
+		cb.buf.Write(p[:room])
+		cb.truncated = true
+		return len(p), nil
+	}
+	cb.buf.Write(p)
+	return len(p), nil
+}
+
+func (cb *cappedBuffer) String() string {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.truncated {
+		return cb.buf.String() + truncatedSuffix
+	}
+	return cb.buf.String()
+}
+
+type outputKey struct{}
+
+// withOutputCapture returns a context that carries a cappedBuffer if
+// output capture is enabled.
+func (s *Scheduler) withOutputCapture(ctx context.Context) (context.Context, *cappedBuffer) {
+	if s.opOutputLimit <= 0 {
+		return ctx, nil
+	}
+	cb := &cappedBuffer{limit: s.opOutputLimit}
+	return context.WithValue(ctx, outputKey{}, cb), cb
+}
+
+// opWriterFor returns the writer to be used by the operation invoked
+// with the supplied context.
+func (s *Scheduler) opWriterFor(ctx context.Context) io.Writer {
+	if cb, ok := ctx.Value(outputKey{}).(*cappedBuffer); ok {
+		return io.MultiWriter(s.opWriter, cb)
+	}
+	return s.opWriter
+}
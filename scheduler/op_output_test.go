@@ -0,0 +1,69 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/cosnicolaou/automation/internal/logging"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+const opOutputSchedule = `
+schedules:
+  - name: output
+    device: device
+    ranges:
+      - 01/02:01/02
+    actions_detailed:
+      - action: on
+        when: 12:00
+        args: [a]
+      - action: off
+        when: 13:00
+        args: [a-very-long-argument, x]
+`
+
+func TestOperationOutputInLog(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	sched := parseSchedule(t, sys, opOutputSchedule)
+
+	outputs := func(logs []logging.Entry) map[string]string {
+		out := map[string]string{}
+		for _, l := range logs {
+			if l.Msg == logging.LogCompleted {
+				out[l.Op] = l.Output
+			}
+		}
+		return out
+	}
+
+	deviceRecorder, logRecorder := runScheduleForYear(ctx, t, sys, sched, 2024,
+		scheduler.WithOperationOutputInLog(40))
+	got := outputs(logRecorder.Logs(t))
+	if got, want := len(got), 2; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := got["on"], "device[device].On: [1] a\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := got["off"], "device[device].Off: [2] a-very-long-argu...[truncated]"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// The output is still written to the operation writer in full.
+	if got, want := deviceRecorder.Lines(), []string{"device[device].On: [1] a", "device[device].Off: [2] a-very-long-argument--x"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	_, logRecorder = runScheduleForYear(ctx, t, sys, sched, 2024)
+	for op, out := range outputs(logRecorder.Logs(t)) {
+		if len(out) != 0 {
+			t.Errorf("%v: unexpected output: %q", op, out)
+		}
+	}
+}
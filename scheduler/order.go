@@ -0,0 +1,50 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import "fmt"
+
+// OrderedAction describes one of a schedule's daily actions. Due is the
+// action's time of day, literal or dynamic, Before or After is set
+// to the action that it was constrained to run immediately before or
+// after, if any, and Priority to its configured priority.
+type OrderedAction struct {
+	Name     string `json:"name"`
+	Due      string `json:"due"`
+	Before   string `json:"before,omitempty"`
+	After    string `json:"after,omitempty"`
+	Priority int    `json:"priority,omitempty"`
+}
+
+// Constraint returns a description of the before or after constraint,
+// or non-zero priority, applied to the action, if any.
+func (oa OrderedAction) Constraint() string {
+	switch {
+	case len(oa.Before) > 0:
+		return "before " + oa.Before
+	case len(oa.After) > 0:
+		return "after " + oa.After
+	case oa.Priority != 0:
+		return fmt.Sprintf("priority %v", oa.Priority)
+	}
+	return ""
+}
+
+// ExecutionOrder returns the schedule's daily actions in the order in
+// which they are run when due at the same time, that is, once any
+// priorities and before and after constraints have been applied.
+func (a Annual) ExecutionOrder() []OrderedAction {
+	order := make([]OrderedAction, len(a.DailyActions))
+	for i, spec := range a.DailyActions {
+		order[i] = OrderedAction{
+			Name:     spec.Name,
+			Due:      formatDue(spec),
+			Before:   spec.T.Before,
+			After:    spec.T.After,
+			Priority: spec.T.Priority,
+		}
+	}
+	return order
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"context"
+	"sync"
+)
+
+// Pause is used to pause, and subsequently resume, all of the schedulers
+// that share it via WithPause. Actions that become due whilst paused are
+// held until scheduling is resumed, at which point they are run if they
+// are still within the overdue grace period (see WithOverdueGrace) and
+// are otherwise skipped.
+type Pause struct {
+	mu      sync.Mutex
+	resumed chan struct{} // nil when not paused.
+}
+
+// NewPause returns a new, unpaused, Pause.
+func NewPause() *Pause {
+	return &Pause{}
+}
+
+// Pause pauses scheduling, it has no effect if already paused.
+func (p *Pause) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resumed == nil {
+		p.resumed = make(chan struct{})
+	}
+}
+
+// Resume resumes scheduling, it has no effect if not paused.
+func (p *Pause) Resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resumed != nil {
+		close(p.resumed)
+		p.resumed = nil
+	}
+}
+
+// Paused returns true if scheduling is paused.
+func (p *Pause) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.resumed != nil
+}
+
+// wait blocks until scheduling is resumed or the context is canceled.
+func (p *Pause) wait(ctx context.Context) error {
+	p.mu.Lock()
+	ch := p.resumed
+	p.mu.Unlock()
+	if ch == nil {
+		return nil
+	}
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
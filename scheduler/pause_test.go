@@ -0,0 +1,139 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"cloudeng.io/datetime"
+	"github.com/cosnicolaou/automation/internal/logging"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+func logMessages(t *testing.T, r *recorder) map[string]int {
+	msgs := map[string]int{}
+	for _, l := range r.Lines() {
+		e, err := logging.ParseLogLine(l)
+		if err != nil {
+			t.Fatalf("failed to parse: %v: %v", l, err)
+		}
+		msgs[e.Msg]++
+	}
+	return msgs
+}
+
+func TestPause(t *testing.T) {
+	ctx := context.Background()
+	year := time.Now().Year()
+
+	for _, tc := range []struct {
+		grace time.Duration
+		ran   bool
+	}{
+		{time.Minute, true},
+		{100 * time.Millisecond, false},
+	} {
+		sys, spec := setupSchedules(t, "Local")
+		now := time.Now().In(sys.Location.TimeLocation)
+		today := datetime.DateFromTime(now)
+		sched := lookupSchedule(t, spec, "simple")
+		sched.Dates.Ranges = []datetime.DateRange{datetime.NewDateRange(today, today)}
+		sched.DailyActions = sched.DailyActions[:1]
+		dueAt := now.Add(time.Second).Truncate(time.Second)
+		sched.DailyActions[0].Due = datetime.TimeOfDayFromTime(dueAt)
+
+		deviceRecorder, logRecorder := newRecorder(), newRecorder()
+		pause := scheduler.NewPause()
+		s := createScheduler(t, sys, sched,
+			scheduler.WithLogger(slog.New(slog.NewJSONHandler(logRecorder, nil))),
+			scheduler.WithOperationWriter(deviceRecorder),
+			scheduler.WithOverdueGrace(tc.grace),
+			scheduler.WithPause(pause))
+
+		pause.Pause()
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- s.RunYear(ctx, datetime.NewCalendarDate(year, 1, 1))
+		}()
+
+		// Pause across the action's due time.
+		time.Sleep(time.Until(dueAt) + 500*time.Millisecond)
+		if got, want := len(deviceRecorder.Lines()), 0; got != want {
+			t.Errorf("grace %v: got %v, want %v", tc.grace, got, want)
+		}
+		if !pause.Paused() {
+			t.Errorf("grace %v: not paused", tc.grace)
+		}
+		pause.Resume()
+		if err := <-errCh; err != nil {
+			t.Fatal(err)
+		}
+
+		msgs := logMessages(t, logRecorder)
+		if got, want := msgs[logging.LogPaused], 1; got != want {
+			t.Errorf("grace %v: got %v, want %v", tc.grace, got, want)
+		}
+		ran, skipped := 0, 1
+		if tc.ran {
+			ran, skipped = 1, 0
+		}
+		if got, want := len(deviceRecorder.Lines()), ran; got != want {
+			t.Errorf("grace %v: got %v, want %v", tc.grace, got, want)
+		}
+		if got, want := msgs[logging.LogCompleted], ran; got != want {
+			t.Errorf("grace %v: got %v, want %v", tc.grace, got, want)
+		}
+		if got, want := msgs[logging.LogSkipped], skipped; got != want {
+			t.Errorf("grace %v: got %v, want %v", tc.grace, got, want)
+		}
+	}
+}
+
+func TestPauseTimeSource(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	sched := parseSchedule(t, sys, singleActionSchedule)
+
+	// The time at which scheduling is resumed is obtained from the
+	// time source.
+	for _, tc := range []struct {
+		resumed time.Duration
+		ran     bool
+	}{
+		{30 * time.Second, true},
+		{2 * time.Minute, false},
+	} {
+		ts := &timesource{ch: make(chan time.Time, 1)}
+		deviceRecorder, logRecorder, opts := newRecordersAndLogger(ts)
+		pause := scheduler.NewPause()
+		s := createScheduler(t, sys, sched, append(opts, scheduler.WithPause(pause))...)
+		year := 2024
+		_, times, ticks := allActive(s, year, time.Millisecond*5)
+		ticks = append(ticks, times[0].Add(tc.resumed))
+		_, ticks = appendYearEndTimesTicks(year, sys.Location.TimeLocation, times, ticks)
+
+		pause.Pause()
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			pause.Resume()
+		}()
+		runScheduler(ctx, t, s, year, ts, ticks)
+
+		msgs := logMessages(t, logRecorder)
+		ran, skipped := 0, 1
+		if tc.ran {
+			ran, skipped = 1, 0
+		}
+		if got, want := len(deviceRecorder.Lines()), ran; got != want {
+			t.Errorf("resumed %v: got %v, want %v", tc.resumed, got, want)
+		}
+		if got, want := msgs[logging.LogSkipped], skipped; got != want {
+			t.Errorf("resumed %v: got %v, want %v", tc.resumed, got, want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/internal/logging"
+	"github.com/cosnicolaou/automation/internal/testutil"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+// shadeDevice is a shade that only opens on the second attempt and
+// that never closes.
+type shadeDevice struct {
+	testutil.MockDevice
+	mu    sync.Mutex
+	opens int
+}
+
+func (sd *shadeDevice) Operations() map[string]devices.Operation {
+	return map[string]devices.Operation{
+		"open": func(context.Context, devices.OperationArgs) (any, error) {
+			sd.mu.Lock()
+			defer sd.mu.Unlock()
+			sd.opens++
+			return nil, nil
+		},
+		"close": func(context.Context, devices.OperationArgs) (any, error) {
+			return nil, nil
+		},
+	}
+}
+
+func (sd *shadeDevice) Conditions() map[string]devices.Condition {
+	return map[string]devices.Condition{
+		"opened": func(context.Context, devices.OperationArgs) (any, bool, error) {
+			sd.mu.Lock()
+			defer sd.mu.Unlock()
+			return nil, sd.opens >= 2, nil
+		},
+	}
+}
+
+func TestPostcondition(t *testing.T) {
+	ctx := context.Background()
+	sd := &shadeDevice{}
+	sys, err := devices.ParseSystemConfig(ctx, []byte(`
+time_location: Local
+devices:
+  - name: shade
+    type: shade
+    timeout: 1s
+    retries: 2
+    operations:
+      open:
+      close:
+    conditions:
+      opened:
+`), devices.WithDevices(devices.SupportedDevices{
+		"shade": func(string, devices.Options) (devices.Device, error) {
+			return sd, nil
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sched := parseSchedule(t, sys, `
+schedules:
+  - name: verified
+    device: shade
+    ranges:
+      - 01/02:01/02
+    actions_detailed:
+      - action: open
+        when: 12:00
+        postcondition:
+          device: shade
+          op: opened
+      - action: close
+        when: 13:00
+        postcondition:
+          device: shade
+          op: "!opened"
+`)
+	_, logRecorder := runScheduleForYear(ctx, t, sys, sched, 2024,
+		scheduler.WithRetrySleep(func(time.Duration) {}))
+
+	entries := map[string]logging.Entry{}
+	for _, l := range logRecorder.Logs(t) {
+		if len(l.Op) > 0 {
+			entries[l.Op] = l
+		}
+	}
+
+	// The shade opens on the second attempt.
+	if got, want := entries["open"].Msg, logging.LogCompleted; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := entries["open"].Attempts, 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// The shade never closes and all attempts fail.
+	if got, want := entries["close"].Msg, logging.LogFailed; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err := entries["close"].Err; err == nil || err.Error() != "failed after 3 attempts: postcondition not met: shade.!opened" {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+
+	postconditions := 0
+	for _, l := range logRecorder.Lines() {
+		if strings.Contains(l, `"msg":"postcondition"`) {
+			postconditions++
+		}
+	}
+	if got, want := postconditions, 5; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	_, err = scheduler.ParseConfig(ctx, []byte(`
+schedules:
+  - name: invalid
+    device: shade
+    actions_detailed:
+      - action: open
+        when: 12:00
+        postcondition:
+          device: shade
+          op: closed
+`), sys)
+	if err == nil || !strings.Contains(err.Error(), `unknown postcondition: "closed" for device: "shade"`) {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+}
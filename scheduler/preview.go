@@ -0,0 +1,39 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"slices"
+	"time"
+
+	"cloudeng.io/datetime"
+)
+
+// Upcoming returns the actions, across all of the calendar's schedules,
+// that would be due in the window starting at, and including, the
+// specified instant, treating that instant as the current time. The
+// entries are ordered by their due time. Nothing is run.
+func (c *Calendar) Upcoming(at time.Time, window time.Duration) []CalendarEntry {
+	at = at.In(c.place.TimeLocation)
+	end := at.Add(window)
+	var entries []CalendarEntry
+	// Schedules with their own time zone may have actions on the
+	// preceding or following day relative to the calendar's time zone.
+	last := datetime.CalendarDateFromTime(end).Tomorrow()
+	for day := datetime.CalendarDateFromTime(at.AddDate(0, 0, -1)); ; day = day.Tomorrow() {
+		for _, entry := range c.Scheduled(day) {
+			if !entry.When.Before(at) && entry.When.Before(end) {
+				entries = append(entries, entry)
+			}
+		}
+		if day >= last {
+			break
+		}
+	}
+	slices.SortStableFunc(entries, func(a, b CalendarEntry) int {
+		return a.When.Compare(b.When)
+	})
+	return entries
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+const previewSchedules = `
+schedules:
+  - name: porch
+    device: porch
+    ranges:
+      - 03/01:03/05
+    actions:
+      on: 18:00
+      off: 23:30
+  - name: garden
+    device: garden
+    ranges:
+      - 03/02:03/02
+    actions:
+      on: 06:00
+      off: 19:00
+`
+
+func TestUpcoming(t *testing.T) {
+	ctx := context.Background()
+	sys, err := devices.ParseSystemConfig(ctx, []byte(conflictSystem),
+		devices.WithDevices(supportedDevices))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheds, err := scheduler.ParseConfig(ctx, []byte(previewSchedules), sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal, err := scheduler.NewCalendar(scheds, sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upcoming := func(at time.Time, window time.Duration) []string {
+		var out []string
+		for _, e := range cal.Upcoming(at, window) {
+			out = append(out, fmt.Sprintf("%v %v.%v", e.When.Format("01/02 15:04"), e.Schedule, e.Name))
+		}
+		return out
+	}
+
+	at := time.Date(2025, 3, 1, 20, 0, 0, 0, time.UTC)
+	if got, want := upcoming(at, 24*time.Hour), []string{
+		"03/01 23:30 porch.off",
+		"03/02 06:00 garden.on",
+		"03/02 18:00 porch.on",
+		"03/02 19:00 garden.off",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// The window includes its start but not its end.
+	at = time.Date(2025, 3, 2, 18, 0, 0, 0, time.UTC)
+	if got, want := upcoming(at, time.Hour), []string{
+		"03/02 18:00 porch.on",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// The instant is interpreted in the system's location.
+	at = time.Date(2025, 3, 5, 15, 0, 0, 0, time.FixedZone("PST", -8*3600))
+	if got, want := upcoming(at, 48*time.Hour), []string{
+		"03/05 23:30 porch.off",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got := upcoming(time.Date(2025, 3, 6, 0, 0, 0, 0, time.UTC), 24*time.Hour); len(got) != 0 {
+		t.Errorf("unexpected actions: %v", got)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cosnicolaou/automation/devices"
+)
+
+// rateLimiter is a token bucket with a capacity of one token, that is,
+// it paces requests to be at least interval apart.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// Wait blocks until the next request is allowed or the context is canceled.
+// The returned reservation may be used to give back the request's slot if
+// it is not used, the slot is given back if the context is canceled.
+func (rl *rateLimiter) Wait(ctx context.Context) (reservation, error) {
+	rl.mu.Lock()
+	now := time.Now()
+	res := reservation{rl: rl, prev: rl.next}
+	at := rl.next
+	if at.Before(now) {
+		at = now
+	}
+	rl.next = at.Add(rl.interval)
+	res.next = rl.next
+	rl.mu.Unlock()
+	delay := at.Sub(now)
+	if delay <= 0 {
+		return res, nil
+	}
+	select {
+	case <-ctx.Done():
+		res.cancel()
+		return reservation{}, ctx.Err()
+	case <-time.After(delay):
+	}
+	return res, nil
+}
+
+// reservation represents a slot obtained from a rateLimiter.
+type reservation struct {
+	rl         *rateLimiter
+	prev, next time.Time
+}
+
+// cancel gives back the reserved slot, provided that no subsequent slot
+// has been reserved since, since otherwise the requests that reserved
+// those slots would need to be rescheduled.
+func (r reservation) cancel() {
+	if r.rl == nil {
+		return
+	}
+	r.rl.mu.Lock()
+	defer r.rl.mu.Unlock()
+	if r.rl.next.Equal(r.next) {
+		r.rl.next = r.prev
+	}
+}
+
+// controllerRateLimiters maintains a rateLimiter per controller that
+// has commands_per_minute configured and is shared by all of the
+// schedulers created by RunSchedulers.
+type controllerRateLimiters struct {
+	mu       sync.Mutex
+	limiters map[string]*rateLimiter
+}
+
+func newControllerRateLimiters() *controllerRateLimiters {
+	return &controllerRateLimiters{limiters: map[string]*rateLimiter{}}
+}
+
+func (cl *controllerRateLimiters) forController(ctrl devices.Controller) *rateLimiter {
+	if ctrl == nil {
+		return nil
+	}
+	cfg := ctrl.Config()
+	if cfg.CommandsPerMinute <= 0 {
+		return nil
+	}
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	rl, ok := cl.limiters[cfg.Name]
+	if !ok {
+		rl = &rateLimiter{interval: time.Minute / time.Duration(cfg.CommandsPerMinute)}
+		cl.limiters[cfg.Name] = rl
+	}
+	return rl
+}
+
+// Wait blocks until the specified controller allows another operation
+// to be issued.
+func (cl *controllerRateLimiters) Wait(ctx context.Context, ctrl devices.Controller) (reservation, error) {
+	if rl := cl.forController(ctrl); rl != nil {
+		return rl.Wait(ctx)
+	}
+	return reservation{}, nil
+}
+
+func withControllerRateLimiters(cl *controllerRateLimiters) Option {
+	return func(o *options) {
+		o.rateLimiters = cl
+	}
+}
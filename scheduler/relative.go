@@ -0,0 +1,144 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"cloudeng.io/datetime/schedule"
+)
+
+// relativePrefix introduces a time of day that is specified relative to
+// an action in another schedule, eg. "after living-room.on + 30m".
+const relativePrefix = "after "
+
+// ActionRef refers to an action in another schedule and an offset
+// from the time that action is due.
+type ActionRef struct {
+	Schedule string
+	Action   string
+	Offset   time.Duration
+}
+
+func (r ActionRef) String() string {
+	if r.Offset == 0 {
+		return fmt.Sprintf("%s%s.%s", relativePrefix, r.Schedule, r.Action)
+	}
+	sign := "+"
+	if r.Offset < 0 {
+		sign = "-"
+	}
+	return fmt.Sprintf("%s%s.%s%s%v", relativePrefix, r.Schedule, r.Action, sign, r.Offset.Abs())
+}
+
+// parseActionRef parses a time of day of the form:
+//
+//	after <schedule>.<action> [+|- <duration>]
+//
+// The returned bool is false if v is not of this form.
+func parseActionRef(v string) (ActionRef, bool, error) {
+	v = strings.TrimSpace(v)
+	if !strings.HasPrefix(strings.ToLower(v), relativePrefix) {
+		return ActionRef{}, false, nil
+	}
+	ref := strings.TrimSpace(v[len(relativePrefix):])
+	var ar ActionRef
+	// The offset starts at the first + or - whose remainder is a valid
+	// duration so that schedule and action names may contain a -.
+	for i, c := range ref {
+		if c != '+' && c != '-' {
+			continue
+		}
+		offset, err := time.ParseDuration(strings.ReplaceAll(ref[i:], " ", ""))
+		if err != nil {
+			continue
+		}
+		ar.Offset = offset
+		ref = strings.TrimSpace(ref[:i])
+		break
+	}
+	idx := strings.LastIndex(ref, ".")
+	if idx <= 0 || idx == len(ref)-1 {
+		return ActionRef{}, true, fmt.Errorf("invalid relative time: %q, should be %s<schedule>.<action>[+|-<duration>]", v, relativePrefix)
+	}
+	ar.Schedule, ar.Action = ref[:idx], ref[idx+1:]
+	return ar, true, nil
+}
+
+// resolveRelativeTimes sets the due time of every action that is
+// specified relative to another schedule's action to that of the
+// referenced action plus its offset. References may be chained but
+// must not form a cycle and must refer to an action that occurs exactly
+// once in the referenced schedule. The offset must not move a statically
+// scheduled action into the previous or following day. On error, the
+// index of the schedule containing the action that could not be resolved
+// is returned.
+func resolveRelativeTimes(scheds []Annual) (int, error) {
+	byName := map[string]*Annual{}
+	for i := range scheds {
+		byName[scheds[i].Name] = &scheds[i]
+	}
+	const (
+		visiting = 1
+		resolved = 2
+	)
+	state := map[*schedule.ActionSpec[Action]]int{}
+	var resolve func(sched string, spec *schedule.ActionSpec[Action], path []string) error
+	resolve = func(sched string, spec *schedule.ActionSpec[Action], path []string) error {
+		ref := spec.T.RelativeTo
+		if len(ref.Schedule) == 0 || state[spec] == resolved {
+			return nil
+		}
+		path = append(path, sched+"."+spec.Name)
+		if state[spec] == visiting {
+			return fmt.Errorf("cycle in relative times: %v", strings.Join(path, " -> "))
+		}
+		state[spec] = visiting
+		target, ok := byName[ref.Schedule]
+		if !ok {
+			return fmt.Errorf("schedule %q, operation %q: unknown schedule: %q", sched, spec.Name, ref.Schedule)
+		}
+		var referenced *schedule.ActionSpec[Action]
+		for i := range target.DailyActions {
+			if target.DailyActions[i].Name != ref.Action {
+				continue
+			}
+			if referenced != nil {
+				return fmt.Errorf("schedule %q, operation %q: %q occurs more than once in schedule %q", sched, spec.Name, ref.Action, ref.Schedule)
+			}
+			referenced = &target.DailyActions[i]
+		}
+		if referenced == nil {
+			return fmt.Errorf("schedule %q, operation %q: unknown operation %q in schedule %q", sched, spec.Name, ref.Action, ref.Schedule)
+		}
+		if err := resolve(target.Name, referenced, path); err != nil {
+			return err
+		}
+		if referenced.Dynamic.Due != nil {
+			spec.Due = referenced.Due
+			spec.Dynamic.Due = referenced.Dynamic.Due
+			spec.Dynamic.Offset = referenced.Dynamic.Offset + ref.Offset
+		} else {
+			// TimeOfDay.Add clamps to the start or end of the day and
+			// hence offsets that would cross midnight are rejected.
+			if due := referenced.Due.Duration() + ref.Offset; due < 0 || due >= 24*time.Hour {
+				return fmt.Errorf("schedule %q, operation %q: %v crosses a day boundary since %v.%v is due at %v", sched, spec.Name, ref, ref.Schedule, ref.Action, referenced.Due)
+			}
+			spec.Due = referenced.Due.Add(ref.Offset)
+		}
+		state[spec] = resolved
+		return nil
+	}
+	for i := range scheds {
+		for j := range scheds[i].DailyActions {
+			if err := resolve(scheds[i].Name, &scheds[i].DailyActions[j], nil); err != nil {
+				return i, err
+			}
+		}
+	}
+	return 0, nil
+}
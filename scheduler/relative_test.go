@@ -0,0 +1,168 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudeng.io/datetime"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+const relativeSchedules = `
+schedules:
+  - name: porch
+    device: device
+    ranges:
+      - 01/01:12/31
+    actions_detailed:
+      - action: off
+        when: after living-room.on + 30m
+      - action: another
+        when: after porch.off - 5m
+  - name: living-room
+    device: device
+    ranges:
+      - 01/01:12/31
+    actions:
+      on: sunset
+      off: 22:00
+  - name: garden
+    device: device
+    ranges:
+      - 01/01:12/31
+    actions:
+      on: after living-room.off+1h
+`
+
+func TestRelativeTimes(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "America/Los_Angeles")
+	scheds, err := scheduler.ParseConfig(ctx, []byte(relativeSchedules), sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	porch := lookupSchedule(t, scheds, "porch")
+	off := porch.DailyActions[0]
+	if got, want := off.T.RelativeTo.String(), "after living-room.on+30m0s"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	place := datetime.Place{TimeLocation: loc, Latitude: 37.3547, Longitude: -122.0862}
+	var dr datetime.CalendarDateRange
+	if err := dr.Parse("06/01/2025:07/15/2025"); err != nil {
+		t.Fatal(err)
+	}
+	// The off action resolves to sunset+30m and the chained another
+	// action to sunset+25m.
+	drifts := porch.Drift(place, dr)
+	if got, want := len(drifts), 2; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	sunset := scheduler.DailyDynamic["sunset"]
+	offsets := map[string]time.Duration{"off": 30 * time.Minute, "another": 25 * time.Minute}
+	for _, drift := range drifts {
+		for _, day := range drift.Days {
+			if got, want := day.Due, sunset.Evaluate(day.Date, place).Add(offsets[drift.Action]); got != want {
+				t.Errorf("%v: %v: got %v, want %v", drift.Action, day.Date, got, want)
+			}
+		}
+	}
+
+	garden := lookupSchedule(t, scheds, "garden")
+	if got, want := garden.DailyActions[0].Due, datetime.NewTimeOfDay(23, 0, 0); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if garden.DailyActions[0].Dynamic.Due != nil {
+		t.Errorf("unexpected dynamic time")
+	}
+
+	for _, tc := range []struct {
+		cfg, err string
+	}{
+		{`
+schedules:
+  - name: a
+    device: device
+    actions:
+      on: after b.on
+  - name: b
+    device: device
+    actions:
+      on: after a.on+1m
+`, "cycle in relative times: a.on -> b.on -> a.on"},
+		{`
+schedules:
+  - name: a
+    device: device
+    actions:
+      on: after unknown.on
+`, `unknown schedule: "unknown"`},
+		{`
+schedules:
+  - name: a
+    device: device
+    actions:
+      on: after b.off
+  - name: b
+    device: device
+    actions:
+      on: 12:00
+`, `unknown operation "off" in schedule "b"`},
+		{`
+schedules:
+  - name: a
+    device: device
+    actions:
+      on: after b.on
+  - name: b
+    device: device
+    actions:
+      on: 12:00,13:00
+`, `"on" occurs more than once in schedule "b"`},
+		{`
+schedules:
+  - name: a
+    device: device
+    actions:
+      on: after b.off+3h
+  - name: b
+    device: device
+    actions:
+      off: 22:00
+`, `after b.off+3h0m0s crosses a day boundary`},
+		{`
+schedules:
+  - name: a
+    device: device
+    actions:
+      on: after b.on-2h
+  - name: b
+    device: device
+    actions:
+      on: 01:00
+`, `after b.on-2h0m0s crosses a day boundary`},
+		{`
+schedules:
+  - name: a
+    device: device
+    actions:
+      on: after b
+`, "invalid relative time"},
+	} {
+		_, err := scheduler.ParseConfig(ctx, []byte(tc.cfg), sys)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("missing or unexpected error: %v, want %v", err, tc.err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"cloudeng.io/datetime"
+	"cloudeng.io/datetime/schedule"
+)
+
+// RepeatUntil represents the time of day, literal or dynamic, after which
+// a repeating action is no longer repeated. If num_repeats is also
+// specified then the action stops repeating at whichever of the two
+// comes first.
+type RepeatUntil struct {
+	ActionTime
+	Set bool
+}
+
+// ParseRepeatUntil parses a time of day as per ParseActionTime, an empty
+// string results in an unset RepeatUntil.
+func ParseRepeatUntil(v string) (RepeatUntil, error) {
+	if len(v) == 0 {
+		return RepeatUntil{}, nil
+	}
+	literal, dyn, delta, err := ParseActionTime(v)
+	if err != nil {
+		return RepeatUntil{}, err
+	}
+	return RepeatUntil{ActionTime: ActionTime{Literal: literal, Dynamic: dyn, Delta: delta}, Set: true}, nil
+}
+
+// Evaluate returns the time of day for the specified date and place.
+func (ru RepeatUntil) Evaluate(cd datetime.CalendarDate, place datetime.Place) datetime.TimeOfDay {
+	if ru.Dynamic != nil {
+		return ru.Dynamic.Evaluate(cd, place).Add(ru.Delta)
+	}
+	return ru.Literal
+}
+
+// repeatsUntil returns the number of times that an action due at due
+// can be repeated at the specified interval without exceeding until.
+func repeatsUntil(due, until datetime.TimeOfDay, interval time.Duration) int {
+	if until <= due {
+		return 0
+	}
+	return int((until.Duration() - due.Duration()) / interval)
+}
+
+// validateRepeat checks for contradictory combinations of repeat,
+// num_repeats and until. The due time is only checked against until
+// if both are literal times of day, dynamic times are bounded as the
+// schedule is run.
+func validateRepeat(details actionDetailed, until RepeatUntil, due datetime.TimeOfDay, dynamic, relative bool) error {
+	interval := time.Duration(details.Repeat)
+	switch {
+	case details.NumRepeats < 0:
+		return fmt.Errorf("num_repeats must not be negative: %v", details.NumRepeats)
+	case details.NumRepeats > 0 && interval == 0:
+		return fmt.Errorf("num_repeats requires a repeat interval")
+	case until.Set && interval == 0:
+		return fmt.Errorf("until requires a repeat interval")
+	case until.Set && relative:
+		return fmt.Errorf("until is not supported for relative times")
+	}
+	if !until.Set || dynamic || until.Dynamic != nil {
+		return nil
+	}
+	if until.Literal <= due {
+		return fmt.Errorf("until %v must be after when %v", until.Literal, due)
+	}
+	possible := repeatsUntil(due, until.Literal, interval)
+	if possible == 0 {
+		return fmt.Errorf("until %v is less than one repeat interval of %v after when %v", until.Literal, interval, due)
+	}
+	if details.NumRepeats > possible {
+		return fmt.Errorf("num_repeats %v exceeds the %v repeats of %v possible between when %v and until %v", details.NumRepeats, possible, interval, due, until.Literal)
+	}
+	return nil
+}
+
+// hasRepeatUntil returns true if any of the schedule's actions have
+// an until time.
+func (a Annual) hasRepeatUntil() bool {
+	for _, spec := range a.DailyActions {
+		if spec.T.Until.Set {
+			return true
+		}
+	}
+	return false
+}
+
+// boundRepeats returns a copy of the supplied day with the number of
+// repeats of each action with an until time bounded by the number that
+// can occur before that time on that day.
+func boundRepeats(day schedule.Scheduled[Action], place datetime.Place) schedule.Scheduled[Action] {
+	specs := make(schedule.ActionSpecs[Action], len(day.Specs))
+	for i, spec := range day.Specs {
+		specs[i] = spec
+		if !spec.T.Until.Set || spec.Repeat.Interval == 0 {
+			continue
+		}
+		due := spec.Evaluate(day.Date, place).Due
+		possible := repeatsUntil(due, spec.T.Until.Evaluate(day.Date, place), spec.Repeat.Interval)
+		switch {
+		case possible == 0:
+			specs[i].Repeat = schedule.RepeatSpec{}
+		case spec.Repeat.Repeats == 0 || possible < spec.Repeat.Repeats:
+			specs[i].Repeat.Repeats = possible
+		}
+	}
+	day.Specs = specs
+	return day
+}
@@ -0,0 +1,162 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudeng.io/datetime"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+func TestRepeatValidation(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	for _, tc := range []struct {
+		action, err string
+	}{
+		{`when: 12:00
+        repeat: 1h
+        num_repeats: -1`, "num_repeats must not be negative"},
+		{`when: 12:00
+        num_repeats: 2`, "num_repeats requires a repeat interval"},
+		{`when: 12:00
+        until: 14:00`, "until requires a repeat interval"},
+		{`when: 12:00
+        repeat: 1h
+        until: 11:00`, "until 11:00:00 must be after when 12:00:00"},
+		{`when: 12:00
+        repeat: 1h
+        until: 12:00`, "until 12:00:00 must be after when 12:00:00"},
+		{`when: 12:00
+        repeat: 1h
+        until: 12:30`, "until 12:30:00 is less than one repeat interval of 1h0m0s after when 12:00:00"},
+		{`when: 12:00
+        repeat: 1h
+        num_repeats: 3
+        until: 14:30`, "num_repeats 3 exceeds the 2 repeats of 1h0m0s possible between when 12:00:00 and until 14:30:00"},
+		{`when: after other.on
+        repeat: 1h
+        until: 14:00`, "until is not supported for relative times"},
+		{`when: 12:00
+        repeat: 1h
+        until: teatime`, `failed to parse until "teatime"`},
+	} {
+		cfg := `
+schedules:
+  - name: other
+    device: device
+    actions:
+      on: 10:00
+  - name: repeats
+    device: device
+    actions_detailed:
+      - action: on
+        ` + tc.action + `
+`
+		_, err := scheduler.ParseConfig(ctx, []byte(cfg), sys)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%v: missing or unexpected error: %v, want %v", tc.action, err, tc.err)
+		}
+	}
+
+	// Consistent combinations are accepted, including those that can only
+	// be checked once dynamic times have been evaluated.
+	for _, action := range []string{
+		`when: 12:00
+        repeat: 1h
+        num_repeats: 2
+        until: 14:30`,
+		`when: 12:00
+        repeat: 1h
+        until: 14:00`,
+		`when: sunset
+        repeat: 1h
+        until: 11:00`,
+		`when: 12:00
+        repeat: 1h
+        num_repeats: 100
+        until: sunset`,
+	} {
+		cfg := `
+schedules:
+  - name: repeats
+    device: device
+    actions_detailed:
+      - action: on
+        ` + action + `
+`
+		if _, err := scheduler.ParseConfig(ctx, []byte(cfg), sys); err != nil {
+			t.Errorf("%v: %v", action, err)
+		}
+	}
+}
+
+const repeatUntilSchedule = `
+schedules:
+  - name: until
+    device: device
+    ranges:
+      - 01/01:12/31
+    actions_detailed:
+      - action: on
+        when: 16:00
+        repeat: 1h
+        num_repeats: 3
+        until: sunset
+      - action: off
+        when: sunset
+        repeat: 30m
+        until: 18:00
+`
+
+func TestRepeatUntil(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "America/Los_Angeles")
+	sys.Location.Latitude, sys.Location.Longitude = 37.3547, -122.0862
+	scheds, err := scheduler.ParseConfig(ctx, []byte(repeatUntilSchedule), sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal, err := scheduler.NewCalendar(scheds, sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sunset := scheduler.DailyDynamic["sunset"]
+	repeats := func(from, until datetime.TimeOfDay, interval time.Duration) int {
+		if until <= from {
+			return 0
+		}
+		return int((until.Duration() - from.Duration()) / interval)
+	}
+	byUntil, byNumRepeats := 0, 0
+	for _, month := range []datetime.Month{1, 3, 6, 12} {
+		cd := datetime.NewCalendarDate(2025, month, 15)
+		ss := sunset.Evaluate(cd, sys.Location.Place)
+		counts := map[string]int{}
+		for _, entry := range cal.Scheduled(cd) {
+			counts[entry.Name]++
+		}
+		// Repeats stop at whichever of num_repeats and until comes first.
+		possible := repeats(datetime.NewTimeOfDay(16, 0, 0), ss, time.Hour)
+		if possible < 3 {
+			byUntil++
+		} else {
+			byNumRepeats++
+		}
+		if got, want := counts["on"], 1+min(3, possible); got != want {
+			t.Errorf("%v: sunset %v: got %v, want %v", cd, ss, got, want)
+		}
+		if got, want := counts["off"], 1+repeats(ss, datetime.NewTimeOfDay(18, 0, 0), 30*time.Minute); got != want {
+			t.Errorf("%v: sunset %v: got %v, want %v", cd, ss, got, want)
+		}
+	}
+	if byUntil == 0 || byNumRepeats == 0 {
+		t.Errorf("repeats were not bounded by both until (%v) and num_repeats (%v)", byUntil, byNumRepeats)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloudeng.io/datetime"
+	"cloudeng.io/datetime/schedule"
+	"cloudeng.io/sync/errgroup"
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/internal/logging"
+)
+
+// yearBounds returns the portion of the supplied range that falls within
+// the specified year.
+func yearBounds(dr datetime.CalendarDateRange, year int) datetime.DateRange {
+	from, to := datetime.NewDate(1, 1), datetime.NewDate(12, 31)
+	if dr.From().Year() == year {
+		from = dr.From().Date()
+	}
+	if dr.To().Year() == year {
+		to = dr.To().Date()
+	}
+	return datetime.NewDateRange(from, to)
+}
+
+// ticksForRange returns the simulated times at which each of the actions
+// in the supplied range is run.
+func ticksForRange(scheduler *schedule.AnnualScheduler[Action], place datetime.Place, annual Annual, dr datetime.CalendarDateRange, delay time.Duration) []time.Time {
+	times := []time.Time{}
+	for year := dr.From().Year(); year <= dr.To().Year(); year++ {
+		yp := datetime.YearPlace{Place: place, Year: year}
+		for active := range annual.scheduled(scheduler, yp, yearBounds(dr, year)) {
+			for action := range active.Active(place) {
+				times = append(times, action.When.Add(-delay))
+			}
+		}
+	}
+	return times
+}
+
+// RunRange runs the scheduler for each day in the specified range.
+func (s *Scheduler) RunRange(ctx context.Context, dr datetime.CalendarDateRange) error {
+	for year := dr.From().Year(); year <= dr.To().Year(); year++ {
+		yp := datetime.YearPlace{Place: s.place, Year: year}
+		for active := range s.schedule.scheduled(s.scheduler, yp, yearBounds(dr, year)) {
+			logging.WriteNewDay(s.logger, active.Date, len(active.Specs))
+			if len(active.Specs) == 0 || !s.dayConditionMet(ctx, active.Date) {
+				continue
+			}
+			if err := s.RunDay(ctx, yp.Place, active); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RunNamed runs the named schedule against the supplied system for each
+// day in the specified range. Simulated time, as per RunSimulation, is
+// used if a delay is specified via WithSimulationDelay, otherwise the
+// schedule is run in real time, or that provided by WithTimeSource, and
+// hence days in the past are run immediately with all of their actions
+// being overdue. An error is returned if there is no such schedule.
+func (s Schedules) RunNamed(ctx context.Context, system devices.System, name string, dr datetime.CalendarDateRange, opts ...Option) error {
+	sched, ok := s.Lookup(name)
+	if !ok {
+		return fmt.Errorf("unknown schedule: %q", name)
+	}
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.simulatedDelay <= 0 {
+		scheduler, err := New(sched, system, opts...)
+		if err != nil {
+			return err
+		}
+		return scheduler.RunRange(ctx, dr)
+	}
+	ticks := ticksForRange(schedule.NewAnnualScheduler(sched.DailyActions), sched.place(system.Location.Place), sched, dr, o.simulatedDelay)
+	ts := timesource{ch: make(chan time.Time), ticks: ticks}
+	scheduler, err := New(sched, system, append(opts, WithTimeSource(ts))...)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var g errgroup.T
+	g.Go(func() error {
+		// Stop supplying ticks once the schedule has been run, eg. if
+		// some days were skipped due to a day condition.
+		defer cancel()
+		return scheduler.RunRange(ctx, dr)
+	})
+	g.Go(func() error {
+		if err := ts.run(ctx); err != nil && ctx.Err() == nil {
+			return err
+		}
+		return nil
+	})
+	return g.Wait()
+}
@@ -0,0 +1,111 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudeng.io/datetime"
+	"github.com/cosnicolaou/automation/internal/logging"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+const runNamedSchedules = `
+schedules:
+  - name: first
+    device: device
+    ranges:
+      - 01/01:12/31
+    actions:
+      on: 08:00
+  - name: second
+    device: device
+    ranges:
+      - 01/01:01/05
+      - 12/30:12/31
+    actions:
+      off: 20:00
+      another: 21:00
+`
+
+func TestRunNamed(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "UTC")
+	scheds, err := scheduler.ParseConfig(ctx, []byte(runNamedSchedules), sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(dr string, opts ...scheduler.Option) []logging.Entry {
+		var cdr datetime.CalendarDateRange
+		if err := cdr.Parse(dr); err != nil {
+			t.Fatal(err)
+		}
+		logRecorder := newRecorder()
+		opts = append(opts,
+			scheduler.WithLogger(slog.New(slog.NewJSONHandler(logRecorder, nil))),
+			scheduler.WithOperationWriter(newRecorder()))
+		if err := scheds.RunNamed(ctx, sys, "second", cdr, opts...); err != nil {
+			t.Fatal(err)
+		}
+		var entries []logging.Entry
+		for _, l := range logRecorder.Lines() {
+			e, err := logging.ParseLogLine(l)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries = append(entries, e)
+		}
+		return entries
+	}
+
+	// Simulated time, across the end of a year.
+	var got []string
+	for _, e := range run("12/31/2024:01/02/2025", scheduler.WithSimulationDelay(time.Millisecond)) {
+		if e.Msg == logging.LogCompleted {
+			got = append(got, fmt.Sprintf("%v %v %v", e.Schedule, e.Op, e.Due.Format("01/02/2006 15:04")))
+		}
+	}
+	if want := []string{
+		"second off 12/31/2024 20:00",
+		"second another 12/31/2024 21:00",
+		"second off 01/01/2025 20:00",
+		"second another 01/01/2025 21:00",
+		"second off 01/02/2025 20:00",
+		"second another 01/02/2025 21:00",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Real time, all of the actions are in the past and hence overdue.
+	got = nil
+	for _, e := range run("01/03/2024:01/04/2024") {
+		if e.Msg == logging.LogTooLate {
+			got = append(got, fmt.Sprintf("%v %v", e.Op, e.Due.Format("01/02/2006 15:04")))
+		}
+	}
+	if want := []string{
+		"off 01/03/2024 20:00",
+		"another 01/03/2024 21:00",
+		"off 01/04/2024 20:00",
+		"another 01/04/2024 21:00",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	var dr datetime.CalendarDateRange
+	if err := dr.Parse("01/03/2024:01/04/2024"); err != nil {
+		t.Fatal(err)
+	}
+	if err := scheds.RunNamed(ctx, sys, "unknown", dr); err == nil || !strings.Contains(err.Error(), `unknown schedule: "unknown"`) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"cloudeng.io/datetime"
+	"cloudeng.io/sync/errgroup"
+	"github.com/cosnicolaou/automation/devices"
+)
+
+// Runner runs a set of schedules, as per RunSchedulers, but allows them
+// to be replaced, eg. by a schedule received over the network, whilst
+// running.
+type Runner struct {
+	system     devices.System
+	opts       []Option
+	timeSource TimeSource
+
+	mu         sync.Mutex
+	schedules  Schedules
+	schedulers []*Scheduler
+	swapped    chan struct{}
+}
+
+// NewRunner creates a Runner for the supplied schedules, it returns an
+// error if a scheduler cannot be created for any of them. The state
+// shared by all of the schedulers, such as the per-controller rate
+// limiters, is retained when the schedules are replaced.
+func NewRunner(schedules Schedules, system devices.System, opts ...Option) (*Runner, error) {
+	r := &Runner{
+		system: system,
+		opts: slices.Concat(opts, []Option{
+			withControllerRateLimiters(newControllerRateLimiters()),
+			withDeviceStates(newDeviceStates()),
+			withDispatches(newDispatches())}),
+		timeSource: SystemTimeSource{},
+		swapped:    make(chan struct{}, 1),
+	}
+	var o options
+	for _, fn := range opts {
+		fn(&o)
+	}
+	if o.timeSource != nil {
+		r.timeSource = o.timeSource
+	}
+	if err := r.Swap(schedules); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Runner) newSchedulers(schedules Schedules) ([]*Scheduler, error) {
+	schedulers := make([]*Scheduler, len(schedules.Schedules))
+	for i, sched := range schedules.Schedules {
+		s, err := New(sched, r.system, r.opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create scheduler for %v: %w", sched.Name, err)
+		}
+		schedulers[i] = s
+	}
+	return schedulers, nil
+}
+
+// Schedules returns the schedules currently being run.
+func (r *Runner) Schedules() Schedules {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.schedules
+}
+
+// Swap replaces the schedules being run. The new schedules are validated
+// by creating a scheduler for each of them and are only installed if all
+// of them can be created, otherwise the current schedules are retained.
+// Any actions that are in flight are allowed to complete before a running
+// Runner restarts from the current date, as determined by the time source
+// specified via WithTimeSource, using the new schedules,
+// any of their actions that are overdue for that day are not executed and
+// nor are any that are due at or before the most recently dispatched action
+// of the schedule, with the same name, that they replace. This ensures that
+// actions that were run within the overdue grace period are not run again.
+func (r *Runner) Swap(schedules Schedules) error {
+	schedulers, err := r.newSchedulers(schedules)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.schedules, r.schedulers = schedules, schedulers
+	r.mu.Unlock()
+	select {
+	case r.swapped <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run runs the current schedules starting at the specified date until the
+// context is canceled.
+func (r *Runner) Run(ctx context.Context, start datetime.CalendarDate) error {
+	select {
+	case <-r.swapped: // Ignore the swap made by NewRunner.
+	default:
+	}
+	for {
+		r.mu.Lock()
+		schedulers := r.schedulers
+		r.mu.Unlock()
+		for _, s := range schedulers {
+			s.resumeAfter = s.dispatches.lastFor(s.schedule.Name)
+		}
+		// Canceling rctx stops the schedulers from starting any new
+		// actions, those in flight are run using ctx and are waited for.
+		rctx, cancel := context.WithCancel(withOperationContext(ctx, ctx))
+		done := make(chan error, 1)
+		go func() {
+			done <- runSchedulers(rctx, schedulers, start)
+		}()
+		select {
+		case err := <-done:
+			cancel()
+			return err
+		case <-r.swapped:
+		}
+		cancel()
+		if err := <-done; err != nil && !errors.Is(err, context.Canceled) {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		start = datetime.CalendarDateFromTime(r.timeSource.NowIn(r.system.Location.TimeLocation))
+	}
+}
+
+type operationContextKey struct{}
+
+// withOperationContext returns a context that records opCtx as the
+// context whose cancelation, rather than that of the returned context or
+// any derived from it, is to cancel the operations of actions that have
+// already been started. This allows a Runner to stop its schedulers
+// without interrupting any operations that are in flight.
+func withOperationContext(ctx, opCtx context.Context) context.Context {
+	return context.WithValue(ctx, operationContextKey{}, opCtx)
+}
+
+// operationContext returns the context to use for running the operation
+// of an action that has been started, see withOperationContext. The
+// returned function must be called once the operation has completed.
+func operationContext(ctx context.Context) (context.Context, func()) {
+	opCtx, ok := ctx.Value(operationContextKey{}).(context.Context)
+	if !ok {
+		return ctx, func() {}
+	}
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(opCtx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+func runSchedulers(ctx context.Context, schedulers []*Scheduler, start datetime.CalendarDate) error {
+	var g errgroup.T
+	for _, s := range schedulers {
+		g.Go(func() error {
+			if err := s.RunYearEnd(ctx, start); err != nil {
+				return err
+			}
+			year := start.Year() + 1
+			for {
+				cd := datetime.NewCalendarDate(year, 1, 1)
+				if err := s.RunYearEnd(ctx, cd); err != nil {
+					return err
+				}
+				year++
+			}
+		})
+	}
+	return g.Wait()
+}
+
+// dispatches records the due time of the most recently dispatched action
+// for each schedule and is shared by all of the schedulers created by
+// a Runner so that a restarted Runner does not run any of them again.
+type dispatches struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+func newDispatches() *dispatches {
+	return &dispatches{last: map[string]time.Time{}}
+}
+
+func withDispatches(d *dispatches) Option {
+	return func(o *options) {
+		o.dispatches = d
+	}
+}
+
+func (d *dispatches) record(schedule string, due time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if due.After(d.last[schedule]) {
+		d.last[schedule] = due
+	}
+}
+
+func (d *dispatches) lastFor(schedule string) time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.last[schedule]
+}
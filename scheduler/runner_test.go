@@ -0,0 +1,277 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudeng.io/datetime"
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/internal/logging"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+const runnerSchedules = `
+schedules:
+  - name: lights
+    device: device
+    actions:
+      on: 12:00
+`
+
+func TestRunnerSwap(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sys := createSystem(t, "Local")
+	parse := func(cfg string) scheduler.Schedules {
+		scheds, err := scheduler.ParseConfig(ctx, []byte(cfg), sys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return scheds
+	}
+	sr := logging.NewStatusRecorder()
+	runner, err := scheduler.NewRunner(parse(runnerSchedules), sys,
+		scheduler.WithLogger(slog.New(slog.NewJSONHandler(io.Discard, nil))),
+		scheduler.WithOperationWriter(io.Discard),
+		scheduler.WithStatusRecorder(sr),
+		scheduler.WithNotifiers(scheduler.Notifiers{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		// Start far enough into the future that no actions are executed.
+		errCh <- runner.Run(ctx, datetime.NewCalendarDate(time.Now().Year()+10, 1, 1))
+	}()
+
+	updated := strings.ReplaceAll(runnerSchedules, "on: 12:00", "on: 12:00\n      off: 13:00")
+	if err := runner.Swap(parse(updated)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(runner.Schedules().Schedules[0].DailyActions), 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// A schedule that refers to an unknown notifier is rejected and the
+	// current schedules retained.
+	err = runner.Swap(parse(strings.ReplaceAll(updated, "device: device", "device: device\n    notify: email")))
+	if err == nil || !strings.Contains(err.Error(), `unknown notifier: "email"`) {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+	if got, want := len(runner.Schedules().Schedules[0].DailyActions), 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	cancel()
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for rec := range sr.Pending() {
+		t.Errorf("unexpected pending record: %v", rec.Name())
+	}
+}
+
+func TestRunnerSwapDoesNotRepeat(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sys := createSystem(t, "Local")
+	now := time.Now().In(sys.Location.TimeLocation)
+	today := datetime.DateFromTime(now)
+	due := now.Add(time.Second).Truncate(time.Second)
+	// parse returns schedules with all of their actions due at the
+	// supplied times.
+	parse := func(cfg string, when ...time.Time) scheduler.Schedules {
+		scheds, err := scheduler.ParseConfig(ctx, []byte(cfg), sys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sched := &scheds.Schedules[0]
+		sched.Dates.Ranges = []datetime.DateRange{datetime.NewDateRange(today, today)}
+		for i := range sched.DailyActions {
+			sched.DailyActions[i].Due = datetime.TimeOfDayFromTime(when[i])
+		}
+		return scheds
+	}
+
+	deviceRecorder := newRecorder()
+	runner, err := scheduler.NewRunner(parse(runnerSchedules, due), sys,
+		scheduler.WithLogger(slog.New(slog.NewJSONHandler(io.Discard, nil))),
+		scheduler.WithOperationWriter(deviceRecorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- runner.Run(ctx, datetime.CalendarDateFromTime(now))
+	}()
+
+	// Replace the schedule once its action has been run, but is still
+	// within the overdue grace period, with one that adds a new action.
+	time.Sleep(time.Until(due) + time.Millisecond*200)
+	updated := strings.ReplaceAll(runnerSchedules, "on: 12:00", "on: 12:00\n      off: 13:00")
+	if err := runner.Swap(parse(updated, due, due.Add(time.Second))); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Until(due.Add(time.Second)) + time.Millisecond*200)
+	cancel()
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	var ops []string
+	for _, l := range deviceRecorder.Lines() {
+		ops = append(ops, strings.TrimSpace(l))
+	}
+	if got, want := ops, []string{"device[device].On: [0]", "device[device].Off: [0]"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRunnerSwapInFlight(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sys, err := devices.ParseSystemConfig(ctx, []byte(`
+time_location: Local
+devices:
+  - name: slow
+    type: slow
+    operations:
+      on:
+`), devices.WithDevices(devices.SupportedDevices{
+		"slow": func(string, devices.Options) (devices.Device, error) {
+			return &slowDevice{timeout: time.Hour, delay: time.Millisecond * 500}, nil
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().In(sys.Location.TimeLocation)
+	today := datetime.DateFromTime(now)
+	due := now.Add(time.Second).Truncate(time.Second)
+	parse := func() scheduler.Schedules {
+		scheds, err := scheduler.ParseConfig(ctx, []byte(shutdownSchedule), sys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sched := &scheds.Schedules[0]
+		sched.Dates.Ranges = []datetime.DateRange{datetime.NewDateRange(today, today)}
+		sched.DailyActions[0].Due = datetime.TimeOfDayFromTime(due)
+		return scheds
+	}
+
+	logRecorder := newRecorder()
+	runner, err := scheduler.NewRunner(parse(), sys,
+		scheduler.WithLogger(slog.New(slog.NewJSONHandler(logRecorder, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- runner.Run(ctx, datetime.CalendarDateFromTime(now))
+	}()
+
+	// Swap the schedules whilst the operation is in flight.
+	time.Sleep(time.Until(due) + time.Millisecond*100)
+	if err := runner.Swap(parse()); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Until(due) + time.Millisecond*800)
+	cancel()
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	var completions []logging.Entry
+	for _, l := range logRecorder.Lines() {
+		e, err := logging.ParseLogLine(l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Msg == logging.LogCompleted || e.Msg == logging.LogFailed {
+			completions = append(completions, e)
+		}
+	}
+	if got, want := len(completions), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := completions[0].Msg, logging.LogCompleted; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err := completions[0].Err; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// fixedTimeSource always returns the same time.
+type fixedTimeSource time.Time
+
+func (ts fixedTimeSource) NowIn(loc *time.Location) time.Time {
+	return time.Time(ts).In(loc)
+}
+
+func TestRunnerSwapTimeSource(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sys := createSystem(t, "Local")
+	parse := func(cfg string) scheduler.Schedules {
+		scheds, err := scheduler.ParseConfig(ctx, []byte(cfg), sys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return scheds
+	}
+	cfg := strings.ReplaceAll(runnerSchedules, "device: device", "device: device\n    ranges:\n      - 01/01:12/31")
+	now := time.Date(time.Now().Year()+10, 6, 15, 11, 0, 0, 0, sys.Location.TimeLocation)
+	logRecorder := newRecorder()
+	runner, err := scheduler.NewRunner(parse(cfg), sys,
+		scheduler.WithLogger(slog.New(slog.NewJSONHandler(logRecorder, nil))),
+		scheduler.WithOperationWriter(io.Discard),
+		scheduler.WithTimeSource(fixedTimeSource(now)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- runner.Run(ctx, datetime.NewCalendarDate(now.Year(), 7, 1))
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if err := runner.Swap(parse(cfg)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// The runner restarts on the date given by the time source.
+	var days []string
+	for _, l := range logRecorder.Lines() {
+		e, err := logging.ParseLogLine(l)
+		if err != nil {
+			t.Fatalf("failed to parse: %v: %v", l, err)
+		}
+		if e.Msg == logging.LogNewDay {
+			days = append(days, e.Date.String())
+		}
+	}
+	want := []string{
+		datetime.NewCalendarDate(now.Year(), 7, 1).String(),
+		datetime.CalendarDateFromTime(now).String(),
+	}
+	if got := days; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
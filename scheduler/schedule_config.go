@@ -7,6 +7,7 @@ package scheduler
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"cloudeng.io/cmdutil/cmdyaml"
@@ -37,9 +38,12 @@ func (ml *monthList) UnmarshalYAML(node *yaml.Node) error {
 }
 
 type constraintsConfig struct {
-	Weekdays bool   `yaml:"weekdays" cmd:"only on weekdays"`
-	Weekends bool   `yaml:"weekends" cmd:"only on weekends"`
-	Custom   string `yaml:"exclude_dates" cmd:"exclude the specified dates eg: 01/02,jan-02"`
+	Weekdays            bool   `yaml:"weekdays" cmd:"only on weekdays"`
+	Weekends            bool   `yaml:"weekends" cmd:"only on weekends"`
+	DaysOfWeek          string `yaml:"days_of_week" cmd:"only on the specified days of the week eg: mon,thu"`
+	LunarPhases         string `yaml:"lunar_phases" cmd:"only on the dates of the specified lunar phases eg: full-moon,new-moon"`
+	LunarPhaseTolerance int    `yaml:"lunar_phase_tolerance" cmd:"include dates within this number of days of each of the lunar_phases"`
+	Custom              string `yaml:"exclude_dates" cmd:"exclude the specified dates eg: 01/02,jan-02"`
 }
 
 func (cc constraintsConfig) parse() (datetime.Constraints, error) {
@@ -78,53 +82,253 @@ func (dc *datesConfig) parse() (schedule.Dates, error) {
 	return d, nil
 }
 
-type precondition struct {
+type condition struct {
 	Device string   `yaml:"device" cmd:"name of the device that the pre-condition applies to"`
 	Op     string   `yaml:"op" cmd:"name of the pre-condition in device.op format, use \"!op\" for negation"`
 	Args   []string `yaml:"args,flow" cmd:"arguments to be passed to the pre-condition"`
 }
 
+type precondition struct {
+	condition   `yaml:",inline" cmd:"a single pre-condition"`
+	Match       string      `yaml:"match" cmd:"how the results of multiple conditions are combined: all (the default) or any"`
+	Conditions  []condition `yaml:"conditions" cmd:"multiple pre-conditions, each with a device, op and args, to be combined as per match"`
+	Concurrency int         `yaml:"concurrency" cmd:"maximum number of conditions that are evaluated at once, zero (the default) means no limit and one that they are evaluated in order"`
+}
+
+// parse parses the condition, kind is used in error messages, eg.
+// "precondition".
+func (c condition) parse(sys devices.System, kind string) (Precondition, error) {
+	if c.Op == "" {
+		return Precondition{}, nil
+	}
+	if isLastOpCondition(c.Op) {
+		return parseLastOpCondition(sys, c)
+	}
+	fn, _, ok := sys.DeviceCondition(c.Device, c.Op)
+	if !ok {
+		return Precondition{}, fmt.Errorf("unknown %v: %q for device: %q", kind, c.Op, c.Device)
+	}
+	return Precondition{
+		Device:    c.Device,
+		Name:      c.Op,
+		Condition: fn,
+		Args:      c.Args,
+	}, nil
+}
+
+// parse parses either a single condition or a list of conditions to be
+// combined as per match. The returned Precondition is empty if neither
+// is specified.
+func (pc precondition) parse(sys devices.System) (Precondition, error) {
+	if len(pc.Conditions) == 0 {
+		if pc.Match != "" {
+			return Precondition{}, fmt.Errorf("match: %q requires a list of conditions", pc.Match)
+		}
+		if pc.Concurrency != 0 {
+			return Precondition{}, fmt.Errorf("concurrency: %v requires a list of conditions", pc.Concurrency)
+		}
+		return pc.condition.parse(sys, "precondition")
+	}
+	if pc.Op != "" {
+		return Precondition{}, fmt.Errorf("precondition specifies both an op: %q and a list of conditions", pc.Op)
+	}
+	match, err := ParseConditionMatch(pc.Match)
+	if err != nil {
+		return Precondition{}, err
+	}
+	if pc.Concurrency < 0 {
+		return Precondition{}, fmt.Errorf("concurrency: %v must not be negative", pc.Concurrency)
+	}
+	conds := make([]Precondition, 0, len(pc.Conditions))
+	for i, c := range pc.Conditions {
+		if c.Op == "" {
+			return Precondition{}, fmt.Errorf("condition %v: missing op", i)
+		}
+		p, err := c.parse(sys, "precondition")
+		if err != nil {
+			return Precondition{}, err
+		}
+		conds = append(conds, p)
+	}
+	return NewCombinedPrecondition(match, pc.Concurrency, conds), nil
+}
+
+// parseDayCondition parses a day condition, which, unlike a
+// precondition, must be a single condition.
+func (pc precondition) parseDayCondition(sys devices.System) (Precondition, error) {
+	switch {
+	case len(pc.Conditions) > 0:
+		return Precondition{}, fmt.Errorf("day condition does not support a list of conditions")
+	case pc.Match != "":
+		return Precondition{}, fmt.Errorf("day condition does not support match: %q", pc.Match)
+	case pc.Concurrency != 0:
+		return Precondition{}, fmt.Errorf("day condition does not support concurrency: %v", pc.Concurrency)
+	case pc.Op == "" && pc.Device != "":
+		return Precondition{}, fmt.Errorf("day condition for device: %q is missing an op", pc.Device)
+	}
+	return pc.condition.parse(sys, "day condition")
+}
+
 type actionDetailed struct {
-	When         string         `yaml:"when" cmd:"time of day when the action is to be taken"`
-	Action       string         `yaml:"action" cmd:"action to be taken"`
-	Args         []string       `yaml:"args,flow" cmd:"argument to be passed to the action"`
-	Precondition precondition   `yaml:"precondition" cmd:"precondition that must be satisfied before the action is taken"`
-	Before       string         `yaml:"before" cmd:"action that must be taken before this one if it is scheduled for the same time"`
-	After        string         `yaml:"after" cmd:"action that must be taken after this one if it is scheduled for the same time"`
-	Repeat       repeatDuration `yaml:"repeat" cmd:"repeat the action every specified duration, starting at 'when'"`
-	NumRepeats   int            `yaml:"num_repeats" cmd:"number of times to repeat"`
+	When                string         `yaml:"when" cmd:"time of day when the action is to be taken, or relative to an action in another schedule eg. after living-room.on+30m"`
+	Action              string         `yaml:"action" cmd:"action to be taken"`
+	Args                []string       `yaml:"args,flow" cmd:"argument to be passed to the action"`
+	Precondition        precondition   `yaml:"precondition" cmd:"precondition that must be satisfied before the action is taken"`
+	Postcondition       condition      `yaml:"postcondition" cmd:"condition that must be satisfied once the action has been taken for it to be considered successful"`
+	Before              string         `yaml:"before" cmd:"action that must be taken before this one if it is scheduled for the same time"`
+	After               string         `yaml:"after" cmd:"action that must be taken after this one if it is scheduled for the same time"`
+	Priority            int            `yaml:"priority" cmd:"order of the action relative to others scheduled for the same time; lower values run first and before or after take precedence"`
+	Repeat              repeatDuration `yaml:"repeat" cmd:"repeat the action every specified duration, starting at 'when'"`
+	NumRepeats          int            `yaml:"num_repeats" cmd:"number of times to repeat"`
+	Until               string         `yaml:"until" cmd:"time of day after which the action is no longer repeated; literal or dynamic eg. sunset-30m; if num_repeats is also specified the action stops repeating at whichever comes first"`
+	Align               bool           `yaml:"align" cmd:"align repeats, after the first, to clock boundaries of the repeat interval, eg. on the hour for a 1h repeat"`
+	Coalesce            bool           `yaml:"coalesce" cmd:"when multiple instances of a repeating action are overdue, run only the most recent"`
+	LogOnChange         bool           `yaml:"log_on_change" cmd:"only log the completion of the action when its result differs from that of its previous invocation"`
+	OnPreconditionError string         `yaml:"on_precondition_error" cmd:"how to handle an error evaluating the precondition: run the action anyway, skip it, or fail (the default)"`
+	MaxTotalTime        time.Duration  `yaml:"max_total_time" cmd:"maximum total time to spend on the action across all retries, zero means no limit"`
+	SkipIfUnchanged     bool           `yaml:"skip_if_unchanged" cmd:"skip the action if the same operation, with the same arguments, was the last one successfully commanded on the device that day"`
+	Critical            bool           `yaml:"critical" cmd:"abort the remaining actions for the day, for this schedule, if this action fails"`
+	SuccessWhen         string         `yaml:"success_when" cmd:"expression evaluated over the data returned by the operation that must be true for it to be considered successful, eg. status == ok"`
+
+	line int // line number in the config file.
+}
+
+func (ad *actionDetailed) UnmarshalYAML(node *yaml.Node) error {
+	type plain actionDetailed
+	if err := node.Decode((*plain)(ad)); err != nil {
+		return err
+	}
+	ad.line = node.Line
+	return nil
 }
 
 type actionScheduleConfig struct {
-	Name            string            `yaml:"name" cmd:"name of the schedule"`
-	Device          string            `yaml:"device" cmd:"name of the device that the schedule applies to"`
-	Dates           datesConfig       `yaml:",inline" cmd:"dates that the schedule applies to"`
-	Actions         map[string]string `yaml:"actions" cmd:"actions to be taken and when"`
-	ActionsDetailed []actionDetailed  `yaml:"actions_detailed" cmd:"actions that accept arguments"`
+	Name            string           `yaml:"name" cmd:"name of the schedule"`
+	Device          string           `yaml:"device" cmd:"name of the device that the schedule applies to"`
+	Controller      string           `yaml:"controller" cmd:"name of the controller that the schedule applies to, for operations implemented by a controller rather than a device"`
+	Dates           datesConfig      `yaml:",inline" cmd:"dates that the schedule applies to"`
+	Actions         actionTimes      `yaml:"actions" cmd:"actions to be taken and when"`
+	ActionsDetailed []actionDetailed `yaml:"actions_detailed" cmd:"actions that accept arguments"`
+	DayCondition    precondition     `yaml:"day_condition" cmd:"condition evaluated once per day that must be true for the schedule to be active on that day"`
+	Notify          string           `yaml:"notify" cmd:"name of the notifier to be used for failed or aborted actions"`
+	TimeZone        string           `yaml:"time_zone" cmd:"time zone for this schedule, eg. America/New_York, overriding that of the system; the system's latitude and longitude are still used for sunrise and sunset"`
+	Vacation        *vacationConfig  `yaml:"vacation" cmd:"generate randomized daily actions that turn a group of devices on and off to simulate occupancy"`
+
+	line int // line number in the config file.
+}
+
+func (asc *actionScheduleConfig) UnmarshalYAML(node *yaml.Node) error {
+	type plain actionScheduleConfig
+	if err := node.Decode((*plain)(asc)); err != nil {
+		return err
+	}
+	asc.line = node.Line
+	return nil
+}
+
+// actionTime represents a single entry in an 'actions:' map.
+type actionTime struct {
+	name string
+	when string
+	line int // line number in the config file.
+}
+
+// actionTimes represents an 'actions:' map with its entries in the
+// order in which they appear in the configuration file so that actions
+// scheduled for the same time, and that are not otherwise ordered, are
+// run in that order.
+type actionTimes []actionTime
+
+func (at *actionTimes) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: actions must be a map of action names to times", node.Line)
+	}
+	seen := map[string]bool{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		k, v := node.Content[i], node.Content[i+1]
+		if seen[k.Value] {
+			return fmt.Errorf("line %d: duplicate action: %q", k.Line, k.Value)
+		}
+		seen[k.Value] = true
+		var when string
+		if err := v.Decode(&when); err != nil {
+			return err
+		}
+		*at = append(*at, actionTime{name: k.Value, when: when, line: k.Line})
+	}
+	return nil
 }
 
 type schedulesConfig struct {
 	Schedules []actionScheduleConfig `yaml:"schedules" cmd:"the schedules"`
+
+	filename string // name of the config file, if any.
+}
+
+// ConfigError is used to report the location within a schedule
+// configuration file that an error was found at.
+type ConfigError struct {
+	File string // Empty if the configuration was not read from a file.
+	Line int
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	if len(e.File) == 0 {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+func (cfg schedulesConfig) errorf(line int, format string, args ...any) error {
+	return &ConfigError{
+		File: cfg.filename,
+		Line: line,
+		Err:  fmt.Errorf(format, args...),
+	}
 }
 
 type Annual struct {
 	Name         string
 	Dates        schedule.Dates
+	DaysOfWeek   DaysOfWeek     // If non-empty, restricts Dates to these days of the week.
+	LunarPhases  LunarPhases    // If non-empty, restricts Dates to those close to these lunar phases.
+	DayCondition Precondition   // If set, evaluated once per day to determine if the schedule is active.
+	Notify       string         // If set, the name of the notifier to use for failed or aborted actions.
+	TimeLocation *time.Location // If set, overrides the system's time zone for this schedule.
 	DailyActions schedule.ActionSpecs[Action]
 }
 
+// place returns the supplied place with its time zone overridden by
+// that of the schedule, if any.
+func (a Annual) place(place datetime.Place) datetime.Place {
+	if a.TimeLocation != nil {
+		place.TimeLocation = a.TimeLocation
+	}
+	return place
+}
+
 type Schedules struct {
 	System    devices.System
 	Schedules []Annual
 }
 
-func (s Schedules) Lookup(name string) Annual {
+// Lookup returns the schedule with the specified name, leading and
+// trailing whitespace and case are ignored since schedule names that
+// differ only in case are rejected when loaded. The returned bool is
+// false if no such schedule exists.
+func (s Schedules) Lookup(name string) (Annual, bool) {
+	name = strings.TrimSpace(name)
 	for _, sched := range s.Schedules {
-		if sched.Name == name {
-			return sched
+		if strings.EqualFold(sched.Name, name) {
+			return sched, true
 		}
 	}
-	return Annual{}
+	return Annual{}, false
 }
 
 func ParseConfigFile(ctx context.Context, cfgFile string, system devices.System) (Schedules, error) {
@@ -132,6 +336,7 @@ func ParseConfigFile(ctx context.Context, cfgFile string, system devices.System)
 	if err := cmdyaml.ParseConfigFile(ctx, cfgFile, &cfg); err != nil {
 		return Schedules{}, err
 	}
+	cfg.filename = cfgFile
 	pcfg, err := cfg.createSchedules(system)
 	if err != nil {
 		return Schedules{}, err
@@ -151,31 +356,76 @@ func ParseConfig(_ context.Context, cfgData []byte, system devices.System) (Sche
 	return pcfg, err
 }
 
-func (cfg schedulesConfig) createActions(sys devices.System, times, scheduleName, deviceName, actionName string, details actionDetailed) (schedule.ActionSpecs[Action], error) {
+func (cfg schedulesConfig) createActions(sys devices.System, line int, times, scheduleName, deviceName string, onController bool, actionName string, details actionDetailed) (schedule.ActionSpecs[Action], error) {
+	ref, relative, err := parseActionRef(times)
+	if err != nil {
+		return nil, cfg.errorf(line, "schedule %q, operation: %q: %v", scheduleName, actionName, err)
+	}
 	var actionTimes ActionTimeList
-	if err := actionTimes.Parse(times); err != nil {
-		return nil, fmt.Errorf("failed to parse time of day %q for schedule %q, operation: %q: %v", times, scheduleName, actionName, err)
+	if relative {
+		// The due time is resolved once all schedules have been created.
+		actionTimes = ActionTimeList{{}}
+	} else if err := actionTimes.Parse(times); err != nil {
+		return nil, cfg.errorf(line, "failed to parse time of day %q for schedule %q, operation: %q: %v", times, scheduleName, actionName, err)
 	}
 	actions := schedule.ActionSpecs[Action]{}
 	for _, actionTime := range actionTimes {
 		due, dynDue, delta := actionTime.Literal, actionTime.Dynamic, actionTime.Delta
-		if _, _, ok := sys.DeviceConfigs(deviceName); !ok {
-			return nil, fmt.Errorf("unknown device: %s for schedule %q", deviceName, scheduleName)
+		if onController {
+			if _, _, ok := sys.ControllerConfigs(deviceName); !ok {
+				return nil, cfg.errorf(line, "unknown controller: %s for schedule %q", deviceName, scheduleName)
+			}
+			if _, _, ok := sys.ControllerOp(deviceName, actionName); !ok {
+				return nil, cfg.errorf(line, "unknown operation: %q for controller: %q for schedule %q", actionName, deviceName, scheduleName)
+			}
+		} else {
+			if _, _, ok := sys.DeviceConfigs(deviceName); !ok {
+				return nil, cfg.errorf(line, "unknown device: %s for schedule %q", deviceName, scheduleName)
+			}
+			if _, _, ok := sys.DeviceOp(deviceName, actionName); !ok {
+				return nil, cfg.errorf(line, "unknown operation: %q for device: %q for schedule %q", actionName, deviceName, scheduleName)
+			}
 		}
-		if _, _, ok := sys.DeviceOp(deviceName, actionName); !ok {
-			return nil, fmt.Errorf("unknown operation: %q for device: %q for schedule %q", actionName, deviceName, scheduleName)
+
+		pre, err := details.Precondition.parse(sys)
+		if err != nil {
+			return nil, cfg.errorf(line, "%v for schedule %q", err, scheduleName)
+		}
+		post, err := details.Postcondition.parse(sys, "postcondition")
+		if err != nil {
+			return nil, cfg.errorf(line, "%v for schedule %q", err, scheduleName)
 		}
 
-		var condition devices.Condition
-		if details.Precondition.Op != "" {
-			c, _, ok := sys.DeviceCondition(details.Precondition.Device, details.Precondition.Op)
-			if !ok {
-				return nil, fmt.Errorf("unknown precondition: %q for device: %q for schedule %q", details.Precondition.Op, deviceName, scheduleName)
-			}
-			condition = c
+		onPreErr, err := ParsePreconditionErrorAction(details.OnPreconditionError)
+		if err != nil {
+			return nil, cfg.errorf(line, "schedule %q, operation: %q: %v", scheduleName, actionName, err)
 		}
 
-		actions = append(actions, schedule.ActionSpec[Action]{
+		successWhen, err := ParseSuccessPredicate(details.SuccessWhen)
+		if err != nil {
+			return nil, cfg.errorf(line, "schedule %q, operation: %q: %v", scheduleName, actionName, err)
+		}
+
+		if details.MaxTotalTime < 0 {
+			return nil, cfg.errorf(line, "max_total_time must not be negative for schedule %q, operation: %q", scheduleName, actionName)
+		}
+		until, err := ParseRepeatUntil(details.Until)
+		if err != nil {
+			return nil, cfg.errorf(line, "failed to parse until %q for schedule %q, operation: %q: %v", details.Until, scheduleName, actionName, err)
+		}
+		if err := validateRepeat(details, until, due, dynDue != nil, relative); err != nil {
+			return nil, cfg.errorf(line, "schedule %q, operation: %q: %v", scheduleName, actionName, err)
+		}
+		if details.Align && details.Repeat == 0 {
+			return nil, cfg.errorf(line, "align requires a repeat interval for schedule %q, operation: %q", scheduleName, actionName)
+		}
+		if details.Align && dynDue != nil {
+			return nil, cfg.errorf(line, "align is not supported for dynamic times for schedule %q, operation: %q", scheduleName, actionName)
+		}
+		if details.Align && relative {
+			return nil, cfg.errorf(line, "align is not supported for relative times for schedule %q, operation: %q", scheduleName, actionName)
+		}
+		spec := schedule.ActionSpec[Action]{
 			Due:  due,
 			Name: actionName,
 			Dynamic: schedule.DynamicTimeOfDaySpec{
@@ -192,57 +442,156 @@ func (cfg schedulesConfig) createActions(sys devices.System, times, scheduleName
 					Name:       actionName,
 					Args:       details.Args,
 				},
-				Precondition: Precondition{
-					Device:    details.Precondition.Device,
-					Name:      details.Precondition.Op,
-					Condition: condition,
-					Args:      details.Precondition.Args,
-				}}})
+				Precondition:        pre,
+				Postcondition:       post,
+				OnPreconditionError: onPreErr,
+				OnController:        onController,
+				Coalesce:            details.Coalesce,
+				LogOnChange:         details.LogOnChange,
+				MaxTotalTime:        details.MaxTotalTime,
+				SkipIfUnchanged:     details.SkipIfUnchanged,
+				Critical:            details.Critical,
+				SuccessWhen:         successWhen,
+				RelativeTo:          ref,
+				Until:               until,
+				Before:              details.Before,
+				After:               details.After,
+				Priority:            details.Priority,
+			}}
+		if details.Align {
+			actions = append(actions, alignRepeats(spec)...)
+			continue
+		}
+		actions = append(actions, spec)
 	}
 	return actions, nil
 }
 
+// alignRepeats splits a repeating action into an initial, non-repeating,
+// action at the original time and a repeating action whose first
+// occurrence is on the next clock boundary of the repeat interval.
+func alignRepeats(spec schedule.ActionSpec[Action]) schedule.ActionSpecs[Action] {
+	interval := spec.Repeat.Interval
+	start := spec.Due.Duration()
+	if start%interval == 0 {
+		return schedule.ActionSpecs[Action]{spec}
+	}
+	aligned := (start/interval + 1) * interval
+	initial := spec
+	initial.Repeat = schedule.RepeatSpec{}
+	if aligned >= 24*time.Hour {
+		return schedule.ActionSpecs[Action]{initial}
+	}
+	repeats := spec
+	repeats.Due = datetime.NewTimeOfDay(0, 0, 0).Add(aligned)
+	switch spec.Repeat.Repeats {
+	case 0: // unbounded.
+	case 1:
+		repeats.Repeat = schedule.RepeatSpec{}
+	default:
+		repeats.Repeat.Repeats--
+	}
+	return schedule.ActionSpecs[Action]{initial, repeats}
+}
+
 func (cfg schedulesConfig) createSchedules(sys devices.System) (Schedules, error) {
 	var sched Schedules
-	names := map[string]struct{}{}
+	names := map[string]string{}
 	for _, csched := range cfg.Schedules {
-		if _, ok := names[csched.Name]; ok {
-			return Schedules{}, fmt.Errorf("duplicate schedule name: %v", csched.Name)
+		// Names are trimmed of whitespace and are required to be unique
+		// ignoring case to catch near-duplicates.
+		csched.Name = strings.TrimSpace(csched.Name)
+		if len(csched.Name) == 0 {
+			return Schedules{}, cfg.errorf(csched.line, "missing schedule name")
+		}
+		key := strings.ToLower(csched.Name)
+		if prev, ok := names[key]; ok {
+			if prev == csched.Name {
+				return Schedules{}, cfg.errorf(csched.line, "duplicate schedule name: %v", csched.Name)
+			}
+			return Schedules{}, cfg.errorf(csched.line, "duplicate schedule name: %v differs from %v only in case", csched.Name, prev)
 		}
-		names[csched.Name] = struct{}{}
+		names[key] = csched.Name
 		var annual Annual
 		annual.Name = csched.Name
 		dates, err := csched.Dates.parse()
 		if err != nil {
-			return Schedules{}, err
+			return Schedules{}, cfg.errorf(csched.line, "%w", err)
 		}
 
 		annual.Dates = dates
+		annual.Notify = csched.Notify
+		if tz := csched.TimeZone; len(tz) > 0 {
+			loc, err := time.LoadLocation(tz)
+			if err != nil {
+				return Schedules{}, cfg.errorf(csched.line, "invalid time_zone: %q for schedule %q: %v", tz, csched.Name, err)
+			}
+			annual.TimeLocation = loc
+		}
+		annual.DaysOfWeek, err = ParseDaysOfWeek(csched.Dates.Constraints.DaysOfWeek)
+		if err != nil {
+			return Schedules{}, cfg.errorf(csched.line, "%w", err)
+		}
+		annual.LunarPhases, err = ParseLunarPhases(csched.Dates.Constraints.LunarPhases, csched.Dates.Constraints.LunarPhaseTolerance)
+		if err != nil {
+			return Schedules{}, cfg.errorf(csched.line, "%w", err)
+		}
+
+		annual.DayCondition, err = csched.DayCondition.parseDayCondition(sys)
+		if err != nil {
+			return Schedules{}, cfg.errorf(csched.line, "schedule %q: %w", csched.Name, err)
+		}
 
-		for name, when := range csched.Actions {
-			actions, err := cfg.createActions(sys, when, csched.Name, csched.Device, name, actionDetailed{})
+		// Schedules apply to either a device or a controller.
+		target, onController := csched.Device, false
+		if len(csched.Controller) > 0 {
+			if len(csched.Device) > 0 {
+				return Schedules{}, cfg.errorf(csched.line, "schedule %q specifies both a device and a controller", csched.Name)
+			}
+			target, onController = csched.Controller, true
+		}
+		for _, at := range csched.Actions {
+			actions, err := cfg.createActions(sys, at.line, at.when, csched.Name, target, onController, at.name, actionDetailed{})
 			if err != nil {
 				return Schedules{}, err
 			}
 			annual.DailyActions = append(annual.DailyActions, actions...)
 		}
 		for _, details := range csched.ActionsDetailed {
-			actions, err := cfg.createActions(sys, details.When, csched.Name, csched.Device, details.Action, details)
+			actions, err := cfg.createActions(sys, details.line, details.When, csched.Name, target, onController, details.Action, details)
 			if err != nil {
 				return Schedules{}, err
 			}
 			annual.DailyActions = append(annual.DailyActions, actions...)
 		}
-		annual.DailyActions.Sort()
-		annual.DailyActions, err = orderActionsStatic(annual.DailyActions, csched.ActionsDetailed)
-		if err != nil {
-			return Schedules{}, fmt.Errorf("failed to order actions for schedule %q: %v", csched.Name, err)
+		if vc := csched.Vacation; vc != nil {
+			actions, err := cfg.createVacationActions(sys, csched.line, csched.Name, *vc)
+			if err != nil {
+				return Schedules{}, err
+			}
+			annual.DailyActions = append(annual.DailyActions, actions...)
 		}
 		if len(annual.DailyActions) == 0 {
-			return Schedules{}, fmt.Errorf("no actions defined for schedule %q", csched.Name)
+			return Schedules{}, cfg.errorf(csched.line, "no actions defined for schedule %q", csched.Name)
 		}
 		sched.Schedules = append(sched.Schedules, annual)
 	}
+
+	// Actions may be specified relative to those in other schedules
+	// and so can only be sorted once all schedules have been created.
+	if i, err := resolveRelativeTimes(sched.Schedules); err != nil {
+		return Schedules{}, cfg.errorf(cfg.Schedules[i].line, "%w", err)
+	}
+	for i, csched := range cfg.Schedules {
+		annual := &sched.Schedules[i]
+		annual.DailyActions.SortStable()
+		var err error
+		annual.DailyActions, err = orderActionsStatic(annual.DailyActions, csched.ActionsDetailed)
+		if err != nil {
+			return Schedules{}, cfg.errorf(csched.line, "failed to order actions for schedule %q: %v", csched.Name, err)
+		}
+		markOrdered(annual.DailyActions, csched.ActionsDetailed)
+	}
 	sched.System = sys
 
 	return sched, nil
@@ -6,6 +6,9 @@ package scheduler_test
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
 	"reflect"
 	"slices"
 	"strings"
@@ -330,7 +333,7 @@ func TestParseActionsBasic(t *testing.T) {
 		t.Fatalf("got %d schedules, want %d", got, want)
 	}
 
-	simple := scheds.Lookup("simple")
+	simple := lookupSchedule(t, scheds, "simple")
 	if got, want := simple.Name, "simple"; got != want {
 		t.Fatalf("got %s, want %s", got, want)
 	}
@@ -364,7 +367,7 @@ func TestParseActionsBasic(t *testing.T) {
 		t.Errorf("got %#v, want %#v", got, want)
 	}
 
-	args := scheds.Lookup("simple_args")
+	args := lookupSchedule(t, scheds, "simple_args")
 	if got, want := len(args.DailyActions), 2; got != want {
 		t.Fatalf("got %d actions, want %d", got, want)
 	}
@@ -389,7 +392,7 @@ func TestParseActionsMulti(t *testing.T) {
 	sys := createSystem(t, "Local")
 	scheds := createSchedules(t, sys)
 
-	multi := scheds.Lookup("multi-time")
+	multi := lookupSchedule(t, scheds, "multi-time")
 	if got, want := len(multi.DailyActions), 4; got != want {
 		t.Fatalf("got %d actions, want %d", got, want)
 	}
@@ -431,7 +434,7 @@ func TestParseActionsMulti(t *testing.T) {
 		t.Errorf("got %#v, want %#v", got, want)
 	}
 
-	repeat := scheds.Lookup("repeating")
+	repeat := lookupSchedule(t, scheds, "repeating")
 	if got, want := len(repeat.DailyActions), 3; got != want {
 		t.Fatalf("got %d actions, want %d", got, want)
 	}
@@ -456,7 +459,7 @@ func TestParseActionsPreCond(t *testing.T) {
 	sys := createSystem(t, "Local")
 	scheds := createSchedules(t, sys)
 
-	precondition := scheds.Lookup("precondition")
+	precondition := lookupSchedule(t, scheds, "precondition")
 	if got, want := len(precondition.DailyActions), 3; got != want {
 		t.Fatalf("got %d actions, want %d", got, want)
 	}
@@ -466,37 +469,38 @@ func TestParseActionsPreCond(t *testing.T) {
 		Args: []string{"sunny"},
 	}
 
-	// negation.
-	if got := precondition.DailyActions[1].T.Precondition.Condition; got != nil {
+	// negation, actions at the same time are ordered as they appear in
+	// the config file.
+	if got := precondition.DailyActions[2].T.Precondition.Condition; got != nil {
 		if _, ok, err := got(context.Background(), devices.OperationArgs{}); ok || err != nil {
 			t.Errorf("expected precondition to be true and without error: %v", err)
 		}
 	}
 
-	if got, want := precondition.DailyActions[1].T.Precondition.Name, "!"+withoutFunc.Name; got != want {
+	if got, want := precondition.DailyActions[2].T.Precondition.Name, "!"+withoutFunc.Name; got != want {
 		t.Errorf("got %v, want %v", got, want)
 	}
-	if got, want := precondition.DailyActions[1].T.Precondition.Args, withoutFunc.Args; !reflect.DeepEqual(got, want) {
+	if got, want := precondition.DailyActions[2].T.Precondition.Args, withoutFunc.Args; !reflect.DeepEqual(got, want) {
 		t.Errorf("got %v, want %v", got, want)
 	}
 
-	if got := precondition.DailyActions[2].T.Precondition.Condition; got != nil {
+	if got := precondition.DailyActions[1].T.Precondition.Condition; got != nil {
 		if _, ok, err := got(context.Background(), devices.OperationArgs{}); !ok || err != nil {
 			t.Errorf("expected precondition to be true and without error: %v", err)
 		}
 	}
 
-	if got, want := precondition.DailyActions[2].T.Precondition.Name, withoutFunc.Name; got != want {
+	if got, want := precondition.DailyActions[1].T.Precondition.Name, withoutFunc.Name; got != want {
 		t.Errorf("got %v, want %v", got, want)
 	}
-	if got, want := precondition.DailyActions[2].T.Precondition.Args, withoutFunc.Args; !reflect.DeepEqual(got, want) {
+	if got, want := precondition.DailyActions[1].T.Precondition.Args, withoutFunc.Args; !reflect.DeepEqual(got, want) {
 		t.Errorf("got %v, want %v", got, want)
 	}
 
 }
 
 func scheduledActions(t *testing.T, scheds scheduler.Schedules, sys devices.System, year int, name string) ([]time.Time, []datetime.Date) {
-	s := scheds.Lookup(name)
+	s := lookupSchedule(t, scheds, name)
 	sr, err := scheduler.New(s, sys)
 	if err != nil {
 		t.Fatal(err)
@@ -581,7 +585,7 @@ func TestParseOperationOrder(t *testing.T) {
 		{"order-6", []string{"a", "b", "d", "c"}},
 		{"order-7", []string{"d", "a", "b", "c"}},
 	} {
-		sched := scheds.Lookup(tc.name)
+		sched := lookupSchedule(t, scheds, tc.name)
 		names := []string{}
 		for _, a := range sched.DailyActions {
 			names = append(names, a.Name)
@@ -741,3 +745,472 @@ func TestValidation(t *testing.T) {
 	}
 
 }
+
+const unknownOps = `
+schedules:
+  - name: simple
+    device: device
+    actions:
+      on: 00:00:01
+      not-an-op: 00:00:02
+  - name: detailed
+    device: device
+    actions_detailed:
+      - action: off
+        when: 00:00:02
+      - action: also-not-an-op
+        when: 00:00:02
+`
+
+func TestValidationLineNumbers(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+
+	_, err := scheduler.ParseConfig(ctx, []byte(unknownOps), sys)
+	if got, want := err.Error(), `line 7: unknown operation: "not-an-op" for device: "device" for schedule "simple"`; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	var cerr *scheduler.ConfigError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("unexpected error type: %T", err)
+	}
+	if got, want := cerr.Line, 7; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	filename := filepath.Join(t.TempDir(), "schedule.yaml")
+	cfg := strings.Replace(unknownOps, "not-an-op: 00:00:02", "off: 00:00:02", 1)
+	if err := os.WriteFile(filename, []byte(cfg), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = scheduler.ParseConfigFile(ctx, filename, sys)
+	if got, want := err.Error(), filename+`:13: unknown operation: "also-not-an-op" for device: "device" for schedule "detailed"`; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+const alignedSchedules = `
+schedules:
+  - name: offset
+    device: device
+    months: mar
+    actions_detailed:
+      - action: on
+        when: 01:13
+        repeat: 1h
+        num_repeats: 3
+  - name: aligned
+    device: device
+    months: mar
+    actions_detailed:
+      - action: on
+        when: 01:13
+        repeat: 1h
+        num_repeats: 3
+        align: true
+  - name: aligned-unbounded
+    device: device
+    months: mar
+    actions_detailed:
+      - action: on
+        when: 21:13
+        repeat: 1h
+        align: true
+  - name: already-aligned
+    device: device
+    months: mar
+    actions_detailed:
+      - action: on
+        when: 22:30
+        repeat: 30m
+        align: true
+`
+
+func TestAlignedRepeats(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	scheds, err := scheduler.ParseConfig(ctx, []byte(alignedSchedules), sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal, err := scheduler.NewCalendar(scheds, sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	times := map[string][]string{}
+	for _, e := range cal.Scheduled(datetime.NewCalendarDate(2024, 3, 1)) {
+		times[e.Schedule] = append(times[e.Schedule], e.When.Format("15:04"))
+	}
+	for _, tc := range []struct {
+		schedule string
+		times    []string
+	}{
+		{"offset", []string{"01:13", "02:13", "03:13", "04:13"}},
+		{"aligned", []string{"01:13", "02:00", "03:00", "04:00"}},
+		{"aligned-unbounded", []string{"21:13", "22:00", "23:00"}},
+		{"already-aligned", []string{"22:30", "23:00", "23:30"}},
+	} {
+		if got, want := times[tc.schedule], tc.times; !slices.Equal(got, want) {
+			t.Errorf("%v: got %v, want %v", tc.schedule, got, want)
+		}
+	}
+
+	_, err = scheduler.ParseConfig(ctx, []byte(`
+schedules:
+  - name: bad
+    device: device
+    actions_detailed:
+      - action: on
+        when: 01:13
+        align: true
+`), sys)
+	if err == nil || !strings.Contains(err.Error(), "align requires a repeat interval") {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+}
+
+const daysOfWeekSchedule = `
+schedules:
+  - name: mon-thu
+    device: device
+    months: jan
+    weekdays: true
+    days_of_week: mon, Thursday
+    actions:
+      on: 08:00
+`
+
+func TestDaysOfWeek(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	scheds, err := scheduler.ParseConfig(ctx, []byte(daysOfWeekSchedule), sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := scheds.Schedules[0].DaysOfWeek.String(), "Mon, Thu"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	cal, err := scheduler.NewCalendar(scheds, sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var days []int
+	for day := 1; day <= 31; day++ {
+		if len(cal.Scheduled(datetime.NewCalendarDate(2025, 1, day))) > 0 {
+			days = append(days, day)
+		}
+	}
+	if got, want := days, []int{2, 6, 9, 13, 16, 20, 23, 27, 30}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := cal.Scheduled(datetime.NewCalendarDate(2025, 2, 3)); len(got) != 0 {
+		t.Errorf("unexpected actions for a monday in february: %v", got)
+	}
+
+	_, err = scheduler.ParseConfig(ctx, []byte(strings.ReplaceAll(daysOfWeekSchedule, "Thursday", "thurs")), sys)
+	if err == nil || !strings.Contains(err.Error(), `invalid day of week: "thurs"`) {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+}
+
+const daysOfWeekAcrossYearsSchedule = `
+schedules:
+  - name: feb-thu
+    device: device
+    months: feb
+    days_of_week: thu
+    actions:
+      on: 08:00
+  - name: jan-fri
+    device: device
+    ranges:
+      - 01/01:01/31
+    days_of_week: fri
+    actions:
+      off: 08:00
+`
+
+func TestDaysOfWeekAcrossYears(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	scheds, err := scheduler.ParseConfig(ctx, []byte(daysOfWeekAcrossYearsSchedule), sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal, err := scheduler.NewCalendar(scheds, sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduledDays := func(name string, year, month int) []int {
+		var days []int
+		for day := 1; day <= int(datetime.DaysInMonth(year, datetime.Month(month))); day++ {
+			cd := datetime.NewCalendarDate(year, datetime.Month(month), day)
+			for _, e := range cal.Scheduled(cd) {
+				if e.Schedule == name {
+					days = append(days, day)
+					break
+				}
+			}
+		}
+		return days
+	}
+	for _, tc := range []struct {
+		name        string
+		year, month int
+		want        []int
+	}{
+		// February 2024 has five Thursdays, February 2025 only four.
+		{"feb-thu", 2024, 2, []int{1, 8, 15, 22, 29}},
+		{"feb-thu", 2025, 2, []int{6, 13, 20, 27}},
+		{"feb-thu", 2025, 3, nil},
+		// January 2024 has four Fridays, January 2025 five.
+		{"jan-fri", 2024, 1, []int{5, 12, 19, 26}},
+		{"jan-fri", 2025, 1, []int{3, 10, 17, 24, 31}},
+		{"jan-fri", 2025, 2, nil},
+	} {
+		if got := scheduledDays(tc.name, tc.year, tc.month); !slices.Equal(got, tc.want) {
+			t.Errorf("%v: %v/%v: got %v, want %v", tc.name, tc.month, tc.year, got, tc.want)
+		}
+	}
+}
+
+const timeZoneSchedules = `
+schedules:
+  - name: system
+    device: device
+    ranges:
+      - 01/02:01/02
+    actions:
+      on: 08:00
+      off: sunrise
+  - name: vacation-home
+    device: device
+    time_zone: America/New_York
+    ranges:
+      - 01/02:01/02
+    actions:
+      on: 08:00
+      off: sunrise
+`
+
+func TestScheduleTimeZone(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "UTC")
+	scheds, err := scheduler.ParseConfig(ctx, []byte(timeZoneSchedules), sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := lookupSchedule(t, scheds, "system").TimeLocation; got != nil {
+		t.Errorf("unexpected time location: %v", got)
+	}
+	if got, want := lookupSchedule(t, scheds, "vacation-home").TimeLocation.String(), "America/New_York"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	cal, err := scheduler.NewCalendar(scheds, sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	when := map[string]time.Time{}
+	for _, e := range cal.Scheduled(datetime.NewCalendarDate(2025, 1, 2)) {
+		when[e.Schedule+"."+e.Name] = e.When
+	}
+	// Literal times of day are in the schedule's time zone.
+	if got, want := when["system.on"], time.Date(2025, 1, 2, 8, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := when["vacation-home.on"], time.Date(2025, 1, 2, 13, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := when["vacation-home.on"].Location().String(), "America/New_York"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// Sunrise is still that of the system's location.
+	if got, want := when["vacation-home.off"], when["system.off"]; !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	_, err = scheduler.ParseConfig(ctx, []byte(strings.ReplaceAll(timeZoneSchedules, "America/New_York", "America/Nowhere")), sys)
+	if err == nil || !strings.Contains(err.Error(), `invalid time_zone: "America/Nowhere" for schedule "vacation-home"`) {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+}
+
+const coScheduledConfig = `
+schedules:
+  - name: co-scheduled
+    device: device
+    actions:
+      d: 12:00
+      a: 12:00
+      off: 11:00
+      c: 12:00
+    actions_detailed:
+      - action: b
+        when: 12:00
+`
+
+func TestActionsDocumentOrder(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	for range 20 {
+		scheds, err := scheduler.ParseConfig(ctx, []byte(coScheduledConfig), sys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, a := range lookupSchedule(t, scheds, "co-scheduled").DailyActions {
+			names = append(names, a.Name)
+		}
+		if got, want := strings.Join(names, " "), "off d a c b"; got != want {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+
+	_, err := scheduler.ParseConfig(ctx, []byte(strings.Replace(coScheduledConfig, "c: 12:00", "a: 13:00", 1)), sys)
+	if err == nil || !strings.Contains(err.Error(), `duplicate action: "a"`) {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+}
+
+const scheduleNamesConfig = `
+schedules:
+  - name: "  lights "
+    device: device
+    actions:
+      on: 12:00
+  - name: garden
+    device: device
+    actions:
+      on: 13:00
+`
+
+func TestScheduleNames(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	scheds, err := scheduler.ParseConfig(ctx, []byte(scheduleNamesConfig), sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := scheds.Schedules[0].Name, "lights"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	for _, name := range []string{"lights", " lights\t", "Lights", " LIGHTS "} {
+		if sched, ok := scheds.Lookup(name); !ok || sched.Name != "lights" {
+			t.Errorf("%q: got %v, %v", name, sched.Name, ok)
+		}
+	}
+	if _, ok := scheds.Lookup("light"); ok {
+		t.Errorf("unexpected schedule found")
+	}
+
+	for _, tc := range []struct {
+		name, err string
+	}{
+		{"lights", "duplicate schedule name: lights"},
+		{"Lights", "duplicate schedule name: Lights differs from lights only in case"},
+		{"' '", "missing schedule name"},
+	} {
+		cfg := strings.Replace(scheduleNamesConfig, "name: garden", "name: "+tc.name, 1)
+		_, err := scheduler.ParseConfig(ctx, []byte(cfg), sys)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%v: missing or unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestExecutionOrder(t *testing.T) {
+	sys := createSystem(t, "Local")
+	scheds := createSchedules(t, sys)
+	for _, tc := range []struct {
+		name     string
+		expected []string
+	}{
+		{"ranges", []string{"another@12:00:00 before on", "on@12:00:00", "off@16:00:00"}},
+		{"order-1", []string{"a@12:00:00", "b@12:00:00", "c@12:00:00"}},
+		{"order-2", []string{"a@12:00:00", "b@12:00:00", "c@12:00:00", "d@12:00:00"}},
+		{"order-3", []string{"d@12:00:00 before a", "a@12:00:00", "b@12:00:00", "c@12:00:00"}},
+		{"order-4", []string{"a@12:00:00", "d@12:00:00 after a", "b@12:00:00", "c@12:00:00"}},
+		{"order-5", []string{"a@12:00:00", "b@12:00:00", "c@12:00:00", "d@12:00:00 after c"}},
+		{"order-6", []string{"a@12:00:00", "b@12:00:00", "d@12:00:00 before c", "c@12:00:00"}},
+		{"order-7", []string{"d@Sunset", "a@12:00:00", "b@12:00:00", "c@12:00:00"}},
+	} {
+		sched := lookupSchedule(t, scheds, tc.name)
+		got := []string{}
+		for _, oa := range sched.ExecutionOrder() {
+			got = append(got, strings.TrimSpace(oa.Name+"@"+oa.Due+" "+oa.Constraint()))
+		}
+		if want := tc.expected; !slices.Equal(got, want) {
+			t.Errorf("%v: got %v, want %v", tc.name, got, want)
+		}
+	}
+}
+
+func TestActionPriority(t *testing.T) {
+	sys := createSystem(t, "Local")
+	for _, tc := range []struct {
+		cfg      string
+		expected []string
+	}{
+		{`
+schedules:
+  - name: priority
+    device: device
+    actions:
+      a: 12:00
+      b: 12:00
+      c: 12:00
+    actions_detailed:
+      - action: d
+        when: 12:00
+        priority: 2
+      - action: on
+        when: 12:00
+        priority: 1
+      - action: off
+        when: 12:00
+        priority: -1
+      - action: another
+        when: 11:00
+        priority: 10
+`, []string{"another", "off", "a", "b", "c", "on", "d"}},
+		{`
+schedules:
+  - name: priority-before
+    device: device
+    actions:
+      a: 12:00
+      b: 12:00
+      c: 12:00
+    actions_detailed:
+      - action: d
+        when: 12:00
+        priority: 5
+        before: b
+      - action: on
+        when: 12:00
+        priority: -5
+`, []string{"on", "a", "d", "b", "c"}},
+	} {
+		sched := parseSchedule(t, sys, tc.cfg)
+		names := []string{}
+		for _, a := range sched.DailyActions {
+			names = append(names, a.Name)
+		}
+		if got, want := names, tc.expected; !slices.Equal(got, want) {
+			t.Errorf("%v: got %v, want %v", sched.Name, got, want)
+		}
+	}
+
+	sched := parseSchedule(t, sys, `
+schedules:
+  - name: priority
+    device: device
+    actions_detailed:
+      - action: d
+        when: 12:00
+        priority: 2
+`)
+	if got, want := sched.ExecutionOrder()[0].Constraint(), "priority 2"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
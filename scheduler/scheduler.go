@@ -12,19 +12,47 @@ import (
 	"iter"
 	"log/slog"
 	"os"
+	"slices"
+	"sync"
 	"time"
 
 	"cloudeng.io/datetime"
 	"cloudeng.io/datetime/schedule"
 	"cloudeng.io/logging/ctxlog"
-	"cloudeng.io/sync/errgroup"
 	"github.com/cosnicolaou/automation/devices"
 	"github.com/cosnicolaou/automation/internal/logging"
 )
 
 var ErrOpTimeout = errors.New("op-timeout")
 
-func (s *Scheduler) invokeOp(ctx context.Context, action Action, opts devices.OperationArgs) (bool, error) {
+// ErrBudgetExceeded is returned, wrapping the error from the last attempt,
+// when an action is not retried because doing so would exceed its
+// max_total_time budget.
+var ErrBudgetExceeded = errors.New("budget-exceeded")
+
+// ErrPostconditionNotMet is returned, wrapped, for operations that
+// completed without error but whose postcondition was not satisfied.
+var ErrPostconditionNotMet = errors.New("postcondition not met")
+
+// ErrSkippedWhilePaused is recorded as the error for actions that were
+// skipped because they became overdue whilst scheduling was paused.
+var ErrSkippedWhilePaused = errors.New("skipped-while-paused")
+
+type idKey struct{}
+
+// withInvocationID returns a context that carries the id used to correlate
+// all of the log records for a single invocation of an action.
+func withInvocationID(ctx context.Context, id int64) context.Context {
+	return context.WithValue(ctx, idKey{}, id)
+}
+
+func invocationID(ctx context.Context) int64 {
+	id, _ := ctx.Value(idKey{}).(int64)
+	return id
+}
+
+func (s *Scheduler) invokeOp(ctx context.Context, action Action, opts devices.OperationArgs) (any, bool, error) {
+	ctx = withStatusRecorder(ctx, s.statusRecorder)
 	if pre := action.Precondition; pre.Condition != nil {
 		preOpts := devices.OperationArgs{
 			Due:    opts.Due,
@@ -32,61 +60,252 @@ func (s *Scheduler) invokeOp(ctx context.Context, action Action, opts devices.Op
 			Writer: opts.Writer,
 			Args:   pre.Args,
 		}
-		ctx = ctxlog.WithAttributes(ctx, slog.Group("precondition", "name", pre.Name, "args", opts.Args))
-		_, ok, err := pre.Condition(ctx, preOpts)
+		ctx = ctxlog.WithAttributes(ctx, slog.Group("precondition", "name", pre.Name, "args", action.Args))
+		pctx, span := s.tracer.Start(ctx, "precondition",
+			slog.String("device", pre.Device), slog.String("condition", pre.Name))
+		var ok bool
+		var err error
+		logger := s.logger
+		if len(pre.Conditions) > 0 {
+			// Evaluate combined conditions directly in order to log
+			// those that failed.
+			var failed []string
+			ok, failed, err = EvalConditionsParallel(pctx, pre.Match, pre.Concurrency, preOpts, pre.Conditions)
+			logger = logger.With("match", pre.Match.String())
+			if len(failed) > 0 {
+				logger = logger.With("failed", failed)
+			}
+		} else {
+			_, ok, err = pre.Condition(pctx, preOpts)
+		}
+		span.SetAttributes(slog.Bool("result", ok))
+		endSpan(span, spanStatus(false, err), err)
 		if err != nil {
-			s.logger.Error("precondition", "op", action.Name, "err", err)
-			return true, fmt.Errorf("failed to evaluate precondition: %v: %v", pre.Name, err)
+			logger.Error("precondition", "id", invocationID(ctx), "op", action.Name, "err", err, "on-error", action.OnPreconditionError.String())
+			switch action.OnPreconditionError {
+			case PreconditionErrorRun:
+				ok = true
+			case PreconditionErrorSkip:
+				ok = false
+			default:
+				return nil, true, fmt.Errorf("failed to evaluate precondition: %v: %v", pre.Name, err)
+			}
+		} else {
+			logger.Info("precondition", "id", invocationID(ctx), "op", action.Name, "passed", ok)
 		}
-		s.logger.Info("precondition", "op", action.Name, "passed", ok)
 		if !ok {
-			return true, nil
+			return nil, true, nil
 		}
 	}
-	_, err := action.Op(ctx, opts)
-	return false, err
+	result, err := action.Op(ctx, opts)
+	if err == nil && action.Postcondition.Condition != nil {
+		err = s.checkPostcondition(ctx, action, opts)
+	}
+	return result, false, err
 }
 
-func (s *Scheduler) runSingleOp(ctx context.Context, due time.Time, action schedule.Active[Action]) (aborted bool, err error) {
+// checkPostcondition evaluates the action's postcondition and returns
+// ErrPostconditionNotMet, wrapped, if it is false.
+func (s *Scheduler) checkPostcondition(ctx context.Context, action Action, opts devices.OperationArgs) error {
+	post := action.Postcondition
+	opts.Args = post.Args
+	pctx, span := s.tracer.Start(ctx, "postcondition",
+		slog.String("device", post.Device), slog.String("condition", post.Name))
+	_, ok, err := post.Condition(pctx, opts)
+	span.SetAttributes(slog.Bool("result", ok))
+	endSpan(span, spanStatus(false, err), err)
+	if err != nil {
+		s.logger.Error("postcondition", "id", invocationID(ctx), "op", action.Name, "err", err)
+		return fmt.Errorf("failed to evaluate postcondition: %v: %w", post.Name, err)
+	}
+	s.logger.Info("postcondition", "id", invocationID(ctx), "op", action.Name, "passed", ok)
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrPostconditionNotMet, post)
+	}
+	return nil
+}
+
+func (s *Scheduler) runSingleOp(ctx context.Context, due time.Time, action schedule.Active[Action], timeout time.Duration, attempt int) (result any, aborted bool, err error) {
+	ctx, span := s.tracer.Start(ctx, "attempt", slog.Int("attempt", attempt))
+	defer func() {
+		endSpan(span, spanStatus(aborted, err), err)
+	}()
 	op := action.T.Action
-	ctx, cancel := context.WithTimeoutCause(ctx, op.Device.Config().Timeout, ErrOpTimeout)
+	// The rate limit is applied before the precondition is evaluated so
+	// that the time spent waiting is not counted against the operation's
+	// timeout. The slot is given back if the precondition aborts the
+	// operation since only operations are paced.
+	res, err := s.rateLimiters.Wait(ctx, action.T.controller())
+	if err != nil {
+		return nil, false, err
+	}
+	if err := s.opSemaphore.acquire(ctx); err != nil {
+		res.cancel()
+		return nil, false, err
+	}
+	ctx, cancel := context.WithTimeoutCause(ctx, timeout, ErrOpTimeout)
 	defer cancel()
+	args, secrets, err := resolveSecretArgs(ctx, op.Args)
+	if err != nil {
+		s.opSemaphore.release()
+		res.cancel()
+		return nil, false, err
+	}
+	writer, flush := newRedactingWriter(s.opWriterFor(ctx), secrets)
 	opts := devices.OperationArgs{
 		Due:    due,
 		Place:  s.place,
-		Writer: s.opWriter,
-		Args:   op.Args,
+		Writer: writer,
+		Args:   args,
 	}
-	errCh := make(chan error)
+	// The semaphore is released only once the operation returns, rather
+	// than when it times out, so that operations that ignore their
+	// context still count towards WithMaxConcurrentOps. Similarly, the
+	// writer is flushed only once the operation can no longer write to it.
+	errCh := make(chan error, 1)
 	var preconditionAbort bool
+	var opResult any
 	go func() {
 		var err error
-		preconditionAbort, err = s.invokeOp(ctx, action.T, opts)
+		opResult, preconditionAbort, err = s.invokeOp(ctx, action.T, opts)
+		flush()
+		s.opSemaphore.release()
 		errCh <- err
 	}()
 	select {
 	case err = <-errCh:
-		close(errCh)
 	case <-ctx.Done():
-		err = ctx.Err()
+		return nil, false, ctx.Err()
 	}
-	return preconditionAbort, err
+	if preconditionAbort {
+		res.cancel()
+	}
+	if err == nil && !preconditionAbort {
+		err = action.T.SuccessWhen.Evaluate(opResult)
+	}
+	return opResult, preconditionAbort, err
+}
+
+// deadline returns the time by which all attempts of the i'th action in
+// the supplied list of actions for the day must have completed. This is
+// the zero time, ie. unbounded, unless WithBoundByNextAction is in effect
+// in which case it is the time at which the next action on the same
+// device is to be issued, that is, its due time less its lead time.
+func (s *Scheduler) deadline(actions []schedule.Active[Action], i int) time.Time {
+	if !s.boundByNextAction {
+		return time.Time{}
+	}
+	cur := actions[i]
+	for _, next := range actions[i+1:] {
+		if next.T.DeviceName != cur.T.DeviceName {
+			continue
+		}
+		if fireAt := next.When.Add(-s.leadTime(next.T)); fireAt.After(cur.When) {
+			return fireAt
+		}
+	}
+	return time.Time{}
 }
 
-func (s *Scheduler) runSingleOpWithRetries(ctx context.Context, due time.Time, action schedule.Active[Action]) (aborted bool, err error) {
-	retries := max(action.T.Device.Config().Retries, 1)
-	for i := range retries {
-		aborted, err = s.runSingleOp(ctx, due, action)
-		if err == nil || aborted || errors.Is(err, context.Canceled) {
+// supersededBy returns the due time of the most recent instance of
+// actions[i] that is also due by now, if any.
+func supersededBy(actions []schedule.Active[Action], i int, now time.Time) (time.Time, bool) {
+	cur := actions[i]
+	var latest time.Time
+	for _, next := range actions[i+1:] {
+		if next.T.DeviceName != cur.T.DeviceName || next.T.Name != cur.T.Name {
+			continue
+		}
+		if next.When.After(now) {
+			break
+		}
+		latest = next.When
+	}
+	return latest, !latest.IsZero()
+}
+
+// runSingleOpWithRetries runs the action's operation, retrying it up to
+// the configured number of times with the timeout doubling for each
+// attempt, and returns the number of attempts made. If deadline is
+// non-zero each attempt is limited to the time remaining until it and
+// no further attempts are made once it has passed. An action whose
+// precondition is not satisfied is aborted without being retried.
+func (s *Scheduler) runSingleOpWithRetries(ctx context.Context, due time.Time, action schedule.Active[Action], deadline time.Time) (result any, made int, aborted bool, err error) {
+	ctx, span := s.tracer.Start(ctx, "action",
+		slog.String("schedule", s.schedule.Name),
+		slog.String("device", action.T.DeviceName),
+		slog.String("op", action.T.Name))
+	defer func() {
+		endSpan(span, spanStatus(aborted, err), err)
+	}()
+	retryConfig := action.T.retryConfig()
+	attempts := max(retryConfig.Retries, 0) + 1
+	now := func() time.Time { return s.timeSource.NowIn(s.place.TimeLocation) }
+	var budget time.Time
+	if d := action.T.MaxTotalTime; d > 0 {
+		budget = now().Add(d)
+	}
+	for i := range attempts {
+		attemptTimeout := retryConfig.AttemptTimeout(i)
+		if !deadline.IsZero() {
+			attemptTimeout = min(attemptTimeout, deadline.Sub(now()))
+		}
+		if !budget.IsZero() {
+			attemptTimeout = min(attemptTimeout, budget.Sub(now()))
+		}
+		result, aborted, err = s.runSingleOp(ctx, due, action, attemptTimeout, i)
+		made = i + 1
+		if err == nil || aborted || errors.Is(err, context.Canceled) || errors.Is(err, ErrMissingSecret) {
+			return
+		}
+		if i == attempts-1 {
+			if attempts > 1 {
+				err = fmt.Errorf("failed after %v attempts: %w", attempts, err)
+			}
+			return
+		}
+		// Unmet postconditions are always retried since they indicate
+		// that the operation failed at the device.
+		if !retryConfig.ShouldRetry(err) && !errors.Is(err, ErrPostconditionNotMet) {
+			s.logger.Info("scheduler: not retrying", "id", invocationID(ctx), "op", action.T.Name, "device", action.T.DeviceName, "retries", i, "max_retries", retryConfig.Retries, "kind", devices.ClassifyError(err), "err", err)
+			return
+		}
+		timeout := retryConfig.Delay(i)
+		if !budget.IsZero() && now().Add(timeout).After(budget) {
+			s.logger.Info("scheduler: retry budget exceeded", "id", invocationID(ctx), "op", action.T.Name, "device", action.T.DeviceName, "retries", i, "max_retries", retryConfig.Retries, "max_total_time", action.T.MaxTotalTime, "err", err)
+			err = fmt.Errorf("%w: %w", ErrBudgetExceeded, err)
+			return
+		}
+		if !deadline.IsZero() && !now().Add(timeout).Before(deadline) {
+			s.logger.Info("scheduler: not retrying, next action due", "id", invocationID(ctx), "op", action.T.Name, "device", action.T.DeviceName, "retries", i, "max_retries", retryConfig.Retries, "deadline", deadline, "err", err)
+			return
+		}
+		s.logger.Info("scheduler: retrying", "id", invocationID(ctx), "op", action.T.Name, "device", action.T.DeviceName, "retries", i, "max_retries", retryConfig.Retries, "attempt", i+1, "attempt_timeout", retryConfig.AttemptTimeout(i+1), "timeout", timeout, "err", err)
+		if werr := s.retryWait(ctx, timeout); werr != nil {
+			err = werr
 			return
 		}
-		timeout := action.T.Device.Config().Timeout
-		ctxlog.Info(ctx, "scheduler: retrying", "op", action.T.Name, "device", action.T.DeviceName, "retries", i, "max_retries", retries, "timeout", timeout, "err", err)
-		time.Sleep(timeout)
 	}
 	return
 }
 
+// retryWait waits for delay before the next attempt of an operation,
+// returning early, with the context's error, if ctx is canceled.
+func (s *Scheduler) retryWait(ctx context.Context, delay time.Duration) error {
+	if s.retrySleep != nil {
+		s.retrySleep(delay)
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 func (s *Scheduler) newStatusRecord(delay time.Duration, a schedule.Active[Action]) *logging.StatusRecord {
 	rec := &logging.StatusRecord{
 		Schedule: s.schedule.Name,
@@ -116,14 +335,130 @@ func (s *Scheduler) completed(rec *logging.StatusRecord, precondition bool, err
 	}
 }
 
-func (s *Scheduler) RunDay(ctx context.Context, place datetime.Place, active schedule.Scheduled[Action]) error {
-	for active := range active.Active(place) {
+func (s *Scheduler) completedWithAttempts(rec *logging.StatusRecord, attempts int, precondition bool, err error) {
+	if sr := s.statusRecorder; sr != nil {
+		sr.PendingDoneWithAttempts(rec, attempts, precondition, err)
+	}
+}
+
+func (s *Scheduler) leadTime(a Action) time.Duration {
+	if a.Device == nil {
+		return 0
+	}
+	return a.Device.Config().LeadTime
+}
+
+// fireOrder returns the indices of the supplied actions in the order in
+// which they are to be issued, ie. ordered by their due time less any
+// lead time, so that a lead time that is longer than the gap to the
+// preceding action is honoured. The sort is stable so that actions that
+// are issued at the same time retain their before/after and priority
+// order.
+func (s *Scheduler) fireOrder(actions []schedule.Active[Action]) []int {
+	order := make([]int, len(actions))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		fa := actions[a].When.Add(-s.leadTime(actions[a].T))
+		fb := actions[b].When.Add(-s.leadTime(actions[b].T))
+		return fa.Compare(fb)
+	})
+	return order
+}
+
+func (s *Scheduler) canceled(rec *logging.StatusRecord) {
+	if sr := s.statusRecorder; sr != nil {
+		sr.PendingCanceled(rec)
+	}
+}
+
+func (s *Scheduler) updateCounters(a schedule.Active[Action], aborted bool, err error, took time.Duration) {
+	if s.dryRun {
+		return
+	}
+	s.metrics.Record(s.schedule.Name, a.T.DeviceName, a.T.Name, aborted, err, took)
+	if s.counterStore == nil {
+		return
+	}
+	if cerr := s.counterStore.Record(s.schedule.Name, a.T.DeviceName, a.T.Name, aborted, err); cerr != nil {
+		s.logger.Warn("failed to update counters", "device", a.T.DeviceName, "op", a.T.Name, "err", cerr)
+	}
+}
+
+func (s *Scheduler) RunDay(ctx context.Context, place datetime.Place, scheduled schedule.Scheduled[Action]) error {
+	actions := slices.Collect(scheduled.Active(place))
+	var dispatcher *actionDispatcher
+	if s.concurrentActions {
+		dispatcher = newActionDispatcher()
+		defer dispatcher.wait()
+	}
+	// jitteredUntil is the time that the most recent jittered action was
+	// delayed until, the time spent waiting for that jitter is not counted
+	// when determining if subsequent actions are overdue since otherwise
+	// a jitter larger than the overdue grace would lead to any actions
+	// due at, or shortly after, the same time being skipped.
+	var jitteredUntil time.Time
+	for n, i := range s.fireOrder(actions) {
+		active := actions[i]
 		dueAt := active.When
+		if !dueAt.After(s.resumeAfter) {
+			// Already dispatched by the scheduler that this one replaced.
+			continue
+		}
 		started := s.timeSource.NowIn(dueAt.Location())
 		delay := dueAt.Sub(started)
-		overdue := delay < 0 && -delay > time.Minute
+		overdue := delay < 0 && started.Sub(laterOf(dueAt, jitteredUntil)) > s.overdueGrace
+		if !overdue && delay < 0 && active.T.Coalesce {
+			if next, ok := supersededBy(actions, i, started); ok {
+				logging.WriteCoalesced(
+					s.logger,
+					s.dryRun,
+					active.T.DeviceName,
+					active.T.Name,
+					active.T.Args,
+					started,
+					dueAt,
+					next,
+					delay,
+				)
+				continue
+			}
+		}
+		// Operations on devices with a lead time are issued early but are
+		// still logged and recorded as being due at their nominal time.
+		fireAt := dueAt
+		if lead := s.leadTime(active.T); lead > 0 && !overdue {
+			fireAt = dueAt.Add(-lead)
+			delay = max(fireAt.Sub(started), 0)
+		}
+		// Similarly, jittered operations are issued late but are logged
+		// as being due at their nominal time.
+		var jitter time.Duration
+		if !overdue {
+			if jitter = s.jitterFor(actions, i); jitter > 0 {
+				fireAt = fireAt.Add(jitter)
+				delay = max(fireAt.Sub(started), 0)
+				if delay > 0 {
+					jitteredUntil = fireAt
+				}
+			}
+		}
+		// The pending and completion records for actions that are only
+		// logged on a change of result are held back until the result
+		// is known.
+		logger := s.logger
+		var held *heldRecords
+		if active.T.LogOnChange && !overdue {
+			held = newHeldRecords(s.logger.Handler())
+			logger = slog.New(held)
+		}
+		pendingLogger := logger
+		if s.jitter != nil && !overdue {
+			pendingLogger = logger.With("jitter", jitter)
+		}
 		id := logging.WritePending(
-			s.logger,
+			pendingLogger,
 			overdue,
 			s.dryRun,
 			active.T.DeviceName,
@@ -139,34 +474,55 @@ func (s *Scheduler) RunDay(ctx context.Context, place datetime.Place, active sch
 			continue
 		}
 		rec := s.newPending(id, delay, active)
-		if delay > 0 {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.After(delay):
+		if err := s.waitUntil(ctx, fireAt, delay); err != nil {
+			s.canceled(rec)
+			return err
+		}
+		if s.pause != nil && s.pause.Paused() {
+			logger.Info(logging.LogPaused, "id", id, "device", active.T.DeviceName, "op", active.T.Name, "due", dueAt)
+			if err := s.pause.wait(ctx); err != nil {
+				s.canceled(rec)
+				return err
 			}
+			now := s.timeSource.NowIn(dueAt.Location())
+			if late := now.Sub(laterOf(dueAt, fireAt)); late > s.overdueGrace {
+				logging.WriteSkipped(logger, id, s.dryRun, active.T.DeviceName, active.T.Name, now, dueAt, -late)
+				if held != nil {
+					held.flush(ctx)
+				}
+				s.completed(rec, false, ErrSkippedWhilePaused)
+				continue
+			}
+		}
+		s.dispatches.record(s.schedule.Name, dueAt)
+		da := dueAction{
+			active:   active,
+			id:       id,
+			rec:      rec,
+			logger:   logger,
+			held:     held,
+			started:  started,
+			delay:    delay,
+			deadline: s.deadline(actions, i),
 		}
-		var aborted bool
 		var err error
-		if !s.dryRun {
-			ctx = ctxlog.WithAttributes(ctx, "device", active.T.DeviceName, "op", active.T.Name)
-			aborted, err = s.runSingleOpWithRetries(ctx, dueAt, active)
-		}
-		logging.WriteCompletion(
-			s.logger,
-			id,
-			err,
-			s.dryRun,
-			active.T.DeviceName,
-			active.T.Name,
-			active.T.Precondition.Name,
-			!aborted,
-			started,
-			time.Now().In(dueAt.Location()),
-			dueAt,
-			delay,
-		)
-		s.completed(rec, !aborted, err)
+		switch {
+		case dispatcher == nil:
+			err = s.runDue(ctx, da)
+		case active.T.Ordered || active.T.Critical:
+			// Ordered and critical actions are run once all preceding
+			// actions have completed and before any subsequent ones.
+			dispatcher.wait()
+			err = s.runDue(ctx, da)
+		default:
+			dispatcher.dispatch(active.T.DeviceName, func() {
+				_ = s.runDue(ctx, da)
+			})
+		}
+		if active.T.Critical && err != nil {
+			logging.WriteDayAborted(s.logger, id, s.dryRun, active.T.DeviceName, active.T.Name, dueAt, len(actions)-n-1, err)
+			return nil
+		}
 		if s.dryRun {
 			select {
 			case <-ctx.Done():
@@ -178,6 +534,85 @@ func (s *Scheduler) RunDay(ctx context.Context, place datetime.Place, active sch
 	return nil
 }
 
+// dueAction holds the state needed to run an action once it is due and
+// to record its completion.
+type dueAction struct {
+	active   schedule.Active[Action]
+	id       int64
+	rec      *logging.StatusRecord
+	logger   *slog.Logger
+	held     *heldRecords
+	started  time.Time
+	delay    time.Duration
+	deadline time.Time
+}
+
+// runDue runs an action that is due and records its completion. It
+// returns the error, if any, returned by the action's operation.
+func (s *Scheduler) runDue(ctx context.Context, da dueAction) error {
+	ctx, done := operationContext(ctx)
+	defer done()
+	active, id, rec, logger, held := da.active, da.id, da.rec, da.logger, da.held
+	started, delay, dueAt := da.started, da.delay, active.When
+	today := datetime.CalendarDateFromTime(dueAt)
+	if active.T.SkipIfUnchanged && s.deviceStates.unchanged(active.T.DeviceName, active.T.Name, active.T.Args, today) {
+		logging.WriteNoChange(logger, id, s.dryRun, active.T.DeviceName, active.T.Name, active.T.Args, time.Now().In(dueAt.Location()), dueAt)
+		if held != nil {
+			held.flush(ctx)
+		}
+		s.completed(rec, true, nil)
+		return nil
+	}
+	var result any
+	var aborted bool
+	var err error
+	var attempts int
+	var took time.Duration
+	var output *cappedBuffer
+	if !s.dryRun {
+		actx, drained := s.drainContext(ctx, logger, id, active.T.DeviceName, active.T.Name)
+		actx = ctxlog.WithAttributes(actx, "id", id, "device", active.T.DeviceName, "op", active.T.Name)
+		actx = withInvocationID(actx, id)
+		actx, output = s.withOutputCapture(actx)
+		opStart := time.Now()
+		result, attempts, aborted, err = s.runSingleOpWithRetries(actx, dueAt, active, da.deadline)
+		took = time.Since(opStart)
+		drained()
+	}
+	if output != nil {
+		if out := output.String(); len(out) > 0 {
+			logger = logger.With("output", out)
+		}
+	}
+	completed := time.Now().In(dueAt.Location())
+	logging.WriteCompletion(
+		logger,
+		id,
+		err,
+		s.dryRun,
+		active.T.DeviceName,
+		active.T.Name,
+		active.T.Precondition.Name,
+		!aborted,
+		attempts,
+		started,
+		completed,
+		dueAt,
+		delay,
+	)
+	s.completionCallback(active, started, completed, aborted, err)
+	if held != nil && s.resultChanged(active.T, result, aborted, err) {
+		held.flush(ctx)
+	}
+	if !s.dryRun && !aborted && err == nil {
+		s.deviceStates.record(active.T.DeviceName, active.T.Name, active.T.Args, today)
+	}
+	s.completedWithAttempts(rec, attempts, !aborted, err)
+	s.updateCounters(active, aborted, err, took)
+	s.notify(ctx, active, aborted, err)
+	return err
+}
+
 // Run runs the scheduler from the specified calendar date to the last of the scheduled
 // actions for that year.
 func (s *Scheduler) RunYear(ctx context.Context, cd datetime.CalendarDate) error {
@@ -186,9 +621,9 @@ func (s *Scheduler) RunYear(ctx context.Context, cd datetime.CalendarDate) error
 		Year:  cd.Year(),
 	}
 	toYearEnd := datetime.NewDateRange(cd.Date(), datetime.NewDate(12, 31))
-	for active := range s.scheduler.Scheduled(yp, s.schedule.Dates, toYearEnd) {
+	for active := range s.schedule.scheduled(s.scheduler, yp, toYearEnd) {
 		logging.WriteNewDay(s.logger, active.Date, len(active.Specs))
-		if len(active.Specs) == 0 {
+		if len(active.Specs) == 0 || !s.dayConditionMet(ctx, active.Date) {
 			continue
 		}
 		if err := s.RunDay(ctx, yp.Place, active); err != nil {
@@ -198,6 +633,36 @@ func (s *Scheduler) RunYear(ctx context.Context, cd datetime.CalendarDate) error
 	return nil
 }
 
+// waitUntil waits for delay, the time remaining until dueAt. Delays longer
+// than the configured maximum (see WithMaxDelay) are implausible for all
+// but the first action of a day and may be the result of the system clock
+// jumping backwards, eg. an NTP correction. Such delays are waited for in
+// increments of at most the maximum, with the delay being recomputed from
+// the current time after each increment, so that an action is not stalled
+// for hours once the clock has been corrected.
+func (s *Scheduler) waitUntil(ctx context.Context, dueAt time.Time, delay time.Duration) error {
+	for delay > 0 {
+		wait := delay
+		if s.maxDelay > 0 && delay > s.maxDelay {
+			wait = s.maxDelay
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		if wait == delay {
+			return nil
+		}
+		expected := delay - wait
+		delay = dueAt.Sub(s.timeSource.NowIn(dueAt.Location()))
+		if jump := expected - delay; jump > time.Second || jump < -time.Second {
+			s.logger.Warn("scheduler: clock jump detected", "due", dueAt, "expected", expected, "delay", delay)
+		}
+	}
+	return nil
+}
+
 // RunYear runs the scheduler from the specified calendar date to the end of that
 // year.
 func (s *Scheduler) RunYearEnd(ctx context.Context, cd datetime.CalendarDate) error {
@@ -223,7 +688,7 @@ func (s *Scheduler) ScheduledYearEnd(cd datetime.CalendarDate) iter.Seq[schedule
 		Year:  cd.Year(),
 	}
 	toYearEnd := datetime.NewDateRange(cd.Date(), datetime.NewDate(12, 31))
-	return s.scheduler.Scheduled(yp, s.schedule.Dates, toYearEnd)
+	return s.schedule.scheduled(s.scheduler, yp, toYearEnd)
 }
 
 func (s *Scheduler) Place() datetime.Place {
@@ -235,23 +700,50 @@ type Scheduler struct {
 	schedule  Annual
 	scheduler *schedule.AnnualScheduler[Action]
 	place     datetime.Place
+
+	mu          sync.Mutex        // guards lastResults, see WithConcurrentActions.
+	lastResults map[string]string // last logged result for log_on_change actions.
+	resumeAfter time.Time         // actions due at or before this time are not run, see Runner.
+
+	dayCondition        dayConditionResult
+	dayConditionTimeout time.Duration
+	notifier            Notifier
 }
 
 type Option func(o *options)
 
 type options struct {
-	timeSource     TimeSource
-	logger         *slog.Logger
-	opWriter       io.Writer
-	dryRun         bool
-	statusRecorder *logging.StatusRecorder
-	simulatedDelay time.Duration
+	timeSource        TimeSource
+	logger            *slog.Logger
+	opWriter          io.Writer
+	dryRun            bool
+	statusRecorder    *logging.StatusRecorder
+	counterStore      *logging.CounterStore
+	metrics           *logging.Metrics
+	simulatedDelay    time.Duration
+	overdueGrace      time.Duration
+	rateLimiters      *controllerRateLimiters
+	tracer            Tracer
+	boundByNextAction bool
+	pause             *Pause
+	retrySleep        func(time.Duration)
+	notifiers         Notifiers
+	deviceStates      *deviceStates
+	dispatches        *dispatches
+	maxDelay          time.Duration
+	concurrentActions bool
+	opOutputLimit     int
+	onCompletion      func(CompletionEvent)
+	jitter            *jitter
+	opSemaphore       opSemaphore
+	shutdownGrace     time.Duration
 }
 
 // TimeSource is an interface that provides the current time in a specific
 // location and is intended for testing purposes. It will be called once
-// per iteration of the scheduler to schedule the next action. time.Now().In()
-// will be used for all other time operations.
+// per iteration of the scheduler to schedule the next action and again
+// whenever a delay longer than that set by WithMaxDelay is recomputed.
+// time.Now().In() will be used for all other time operations.
 type TimeSource interface {
 	NowIn(in *time.Location) time.Time
 }
@@ -270,6 +762,16 @@ func WithTimeSource(ts TimeSource) Option {
 	}
 }
 
+// WithRetrySleep sets the function used to wait between retries of
+// an operation and is intended for testing purposes only. By default
+// the scheduler waits for the retry delay or until its context is
+// canceled, whichever comes first.
+func WithRetrySleep(fn func(time.Duration)) Option {
+	return func(o *options) {
+		o.retrySleep = fn
+	}
+}
+
 // WithLogger sets the logger to be used by the scheduler and is also
 // passed to all device operations/conditions.
 func WithLogger(l *slog.Logger) Option {
@@ -298,17 +800,80 @@ func WithStatusRecorder(sr *logging.StatusRecorder) Option {
 	}
 }
 
+// WithCounterStore sets the store used to maintain persistent per-action
+// success/failure counters.
+func WithCounterStore(cs *logging.CounterStore) Option {
+	return func(o *options) {
+		o.counterStore = cs
+	}
+}
+
+// WithMetrics sets the registry used to record per-operation metrics,
+// including the time taken by each operation.
+func WithMetrics(m *logging.Metrics) Option {
+	return func(o *options) {
+		o.metrics = m
+	}
+}
+
 func WithSimulationDelay(d time.Duration) Option {
 	return func(o *options) {
 		o.simulatedDelay = d
 	}
 }
 
+// DefaultMaxDelay is the default value for WithMaxDelay.
+const DefaultMaxDelay = time.Hour
+
+// WithMaxDelay sets the longest period that the scheduler will wait for
+// before re-reading the current time and recomputing the delay until the
+// next action is due. This guards against the system clock jumping
+// backwards, which would otherwise result in the action being delayed
+// by the size of the jump. A value of zero disables the recomputation.
+func WithMaxDelay(d time.Duration) Option {
+	return func(o *options) {
+		o.maxDelay = d
+	}
+}
+
+// WithOverdueGrace sets the amount of time that an action may be overdue
+// by and still be executed, actions that are overdue by more than this
+// are skipped. The default is one minute.
+func WithOverdueGrace(d time.Duration) Option {
+	return func(o *options) {
+		o.overdueGrace = d
+	}
+}
+
+// WithBoundByNextAction bounds the timeout for each operation, including
+// any retries, by the time remaining until the next action scheduled for
+// the same device is to be issued, so that, for example, a stuck 'on'
+// cannot delay the subsequent 'off'.
+func WithBoundByNextAction(v bool) Option {
+	return func(o *options) {
+		o.boundByNextAction = v
+	}
+}
+
+// WithPause sets the Pause used to pause and resume the scheduler. Actions
+// that become due whilst paused are held until resumed and are then run
+// if they are still within the overdue grace period, otherwise they are
+// logged as skipped.
+func WithPause(p *Pause) Option {
+	return func(o *options) {
+		o.pause = p
+	}
+}
+
 // New creates a new scheduler for the supplied schedule and associated devices.
 func New(sched Annual, system devices.System, opts ...Option) (*Scheduler, error) {
 	scheduler := &Scheduler{
 		schedule: sched,
-		place:    system.Location.Place,
+		place:    sched.place(system.Location.Place),
+		options: options{
+			overdueGrace: time.Minute,
+			maxDelay:     DefaultMaxDelay,
+		},
 	}
 	for _, opt := range opts {
 		opt(&scheduler.options)
@@ -322,8 +887,33 @@ func New(sched Annual, system devices.System, opts ...Option) (*Scheduler, error
 	if scheduler.opWriter == nil {
 		scheduler.opWriter = os.Stdout
 	}
+	if scheduler.tracer == nil {
+		scheduler.tracer = noopTracer{}
+	}
+	if scheduler.rateLimiters == nil {
+		scheduler.rateLimiters = newControllerRateLimiters()
+	}
+	if scheduler.deviceStates == nil {
+		scheduler.deviceStates = newDeviceStates()
+	}
+	if scheduler.dispatches == nil {
+		scheduler.dispatches = newDispatches()
+	}
 
 	for i, a := range sched.DailyActions {
+		if a.T.OnController {
+			ctrl := system.Controllers[a.T.DeviceName]
+			if ctrl == nil {
+				return nil, fmt.Errorf("unknown controller: %s", a.T.DeviceName)
+			}
+			op := ctrl.Operations()[a.T.Name]
+			if op == nil {
+				return nil, fmt.Errorf("unknown operation: %s for controller: %v", a.T.Name, a.T.DeviceName)
+			}
+			sched.DailyActions[i].T.Controller = ctrl
+			sched.DailyActions[i].T.Op = op
+			continue
+		}
 		dev := system.Devices[a.T.DeviceName]
 		if dev == nil {
 			return nil, fmt.Errorf("unknown device: %s", a.T.DeviceName)
@@ -335,6 +925,19 @@ func New(sched Annual, system devices.System, opts ...Option) (*Scheduler, error
 		sched.DailyActions[i].T.Device = dev
 		sched.DailyActions[i].T.Op = op
 	}
+	if pre := sched.DayCondition; pre.Condition != nil {
+		scheduler.dayConditionTimeout = dayConditionTimeout(sched, system)
+	}
+	if len(sched.Notify) > 0 {
+		if scheduler.notifiers == nil {
+			scheduler.notifiers = AvailableNotifiers
+		}
+		n, ok := scheduler.notifiers[sched.Notify]
+		if !ok {
+			return nil, fmt.Errorf("unknown notifier: %q for schedule: %q", sched.Notify, sched.Name)
+		}
+		scheduler.notifier = n
+	}
 	scheduler.logger = scheduler.logger.With("mod", "scheduler", "schedule", sched.Name)
 	scheduler.scheduler = schedule.NewAnnualScheduler(sched.DailyActions)
 	return scheduler, nil
@@ -347,29 +950,9 @@ func New(sched Annual, system devices.System, opts ...Option) (*Scheduler, error
 // Simulate function can be used to run multiple schedules using simulated
 // time appropriate for each schedule.
 func RunSchedulers(ctx context.Context, schedules Schedules, system devices.System, start datetime.CalendarDate, opts ...Option) error {
-	schedulers := make([]*Scheduler, len(schedules.Schedules))
-	for i, sched := range schedules.Schedules {
-		s, err := New(sched, system, opts...)
-		if err != nil {
-			return fmt.Errorf("failed to create scheduler for %v: %w", sched.Name, err)
-		}
-		schedulers[i] = s
-	}
-	var g errgroup.T
-	for _, s := range schedulers {
-		g.Go(func() error {
-			if err := s.RunYearEnd(ctx, start); err != nil {
-				return err
-			}
-			year := start.Year() + 1
-			for {
-				cd := datetime.NewCalendarDate(year, 1, 1)
-				if err := s.RunYearEnd(ctx, cd); err != nil {
-					return err
-				}
-				year++
-			}
-		})
+	r, err := NewRunner(schedules, system, opts...)
+	if err != nil {
+		return err
 	}
-	return g.Wait()
+	return r.Run(ctx, start)
 }
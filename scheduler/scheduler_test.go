@@ -9,11 +9,16 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
 	"slices"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"cloudeng.io/cmdutil/keystore"
 	"cloudeng.io/datetime"
 	"cloudeng.io/errors"
 	"github.com/cosnicolaou/automation/devices"
@@ -201,7 +206,7 @@ func TestScheduler(t *testing.T) {
 	deviceRecorder, logRecorder, opts := newRecordersAndLogger(ts)
 	sys, spec := setupSchedules(t, "Local")
 
-	scheduler := createScheduler(t, sys, spec.Lookup("ranges"), opts...)
+	scheduler := createScheduler(t, sys, lookupSchedule(t, spec, "ranges"), opts...)
 
 	year := 2021
 	preDelay := time.Millisecond * 5
@@ -274,7 +279,7 @@ func TestScheduleRealTime(t *testing.T) {
 
 	now := time.Now().In(sys.Location.TimeLocation)
 	today := datetime.DateFromTime(now)
-	sched := spec.Lookup("simple")
+	sched := lookupSchedule(t, spec, "simple")
 	sched.Dates.Ranges = []datetime.DateRange{datetime.NewDateRange(today, today)}
 
 	sched.DailyActions[0].Due = datetime.TimeOfDayFromTime(now.Add(time.Second))
@@ -356,7 +361,7 @@ func TestTimeout(t *testing.T) {
 
 		now := time.Now().In(sys.Location.TimeLocation)
 		today := datetime.DateFromTime(now)
-		sched := spec.Lookup(tc.sched) // slow device schedule
+		sched := lookupSchedule(t, spec, tc.sched) // slow device schedule
 		sched.Dates.Ranges = []datetime.DateRange{datetime.NewDateRange(today, today)}
 
 		sched.DailyActions[0].Due = datetime.TimeOfDayFromTime(now.Add(time.Second))
@@ -383,6 +388,355 @@ func TestTimeout(t *testing.T) {
 	}
 }
 
+const budgetSchedule = `
+schedules:
+  - name: budget
+    device: slow
+    ranges:
+      - 01/02:01/02
+    actions_detailed:
+      - action: on
+        when: 12:00
+        max_total_time: 50ms
+`
+
+// runningTimeSource returns times that advance in real time from start.
+type runningTimeSource struct {
+	start, base time.Time
+}
+
+func (ts *runningTimeSource) NowIn(loc *time.Location) time.Time {
+	return ts.start.Add(time.Since(ts.base)).In(loc)
+}
+
+func TestMaxTotalTime(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	sched := parseSchedule(t, sys, budgetSchedule)
+	if got, want := sched.DailyActions[0].T.MaxTotalTime, 50*time.Millisecond; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Each attempt times out after 10ms and is followed by a 10ms wait.
+	slow := sys.Devices["slow"]
+	cfg := slow.Config()
+	cfg.Retries = 20
+	slow.SetConfig(cfg)
+
+	// The budget is measured using the scheduler's time source, which
+	// here starts just before the action is due and advances in real time.
+	tracer := &recordingTracer{}
+	logRecorder := newRecorder()
+	s := createScheduler(t, sys, sched,
+		scheduler.WithTimeSource(&runningTimeSource{
+			start: time.Date(2024, 1, 2, 11, 59, 59, int(time.Millisecond*990), sys.Location.TimeLocation),
+			base:  time.Now(),
+		}),
+		scheduler.WithLogger(slog.New(slog.NewJSONHandler(logRecorder, nil))),
+		scheduler.WithOperationWriter(newRecorder()),
+		scheduler.WithTracerProvider(tracer))
+	start := time.Now()
+	if err := s.RunYear(ctx, datetime.NewCalendarDate(2024, 1, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("took too long: %v", took)
+	}
+	attempts := 0
+	for _, p := range tracer.paths() {
+		if p == "action/attempt" {
+			attempts++
+		}
+	}
+	if attempts < 1 || attempts >= cfg.Retries {
+		t.Errorf("unexpected number of attempts: %v", attempts)
+	}
+	logs := logRecorder.Logs(t)
+	err := containsError(logs)
+	if err == nil || !strings.HasPrefix(err.Error(), "budget-exceeded: ") {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+
+	if _, err := scheduler.ParseConfig(ctx, []byte(strings.ReplaceAll(budgetSchedule, "50ms", "-1s")), sys); err == nil || !strings.Contains(err.Error(), "max_total_time must not be negative") {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+}
+
+const backoffSchedule = `
+schedules:
+  - name: backoff
+    device: slow
+    ranges:
+      - 01/02:01/02
+    actions:
+      on: 12:00
+`
+
+func TestRetryBackoff(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	sched := parseSchedule(t, sys, backoffSchedule)
+
+	slow := sys.Devices["slow"]
+	cfg := slow.Config()
+	cfg.Retries = 5
+	cfg.Backoff = []time.Duration{time.Millisecond, 5 * time.Millisecond, 30 * time.Millisecond}
+	slow.SetConfig(cfg)
+
+	var mu sync.Mutex
+	var delays []time.Duration
+	sleep := func(d time.Duration) {
+		mu.Lock()
+		delays = append(delays, d)
+		mu.Unlock()
+	}
+	_, logRecorder := runScheduleForYear(ctx, t, sys, sched, 2024, scheduler.WithRetrySleep(sleep))
+	if err := containsError(logRecorder.Logs(t)); err == nil {
+		t.Errorf("expected an error")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if got, want := delays, []time.Duration{time.Millisecond, 5 * time.Millisecond, 30 * time.Millisecond, 30 * time.Millisecond, 30 * time.Millisecond}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+type rejectingDevice struct {
+	testutil.MockDevice
+}
+
+func (rd *rejectingDevice) Operations() map[string]devices.Operation {
+	return map[string]devices.Operation{
+		"on": func(context.Context, devices.OperationArgs) (any, error) {
+			return nil, fmt.Errorf("busy: %w", devices.ErrDeviceRejected)
+		},
+	}
+}
+
+func TestRetryOn(t *testing.T) {
+	ctx := context.Background()
+	sys, err := devices.ParseSystemConfig(ctx, []byte(`
+devices:
+  - name: slow
+    type: slow_device
+    retries: 3
+    operations:
+      on:
+  - name: rejecting
+    type: rejecting_device
+    retries: 3
+    operations:
+      on:
+`), devices.WithDevices(devices.SupportedDevices{
+		"slow_device": supportedDevices["slow_device"],
+		"rejecting_device": func(string, devices.Options) (devices.Device, error) {
+			return &rejectingDevice{}, nil
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attempts := func(device string) int {
+		sched := parseSchedule(t, sys, strings.ReplaceAll(backoffSchedule, "device: slow", "device: "+device))
+		tracer := &recordingTracer{}
+		runScheduleForYear(ctx, t, sys, sched, 2024,
+			scheduler.WithTracerProvider(tracer),
+			scheduler.WithRetrySleep(func(time.Duration) {}))
+		n := 0
+		for _, p := range tracer.paths() {
+			if p == "action/attempt" {
+				n++
+			}
+		}
+		return n
+	}
+
+	// Timeouts are retried by default, device rejections are not, and
+	// retries: 3 results in up to four attempts.
+	if got, want := attempts("slow"), 4; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := attempts("rejecting"), 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	rejecting := sys.Devices["rejecting"]
+	cfg := rejecting.Config()
+	cfg.RetryOn = []devices.ErrorKind{devices.ErrorRejected}
+	rejecting.SetConfig(cfg)
+	if got, want := attempts("rejecting"), 4; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// retryingDevice records the timeout of each attempt of its on operation,
+// which always times out, and has a condition that is never satisfied.
+type retryingDevice struct {
+	testutil.MockDevice
+	mu       sync.Mutex
+	timeouts []time.Duration
+}
+
+func (dd *retryingDevice) Operations() map[string]devices.Operation {
+	return map[string]devices.Operation{
+		"on": func(ctx context.Context, _ devices.OperationArgs) (any, error) {
+			deadline, _ := ctx.Deadline()
+			dd.mu.Lock()
+			dd.timeouts = append(dd.timeouts, time.Until(deadline))
+			dd.mu.Unlock()
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+}
+
+func (dd *retryingDevice) Conditions() map[string]devices.Condition {
+	return map[string]devices.Condition{
+		"never": func(context.Context, devices.OperationArgs) (any, bool, error) {
+			return nil, false, nil
+		},
+	}
+}
+
+func TestRetryTimeouts(t *testing.T) {
+	ctx := context.Background()
+	dd := &retryingDevice{}
+	sys, err := devices.ParseSystemConfig(ctx, []byte(`
+devices:
+  - name: deadline
+    type: retrying_device
+    timeout: 20ms
+    retries: 2
+    operations:
+      on:
+    conditions:
+      never:
+`), devices.WithDevices(devices.SupportedDevices{
+		"retrying_device": func(string, devices.Options) (devices.Device, error) {
+			return dd, nil
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sched := parseSchedule(t, sys, strings.ReplaceAll(backoffSchedule, "device: slow", "device: deadline"))
+	_, logRecorder := runScheduleForYear(ctx, t, sys, sched, 2024,
+		scheduler.WithRetrySleep(func(time.Duration) {}))
+
+	// The timeout doubles for each attempt.
+	dd.mu.Lock()
+	timeouts := dd.timeouts
+	dd.mu.Unlock()
+	if got, want := len(timeouts), 3; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i, want := range []time.Duration{20 * time.Millisecond, 40 * time.Millisecond, 80 * time.Millisecond} {
+		if got := timeouts[i]; got > want || got < want-10*time.Millisecond {
+			t.Errorf("attempt %v: got %v, want %v", i, got, want)
+		}
+	}
+
+	// Each retry is logged and the final error wraps that of the last
+	// attempt.
+	retries := 0
+	for _, l := range logRecorder.Lines() {
+		if strings.Contains(l, `"msg":"scheduler: retrying"`) {
+			retries++
+		}
+	}
+	if got, want := retries, 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err := containsError(logRecorder.Logs(t)); err == nil || err.Error() != "failed after 3 attempts: context deadline exceeded" {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+	for _, l := range logRecorder.Logs(t) {
+		if l.Msg == logging.LogFailed {
+			if got, want := l.Attempts, 3; got != want {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		}
+	}
+
+	// An unsatisfied precondition does not consume any retries.
+	dd.mu.Lock()
+	dd.timeouts = nil
+	dd.mu.Unlock()
+	sched = parseSchedule(t, sys, `
+schedules:
+  - name: precondition
+    device: deadline
+    ranges:
+      - 01/02:01/02
+    actions_detailed:
+      - action: on
+        when: 12:00
+        precondition:
+          device: deadline
+          op: never
+`)
+	tracer := &recordingTracer{}
+	_, logRecorder = runScheduleForYear(ctx, t, sys, sched, 2024,
+		scheduler.WithTracerProvider(tracer),
+		scheduler.WithRetrySleep(func(time.Duration) {}))
+	if got, want := strings.Join(tracer.paths(), " "), "action action/attempt action/attempt/precondition"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err := containsError(logRecorder.Logs(t)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	dd.mu.Lock()
+	defer dd.mu.Unlock()
+	if got := len(dd.timeouts); got != 0 {
+		t.Errorf("unexpected attempts: %v", got)
+	}
+}
+
+func TestExternalDeviceTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a shell script")
+	}
+	ctx := context.Background()
+	script := filepath.Join(t.TempDir(), "device.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nexec sleep 10\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	sys, err := devices.ParseSystemConfig(ctx, []byte(`
+devices:
+  - name: slow
+    type: external
+    command: `+script+`
+    timeout: 20ms
+    retries: 1
+    operations:
+      on:
+`), devices.WithDevices(devices.SupportedDevices{
+		"external": devices.NewExternalDevice,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Timeouts of external commands are retried under the default
+	// retry_on.
+	sched := parseSchedule(t, sys, backoffSchedule)
+	_, logRecorder := runScheduleForYear(ctx, t, sys, sched, 2024,
+		scheduler.WithRetrySleep(func(time.Duration) {}))
+	err = containsError(logRecorder.Logs(t))
+	if err == nil || !strings.HasPrefix(err.Error(), "failed after 2 attempts: context deadline exceeded") {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+	for _, l := range logRecorder.Logs(t) {
+		if l.Msg == logging.LogFailed {
+			if got, want := l.Attempts, 2; got != want {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		}
+	}
+}
+
 func TestMultiYear(t *testing.T) {
 	ctx := context.Background()
 
@@ -398,7 +752,7 @@ func TestMultiYear(t *testing.T) {
 		scheduler.WithLogger(logger),
 	}
 
-	scheduler := createScheduler(t, sys, spec.Lookup("multi-year"), opts...)
+	scheduler := createScheduler(t, sys, lookupSchedule(t, spec, "multi-year"), opts...)
 
 	preDelay := time.Millisecond * 5
 	all2023, times2023, ticks2023 := allActive(scheduler, 2023, preDelay)
@@ -457,7 +811,7 @@ func TestDST(t *testing.T) {
 
 		year := 2024
 
-		scheduler := createScheduler(t, sys, spec.Lookup("daylight-saving-time"), opts...)
+		scheduler := createScheduler(t, sys, lookupSchedule(t, spec, "daylight-saving-time"), opts...)
 
 		all, times, ticks := allActive(scheduler, year, preDelay)
 		times, ticks = appendYearEndTimesTicks(year, sys.Location.TimeLocation, times, ticks)
@@ -685,7 +1039,7 @@ func TestRepeats(t *testing.T) {
 			t.Fatalf("got %v, want %v", got, want)
 		}
 
-		scheduler := createScheduler(t, sys, spec.Lookup(tc.schedule), opts...)
+		scheduler := createScheduler(t, sys, lookupSchedule(t, spec, tc.schedule), opts...)
 
 		year := 2024
 		all, _, ticks := allActive(scheduler, year, preDelay)
@@ -759,7 +1113,7 @@ func TestRepeatsBounded(t *testing.T) {
 	_, logRecorder, opts := newRecordersAndLogger(ts)
 	sys, spec := setupSchedules(t, "Local")
 
-	scheduler := createScheduler(t, sys, spec.Lookup("repeating-bounded"), opts...)
+	scheduler := createScheduler(t, sys, lookupSchedule(t, spec, "repeating-bounded"), opts...)
 
 	year := 2024
 	all, _, ticks := allActive(scheduler, year, preDelay)
@@ -807,3 +1161,1120 @@ func TestRepeatsBounded(t *testing.T) {
 		}
 	}
 }
+
+func parseSchedule(t *testing.T, sys devices.System, cfg string) scheduler.Annual {
+	t.Helper()
+	scheds, err := scheduler.ParseConfig(context.Background(), []byte(cfg), sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scheds.Schedules[0]
+}
+
+func lookupSchedule(t *testing.T, scheds scheduler.Schedules, name string) scheduler.Annual {
+	t.Helper()
+	sched, ok := scheds.Lookup(name)
+	if !ok {
+		t.Fatalf("schedule %q not found", name)
+	}
+	return sched
+}
+
+func runScheduleForYear(ctx context.Context, t *testing.T, sys devices.System, sched scheduler.Annual, year int, opts ...scheduler.Option) (deviceRecorder, logRecorder *recorder) {
+	t.Helper()
+	ts := &timesource{ch: make(chan time.Time, 1)}
+	deviceRecorder, logRecorder, defaultOpts := newRecordersAndLogger(ts)
+	s := createScheduler(t, sys, sched, append(defaultOpts, opts...)...)
+	_, times, ticks := allActive(s, year, time.Millisecond*5)
+	_, ticks = appendYearEndTimesTicks(year, sys.Location.TimeLocation, times, ticks)
+	runScheduler(ctx, t, s, year, ts, ticks)
+	return
+}
+
+const secretSchedule = `
+schedules:
+  - name: secret
+    device: device
+    ranges:
+      - 01/02:01/02
+    actions_detailed:
+      - action: on
+        when: 12:00
+        args: ["$secret:api-key", "plain"]
+`
+
+func TestSecretArgs(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	sched := parseSchedule(t, sys, secretSchedule)
+
+	keys, err := keystore.Parse([]byte(`
+- key_id: api-key
+  user: someone
+  token: the-secret-token
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx = keystore.ContextWithAuth(ctx, keys)
+	deviceRecorder, logRecorder := runScheduleForYear(ctx, t, sys, sched, 2024)
+
+	if err := containsError(logRecorder.Logs(t)); err != nil {
+		t.Fatal(err)
+	}
+	lines := deviceRecorder.Lines()
+	if got, want := len(lines), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	// The mock device echoes its arguments, so the secret must have
+	// been resolved, but then redacted by the operation writer.
+	if got, want := lines[0], "device[device].On: [2] <redacted>--plain"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, l := range logRecorder.Lines() {
+		if strings.Contains(l, "the-secret-token") {
+			t.Errorf("secret leaked into log: %v", l)
+		}
+	}
+	if !strings.Contains(logRecorder.out.String(), "$secret:api-key") {
+		t.Errorf("secret reference missing from log")
+	}
+
+	// Missing secrets result in the operation failing.
+	_, logRecorder = runScheduleForYear(context.Background(), t, sys, sched, 2024)
+	if err := containsError(logRecorder.Logs(t)); err == nil || err.Error() != "missing secret: $secret:api-key" {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+}
+
+// chunkedDevice writes its first argument one byte at a time so that
+// any secret it contains is split across multiple writes.
+type chunkedDevice struct {
+	testutil.MockDevice
+}
+
+func (cd *chunkedDevice) Operations() map[string]devices.Operation {
+	return map[string]devices.Operation{
+		"on": cd.On,
+	}
+}
+
+func (cd *chunkedDevice) On(_ context.Context, opts devices.OperationArgs) (any, error) {
+	for _, b := range []byte("token=" + opts.Args[0] + "\n") {
+		if _, err := opts.Writer.Write([]byte{b}); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func TestSecretArgsSplitWrites(t *testing.T) {
+	ctx := context.Background()
+	sys, err := devices.ParseSystemConfig(ctx, []byte(`
+time_location: Local
+devices:
+  - name: device
+    type: chunked
+    operations:
+      on:
+`), devices.WithDevices(devices.SupportedDevices{
+		"chunked": func(string, devices.Options) (devices.Device, error) {
+			return &chunkedDevice{}, nil
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sched := parseSchedule(t, sys, secretSchedule)
+	keys, err := keystore.Parse([]byte(`
+- key_id: api-key
+  user: someone
+  token: the-secret-token
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx = keystore.ContextWithAuth(ctx, keys)
+	deviceRecorder, _ := runScheduleForYear(ctx, t, sys, sched, 2024)
+	if got, want := deviceRecorder.Lines(), []string{"token=<redacted>"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+const singleActionSchedule = `
+schedules:
+  - name: single
+    device: device
+    ranges:
+      - 01/02:01/02
+    actions:
+      on: 12:00
+`
+
+func TestOverdueGrace(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	sched := parseSchedule(t, sys, singleActionSchedule)
+
+	run := func(opts ...scheduler.Option) []logging.Entry {
+		ts := &timesource{ch: make(chan time.Time, 1)}
+		_, logRecorder, defaultOpts := newRecordersAndLogger(ts)
+		s := createScheduler(t, sys, sched, append(defaultOpts, opts...)...)
+		year := 2024
+		_, times, ticks := allActive(s, year, 0)
+		for i := range ticks {
+			ticks[i] = ticks[i].Add(90 * time.Second)
+		}
+		_, ticks = appendYearEndTimesTicks(year, sys.Location.TimeLocation, times, ticks)
+		runScheduler(ctx, t, s, year, ts, ticks)
+		return logRecorder.Logs(t)
+	}
+
+	// Skipped with the default grace of one minute, only the
+	// year end log entry is present.
+	logs := run()
+	if got, want := len(logs), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if logs[0].YearEndDelay == 0 {
+		t.Errorf("missing year end delay")
+	}
+
+	logs = run(scheduler.WithOverdueGrace(2 * time.Minute))
+	if got, want := len(logs), 2; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := logs[0].Msg, logging.LogCompleted; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := logs[0].Delay, -90*time.Second; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestLeadTime(t *testing.T) {
+	ctx := context.Background()
+	cfg := strings.ReplaceAll(devicesConfig, "    type: device\n", "    type: device\n    lead_time: 1h\n")
+	sys, err := devices.ParseSystemConfig(ctx, []byte(cfg),
+		devices.WithDevices(supportedDevices),
+		devices.WithControllers(supportedControllers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sched := parseSchedule(t, sys, singleActionSchedule)
+
+	ts := &timesource{ch: make(chan time.Time, 1)}
+	deviceRecorder, logRecorder, opts := newRecordersAndLogger(ts)
+	s := createScheduler(t, sys, sched, opts...)
+	year := 2024
+	// The time source is advanced to a little over the lead time before
+	// the due time, the operation would never be issued without the
+	// lead time being applied.
+	_, times, ticks := allActive(s, year, time.Hour+time.Millisecond*5)
+	_, ticks = appendYearEndTimesTicks(year, sys.Location.TimeLocation, times, ticks)
+	runScheduler(ctx, t, s, year, ts, ticks)
+
+	logs := logRecorder.Logs(t)
+	if err := containsError(logs); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(deviceRecorder.Lines()), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := len(logs), 2; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	// The logged due time is the nominal one.
+	if got, want := logs[0].Due, time.Date(year, 1, 2, 12, 0, 0, 0, time.Local); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := logs[0].Delay, time.Millisecond*5; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestLeadTimeOrder(t *testing.T) {
+	ctx := context.Background()
+	sys, err := devices.ParseSystemConfig(ctx, []byte(`
+time_location: Local
+devices:
+  - name: device
+    type: device
+    operations:
+      a:
+      b:
+  - name: early
+    type: device
+    lead_time: 10s
+    operations:
+      a:
+      b:
+`), devices.WithDevices(supportedDevices))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sched := parseSchedule(t, sys, `
+schedules:
+  - name: lead
+    device: device
+    ranges:
+      - 01/02:01/02
+    actions:
+      a: 18:00:00
+      b: 18:00:05
+`)
+	// b is issued on a device whose lead time is longer than the gap
+	// to a and hence must be issued before a.
+	sched.DailyActions = slices.Clone(sched.DailyActions)
+	for i, a := range sched.DailyActions {
+		if a.Name == "b" {
+			sched.DailyActions[i].T.DeviceName = "early"
+		}
+	}
+
+	ts := &timesource{ch: make(chan time.Time, 1)}
+	_, logRecorder, opts := newRecordersAndLogger(ts)
+	s := createScheduler(t, sys, sched, opts...)
+	year := 2024
+	day := time.Date(year, 1, 2, 18, 0, 0, 0, time.Local)
+	times := []time.Time{day, day.Add(5 * time.Second)}
+	ticks := []time.Time{day.Add(-5*time.Second - 5*time.Millisecond), day.Add(-5 * time.Millisecond)}
+	_, ticks = appendYearEndTimesTicks(year, sys.Location.TimeLocation, times, ticks)
+	runScheduler(ctx, t, s, year, ts, ticks)
+
+	logs := logRecorder.Logs(t)
+	if err := containsError(logs); err != nil {
+		t.Fatal(err)
+	}
+	var ops []string
+	for _, l := range logs {
+		if l.Msg == logging.LogCompleted {
+			ops = append(ops, l.Op)
+			if got, want := l.Delay, 5*time.Millisecond; got != want {
+				t.Errorf("%v: got %v, want %v", l.Op, got, want)
+			}
+		}
+	}
+	if got, want := strings.Join(ops, " "), "b a"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestClockJump(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	sched := parseSchedule(t, sys, singleActionSchedule)
+
+	ts := &timesource{ch: make(chan time.Time, 1)}
+	deviceRecorder, logRecorder, defaultOpts := newRecordersAndLogger(ts)
+	s := createScheduler(t, sys, sched, append(defaultOpts, scheduler.WithMaxDelay(10*time.Millisecond))...)
+	year := 2024
+	_, times, _ := allActive(s, year, 0)
+	if got, want := len(times), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	// The clock has jumped back by 5 hours when the delay for the action
+	// is first computed and is corrected by the time it is recomputed.
+	ticks := []time.Time{times[0].Add(-5 * time.Hour), times[0]}
+	_, ticks = appendYearEndTimesTicks(year, sys.Location.TimeLocation, times, ticks)
+
+	start := time.Now()
+	runScheduler(ctx, t, s, year, ts, ticks)
+	if took := time.Since(start); took > 5*time.Second {
+		t.Errorf("action was stalled for %v", took)
+	}
+	if err := containsError(logRecorder.Logs(t)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(deviceRecorder.Lines()), 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if !strings.Contains(logRecorder.out.String(), "scheduler: clock jump detected") {
+		t.Errorf("clock jump was not logged")
+	}
+}
+
+const rateLimitedSystem = `
+time_location: Local
+controllers:
+  - name: ctrl
+    type: controller
+    commands_per_minute: 600
+devices:
+  - name: device
+    type: device
+    controller: ctrl
+    operations:
+      a:
+      b:
+      c:
+      d:
+`
+
+const rateLimitedSchedule = `
+schedules:
+  - name: rate-limited
+    device: device
+    ranges:
+      - 01/02:01/02
+    actions:
+      a: 12:00
+      b: 12:00
+      c: 12:00
+      d: 12:00
+`
+
+type timedRecorder struct {
+	sync.Mutex
+	times []time.Time
+}
+
+func (tr *timedRecorder) Write(p []byte) (int, error) {
+	tr.Lock()
+	defer tr.Unlock()
+	tr.times = append(tr.times, time.Now())
+	return len(p), nil
+}
+
+func TestRateLimit(t *testing.T) {
+	ctx := context.Background()
+	sys, err := devices.ParseSystemConfig(ctx, []byte(rateLimitedSystem),
+		devices.WithDevices(supportedDevices),
+		devices.WithControllers(supportedControllers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sched := parseSchedule(t, sys, rateLimitedSchedule)
+	tr := &timedRecorder{}
+	_, logRecorder := runScheduleForYear(ctx, t, sys, sched, 2024, scheduler.WithOperationWriter(tr))
+	if err := containsError(logRecorder.Logs(t)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(tr.times), 4; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	// 600 commands per minute is one every 100ms, allow for some
+	// timer slop.
+	for i := 1; i < len(tr.times); i++ {
+		if gap := tr.times[i].Sub(tr.times[i-1]); gap < 90*time.Millisecond {
+			t.Errorf("op %v: too close to previous op: %v", i, gap)
+		}
+	}
+}
+
+func TestRateLimitPrecondition(t *testing.T) {
+	ctx := context.Background()
+	sys, err := devices.ParseSystemConfig(ctx, []byte(rateLimitedSystem+"    conditions:\n      weather:\n"),
+		devices.WithDevices(supportedDevices),
+		devices.WithControllers(supportedControllers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aborted := "        precondition:\n          device: device\n          op: \"!weather\"\n"
+	cfg := `
+schedules:
+  - name: rate-limited
+    device: device
+    ranges:
+      - 01/02:01/02
+    actions_detailed:
+`
+	for _, op := range []string{"a", "b", "c"} {
+		cfg += "      - action: " + op + "\n        when: 12:00:00\n" + aborted
+	}
+	cfg += "      - action: d\n        when: 12:00:01\n      - action: d\n        when: 12:00:01\n"
+	sched := parseSchedule(t, sys, cfg)
+
+	// Actions aborted by their precondition do not use up any of the
+	// controller's rate limit.
+	tr := &timedRecorder{}
+	start := time.Now()
+	_, logRecorder := runScheduleForYear(ctx, t, sys, sched, 2024, scheduler.WithOperationWriter(tr))
+	if err := containsError(logRecorder.Logs(t)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(tr.times), 2; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if delay := tr.times[0].Sub(start); delay > 150*time.Millisecond {
+		t.Errorf("first op was delayed by aborted ops: %v", delay)
+	}
+	if gap := tr.times[1].Sub(tr.times[0]); gap < 90*time.Millisecond {
+		t.Errorf("op too close to previous op: %v", gap)
+	}
+}
+
+type deadlineDevice struct {
+	testutil.MockDevice
+	sync.Mutex
+	remaining []time.Duration
+}
+
+func (dd *deadlineDevice) Operations() map[string]devices.Operation {
+	return map[string]devices.Operation{
+		"on":  dd.record,
+		"off": dd.record,
+	}
+}
+
+func (dd *deadlineDevice) record(ctx context.Context, _ devices.OperationArgs) (any, error) {
+	dl, _ := ctx.Deadline()
+	dd.Lock()
+	defer dd.Unlock()
+	dd.remaining = append(dd.remaining, time.Until(dl))
+	return nil, nil
+}
+
+const boundedSystem = `
+time_location: Local
+devices:
+  - name: device
+    type: deadline
+    timeout: 1h
+    operations:
+      on:
+      off:
+`
+
+const boundedSchedule = `
+schedules:
+  - name: bounded
+    device: device
+    ranges:
+      - 01/02:01/02
+    actions:
+      on: 12:00:00
+      off: 12:00:01
+`
+
+// runBounded runs sched, using a time source that starts just before its
+// first action is due and advances in real time, since the bound on each
+// operation is determined using the scheduler's time source.
+func runBounded(ctx context.Context, t *testing.T, sys devices.System, sched scheduler.Annual, bound bool) *recorder {
+	t.Helper()
+	logRecorder := newRecorder()
+	s := createScheduler(t, sys, sched,
+		scheduler.WithTimeSource(&runningTimeSource{
+			start: time.Date(2024, 1, 2, 11, 59, 59, int(time.Millisecond*900), sys.Location.TimeLocation),
+			base:  time.Now(),
+		}),
+		scheduler.WithLogger(slog.New(slog.NewJSONHandler(logRecorder, nil))),
+		scheduler.WithOperationWriter(newRecorder()),
+		scheduler.WithBoundByNextAction(bound))
+	if err := s.RunYear(ctx, datetime.NewCalendarDate(2024, 1, 2)); err != nil {
+		t.Fatal(err)
+	}
+	return logRecorder
+}
+
+func TestBoundByNextAction(t *testing.T) {
+	ctx := context.Background()
+	dd := &deadlineDevice{}
+	sys, err := devices.ParseSystemConfig(ctx, []byte(boundedSystem),
+		devices.WithDevices(devices.SupportedDevices{
+			"deadline": func(string, devices.Options) (devices.Device, error) {
+				return dd, nil
+			},
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sched := parseSchedule(t, sys, boundedSchedule)
+
+	for _, bound := range []bool{false, true} {
+		dd.remaining = nil
+		logRecorder := runBounded(ctx, t, sys, sched, bound)
+		if err := containsError(logRecorder.Logs(t)); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(dd.remaining), 2; got != want {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		first, second := dd.remaining[0], dd.remaining[1]
+		if bound {
+			// The 'on' operation is bounded by the 'off' 1 second later.
+			if first > time.Second || first < 900*time.Millisecond {
+				t.Errorf("first op deadline not bounded by next action: %v", first)
+			}
+		} else if first < 59*time.Minute {
+			t.Errorf("first op deadline should not be bounded: %v", first)
+		}
+		// The last operation of the day is never bounded.
+		if second < 59*time.Minute {
+			t.Errorf("last op deadline should not be bounded: %v", second)
+		}
+	}
+}
+
+// stuckDevice's 'on' operation never completes of its own accord.
+type stuckDevice struct {
+	testutil.MockDevice
+	sync.Mutex
+	started, deadlines []time.Time
+}
+
+func (sd *stuckDevice) Operations() map[string]devices.Operation {
+	return map[string]devices.Operation{
+		"on": sd.on,
+		"off": func(context.Context, devices.OperationArgs) (any, error) {
+			return nil, nil
+		},
+	}
+}
+
+func (sd *stuckDevice) on(ctx context.Context, _ devices.OperationArgs) (any, error) {
+	dl, _ := ctx.Deadline()
+	sd.Lock()
+	sd.started = append(sd.started, time.Now())
+	sd.deadlines = append(sd.deadlines, dl)
+	sd.Unlock()
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestBoundByNextActionRetries(t *testing.T) {
+	ctx := context.Background()
+	sd := &stuckDevice{}
+	// The second attempt, with a timeout of 800ms, would run past the
+	// 'off' operation were it not bounded.
+	sys, err := devices.ParseSystemConfig(ctx, []byte(`
+time_location: Local
+devices:
+  - name: device
+    type: stuck
+    timeout: 400ms
+    retries: 1
+    backoff: [10ms]
+    operations:
+      on:
+      off:
+`), devices.WithDevices(devices.SupportedDevices{
+		"stuck": func(string, devices.Options) (devices.Device, error) {
+			return sd, nil
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sched := parseSchedule(t, sys, boundedSchedule)
+	logRecorder := runBounded(ctx, t, sys, sched, true)
+	if err := containsError(logRecorder.Logs(t)); err == nil {
+		t.Errorf("expected an error")
+	}
+	sd.Lock()
+	defer sd.Unlock()
+	if got, want := len(sd.deadlines), 2; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	// The 'off' operation is due 1 second after the first attempt.
+	for i, dl := range sd.deadlines {
+		if d := dl.Sub(sd.started[0]); d > time.Second {
+			t.Errorf("attempt %v: deadline is %v after the first attempt", i, d)
+		}
+	}
+}
+
+const coalesceSchedule = `
+schedules:
+  - name: coalesce
+    device: device
+    ranges:
+      - 01/02:01/02
+    actions_detailed:
+      - action: on
+        when: 12:00
+        repeat: 1m
+        num_repeats: 5
+        coalesce: %v
+`
+
+func TestCoalesce(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+
+	run := func(coalesce bool) map[string][]time.Time {
+		sched := parseSchedule(t, sys, fmt.Sprintf(coalesceSchedule, coalesce))
+		ts := &timesource{ch: make(chan time.Time, 1)}
+		_, logRecorder, opts := newRecordersAndLogger(ts)
+		s := createScheduler(t, sys, sched, append(opts, scheduler.WithOverdueGrace(time.Hour))...)
+		year := 2024
+		_, times, ticks := allActive(s, year, 0)
+		if got, want := len(ticks), 6; got != want {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		// Simulate the host being paused until after the last action
+		// is due so that all of them are overdue.
+		resumed := times[len(times)-1].Add(30 * time.Second)
+		for i := range ticks {
+			ticks[i] = resumed
+		}
+		_, ticks = appendYearEndTimesTicks(year, sys.Location.TimeLocation, times, ticks)
+		runScheduler(ctx, t, s, year, ts, ticks)
+		msgs := map[string][]time.Time{}
+		for _, l := range logRecorder.Lines() {
+			e, err := logging.ParseLogLine(l)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msgs[e.Msg] = append(msgs[e.Msg], e.Due)
+		}
+		return msgs
+	}
+
+	msgs := run(false)
+	if got, want := len(msgs[logging.LogCompleted]), 6; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := len(msgs[logging.LogCoalesced]), 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	msgs = run(true)
+	if got, want := len(msgs[logging.LogCoalesced]), 5; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	completed := msgs[logging.LogCompleted]
+	if got, want := len(completed), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := completed[0].Format("15:04"), "12:05"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+type probeDevice struct {
+	testutil.MockDevice
+	sync.Mutex
+	results []int
+}
+
+func (pd *probeDevice) Operations() map[string]devices.Operation {
+	return map[string]devices.Operation{
+		"probe": pd.probe,
+	}
+}
+
+func (pd *probeDevice) probe(_ context.Context, opts devices.OperationArgs) (any, error) {
+	pd.Lock()
+	defer pd.Unlock()
+	r := pd.results[0]
+	pd.results = pd.results[1:]
+	fmt.Fprintf(opts.Writer, "%v\n", r)
+	return r, nil
+}
+
+const probeSystem = `
+time_location: Local
+devices:
+  - name: device
+    type: probe
+    operations:
+      probe:
+`
+
+const probeSchedule = `
+schedules:
+  - name: probe
+    device: device
+    ranges:
+      - 01/02:01/02
+    actions_detailed:
+      - action: probe
+        when: 12:00
+        repeat: 1m
+        num_repeats: 5
+        log_on_change: %v
+`
+
+func TestLogOnChange(t *testing.T) {
+	ctx := context.Background()
+	pd := &probeDevice{}
+	sys, err := devices.ParseSystemConfig(ctx, []byte(probeSystem),
+		devices.WithDevices(devices.SupportedDevices{
+			"probe": func(string, devices.Options) (devices.Device, error) {
+				return pd, nil
+			},
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		logOnChange bool
+		due         []string
+		output      []string
+	}{
+		{false, []string{"12:00", "12:01", "12:02", "12:03", "12:04", "12:05"}, []string{"1\n", "1\n", "1\n", "2\n", "2\n", "1\n"}},
+		{true, []string{"12:00", "12:03", "12:05"}, []string{"1\n", "2\n", "1\n"}},
+	} {
+		pd.results = []int{1, 1, 1, 2, 2, 1}
+		sched := parseSchedule(t, sys, fmt.Sprintf(probeSchedule, tc.logOnChange))
+		sr := logging.NewStatusRecorder()
+		_, logRecorder := runScheduleForYear(ctx, t, sys, sched, 2024,
+			scheduler.WithStatusRecorder(sr),
+			scheduler.WithOperationOutputInLog(10))
+		msgs := map[string][]string{}
+		var output []string
+		for _, l := range logRecorder.Lines() {
+			e, err := logging.ParseLogLine(l)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msgs[e.Msg] = append(msgs[e.Msg], e.Due.Format("15:04"))
+			if e.Msg == logging.LogCompleted {
+				output = append(output, e.Output)
+			}
+		}
+		if got, want := msgs[logging.LogCompleted], tc.due; !slices.Equal(got, want) {
+			t.Errorf("%v: got %v, want %v", tc.logOnChange, got, want)
+		}
+		// The captured output is retained for held records.
+		if got, want := output, tc.output; !slices.Equal(got, want) {
+			t.Errorf("%v: got %q, want %q", tc.logOnChange, got, want)
+		}
+		if got, want := msgs[logging.LogPending], tc.due; !slices.Equal(got, want) {
+			t.Errorf("%v: got %v, want %v", tc.logOnChange, got, want)
+		}
+		// All invocations are still recorded as having been completed.
+		if got, want := len(slices.Collect(sr.Completed())), 6; got != want {
+			t.Errorf("%v: got %v, want %v", tc.logOnChange, got, want)
+		}
+	}
+}
+
+type brokenConditionDevice struct {
+	testutil.MockDevice
+	sync.Mutex
+	calls int
+}
+
+func (bd *brokenConditionDevice) Operations() map[string]devices.Operation {
+	return map[string]devices.Operation{
+		"on": func(context.Context, devices.OperationArgs) (any, error) {
+			bd.Lock()
+			defer bd.Unlock()
+			bd.calls++
+			return nil, nil
+		},
+	}
+}
+
+func (bd *brokenConditionDevice) Conditions() map[string]devices.Condition {
+	return map[string]devices.Condition{
+		"broken": func(context.Context, devices.OperationArgs) (any, bool, error) {
+			return nil, false, fmt.Errorf("service unavailable")
+		},
+	}
+}
+
+const brokenConditionSystem = `
+time_location: Local
+devices:
+  - name: device
+    type: broken
+    operations:
+      on:
+    conditions:
+      broken:
+`
+
+const brokenConditionSchedule = `
+schedules:
+  - name: broken
+    device: device
+    ranges:
+      - 01/02:01/02
+    actions_detailed:
+      - action: on
+        when: 12:00
+        precondition:
+          device: device
+          op: broken
+        on_precondition_error: %v
+`
+
+func TestOnPreconditionError(t *testing.T) {
+	ctx := context.Background()
+	bd := &brokenConditionDevice{}
+	sys, err := devices.ParseSystemConfig(ctx, []byte(brokenConditionSystem),
+		devices.WithDevices(devices.SupportedDevices{
+			"broken": func(string, devices.Options) (devices.Device, error) {
+				return bd, nil
+			},
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		mode      string
+		calls     int
+		msg       string
+		preResult bool
+	}{
+		{"", 0, logging.LogFailed, false},
+		{"fail", 0, logging.LogFailed, false},
+		{"run", 1, logging.LogCompleted, true},
+		{"skip", 0, logging.LogCompleted, false},
+	} {
+		bd.calls = 0
+		sched := parseSchedule(t, sys, fmt.Sprintf(brokenConditionSchedule, tc.mode))
+		_, logRecorder := runScheduleForYear(ctx, t, sys, sched, 2024)
+		if got, want := bd.calls, tc.calls; got != want {
+			t.Errorf("%q: got %v, want %v", tc.mode, got, want)
+		}
+		logs := logRecorder.Logs(t)
+		if got, want := len(logs), 2; got != want {
+			t.Fatalf("%q: got %v, want %v", tc.mode, got, want)
+		}
+		if got, want := logs[0].Msg, tc.msg; got != want {
+			t.Errorf("%q: got %v, want %v", tc.mode, got, want)
+		}
+		if got, want := logs[0].PreCondResult, tc.preResult; got != want {
+			t.Errorf("%q: got %v, want %v", tc.mode, got, want)
+		}
+	}
+
+	_, err = scheduler.ParseConfig(ctx, []byte(fmt.Sprintf(brokenConditionSchedule, "maybe")), sys)
+	if err == nil || !strings.Contains(err.Error(), "invalid precondition error action") {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+}
+
+const skipIfUnchangedSchedule = `
+schedules:
+  - name: unchanged
+    device: device
+    ranges:
+      - 01/02:01/02
+    actions_detailed:
+      - action: on
+        when: 12:00
+        skip_if_unchanged: true
+      - action: on
+        when: 12:01
+        skip_if_unchanged: true
+      - action: off
+        when: 12:02
+        skip_if_unchanged: true
+      - action: on
+        when: 12:03
+        args: ["changed"]
+        skip_if_unchanged: true
+`
+
+func TestSkipIfUnchanged(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	sched := parseSchedule(t, sys, skipIfUnchangedSchedule)
+
+	deviceRecorder, logRecorder := runScheduleForYear(ctx, t, sys, sched, 2024)
+	if err := containsError(logRecorder.Logs(t)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(deviceRecorder.Lines(), "\n"), `device[device].On: [0] 
+device[device].Off: [0] 
+device[device].On: [1] changed`; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	noChange := 0
+	for _, l := range logRecorder.Lines() {
+		e, err := logging.ParseLogLine(l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Msg == logging.LogNoChange {
+			noChange++
+			if got, want := e.Due.Format("15:04"), "12:01"; got != want {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		}
+	}
+	if got, want := noChange, 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+type valveDevice struct {
+	*testutil.MockDevice
+}
+
+func (vd *valveDevice) Operations() map[string]devices.Operation {
+	ops := map[string]devices.Operation{
+		"open": func(context.Context, devices.OperationArgs) (any, error) {
+			return nil, fmt.Errorf("valve is stuck")
+		},
+	}
+	for name, op := range vd.MockDevice.Operations() {
+		ops[name] = op
+	}
+	return ops
+}
+
+const criticalSchedules = `
+schedules:
+  - name: irrigation
+    device: valve
+    ranges:
+      - 01/02:01/03
+    actions_detailed:
+      - action: open
+        when: 06:00
+        critical: true
+      - action: water
+        when: 06:05
+      - action: close
+        when: 06:30
+  - name: lights
+    device: lights
+    ranges:
+      - 01/02:01/02
+    actions:
+      on: 06:10
+`
+
+func TestCriticalAction(t *testing.T) {
+	ctx := context.Background()
+	sys, err := devices.ParseSystemConfig(ctx, []byte(`
+devices:
+  - name: valve
+    type: valve
+    operations:
+      open:
+      water:
+      close:
+  - name: lights
+    type: device
+    operations:
+      on:
+`), devices.WithDevices(devices.SupportedDevices{
+		"device": supportedDevices["device"],
+		"valve": func(string, devices.Options) (devices.Device, error) {
+			md := testutil.NewMockDevice("water", "close")
+			md.SetOutput(true)
+			return &valveDevice{MockDevice: md}, nil
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheds, err := scheduler.ParseConfig(ctx, []byte(criticalSchedules), sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	year := 2024
+	ts := &timesource{ch: make(chan time.Time, 1)}
+	deviceRecorder, logRecorder, opts := newRecordersAndLogger(ts)
+	s := createScheduler(t, sys, lookupSchedule(t, scheds, "irrigation"), opts...)
+
+	// Only the failing, critical, action is run on each day.
+	all, times, ticks := allActive(s, year, time.Millisecond*5)
+	var openTimes, openTicks []time.Time
+	for i, a := range all {
+		if a.action.Name == "open" {
+			openTimes = append(openTimes, times[i])
+			openTicks = append(openTicks, ticks[i])
+		}
+	}
+	if got, want := len(openTicks), 2; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	_, openTicks = appendYearEndTimesTicks(year, sys.Location.TimeLocation, openTimes, openTicks)
+	runScheduler(ctx, t, s, year, ts, openTicks)
+
+	if got, want := len(deviceRecorder.Lines()), 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	var aborted []logging.Entry
+	for _, l := range logRecorder.Lines() {
+		e, err := logging.ParseLogLine(l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Msg == logging.LogDayAborted {
+			aborted = append(aborted, e)
+		}
+	}
+	if got, want := len(aborted), 2; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i, e := range aborted {
+		if got, want := e.Due, openTimes[i]; !got.Equal(want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if e.Err == nil || e.Err.Error() != "valve is stuck" {
+			t.Errorf("missing or unexpected error: %v", e.Err)
+		}
+		if !strings.Contains(e.LogEntry, `"remaining":2`) {
+			t.Errorf("missing remaining count: %v", e.LogEntry)
+		}
+	}
+
+	// Other schedules are unaffected.
+	deviceRecorder, logRecorder = runScheduleForYear(ctx, t, sys, lookupSchedule(t, scheds, "lights"), year)
+	if err := containsError(logRecorder.Logs(t)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := deviceRecorder.Lines(), []string{"device[lights].On: [0] "}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+const controllerSchedule = `
+schedules:
+  - name: nightly
+    controller: hub
+    ranges:
+      - 01/02:01/02
+    actions:
+      enable: 03:00
+`
+
+func TestControllerOperations(t *testing.T) {
+	ctx := context.Background()
+	sys, err := devices.ParseSystemConfig(ctx, []byte(`
+controllers:
+  - name: hub
+    type: controller
+    operations:
+      enable:
+devices:
+  - name: device
+    type: device
+    controller: hub
+    operations:
+      on:
+`), devices.WithDevices(supportedDevices), devices.WithControllers(supportedControllers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sched := parseSchedule(t, sys, controllerSchedule)
+	if got, want := len(sched.DailyActions), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if a := sched.DailyActions[0].T; !a.OnController || a.DeviceName != "hub" {
+		t.Errorf("unexpected action: %+v", a)
+	}
+
+	deviceRecorder, logRecorder := runScheduleForYear(ctx, t, sys, sched, 2024)
+	if err := containsError(logRecorder.Logs(t)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := deviceRecorder.Lines(), []string{"controller[hub].Enable: [0] "}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, tc := range []struct {
+		cfg, err string
+	}{
+		{strings.ReplaceAll(controllerSchedule, "enable:", "disable:"), `unknown operation: "disable" for controller: "hub"`},
+		{strings.ReplaceAll(controllerSchedule, "controller: hub", "controller: hub\n    device: device"), `schedule "nightly" specifies both a device and a controller`},
+		{strings.ReplaceAll(controllerSchedule, "controller: hub", "controller: device"), "unknown controller: device"},
+	} {
+		_, err := scheduler.ParseConfig(ctx, []byte(tc.cfg), sys)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("missing or unexpected error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"cloudeng.io/cmdutil/keystore"
+)
+
+// SecretArgPrefix is the prefix used to denote an operation argument whose
+// value is to be obtained, at execution time, from the keystore stored in
+// the context passed to the scheduler. That is, an argument of the form
+// $secret:<key_id> is replaced by the token for <key_id>. The original,
+// unresolved, argument is the only form that is ever logged.
+const SecretArgPrefix = "$secret:"
+
+const redacted = "<redacted>"
+
+// ErrMissingSecret is returned when a secret argument cannot be
+// found in the keystore, operations that fail with this error are not
+// retried.
+var ErrMissingSecret = errors.New("missing secret")
+
+// resolveSecretArgs returns a copy of args with all secret arguments
+// replaced by their values from the keystore along with the secrets
+// themselves.
+func resolveSecretArgs(ctx context.Context, args []string) (resolved, secrets []string, err error) {
+	for i, arg := range args {
+		id, ok := strings.CutPrefix(arg, SecretArgPrefix)
+		if !ok {
+			continue
+		}
+		if resolved == nil {
+			resolved = make([]string, len(args))
+			copy(resolved, args)
+		}
+		token := keystore.AuthFromContextForID(ctx, id).Token
+		if len(token) == 0 {
+			return nil, nil, fmt.Errorf("%w: %v", ErrMissingSecret, arg)
+		}
+		resolved[i] = token
+		secrets = append(secrets, token)
+	}
+	if resolved == nil {
+		return args, nil, nil
+	}
+	return resolved, secrets, nil
+}
+
+// redactingWriter replaces all occurrences of the supplied secrets
+// with a fixed string before writing to the underlying writer. Since a
+// secret may be split across multiple calls to Write, any trailing output
+// that may be the start of a secret is held back until the next call to
+// Write, or to flush.
+type redactingWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	secrets [][]byte
+	pending []byte
+}
+
+// newRedactingWriter returns a writer that redacts the supplied secrets
+// and a function that must be called once all output has been written
+// in order to write any output that was held back.
+func newRedactingWriter(w io.Writer, secrets []string) (io.Writer, func()) {
+	if len(secrets) == 0 || w == nil {
+		return w, func() {}
+	}
+	rw := &redactingWriter{w: w}
+	for _, s := range secrets {
+		rw.secrets = append(rw.secrets, []byte(s))
+	}
+	return rw, rw.flush
+}
+
+func (rw *redactingWriter) Write(p []byte) (int, error) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	buf := append(rw.pending, p...)
+	for _, s := range rw.secrets {
+		buf = bytes.ReplaceAll(buf, s, []byte(redacted))
+	}
+	held := rw.partialSecret(buf)
+	rw.pending = bytes.Clone(buf[len(buf)-held:])
+	if _, err := rw.w.Write(buf[:len(buf)-held]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// partialSecret returns the length of the longest suffix of buf that is
+// a prefix of any of the secrets.
+func (rw *redactingWriter) partialSecret(buf []byte) int {
+	longest := 0
+	for _, s := range rw.secrets {
+		for n := min(len(s)-1, len(buf)); n > longest; n-- {
+			if bytes.HasSuffix(buf, s[:n]) {
+				longest = n
+				break
+			}
+		}
+	}
+	return longest
+}
+
+func (rw *redactingWriter) flush() {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if len(rw.pending) > 0 {
+		_, _ = rw.w.Write(rw.pending)
+		rw.pending = nil
+	}
+}
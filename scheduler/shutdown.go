@@ -0,0 +1,63 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/cosnicolaou/automation/internal/logging"
+)
+
+// WithShutdownGrace allows an operation that is in flight when the
+// scheduler's context is canceled up to d to complete, rather than being
+// canceled immediately. No new actions are started once the context is
+// canceled. The default of zero cancels in-flight operations immediately.
+func WithShutdownGrace(d time.Duration) Option {
+	return func(o *options) {
+		o.shutdownGrace = d
+	}
+}
+
+// drainContext returns the context to use for running an operation. If a
+// shutdown grace period is configured, the returned context is canceled
+// that long after ctx is canceled, rather than when ctx is, and draining
+// progress is logged. The returned function must be called once the
+// operation has completed. Operations that have yet to start when ctx
+// is canceled are not granted a grace period.
+func (s *Scheduler) drainContext(ctx context.Context, logger *slog.Logger, id int64, device, op string) (context.Context, func()) {
+	if s.shutdownGrace <= 0 || ctx.Err() != nil {
+		return ctx, func() {}
+	}
+	dctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+		}
+		logger.Info(logging.LogDraining, "id", id, "device", device, "op", op, "grace", s.shutdownGrace)
+		timer := time.NewTimer(s.shutdownGrace)
+		defer timer.Stop()
+		select {
+		case <-done:
+			logger.Info(logging.LogDrained, "id", id, "device", device, "op", op)
+		case <-timer.C:
+			logger.Info(logging.LogDrainTimeout, "id", id, "device", device, "op", op, "grace", s.shutdownGrace)
+			cancel()
+		}
+	}()
+	return dctx, func() {
+		close(done)
+		wg.Wait()
+		cancel()
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"cloudeng.io/datetime"
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/internal/logging"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+const shutdownSchedule = `
+schedules:
+  - name: shutdown
+    device: slow
+    actions:
+      on: 00:00
+`
+
+func TestShutdownGrace(t *testing.T) {
+	ctx := context.Background()
+	sys, err := devices.ParseSystemConfig(ctx, []byte(`
+time_location: Local
+devices:
+  - name: slow
+    type: slow
+    operations:
+      on:
+`), devices.WithDevices(devices.SupportedDevices{
+		"slow": func(string, devices.Options) (devices.Device, error) {
+			return &slowDevice{timeout: time.Hour, delay: time.Millisecond * 500}, nil
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		grace   time.Duration
+		msgs    []string
+		errmsg  string
+		outcome string
+		drained bool
+	}{
+		{0, nil, "context canceled", logging.LogFailed, false},
+		{time.Second, []string{logging.LogDraining, logging.LogDrained}, "", logging.LogCompleted, true},
+		{time.Millisecond * 50, []string{logging.LogDraining, logging.LogDrainTimeout}, "context canceled", logging.LogFailed, false},
+	} {
+		ctx, cancel := context.WithCancel(ctx)
+		logRecorder := newRecorder()
+		logger := slog.New(slog.NewJSONHandler(logRecorder, nil))
+
+		now := time.Now().In(sys.Location.TimeLocation)
+		today := datetime.DateFromTime(now)
+		sched := parseSchedule(t, sys, shutdownSchedule)
+		sched.Dates.Ranges = []datetime.DateRange{datetime.NewDateRange(today, today)}
+		due := now.Add(time.Second).Truncate(time.Second)
+		sched.DailyActions[0].Due = datetime.TimeOfDayFromTime(due)
+
+		s := createScheduler(t, sys, sched,
+			scheduler.WithLogger(logger),
+			scheduler.WithShutdownGrace(tc.grace))
+
+		// Cancel whilst the operation is in flight.
+		canceledAt := make(chan time.Time, 1)
+		go func() {
+			time.Sleep(time.Until(due) + time.Millisecond*100)
+			canceledAt <- time.Now()
+			cancel()
+		}()
+		if err := s.RunYear(ctx, datetime.NewCalendarDate(now.Year(), 1, 1)); err != nil && !errors.Is(err, context.Canceled) {
+			t.Fatal(err)
+		}
+		took := time.Since(<-canceledAt)
+		cancel()
+
+		var msgs []string
+		var completion logging.Entry
+		for _, l := range logRecorder.Lines() {
+			e, err := logging.ParseLogLine(l)
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch e.Msg {
+			case logging.LogDraining, logging.LogDrained, logging.LogDrainTimeout:
+				msgs = append(msgs, e.Msg)
+			case logging.LogCompleted, logging.LogFailed:
+				completion = e
+			}
+		}
+		if got, want := msgs, tc.msgs; !slices.Equal(got, want) {
+			t.Errorf("grace %v: got %v, want %v", tc.grace, got, want)
+		}
+		if got, want := completion.Msg, tc.outcome; got != want {
+			t.Errorf("grace %v: got %v, want %v", tc.grace, got, want)
+		}
+		if err := completion.Err; (err == nil) != (tc.errmsg == "") || (err != nil && err.Error() != tc.errmsg) {
+			t.Errorf("grace %v: unexpected or missing error: %v", tc.grace, err)
+		}
+		// The in-flight operation has ~400ms left to run when the context
+		// is canceled and is only waited for if the grace period allows.
+		if got, want := took > time.Millisecond*300, tc.drained; got != want {
+			t.Errorf("grace %v: waited %v for the operation to complete", tc.grace, took)
+		}
+	}
+}
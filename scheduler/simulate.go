@@ -15,13 +15,13 @@ import (
 	"github.com/cosnicolaou/automation/devices"
 )
 
-func ticksToYearEnd(scheduler *schedule.AnnualScheduler[Action], year int, place datetime.Place, dates schedule.Dates, bound datetime.DateRange, delay time.Duration) []time.Time {
+func ticksToYearEnd(scheduler *schedule.AnnualScheduler[Action], year int, place datetime.Place, annual Annual, bound datetime.DateRange, delay time.Duration) []time.Time {
 	times := []time.Time{}
 	yp := datetime.YearPlace{
 		Place: place,
 		Year:  year,
 	}
-	for active := range scheduler.Scheduled(yp, dates, bound) {
+	for active := range annual.scheduled(scheduler, yp, bound) {
 		for action := range active.Active(place) {
 			times = append(times, action.When.Add(-delay))
 		}
@@ -31,12 +31,12 @@ func ticksToYearEnd(scheduler *schedule.AnnualScheduler[Action], year int, place
 	return times
 }
 
-func ticksForAllYears(scheduler *schedule.AnnualScheduler[Action], place datetime.Place, dates schedule.Dates, period datetime.CalendarDateRange, delay time.Duration) []time.Time {
+func ticksForAllYears(scheduler *schedule.AnnualScheduler[Action], place datetime.Place, annual Annual, period datetime.CalendarDateRange, delay time.Duration) []time.Time {
 	times := []time.Time{}
 	yearStart := period.From().Date()
 	for year := period.From().Year(); year <= period.To().Year(); year++ {
 		thisYear := datetime.NewDateRange(yearStart, datetime.NewDate(12, 31))
-		times = append(times, ticksToYearEnd(scheduler, year, place, dates, thisYear, delay)...)
+		times = append(times, ticksToYearEnd(scheduler, year, place, annual, thisYear, delay)...)
 		yearStart = datetime.NewDate(1, 1)
 	}
 	return times
@@ -77,10 +77,13 @@ func RunSimulation(ctx context.Context, schedules Schedules, system devices.Syst
 	timeSources := make([]timesource, len(schedules.Schedules))
 	for i, s := range schedules.Schedules {
 		scheduler := schedule.NewAnnualScheduler(s.DailyActions)
-		ticks := ticksForAllYears(scheduler, system.Location.Place, s.Dates, period, delay)
+		ticks := ticksForAllYears(scheduler, s.place(system.Location.Place), s, period, delay)
 		timeSources[i] = timesource{ch: make(chan time.Time), ticks: ticks}
 	}
 	schedulers := make([]*Scheduler, len(schedules.Schedules))
+	opts = append(opts,
+		withControllerRateLimiters(newControllerRateLimiters()),
+		withDeviceStates(newDeviceStates()))
 	for i, sched := range schedules.Schedules {
 		psopts := opts
 		psopts = append(psopts, WithTimeSource(timeSources[i]))
@@ -0,0 +1,151 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"cloudeng.io/datetime"
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/internal/logging"
+)
+
+// SimulatedAction represents an action fired during a simulation.
+type SimulatedAction struct {
+	Schedule string
+	Device   string
+	Op       string
+	Args     []string
+	Due      time.Time
+}
+
+func (sa SimulatedAction) String() string {
+	return fmt.Sprintf("%v:%v.%v(%v)", sa.Schedule, sa.Device, sa.Op, strings.Join(sa.Args, ", "))
+}
+
+// SimulateActions runs a dry-run simulation, using RunSimulation, of the
+// specified schedules and returns the actions fired within the specified
+// period ordered by their due time.
+func SimulateActions(ctx context.Context, schedules Schedules, system devices.System, period datetime.CalendarDateRange, opts ...Option) ([]SimulatedAction, error) {
+	sr := logging.NewStatusRecorder()
+	opts = append(opts,
+		WithStatusRecorder(sr),
+		WithDryRun(true),
+		WithOperationWriter(io.Discard))
+	if err := RunSimulation(ctx, schedules, system, period, opts...); err != nil {
+		return nil, err
+	}
+	var actions []SimulatedAction
+	for rec := range sr.Completed() {
+		if !period.Include(datetime.CalendarDateFromTime(rec.Due)) {
+			continue
+		}
+		actions = append(actions, SimulatedAction{
+			Schedule: rec.Schedule,
+			Device:   rec.Device,
+			Op:       rec.Op,
+			Args:     rec.OpArgs,
+			Due:      rec.Due,
+		})
+	}
+	sortSimulatedActions(actions)
+	return actions, nil
+}
+
+func sortSimulatedActions(actions []SimulatedAction) {
+	slices.SortStableFunc(actions, func(a, b SimulatedAction) int {
+		if c := a.Due.Compare(b.Due); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.String(), b.String())
+	})
+}
+
+// Changes reported by DiffSimulatedActions.
+const (
+	SimulationAdded   = "added"
+	SimulationRemoved = "removed"
+	SimulationShifted = "shifted"
+)
+
+// SimulationDiff represents a single difference between the actions fired
+// on a given day by two simulations. Old is zero for an added action and
+// New is zero for a removed one.
+type SimulationDiff struct {
+	Date     datetime.CalendarDate
+	Change   string
+	Schedule string
+	Action   string
+	Old      time.Time
+	New      time.Time
+}
+
+func (sd SimulationDiff) when() time.Time {
+	if sd.Old.IsZero() {
+		return sd.New
+	}
+	return sd.Old
+}
+
+type simulationKey struct {
+	date     datetime.CalendarDate
+	schedule string
+	action   string
+}
+
+func groupSimulatedActions(actions []SimulatedAction) map[simulationKey][]time.Time {
+	grouped := map[simulationKey][]time.Time{}
+	for _, a := range actions {
+		k := simulationKey{date: datetime.CalendarDateFromTime(a.Due), schedule: a.Schedule, action: a.String()}
+		grouped[k] = append(grouped[k], a.Due)
+	}
+	return grouped
+}
+
+// DiffSimulatedActions compares the actions fired by two simulations, as
+// returned by SimulateActions, day by day. Occurrences of the same action
+// on the same day are paired in time order, a pair with differing times is
+// reported as shifted and any unpaired occurrences as added or removed.
+func DiffSimulatedActions(before, after []SimulatedAction) []SimulationDiff {
+	old, updated := groupSimulatedActions(before), groupSimulatedActions(after)
+	keys := map[simulationKey]struct{}{}
+	for k := range old {
+		keys[k] = struct{}{}
+	}
+	for k := range updated {
+		keys[k] = struct{}{}
+	}
+	var diffs []SimulationDiff
+	for k := range keys {
+		o, n := old[k], updated[k]
+		for i := range max(len(o), len(n)) {
+			d := SimulationDiff{Date: k.date, Schedule: k.schedule, Action: k.action}
+			switch {
+			case i >= len(o):
+				d.Change, d.New = SimulationAdded, n[i]
+			case i >= len(n):
+				d.Change, d.Old = SimulationRemoved, o[i]
+			case !o[i].Equal(n[i]):
+				d.Change, d.Old, d.New = SimulationShifted, o[i], n[i]
+			default:
+				continue
+			}
+			diffs = append(diffs, d)
+		}
+	}
+	slices.SortFunc(diffs, func(a, b SimulationDiff) int {
+		if c := a.when().Compare(b.when()); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Action, b.Action)
+	})
+	return diffs
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudeng.io/datetime"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+const simulateDiffSchedule = `
+schedules:
+  - name: lights
+    device: device
+    ranges:
+      - 01/02:01/03
+    actions:
+      on: 12:00
+      off: 18:00
+`
+
+func TestSimulateDiff(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	period := datetime.NewCalendarDateRange(
+		datetime.NewCalendarDate(2024, 1, 1),
+		datetime.NewCalendarDate(2024, 1, 5))
+
+	simulate := func(cfg string) []scheduler.SimulatedAction {
+		scheds, err := scheduler.ParseConfig(ctx, []byte(cfg), sys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actions, err := scheduler.SimulateActions(ctx, scheds, sys, period,
+			scheduler.WithLogger(slog.New(slog.NewJSONHandler(io.Discard, nil))),
+			scheduler.WithSimulationDelay(time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return actions
+	}
+
+	before := simulate(simulateDiffSchedule)
+	if got, want := len(before), 4; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if diffs := scheduler.DiffSimulatedActions(before, before); len(diffs) != 0 {
+		t.Errorf("unexpected differences: %v", diffs)
+	}
+
+	after := simulate(strings.ReplaceAll(simulateDiffSchedule, "off: 18:00", "off: 18:30"))
+	diffs := scheduler.DiffSimulatedActions(before, after)
+	if got, want := len(diffs), 2; got != want {
+		t.Fatalf("got %v, want %v: %v", got, want, diffs)
+	}
+	for i, d := range diffs {
+		day := 2 + i
+		if got, want := d.Date, datetime.NewCalendarDate(2024, 1, day); got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got, want := d.Change, scheduler.SimulationShifted; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got, want := d.Action, "lights:device.off()"; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got, want := d.Old, time.Date(2024, 1, day, 18, 0, 0, 0, time.Local); !got.Equal(want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got, want := d.New, time.Date(2024, 1, day, 18, 30, 0, 0, time.Local); !got.Equal(want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+
+	// Removing the off action and running for an extra day.
+	after = simulate(strings.ReplaceAll(strings.ReplaceAll(simulateDiffSchedule, "      off: 18:00\n", ""), "01/03", "01/04"))
+	var changes []string
+	for _, d := range scheduler.DiffSimulatedActions(before, after) {
+		changes = append(changes, d.Date.String()+" "+d.Change+" "+d.Action)
+	}
+	if got, want := strings.Join(changes, "\n"), `01/02/2024 removed lights:device.off()
+01/03/2024 removed lights:device.off()
+01/04/2024 added lights:device.on()`; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// The actions enumerated by a calendar are the same as those fired
+	// by a simulation.
+	scheds, err := scheduler.ParseConfig(ctx, []byte(simulateDiffSchedule), sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal, err := scheduler.NewCalendar(scheds, sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enumerated := cal.Actions(period)
+	if diffs := scheduler.DiffSimulatedActions(before, enumerated); len(diffs) != 0 || len(enumerated) != len(before) {
+		t.Errorf("unexpected differences: %v: %v", diffs, enumerated)
+	}
+	for _, d := range scheduler.DiffSimulatedActions(enumerated, after) {
+		if got, want := d.Schedule, "lights"; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsuccessful is returned, wrapped, for operations that completed
+// without error but whose returned data does not satisfy the action's
+// success_when predicate.
+var ErrUnsuccessful = errors.New("unsuccessful")
+
+// SuccessPredicate is a small expression evaluated over the data returned
+// by an operation to determine if it succeeded. It is a conjunction of
+// comparisons of the form:
+//
+//	<path> <op> <value> [&& <path> <op> <value>]...
+//
+// where path is a dot separated list of field names, or array indices,
+// within the returned data, with "." referring to the data itself, op is
+// one of ==, !=, <, <=, > or >= and value is a number, a quoted or bare
+// string, true, false or null. The returned data is compared as if it
+// had been JSON encoded.
+type SuccessPredicate struct {
+	text  string
+	terms []successTerm
+}
+
+type successTerm struct {
+	path  []string
+	op    string
+	value any
+}
+
+var successOps = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// ParseSuccessPredicate parses a SuccessPredicate, an empty string
+// results in a predicate that is always satisfied.
+func ParseSuccessPredicate(text string) (SuccessPredicate, error) {
+	text = strings.TrimSpace(text)
+	if len(text) == 0 {
+		return SuccessPredicate{}, nil
+	}
+	sp := SuccessPredicate{text: text}
+	for _, t := range strings.Split(text, "&&") {
+		term, err := parseSuccessTerm(strings.TrimSpace(t))
+		if err != nil {
+			return SuccessPredicate{}, fmt.Errorf("invalid success_when: %q: %v", text, err)
+		}
+		sp.terms = append(sp.terms, term)
+	}
+	return sp, nil
+}
+
+func parseSuccessTerm(t string) (successTerm, error) {
+	idx, op := -1, ""
+	for _, o := range successOps {
+		if i := strings.Index(t, o); i >= 0 && (idx < 0 || i < idx) {
+			idx, op = i, o
+		}
+	}
+	if idx < 0 {
+		return successTerm{}, fmt.Errorf("%q: missing comparison operator", t)
+	}
+	path := strings.TrimSpace(t[:idx])
+	value := strings.TrimSpace(t[idx+len(op):])
+	if len(path) == 0 || len(value) == 0 {
+		return successTerm{}, fmt.Errorf("%q: should be <path> %v <value>", t, op)
+	}
+	term := successTerm{op: op, value: parseSuccessValue(value)}
+	if path != "." {
+		term.path = strings.Split(path, ".")
+	}
+	return term, nil
+}
+
+func parseSuccessValue(v string) any {
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+		return v[1 : len(v)-1]
+	}
+	switch v {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f
+	}
+	return v
+}
+
+// IsSet returns true if the predicate is non-empty.
+func (sp SuccessPredicate) IsSet() bool {
+	return len(sp.terms) > 0
+}
+
+func (sp SuccessPredicate) String() string {
+	return sp.text
+}
+
+// Evaluate returns nil if the supplied data satisfies the predicate and
+// an error wrapping ErrUnsuccessful otherwise.
+func (sp SuccessPredicate) Evaluate(data any) error {
+	if !sp.IsSet() {
+		return nil
+	}
+	buf, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("%w: %v: failed to encode data: %v", ErrUnsuccessful, sp.text, err)
+	}
+	var generic any
+	if err := json.Unmarshal(buf, &generic); err != nil {
+		return fmt.Errorf("%w: %v: failed to decode data: %v", ErrUnsuccessful, sp.text, err)
+	}
+	for _, term := range sp.terms {
+		got, ok := lookupPath(generic, term.path)
+		if !ok {
+			return fmt.Errorf("%w: %v: %v not found", ErrUnsuccessful, sp.text, strings.Join(term.path, "."))
+		}
+		if !term.satisfied(got) {
+			return fmt.Errorf("%w: %v: got %v", ErrUnsuccessful, sp.text, string(mustJSON(got)))
+		}
+	}
+	return nil
+}
+
+func mustJSON(v any) []byte {
+	buf, _ := json.Marshal(v)
+	return buf
+}
+
+func lookupPath(v any, path []string) (any, bool) {
+	for _, p := range path {
+		switch c := v.(type) {
+		case map[string]any:
+			n, ok := c[p]
+			if !ok {
+				return nil, false
+			}
+			v = n
+		case []any:
+			i, err := strconv.Atoi(p)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, false
+			}
+			v = c[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// compareValues compares a and b, returning false if they cannot be
+// compared. Strings are compared to numbers numerically if they
+// contain a valid number.
+func compareValues(a, b any) (int, bool) {
+	if as, ok := a.(string); ok {
+		if _, ok := b.(float64); ok {
+			f, err := strconv.ParseFloat(as, 64)
+			if err != nil {
+				return 0, false
+			}
+			a = f
+		}
+	}
+	switch av := a.(type) {
+	case float64:
+		if bv, ok := b.(float64); ok {
+			return cmp.Compare(av, bv), true
+		}
+	case string:
+		if bv, ok := b.(string); ok {
+			return strings.Compare(av, bv), true
+		}
+	case bool:
+		if bv, ok := b.(bool); ok && av == bv {
+			return 0, true
+		}
+	case nil:
+		if b == nil {
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+func (t successTerm) satisfied(got any) bool {
+	c, ok := compareValues(got, t.value)
+	switch t.op {
+	case "==":
+		return ok && c == 0
+	case "!=":
+		return !ok || c != 0
+	}
+	if _, isBool := got.(bool); !ok || isBool || got == nil {
+		return false
+	}
+	switch t.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
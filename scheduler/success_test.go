@@ -0,0 +1,149 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/internal/logging"
+	"github.com/cosnicolaou/automation/internal/testutil"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+func TestSuccessPredicate(t *testing.T) {
+	type status struct {
+		Status string `json:"status"`
+		Code   int    `json:"code"`
+		Zones  []bool `json:"zones"`
+	}
+	data := status{Status: "ok", Code: 3, Zones: []bool{true, false}}
+	for _, tc := range []struct {
+		expr string
+		data any
+		ok   bool
+	}{
+		{"", data, true},
+		{"status == ok", data, true},
+		{`status == "ok" && code < 4`, data, true},
+		{"status != ok", data, false},
+		{"code >= 3 && code <= 3", data, true},
+		{"code > 3", data, false},
+		{"zones.0 == true", data, true},
+		{"zones.1 == true", data, false},
+		{"zones.2 == true", data, false},
+		{"missing == 1", data, false},
+		{". == 200", "200", true},
+		{". == 200", 200, true},
+		{". < 100", "abc", false},
+		{". == null", nil, true},
+	} {
+		sp, err := scheduler.ParseSuccessPredicate(tc.expr)
+		if err != nil {
+			t.Errorf("%q: %v", tc.expr, err)
+			continue
+		}
+		err = sp.Evaluate(tc.data)
+		if got, want := err == nil, tc.ok; got != want {
+			t.Errorf("%q: %v: got %v, want %v: %v", tc.expr, tc.data, got, want, err)
+		}
+		if err != nil && !errors.Is(err, scheduler.ErrUnsuccessful) {
+			t.Errorf("%q: unexpected error: %v", tc.expr, err)
+		}
+	}
+
+	for _, expr := range []string{"status", "== ok", "status == ", "a == 1 && b"} {
+		if _, err := scheduler.ParseSuccessPredicate(expr); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+}
+
+// statusDevice has operations that complete without error but return
+// a status payload.
+type statusDevice struct {
+	*testutil.MockDevice
+}
+
+func (sd *statusDevice) Operations() map[string]devices.Operation {
+	status := func(s string) devices.Operation {
+		return func(context.Context, devices.OperationArgs) (any, error) {
+			return map[string]any{"status": s}, nil
+		}
+	}
+	return map[string]devices.Operation{
+		"on":  status("ok"),
+		"off": status("error"),
+	}
+}
+
+func TestSuccessWhen(t *testing.T) {
+	ctx := context.Background()
+	sys, err := devices.ParseSystemConfig(ctx, []byte(`
+time_location: Local
+devices:
+  - name: status
+    type: status
+    operations:
+      on:
+      off:
+`), devices.WithDevices(devices.SupportedDevices{
+		"status": func(string, devices.Options) (devices.Device, error) {
+			return &statusDevice{MockDevice: testutil.NewMockDevice()}, nil
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sched := parseSchedule(t, sys, `
+schedules:
+  - name: checked
+    device: status
+    ranges:
+      - 01/02:01/02
+    actions_detailed:
+      - action: on
+        when: 12:00
+        success_when: status == ok
+      - action: off
+        when: 13:00
+        success_when: status == ok
+`)
+	_, logRecorder := runScheduleForYear(ctx, t, sys, sched, 2024)
+	msgs, errs := map[string]string{}, map[string]error{}
+	for _, l := range logRecorder.Logs(t) {
+		if len(l.Op) > 0 {
+			msgs[l.Op], errs[l.Op] = l.Msg, l.Err
+		}
+	}
+	if got, want := msgs["on"], logging.LogCompleted; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err := errs["on"]; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got, want := msgs["off"], logging.LogFailed; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err := errs["off"]; err == nil || !strings.Contains(err.Error(), `unsuccessful: status == ok: got "error"`) {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+
+	_, err = scheduler.ParseConfig(ctx, []byte(`
+schedules:
+  - name: invalid
+    device: status
+    actions_detailed:
+      - action: on
+        when: 12:00
+        success_when: status
+`), sys)
+	if err == nil || !strings.Contains(err.Error(), "invalid success_when") {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+)
+
+// TracerProvider provides Tracers and is modeled on the OpenTelemetry
+// trace.TracerProvider interface so that a thin adapter is all that
+// is required to emit OpenTelemetry spans without making OpenTelemetry
+// a dependency of this package.
+type TracerProvider interface {
+	Tracer(name string) Tracer
+}
+
+// Tracer creates spans. The returned context must contain the new span
+// so that spans created using it are children of that span.
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, Span)
+}
+
+// Span represents a single traced operation.
+type Span interface {
+	SetAttributes(attrs ...slog.Attr)
+	// RecordError records an error and marks the span as having failed.
+	RecordError(err error)
+	End()
+}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ string, _ ...slog.Attr) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...slog.Attr) {}
+func (noopSpan) RecordError(error)          {}
+func (noopSpan) End()                       {}
+
+// WithTracerProvider sets the TracerProvider used to create a span for
+// every action executed, with child spans for every attempt (ie. retry)
+// and precondition evaluation.
+func WithTracerProvider(tp TracerProvider) Option {
+	return func(o *options) {
+		o.tracer = tp.Tracer("github.com/cosnicolaou/automation/scheduler")
+	}
+}
+
+func endSpan(span Span, status string, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.SetAttributes(slog.String("status", status))
+	span.End()
+}
+
+func spanStatus(aborted bool, err error) string {
+	switch {
+	case err != nil:
+		return "failed"
+	case aborted:
+		return "aborted"
+	}
+	return "completed"
+}
@@ -0,0 +1,161 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+type spanKey struct{}
+
+type recordedSpan struct {
+	sync.Mutex
+	name   string
+	parent *recordedSpan
+	attrs  map[string]string
+	err    error
+	ended  bool
+}
+
+func (rs *recordedSpan) SetAttributes(attrs ...slog.Attr) {
+	rs.Lock()
+	defer rs.Unlock()
+	for _, a := range attrs {
+		rs.attrs[a.Key] = a.Value.String()
+	}
+}
+
+func (rs *recordedSpan) RecordError(err error) {
+	rs.Lock()
+	defer rs.Unlock()
+	rs.err = err
+}
+
+func (rs *recordedSpan) End() {
+	rs.Lock()
+	defer rs.Unlock()
+	rs.ended = true
+}
+
+// path returns the names of the span and its ancestors, root first.
+func (rs *recordedSpan) path() string {
+	if rs.parent == nil {
+		return rs.name
+	}
+	return rs.parent.path() + "/" + rs.name
+}
+
+type recordingTracer struct {
+	sync.Mutex
+	spans []*recordedSpan
+}
+
+func (rt *recordingTracer) Tracer(string) scheduler.Tracer {
+	return rt
+}
+
+func (rt *recordingTracer) Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, scheduler.Span) {
+	parent, _ := ctx.Value(spanKey{}).(*recordedSpan)
+	span := &recordedSpan{name: name, parent: parent, attrs: map[string]string{}}
+	span.SetAttributes(attrs...)
+	rt.Lock()
+	rt.spans = append(rt.spans, span)
+	rt.Unlock()
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (rt *recordingTracer) paths() []string {
+	rt.Lock()
+	defer rt.Unlock()
+	var p []string
+	for _, s := range rt.spans {
+		p = append(p, s.path())
+	}
+	return p
+}
+
+const tracedSchedules = `
+schedules:
+  - name: traced
+    device: device
+    ranges:
+      - 01/02:01/02
+    actions_detailed:
+      - action: on
+        when: 12:00
+        precondition:
+          device: device
+          op: weather
+  - name: traced-slow
+    device: slow
+    ranges:
+      - 01/02:01/02
+    actions:
+      on: 12:00
+`
+
+func TestTracing(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	scheds, err := scheduler.ParseConfig(ctx, []byte(tracedSchedules), sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracer := &recordingTracer{}
+	runScheduleForYear(ctx, t, sys, lookupSchedule(t, scheds, "traced"), 2024, scheduler.WithTracerProvider(tracer))
+
+	if got, want := strings.Join(tracer.paths(), " "), "action action/attempt action/attempt/precondition"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	action := tracer.spans[0]
+	for k, v := range map[string]string{
+		"schedule": "traced",
+		"device":   "device",
+		"op":       "on",
+		"status":   "completed",
+	} {
+		if got, want := action.attrs[k], v; got != want {
+			t.Errorf("%v: got %v, want %v", k, got, want)
+		}
+	}
+	if got, want := tracer.spans[2].attrs["result"], "true"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, s := range tracer.spans {
+		if !s.ended {
+			t.Errorf("span %v was not ended", s.path())
+		}
+	}
+
+	// Retries result in multiple attempt spans.
+	slow := sys.Devices["slow"]
+	cfg := slow.Config()
+	cfg.Retries = 1
+	slow.SetConfig(cfg)
+
+	tracer = &recordingTracer{}
+	runScheduleForYear(ctx, t, sys, lookupSchedule(t, scheds, "traced-slow"), 2024, scheduler.WithTracerProvider(tracer))
+	if got, want := strings.Join(tracer.paths(), " "), "action action/attempt action/attempt"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, s := range tracer.spans {
+		if got, want := s.attrs["status"], "failed"; got != want {
+			t.Errorf("%v: got %v, want %v", s.path(), got, want)
+		}
+		if s.err == nil {
+			t.Errorf("%v: missing error", s.path())
+		}
+	}
+	if got, want := tracer.spans[2].attrs["attempt"], "1"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+
+	"cloudeng.io/datetime/schedule"
+)
+
+// Kinds of reference to a device reported by Uses.
+const (
+	UseOperation     = "operation"     // The device is the target of an action.
+	UsePrecondition  = "precondition"  // The device is used by an action's precondition.
+	UsePostcondition = "postcondition" // The device is used by an action's postcondition.
+	UseDayCondition  = "day-condition" // The device is used by a schedule's day condition.
+)
+
+// DeviceUse represents a single reference to a device by a schedule.
+// Action is the operation, and Due its time of day, of the action that
+// refers to the device, both are empty for day conditions. Name is the
+// operation or condition that is used.
+type DeviceUse struct {
+	Schedule string   `json:"schedule"`
+	Kind     string   `json:"kind"`
+	Action   string   `json:"action,omitempty"`
+	Due      string   `json:"due,omitempty"`
+	Name     string   `json:"name"`
+	Args     []string `json:"args,omitempty"`
+}
+
+func formatDue(a schedule.ActionSpec[Action]) string {
+	if a.Dynamic.Due == nil {
+		return a.Due.String()
+	}
+	if a.Dynamic.Offset != 0 {
+		return fmt.Sprintf("%v%+v", a.Dynamic.Due.Name(), a.Dynamic.Offset)
+	}
+	return a.Dynamic.Due.Name()
+}
+
+// Uses returns every reference to the named device, as the target of an
+// action, by an action's precondition or by a schedule's day condition,
+// in the order in which they appear in the schedules.
+func (s Schedules) Uses(device string) []DeviceUse {
+	if len(device) == 0 {
+		return nil
+	}
+	var uses []DeviceUse
+	for _, sched := range s.Schedules {
+		if dc := sched.DayCondition; dc.Device == device {
+			uses = append(uses, DeviceUse{
+				Schedule: sched.Name,
+				Kind:     UseDayCondition,
+				Name:     dc.Name,
+				Args:     dc.Args,
+			})
+		}
+		for _, a := range sched.DailyActions {
+			due := formatDue(a)
+			if a.T.DeviceName == device {
+				uses = append(uses, DeviceUse{
+					Schedule: sched.Name,
+					Kind:     UseOperation,
+					Action:   a.Name,
+					Due:      due,
+					Name:     a.T.Name,
+					Args:     a.T.Args,
+				})
+			}
+			pres := []Precondition{a.T.Precondition}
+			if conds := a.T.Precondition.Conditions; len(conds) > 0 {
+				pres = conds
+			}
+			for _, pre := range pres {
+				if pre.Device != device {
+					continue
+				}
+				uses = append(uses, DeviceUse{
+					Schedule: sched.Name,
+					Kind:     UsePrecondition,
+					Action:   a.Name,
+					Due:      due,
+					Name:     pre.Name,
+					Args:     pre.Args,
+				})
+			}
+			if post := a.T.Postcondition; post.Condition != nil && post.Device == device {
+				uses = append(uses, DeviceUse{
+					Schedule: sched.Name,
+					Kind:     UsePostcondition,
+					Action:   a.Name,
+					Due:      due,
+					Name:     post.Name,
+					Args:     post.Args,
+				})
+			}
+		}
+	}
+	return uses
+}
+
+func (u DeviceUse) String() string {
+	name := u.Name
+	if len(u.Args) > 0 {
+		name = fmt.Sprintf("%v(%v)", u.Name, strings.Join(u.Args, ", "))
+	}
+	switch u.Kind {
+	case UseOperation:
+		return fmt.Sprintf("%v: %v: %v at %v", u.Schedule, u.Kind, name, u.Due)
+	case UsePrecondition, UsePostcondition:
+		return fmt.Sprintf("%v: %v: %v for %v at %v", u.Schedule, u.Kind, name, u.Action, u.Due)
+	}
+	return fmt.Sprintf("%v: %v: %v", u.Schedule, u.Kind, name)
+}
@@ -0,0 +1,73 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+const usesSchedules = `
+schedules:
+  - name: lights
+    device: slow
+    ranges:
+      - 01/02:01/02
+    actions_detailed:
+      - action: on
+        when: 12:00
+        precondition:
+          device: device
+          op: weather
+          args: ["sunny"]
+  - name: garden
+    device: device
+    ranges:
+      - 01/02:01/02
+    actions_detailed:
+      - action: off
+        when: sunset
+        args: ["all"]
+  - name: unrelated
+    device: slow
+    ranges:
+      - 01/02:01/02
+    actions:
+      on: 13:00
+`
+
+func TestUses(t *testing.T) {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	scheds, err := scheduler.ParseConfig(ctx, []byte(usesSchedules), sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uses := scheds.Uses("device")
+	var lines []string
+	for _, u := range uses {
+		lines = append(lines, u.String())
+	}
+	if got, want := strings.Join(lines, "\n"), `lights: precondition: weather(sunny) for on at 12:00:00
+garden: operation: off(all) at Sunset`; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := uses[0].Kind, scheduler.UsePrecondition; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := uses[1].Kind, scheduler.UseOperation; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got, want := len(scheds.Uses("slow")), 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := scheds.Uses("hanging"); len(got) != 0 {
+		t.Errorf("unexpected uses: %v", got)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"cloudeng.io/datetime"
+	"cloudeng.io/datetime/schedule"
+	"github.com/cosnicolaou/automation/devices"
+)
+
+// vacationConfig represents a 'vacation:' schedule that simulates
+// occupancy by turning each of a group of devices on, and then off,
+// at random times within a window each day.
+type vacationConfig struct {
+	Devices []string `yaml:"devices" cmd:"the devices to be turned on and off"`
+	Window  string   `yaml:"window" cmd:"the time window, <from>-<to>, within which the devices are turned on and off each day, eg. 18:00-23:30"`
+	Count   int      `yaml:"count" cmd:"the number of times that each device is turned on, and then off, each day"`
+	Seed    int64    `yaml:"seed" cmd:"seed for the random times, the same seed always results in the same times"`
+	On      string   `yaml:"on" cmd:"the operation used to turn a device on, defaults to on"`
+	Off     string   `yaml:"off" cmd:"the operation used to turn a device off, defaults to off"`
+}
+
+// vacationTime is a DynamicTimeOfDay that evaluates to a random, but
+// deterministic for a given seed, device and date, time within a slot of
+// a vacation schedule's window. The on time is within the first half of
+// the slot and the off time within the second half so that a device is
+// always turned on before it is turned off and the slots never overlap.
+type vacationTime struct {
+	seed    int64
+	device  string
+	slot    int
+	off     bool
+	start   datetime.TimeOfDay
+	slotLen time.Duration
+}
+
+func (vt vacationTime) Name() string {
+	return "vacation"
+}
+
+func (vt vacationTime) Evaluate(cd datetime.CalendarDate, _ datetime.Place) datetime.TimeOfDay {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%v/%v/%v", vt.device, vt.slot, cd)
+	rng := rand.New(rand.NewPCG(uint64(vt.seed), h.Sum64()))
+	half := int64(vt.slotLen/2) / int64(time.Second)
+	on := time.Duration(rng.Int64N(half)) * time.Second
+	off := time.Duration(half+rng.Int64N(half)) * time.Second
+	start := vt.start.Add(time.Duration(vt.slot) * vt.slotLen)
+	if vt.off {
+		return start.Add(off)
+	}
+	return start.Add(on)
+}
+
+func parseWindow(window string) (from, to datetime.TimeOfDay, err error) {
+	f, t, ok := strings.Cut(window, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid window: %q, should be <from>-<to>", window)
+	}
+	if from, err = ParseTimeOfDay(f); err != nil {
+		return 0, 0, fmt.Errorf("invalid window: %q: %v", window, err)
+	}
+	if to, err = ParseTimeOfDay(t); err != nil {
+		return 0, 0, fmt.Errorf("invalid window: %q: %v", window, err)
+	}
+	if to <= from {
+		return 0, 0, fmt.Errorf("invalid window: %q, the end must be after the start", window)
+	}
+	return from, to, nil
+}
+
+func (cfg schedulesConfig) createVacationActions(sys devices.System, line int, scheduleName string, vc vacationConfig) (schedule.ActionSpecs[Action], error) {
+	from, to, err := parseWindow(vc.Window)
+	if err != nil {
+		return nil, cfg.errorf(line, "schedule %q: %v", scheduleName, err)
+	}
+	if len(vc.Devices) == 0 {
+		return nil, cfg.errorf(line, "schedule %q: vacation requires at least one device", scheduleName)
+	}
+	if vc.Count <= 0 {
+		return nil, cfg.errorf(line, "schedule %q: vacation count must be greater than zero", scheduleName)
+	}
+	slotLen := (to.Duration() - from.Duration()) / time.Duration(vc.Count)
+	if slotLen < 2*time.Second {
+		return nil, cfg.errorf(line, "schedule %q: vacation window %q is too short for a count of %v", scheduleName, vc.Window, vc.Count)
+	}
+	onOp, offOp := vc.On, vc.Off
+	if len(onOp) == 0 {
+		onOp = "on"
+	}
+	if len(offOp) == 0 {
+		offOp = "off"
+	}
+	actions := schedule.ActionSpecs[Action]{}
+	for _, dev := range vc.Devices {
+		if _, _, ok := sys.DeviceConfigs(dev); !ok {
+			return nil, cfg.errorf(line, "unknown device: %s for schedule %q", dev, scheduleName)
+		}
+		for _, op := range []string{onOp, offOp} {
+			if _, _, ok := sys.DeviceOp(dev, op); !ok {
+				return nil, cfg.errorf(line, "unknown operation: %q for device: %q for schedule %q", op, dev, scheduleName)
+			}
+		}
+		for slot := range vc.Count {
+			for _, op := range []string{onOp, offOp} {
+				actions = append(actions, schedule.ActionSpec[Action]{
+					Name: op,
+					Dynamic: schedule.DynamicTimeOfDaySpec{
+						Due: vacationTime{
+							seed:    vc.Seed,
+							device:  dev,
+							slot:    slot,
+							off:     op == offOp,
+							start:   from,
+							slotLen: slotLen,
+						},
+					},
+					T: Action{
+						Action: devices.Action{
+							DeviceName: dev,
+							Name:       op,
+						},
+					},
+				})
+			}
+		}
+	}
+	return actions, nil
+}
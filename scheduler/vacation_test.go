@@ -0,0 +1,110 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudeng.io/datetime"
+	"github.com/cosnicolaou/automation/scheduler"
+)
+
+const vacationSchedule = `
+schedules:
+  - name: away
+    ranges:
+      - 07/01:07/10
+    vacation:
+      devices: [device]
+      window: 18:00-23:00
+      count: 3
+      seed: 42
+`
+
+func vacationTimes(t *testing.T, cfg string, days int) [][]time.Time {
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	scheds, err := scheduler.ParseConfig(ctx, []byte(cfg), sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal, err := scheduler.NewCalendar(scheds, sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var times [][]time.Time
+	for d := range days {
+		day := datetime.NewCalendarDate(2025, 7, 1+d)
+		var perDay []time.Time
+		ops := []string{}
+		for _, e := range cal.Scheduled(day) {
+			perDay = append(perDay, e.When)
+			ops = append(ops, e.T.Name)
+		}
+		// Each on is followed by its off since the slots do not overlap.
+		if got, want := strings.Join(ops, " "), "on off on off on off"; got != want {
+			t.Errorf("%v: got %v, want %v", day, got, want)
+		}
+		times = append(times, perDay)
+	}
+	return times
+}
+
+func TestVacation(t *testing.T) {
+	times := vacationTimes(t, vacationSchedule, 5)
+	for _, perDay := range times {
+		if got, want := len(perDay), 6; got != want {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		for _, when := range perDay {
+			tod := datetime.NewTimeOfDay(when.Hour(), when.Minute(), when.Second())
+			if tod < datetime.NewTimeOfDay(18, 0, 0) || tod >= datetime.NewTimeOfDay(23, 0, 0) {
+				t.Errorf("%v: outside of the window", when)
+			}
+		}
+	}
+
+	// The times vary from day to day.
+	for i := 1; i < len(times); i++ {
+		same := true
+		for j := range times[i] {
+			if times[i][j].Hour() != times[0][j].Hour() || times[i][j].Minute() != times[0][j].Minute() {
+				same = false
+			}
+		}
+		if same {
+			t.Errorf("day %v has the same times as the first day: %v", i, times[i])
+		}
+	}
+
+	// The same seed always results in the same times, a different one
+	// in different times.
+	if got, want := vacationTimes(t, vacationSchedule, 1)[0], times[0]; !slices.EqualFunc(got, want, time.Time.Equal) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := vacationTimes(t, strings.Replace(vacationSchedule, "seed: 42", "seed: 7", 1), 1)[0], times[0]; slices.EqualFunc(got, want, time.Time.Equal) {
+		t.Errorf("different seeds resulted in the same times: %v", got)
+	}
+
+	ctx := context.Background()
+	sys := createSystem(t, "Local")
+	for _, tc := range []struct {
+		from, to, err string
+	}{
+		{"window: 18:00-23:00", "window: 23:00-18:00", "the end must be after the start"},
+		{"count: 3", "count: 0", "count must be greater than zero"},
+		{"devices: [device]", "devices: [unknown]", "unknown device: unknown"},
+		{"seed: 42", "seed: 42\n      on: unknown", `unknown operation: "unknown"`},
+	} {
+		_, err := scheduler.ParseConfig(ctx, []byte(strings.Replace(vacationSchedule, tc.from, tc.to, 1)), sys)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%v: missing or unexpected error: %v", tc.to, err)
+		}
+	}
+}